@@ -0,0 +1,195 @@
+// dashboard-agent collects host metrics locally and pushes them to the
+// dashboard's ingest endpoint, so monitored hosts need no inbound SSH.
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"server-dashboard/internal/agent"
+	"server-dashboard/internal/models"
+)
+
+// Version is set via ldflags during build
+var Version = "dev"
+
+func main() {
+	dashboardURL := flag.String("url", "", "Dashboard base URL, e.g. https://dashboard.example.com:8080")
+	deviceID := flag.String("device", "", "Device ID this host is configured as in the dashboard")
+	tokenFile := flag.String("token-file", "", "File holding the device's agent_token (default: DASHBOARD_AGENT_TOKEN env var)")
+	interval := flag.Duration("interval", 30*time.Second, "Push interval")
+	caFile := flag.String("ca-file", "", "PEM CA bundle for verifying the dashboard's certificate")
+	insecure := flag.Bool("insecure-skip-verify", false, "Do not verify the dashboard's certificate (testing only)")
+	allowHTTP := flag.Bool("allow-http", false, "Allow a plain http:// dashboard URL (testing only)")
+	once := flag.Bool("once", false, "Collect once, print the report as JSON and exit")
+	flag.Parse()
+
+	hostname, _ := os.Hostname()
+	if *once {
+		report, err := collectReport(*deviceID, hostname)
+		if err != nil {
+			log.Fatalf("Collection failed: %v", err)
+		}
+		out, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+
+	if *dashboardURL == "" || *deviceID == "" {
+		fmt.Fprintln(os.Stderr, "dashboard-agent: -url and -device are required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	endpoint, err := ingestURL(*dashboardURL, *allowHTTP)
+	if err != nil {
+		log.Fatalf("Invalid -url: %v", err)
+	}
+	token, err := readToken(*tokenFile)
+	if err != nil {
+		log.Fatalf("Agent token: %v", err)
+	}
+	client, err := newHTTPClient(*caFile, *insecure)
+	if err != nil {
+		log.Fatalf("TLS setup: %v", err)
+	}
+
+	log.Printf("dashboard-agent %s pushing %s to %s every %s", Version, *deviceID, endpoint, *interval)
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := collectReport(*deviceID, hostname)
+		if err != nil {
+			log.Printf("Collection failed: %v", err)
+		} else if err := push(client, endpoint, token, report); err != nil {
+			log.Printf("Push failed: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case sig := <-sigChan:
+			log.Printf("Received signal: %v, exiting", sig)
+			return
+		}
+	}
+}
+
+// collectReport gathers one sample for the device
+func collectReport(deviceID, hostname string) (*models.AgentReport, error) {
+	metrics, errs, err := agent.Collect()
+	if err != nil {
+		return nil, err
+	}
+	return &models.AgentReport{
+		DeviceID:     deviceID,
+		Hostname:     hostname,
+		AgentVersion: Version,
+		CollectedAt:  time.Now().UTC(),
+		Metrics:      *metrics,
+		MetricErrors: errs,
+	}, nil
+}
+
+// ingestURL derives the ingest endpoint from the dashboard's base URL
+func ingestURL(base string, allowHTTP bool) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case u.Scheme == "https":
+	case u.Scheme == "http" && allowHTTP:
+	case u.Scheme == "http":
+		return "", fmt.Errorf("%s is not HTTPS; pass -allow-http to push in plain text", base)
+	default:
+		return "", fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/api/ingest"
+	return u.String(), nil
+}
+
+// readToken reads the bearer token from a file or DASHBOARD_AGENT_TOKEN
+func readToken(path string) (string, error) {
+	if path == "" {
+		token := strings.TrimSpace(os.Getenv("DASHBOARD_AGENT_TOKEN"))
+		if token == "" {
+			return "", fmt.Errorf("set DASHBOARD_AGENT_TOKEN or pass -token-file")
+		}
+		return token, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return token, nil
+}
+
+func newHTTPClient(caFile string, insecure bool) (*http.Client, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: insecure}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport, Timeout: 15 * time.Second}, nil
+}
+
+// push sends one report, returning the dashboard's message on rejection
+func push(client *http.Client, endpoint, token string, report *models.AgentReport) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "dashboard-agent/"+Version)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusAccepted {
+		return nil
+	}
+	var ack struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if json.Unmarshal(data, &ack) == nil && ack.Message != "" {
+		return fmt.Errorf("%s: %s", resp.Status, ack.Message)
+	}
+	return fmt.Errorf("%s", resp.Status)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/handlers"
+	"server-dashboard/internal/models"
+	"server-dashboard/internal/services"
+)
+
+func TestIngestURL(t *testing.T) {
+	tests := []struct {
+		base      string
+		allowHTTP bool
+		want      string // Empty for an error
+	}{
+		{"https://dashboard.example.com:8080", false, "https://dashboard.example.com:8080/api/ingest"},
+		{"https://example.com/dashboard/", false, "https://example.com/dashboard/api/ingest"},
+		{"http://dashboard.example.com", false, ""},
+		{"http://dashboard.example.com", true, "http://dashboard.example.com/api/ingest"},
+		{"ftp://dashboard.example.com", true, ""},
+	}
+	for _, tt := range tests {
+		got, err := ingestURL(tt.base, tt.allowHTTP)
+		if got != tt.want || (err != nil) != (tt.want == "") {
+			t.Errorf("ingestURL(%q, %v) = %q, %v", tt.base, tt.allowHTTP, got, err)
+		}
+	}
+}
+
+func TestPush(t *testing.T) {
+	cfg := &config.Config{Servers: []config.ServerConfig{{ID: "web1", AgentToken: "web1-token"}}}
+	old := services.Config
+	services.Config = cfg
+	defer func() { services.Config = old }()
+
+	srv := httptest.NewTLSServer(handlers.IngestHandler(cfg))
+	defer srv.Close()
+	endpoint, err := ingestURL(srv.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := &models.AgentReport{DeviceID: "web1", Metrics: models.HostMetrics{KernelVersion: "6.1.0"}}
+
+	if err := push(srv.Client(), endpoint, "web1-token", report); err != nil {
+		t.Fatal(err)
+	}
+	err = push(srv.Client(), endpoint, "wrong", report)
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "invalid token") {
+		t.Errorf("err = %v, want the dashboard's rejection", err)
+	}
+	// Without the CA the dashboard's certificate is not trusted
+	client, err := newHTTPClient("", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := push(client, endpoint, "web1-token", report); err == nil {
+		t.Error("pushed to an untrusted certificate")
+	}
+}
@@ -0,0 +1,21 @@
+// Package agent collects host metrics locally for dashboard-agent, the
+// push-based alternative to SSH polling.
+package agent
+
+import (
+	"fmt"
+
+	"server-dashboard/internal/models"
+)
+
+// collection accumulates one sample and the metrics that failed
+type collection struct {
+	m    *models.HostMetrics
+	errs []string
+}
+
+// fail records a metric that could not be read, in the same
+// "section.field: problem" form the SSH collector reports
+func (c *collection) fail(section, field string, err error) {
+	c.errs = append(c.errs, fmt.Sprintf("%s.%s: %v", section, field, err))
+}
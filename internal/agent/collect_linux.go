@@ -0,0 +1,480 @@
+//go:build linux
+
+package agent
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"server-dashboard/internal/models"
+)
+
+// procRoot and sysRoot are where procfs and sysfs are mounted
+const (
+	procRoot = "/proc"
+	sysRoot  = "/sys"
+)
+
+// statfs is syscall.Statfs, replaced in tests
+var statfs = syscall.Statfs
+
+// Collect gathers one sample from /proc, statfs and systemctl. Metrics that
+// cannot be read are returned as errors alongside the rest of the sample.
+func Collect() (*models.HostMetrics, []string, error) {
+	c := &collection{m: &models.HostMetrics{Uptime: "N/A", FullPartitions: []string{}}}
+	c.system()
+	c.cpu()
+	c.pressure()
+	c.memory()
+	c.filesystems(procRoot + "/mounts")
+	c.network()
+	c.services()
+	return c.m, c.errs, nil
+}
+
+func (c *collection) system() {
+	if data, err := os.ReadFile(procRoot + "/sys/kernel/osrelease"); err != nil {
+		c.fail("system", "kernel", err)
+	} else {
+		c.m.KernelVersion = strings.TrimSpace(string(data))
+	}
+
+	if data, err := os.ReadFile(procRoot + "/uptime"); err != nil {
+		c.fail("system", "uptime_seconds", err)
+	} else if fields := strings.Fields(string(data)); len(fields) == 0 {
+		c.fail("system", "uptime_seconds", errors.New("empty /proc/uptime"))
+	} else if secs, err := strconv.ParseFloat(fields[0], 64); err != nil {
+		c.fail("system", "uptime_seconds", err)
+	} else {
+		c.m.Uptime = models.FormatUptime(int64(secs))
+	}
+
+	if entries, err := os.ReadDir(procRoot); err != nil {
+		c.fail("system", "processes", err)
+	} else {
+		for _, e := range entries {
+			if _, err := strconv.Atoi(e.Name()); err == nil && e.IsDir() {
+				c.m.Processes++
+			}
+		}
+	}
+
+	if data, err := os.ReadFile(procRoot + "/loadavg"); err != nil {
+		c.fail("system", "load", err)
+	} else if fields := strings.Fields(string(data)); len(fields) < 3 {
+		c.fail("system", "load", fmt.Errorf("expected three values: %q", string(data)))
+	} else {
+		c.m.LoadAverage = strings.Join(fields[:3], " ")
+	}
+}
+
+// userHZ is the clock tick /proc/stat counts in. Go cannot ask sysconf
+// without cgo, but the kernel fixes it at 100 on every mainstream
+// architecture.
+const userHZ = 100
+
+// cpu reads the cumulative CPU times and the number of logical CPUs from
+// /proc/stat; the dashboard turns the times into usage between samples
+func (c *collection) cpu() {
+	f, err := os.Open(procRoot + "/stat")
+	if err != nil {
+		c.fail("cpu", "times", err)
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch {
+		case len(fields) == 0:
+		case fields[0] == "cpu":
+			// user nice system idle iowait irq softirq steal, the last three
+			// missing on very old kernels
+			var n [8]float64
+			for i := 0; i < len(n) && i+1 < len(fields); i++ {
+				v, err := strconv.ParseUint(fields[i+1], 10, 64)
+				if err != nil {
+					c.fail("cpu", "times", fmt.Errorf("not an integer: %q", fields[i+1]))
+					return
+				}
+				n[i] = float64(v) / userHZ
+			}
+			c.m.CPUTimes = &models.CPUTimes{
+				User: n[0], Nice: n[1], System: n[2], Idle: n[3],
+				IOWait: n[4], IRQ: n[5], SoftIRQ: n[6], Steal: n[7],
+			}
+		case strings.HasPrefix(fields[0], "cpu"):
+			c.m.CPUCores++
+		}
+	}
+	if c.m.CPUTimes == nil {
+		c.fail("cpu", "times", errors.New("missing"))
+	}
+}
+
+// pressure reads pressure stall information, which kernels before 4.20 or
+// booted with psi=0 do not have
+func (c *collection) pressure() {
+	for _, resource := range models.PressureResources {
+		data, err := os.ReadFile(procRoot + "/pressure/" + resource)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			kind, stall, ok := models.ParsePressureStall(line)
+			if !ok {
+				continue
+			}
+			if c.m.Pressure == nil {
+				c.m.Pressure = &models.Pressure{}
+			}
+			c.m.Pressure.SetStall(resource, kind, stall)
+		}
+	}
+}
+
+func (c *collection) memory() {
+	f, err := os.Open(procRoot + "/meminfo")
+	if err != nil {
+		c.fail("memory", "total_kb", err)
+		return
+	}
+	defer f.Close()
+
+	values := make(map[string]int64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// e.g. "MemAvailable:   12345678 kB"
+		key, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		if fields := strings.Fields(rest); len(fields) > 0 {
+			if n, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+				values[key] = n
+			}
+		}
+	}
+	total, ok := values["MemTotal"]
+	if !ok {
+		c.fail("memory", "total_kb", errors.New("missing"))
+		return
+	}
+	available, ok := values["MemAvailable"]
+	if !ok {
+		c.fail("memory", "available_kb", errors.New("missing"))
+		return
+	}
+	c.m.MemoryTotal = float64(total) / 1024
+	if available <= total {
+		c.m.MemoryUsed = float64(total-available) / 1024
+	}
+}
+
+// pseudoFilesystems are mounts df leaves out; they either report no blocks
+// or mirror another filesystem
+var pseudoFilesystems = map[string]bool{
+	"autofs": true, "binfmt_misc": true, "bpf": true, "cgroup": true, "cgroup2": true,
+	"configfs": true, "debugfs": true, "devpts": true, "fusectl": true, "hugetlbfs": true,
+	"mqueue": true, "nsfs": true, "proc": true, "pstore": true, "securityfs": true,
+	"sysfs": true, "tracefs": true, "rpc_pipefs": true,
+}
+
+// statfsUsage returns size and used bytes and the used percentage the way
+// df computes it, where reserved blocks count as unavailable
+func statfsUsage(st *syscall.Statfs_t) (total, used uint64, percent float64) {
+	bsize := blockSize(st)
+	total = st.Blocks * bsize
+	used = (st.Blocks - st.Bfree) * bsize
+	if avail := used + st.Bavail*bsize; avail > 0 {
+		percent = float64(used) / float64(avail) * 100
+	}
+	return total, used, percent
+}
+
+// blockSize is the unit statfs block counts are in
+func blockSize(st *syscall.Statfs_t) uint64 {
+	if st.Frsize != 0 {
+		return uint64(st.Frsize)
+	}
+	return uint64(st.Bsize)
+}
+
+func (c *collection) filesystems(mountsPath string) {
+	var root syscall.Statfs_t
+	if err := statfs("/", &root); err != nil {
+		c.fail("disk", "total_kb", err)
+		c.fail("inodes", "total", err)
+	} else {
+		total, used, percent := statfsUsage(&root)
+		c.m.DiskTotal = float64(total) / (1024 * 1024 * 1024)
+		c.m.DiskUsage = float64(used) / (1024 * 1024 * 1024)
+		c.m.DiskPercent = percent
+		c.m.InodeTotal = int64(root.Files)
+		c.m.InodeUsed = int64(root.Files - root.Ffree)
+		if root.Files > 0 {
+			c.m.InodePercent = float64(c.m.InodeUsed) / float64(root.Files) * 100
+		}
+	}
+
+	mounts, err := readMounts(mountsPath)
+	if err != nil {
+		c.fail("filesystems", "mount", err)
+		return
+	}
+	c.m.Filesystems = []models.Filesystem{}
+	// As on the dashboard, further mounts of a block device already listed
+	// are bind mounts and dropped; mounts are sorted, so the shortest
+	// mountpoint is kept. Pseudo filesystems share device names like
+	// "tmpfs" and are all kept.
+	seenDevices := make(map[string]bool)
+	for _, mnt := range mounts {
+		blockDevice := strings.HasPrefix(mnt.device, "/")
+		if blockDevice && seenDevices[mnt.device] {
+			continue
+		}
+		var st syscall.Statfs_t
+		if err := statfs(mnt.mountpoint, &st); err != nil || st.Blocks == 0 {
+			continue
+		}
+		if blockDevice {
+			seenDevices[mnt.device] = true
+		}
+		total, used, percent := statfsUsage(&st)
+		fs := models.Filesystem{
+			Mountpoint: mnt.mountpoint, FSType: mnt.fstype, Device: mnt.device,
+			SizeBytes: total, UsedBytes: used, AvailBytes: st.Bavail * blockSize(&st), UsedPercent: percent,
+			ReadOnly: mountOption(mnt.options, "ro"),
+		}
+		if st.Files >= st.Ffree {
+			fs.InodesTotal, fs.InodesUsed = st.Files, st.Files-st.Ffree
+		}
+		fs.InodePercent = models.InodeUsage(fs.InodesUsed, fs.InodesTotal)
+		c.m.Filesystems = append(c.m.Filesystems, fs)
+
+		// Kept for dashboards that predate the mount list
+		if percent > models.FullPartitionPercent {
+			c.m.FullPartitions = append(c.m.FullPartitions, fmt.Sprintf("%s %.0f%%", mnt.mountpoint, percent))
+		}
+	}
+}
+
+// mountEntry is a /proc/mounts line with its escapes decoded
+type mountEntry struct {
+	device, mountpoint, fstype, options string
+}
+
+// readMounts lists the mounts in a /proc/mounts file other than pseudo
+// filesystems, sorted by mountpoint
+func readMounts(path string) ([]mountEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var mounts []mountEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// device mountpoint fstype options dump pass
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || pseudoFilesystems[fields[2]] {
+			continue
+		}
+		mounts = append(mounts, mountEntry{
+			device: models.UnescapeMount(fields[0]), mountpoint: models.UnescapeMount(fields[1]),
+			fstype: fields[2], options: fields[3],
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(mounts, func(i, j int) bool { return mounts[i].mountpoint < mounts[j].mountpoint })
+	return mounts, nil
+}
+
+// mountOption reports whether a comma-separated /proc/mounts option list
+// contains opt
+func mountOption(options, opt string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *collection) network() {
+	f, err := os.Open(procRoot + "/net/dev")
+	if err != nil {
+		c.fail("network", "rx_bytes", err)
+		return
+	}
+	defer f.Close()
+	var rx, tx uint64
+	c.m.Interfaces = []models.NetworkInterface{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// "  eth0: rx_bytes rx_packets rx_errs rx_drop fifo frame compressed
+		// multicast tx_bytes tx_packets tx_errs tx_drop ..."; headers have
+		// no colon
+		name, counters, ok := strings.Cut(scanner.Text(), ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "lo" {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 12 {
+			continue
+		}
+		var n [12]uint64
+		for i := range n {
+			n[i], _ = strconv.ParseUint(fields[i], 10, 64)
+		}
+		rx += n[0]
+		tx += n[8]
+		c.m.Interfaces = append(c.m.Interfaces, models.NetworkInterface{
+			Name: name, OperState: linkOperState(name), SpeedMbps: linkSpeed(name),
+			RxBytes: n[0], RxPackets: n[1], RxErrors: n[2], RxDropped: n[3],
+			TxBytes: n[8], TxPackets: n[9], TxErrors: n[10], TxDropped: n[11],
+		})
+	}
+	c.m.NetworkRxMB = float64(rx) / (1024 * 1024)
+	c.m.NetworkTxMB = float64(tx) / (1024 * 1024)
+}
+
+// linkOperState reads an interface's operational state, "unknown" if unreadable
+func linkOperState(name string) string {
+	data, err := os.ReadFile(sysRoot + "/class/net/" + name + "/operstate")
+	if state := strings.TrimSpace(string(data)); err == nil && state != "" {
+		return state
+	}
+	return "unknown"
+}
+
+// linkSpeed reads an interface's link speed in Mbit/s. Virtual interfaces
+// and links that are down fail to read or report -1, returned as 0.
+func linkSpeed(name string) int64 {
+	data, err := os.ReadFile(sysRoot + "/class/net/" + name + "/speed")
+	if err != nil {
+		return 0
+	}
+	speed, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil || speed < 0 {
+		return 0
+	}
+	return speed
+}
+
+func (c *collection) services() {
+	// Same check as sd_booted(3): systemctl may be installed without
+	// systemd running, e.g. in containers
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		return
+	}
+	path, err := exec.LookPath("systemctl")
+	if err != nil {
+		return
+	}
+	out, err := exec.Command(path, "--failed", "--no-legend", "--plain", "--no-pager").Output()
+	if err != nil {
+		c.fail("services", "failed", err)
+		return
+	}
+	var failed []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if u, ok := models.ParseSystemdUnit(line); ok {
+			failed = append(failed, u.Name)
+		}
+		if strings.TrimSpace(line) != "" {
+			c.m.FailedServices++
+		}
+	}
+
+	// Every service unit and failed units of any type, for the dashboard
+	// to pick out the failed and watched ones
+	out, err = exec.Command(path, "list-units", "--all", "--no-legend", "--plain", "--no-pager").Output()
+	if err != nil {
+		c.fail("services", "unit", err)
+		return
+	}
+	units := []models.SystemdUnit{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if u, ok := models.ParseSystemdUnit(line); ok && models.ReportedSystemdUnit(u) {
+			units = append(units, u)
+		}
+	}
+	states := make(map[string]string)
+	if out, err := exec.Command(path, "list-unit-files", "--type=service", "--no-legend", "--no-pager").Output(); err != nil {
+		c.fail("services", "unit_file", err)
+	} else {
+		for _, line := range strings.Split(string(out), "\n") {
+			if name, state, ok := models.ParseUnitFile(line); ok {
+				states[name] = state
+			}
+		}
+	}
+	c.m.Units = models.WithUnitFileStates(units, states)
+	if len(failed) > 0 {
+		c.unitFailures(path, failed)
+	}
+}
+
+// unitFailures adds why and when each failed unit failed, from one
+// "systemctl show" of all of them
+func (c *collection) unitFailures(systemctl string, failed []string) {
+	bootTime, err := bootTime()
+	if err != nil {
+		c.fail("services", "boot_time", err)
+	}
+	args := append([]string{"show", "-p", "Id", "-p", "Result", "-p", "StateChangeTimestampMonotonic", "--"}, failed...)
+	out, err := exec.Command(systemctl, args...).Output()
+	if err != nil {
+		c.fail("services", "failure", err)
+		return
+	}
+	// One block of properties per unit, separated by blank lines
+	props := make(map[string]string)
+	apply := func() {
+		for i := range c.m.Units {
+			if u := &c.m.Units[i]; u.Name == props["Id"] {
+				u.Result = props["Result"]
+				since, _ := strconv.ParseUint(props["StateChangeTimestampMonotonic"], 10, 64)
+				u.FailedAt = models.SystemdTimestamp(bootTime, since)
+			}
+		}
+		props = make(map[string]string)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if key, value, ok := strings.Cut(line, "="); ok {
+			props[key] = value
+		} else if strings.TrimSpace(line) == "" && len(props) > 0 {
+			apply()
+		}
+	}
+	if len(props) > 0 {
+		apply()
+	}
+}
+
+// bootTime reads when the host booted, in seconds since the epoch, from
+// the btime line of /proc/stat
+func bootTime() (int64, error) {
+	data, err := os.ReadFile(procRoot + "/stat")
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "btime" {
+			return strconv.ParseInt(fields[1], 10, 64)
+		}
+	}
+	return 0, errors.New("no btime in /proc/stat")
+}
@@ -0,0 +1,71 @@
+//go:build linux
+
+package agent
+
+import (
+	"errors"
+	"reflect"
+	"syscall"
+	"testing"
+
+	"server-dashboard/internal/models"
+)
+
+func TestFilesystems(t *testing.T) {
+	// Every mount is 1000 blocks of 1 KiB with 100 free, except for a disk
+	// that cannot be reached and an empty filesystem
+	defer func(saved func(string, *syscall.Statfs_t) error) { statfs = saved }(statfs)
+	statfs = func(path string, st *syscall.Statfs_t) error {
+		switch path {
+		case "/mnt/offline":
+			return errors.New("stale file handle")
+		case "/dev":
+			*st = syscall.Statfs_t{}
+			return nil
+		}
+		*st = syscall.Statfs_t{Bsize: 1024, Blocks: 1000, Bfree: 100, Bavail: 100, Files: 10, Ffree: 5}
+		return nil
+	}
+
+	c := &collection{m: &models.HostMetrics{}}
+	c.filesystems("testdata/mounts")
+	if len(c.errs) > 0 {
+		t.Fatalf("errors: %v", c.errs)
+	}
+	type mount struct{ mountpoint, fstype, device string }
+	var got []mount
+	for _, fs := range c.m.Filesystems {
+		got = append(got, mount{fs.Mountpoint, fs.FSType, fs.Device})
+	}
+	// Bind mounts of sda2 and sdb1 are dropped, the offline mount's bind
+	// mount stands in for it, and every tmpfs and overlay mount is kept
+	want := []mount{
+		{"/", "ext4", "/dev/sda2"},
+		{"/boot/efi", "vfat", "/dev/sda1"},
+		{"/dev/shm", "tmpfs", "tmpfs"},
+		{"/mnt/z", "ext4", "/dev/sdc1"},
+		{"/run", "tmpfs", "tmpfs"},
+		{"/srv/media library", "xfs", "/dev/sdb1"},
+		{"/var/lib/docker/overlay2/3f1c/merged", "overlay", "overlay"},
+		{"/var/lib/docker/overlay2/9a7e/merged", "overlay", "overlay"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mounts =\n%v\nwant\n%v", got, want)
+	}
+
+	media := c.m.Filesystems[5]
+	if !media.ReadOnly || media.SizeBytes != 1024000 || media.UsedBytes != 921600 || media.UsedPercent != 90 || media.InodesUsed != 5 {
+		t.Errorf("%s = %+v", media.Mountpoint, media)
+	}
+	if c.m.DiskPercent != 90 || len(c.m.FullPartitions) != 0 {
+		t.Errorf("disk %v%%, full partitions %v", c.m.DiskPercent, c.m.FullPartitions)
+	}
+}
+
+func TestFilesystemsWithoutMounts(t *testing.T) {
+	c := &collection{m: &models.HostMetrics{}}
+	c.filesystems("testdata/no-such-file")
+	if len(c.errs) == 0 || c.m.Filesystems != nil {
+		t.Errorf("errors %v, filesystems %v", c.errs, c.m.Filesystems)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package agent
+
+import (
+	"errors"
+
+	"server-dashboard/internal/models"
+)
+
+// Collect is only implemented on Linux, which provides /proc
+func Collect() (*models.HostMetrics, []string, error) {
+	return nil, nil, errors.New("dashboard-agent only supports Linux")
+}
@@ -0,0 +1,141 @@
+// Package alerting parses alert rule expressions such as
+// "disk_percent > 85 for 5m" or "synthetic.status == fail for 3 runs".
+//
+// An expression compares one metric with a constant and may say how long
+// the comparison has to hold before the alert fires: for a duration, or for
+// a number of consecutive runs (new samples of the metric).
+package alerting
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Comparison operators
+const (
+	OpGreater      = ">"
+	OpGreaterEqual = ">="
+	OpLess         = "<"
+	OpLessEqual    = "<="
+	OpEqual        = "=="
+	OpNotEqual     = "!="
+)
+
+var exprPattern = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_.]*)\s*(>=|<=|==|!=|>|<)\s*("[^"]*"|'[^']*'|[^\s"']+)(?:\s+for\s+(\S+)(?:\s+(runs?))?)?\s*$`)
+
+// Expr is a parsed rule expression
+type Expr struct {
+	Metric string
+	Op     string
+	Value  Value
+	For    time.Duration // Condition must hold this long before firing
+	Runs   int           // Or for this many consecutive samples
+}
+
+// Value is a metric value or the constant it is compared with
+type Value struct {
+	Number float64
+	Text   string
+	IsText bool
+}
+
+// Number returns a numeric value
+func Number(v float64) Value { return Value{Number: v} }
+
+// Text returns a text value such as a status
+func Text(s string) Value { return Value{Text: s, IsText: true} }
+
+// String renders the value for alert messages
+func (v Value) String() string {
+	if v.IsText {
+		return v.Text
+	}
+	return strconv.FormatFloat(math.Round(v.Number*100)/100, 'f', -1, 64)
+}
+
+// ParseExpr parses "<metric> <op> <value> [for <duration> | for <n> runs]".
+// Text values may be quoted and only support == and !=.
+func ParseExpr(s string) (Expr, error) {
+	m := exprPattern.FindStringSubmatch(s)
+	if m == nil {
+		return Expr{}, fmt.Errorf("invalid expression %q: want <metric> <op> <value> [for <duration> | for <n> runs]", s)
+	}
+	e := Expr{Metric: m[1], Op: m[2]}
+
+	value := m[3]
+	if n, err := strconv.ParseFloat(value, 64); err == nil && !math.IsNaN(n) {
+		e.Value = Number(n)
+	} else {
+		e.Value = Text(strings.Trim(value, `"'`))
+		if e.Op != OpEqual && e.Op != OpNotEqual {
+			return Expr{}, fmt.Errorf("invalid expression %q: %s needs a number, got %q", s, e.Op, value)
+		}
+	}
+
+	switch {
+	case m[4] == "":
+	case m[5] != "":
+		n, err := strconv.Atoi(m[4])
+		if err != nil || n < 1 {
+			return Expr{}, fmt.Errorf("invalid expression %q: %q is not a positive number of runs", s, m[4])
+		}
+		e.Runs = n
+	default:
+		d, err := time.ParseDuration(m[4])
+		if err != nil || d < 0 {
+			return Expr{}, fmt.Errorf("invalid expression %q: %q is not a duration", s, m[4])
+		}
+		e.For = d
+	}
+	return e, nil
+}
+
+// Match reports whether a value satisfies the comparison. Text is compared
+// case-insensitively; comparing text with a number never matches.
+func (e Expr) Match(v Value) bool {
+	if v.IsText != e.Value.IsText {
+		return false
+	}
+	if v.IsText {
+		equal := strings.EqualFold(v.Text, e.Value.Text)
+		return equal == (e.Op == OpEqual)
+	}
+	a, b := v.Number, e.Value.Number
+	switch e.Op {
+	case OpGreater:
+		return a > b
+	case OpGreaterEqual:
+		return a >= b
+	case OpLess:
+		return a < b
+	case OpLessEqual:
+		return a <= b
+	case OpEqual:
+		return a == b
+	case OpNotEqual:
+		return a != b
+	}
+	return false
+}
+
+// String renders the expression in its canonical form
+func (e Expr) String() string {
+	value := e.Value.String()
+	if e.Value.IsText && (value == "" || strings.ContainsAny(value, " \t")) {
+		value = strconv.Quote(value)
+	}
+	s := e.Metric + " " + e.Op + " " + value
+	switch {
+	case e.Runs == 1:
+		s += " for 1 run"
+	case e.Runs > 1:
+		s += fmt.Sprintf(" for %d runs", e.Runs)
+	case e.For > 0:
+		s += " for " + e.For.String()
+	}
+	return s
+}
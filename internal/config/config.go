@@ -23,6 +23,13 @@ type Config struct {
 	TLS                TLSConfig              `yaml:"tls"`
 	UI                 UIConfig               `yaml:"ui"`
 	Environment        string                 `yaml:"environment"`
+	DataDirectory      string                 `yaml:"data_directory"` // Persistent state (default ./data)
+	Ingest             IngestConfig           `yaml:"ingest"`         // Metrics pushed by dashboard-agent
+	Metrics            MetricsConfig          `yaml:"metrics"`        // Prometheus /metrics endpoint
+	History            HistoryConfig          `yaml:"history"`        // Embedded metrics history
+	Events             EventsConfig           `yaml:"events"`         // State-transition event log
+	Alerting           AlertingConfig         `yaml:"alerting"`       // Alert rules
+	Notifications      NotificationsConfig    `yaml:"notifications"`  // Where alerts are sent
 }
 
 type LoggingConfig struct {
@@ -63,6 +70,36 @@ type GroupDefinition struct {
 	Permissions []string `yaml:"permissions"` // List of permission strings
 }
 
+// IngestConfig controls the endpoint dashboard-agent pushes metrics to.
+// Agents authenticate with the agent_token of the device they report for.
+type IngestConfig struct {
+	AllowHTTP         bool `yaml:"allow_http"`          // Accept pushes without TLS, e.g. behind a TLS-terminating proxy
+	StaleAfterSeconds int  `yaml:"stale_after_seconds"` // Metrics go stale without a push for this long (default 3x monitoring_interval)
+}
+
+// MetricsConfig controls the Prometheus /metrics endpoint. Scrapers
+// authenticate with the token; logged-in users can view it without one.
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Token   string `yaml:"token"` // Bearer token for scrapers; without one the endpoint needs a session when auth is enabled
+}
+
+// HistoryConfig controls the embedded time-series store kept under
+// <data_directory>/history. Samples are kept raw, then as one-minute and
+// one-hour averages with their min and max.
+type HistoryConfig struct {
+	Enabled             bool `yaml:"enabled"`
+	RawRetentionHours   int  `yaml:"raw_retention_hours"`   // Default 48
+	MinuteRetentionDays int  `yaml:"minute_retention_days"` // Default 14
+	HourRetentionDays   int  `yaml:"hour_retention_days"`   // Default 365
+}
+
+// EventsConfig controls the state-transition event log kept under
+// <data_directory>/events
+type EventsConfig struct {
+	RetentionDays int `yaml:"retention_days"` // Default 30
+}
+
 type TLSConfig struct {
 	Enabled  bool   `yaml:"enabled"`
 	CertFile string `yaml:"cert_file"`
@@ -77,6 +114,22 @@ type ServerConfig struct {
 	Port      int      `yaml:"port"`
 	Enabled   bool     `yaml:"enabled"`
 	Tags      []string `yaml:"tags"`
+
+	UplinkSwitchID string `yaml:"uplink_switch_id"` // Switch the server is connected through; while it is down the server shows as unreachable
+
+	// SSH credentials: a named profile from ssh.profiles plus optional
+	// per-server overrides (both optional, falling back to global SSH config)
+	SSHProfile   string        `yaml:"ssh_profile"`
+	SSHUsername  string        `yaml:"ssh_username"`
+	SSHPassword  string        `yaml:"ssh_password"`
+	SSHKeyPath   string        `yaml:"ssh_key_path"`
+	SSHJumpHosts []SSHJumpHost `yaml:"ssh_jump_hosts"` // Overrides profile/global jump hosts; [] connects directly
+
+	Reachability *ReachabilityConfig `yaml:"reachability"`  // Replaces monitoring.reachability for this device
+	Filesystems  *FilesystemConfig   `yaml:"filesystems"`   // Refines monitoring.filesystems for this device
+	Collector    string              `yaml:"collector"`     // Metrics collector: "ssh", "snmp", "agent", "node_exporter" or "mock" (default monitoring.collector)
+	AgentToken   string              `yaml:"agent_token"`   // Bearer token for dashboard-agent pushes; devices with a token use the agent collector
+	NodeExporter *NodeExporterConfig `yaml:"node_exporter"` // Scrape node_exporter; devices with this block use the node_exporter collector
 }
 
 type VirtualMachineConfig struct {
@@ -89,6 +142,24 @@ type VirtualMachineConfig struct {
 	HostServerID string   `yaml:"host_server_id"`
 	StreamPorts  []int    `yaml:"stream_ports"` // Optional ports for video/media streaming
 	Tags         []string `yaml:"tags"`
+
+	// Dependencies: while the host server or uplink switch is down the VM
+	// shows as unreachable
+	UplinkSwitchID string `yaml:"uplink_switch_id"` // Switch the VM is connected through, if not just its host's
+
+	// SSH credentials: a named profile from ssh.profiles plus optional
+	// per-VM overrides (both optional, falling back to global SSH config)
+	SSHProfile   string        `yaml:"ssh_profile"`
+	SSHUsername  string        `yaml:"ssh_username"`
+	SSHPassword  string        `yaml:"ssh_password"`
+	SSHKeyPath   string        `yaml:"ssh_key_path"`
+	SSHJumpHosts []SSHJumpHost `yaml:"ssh_jump_hosts"` // Overrides profile/global jump hosts; [] connects directly
+
+	Reachability *ReachabilityConfig `yaml:"reachability"`  // Replaces monitoring.reachability for this device
+	Filesystems  *FilesystemConfig   `yaml:"filesystems"`   // Refines monitoring.filesystems for this device
+	Collector    string              `yaml:"collector"`     // Metrics collector: "ssh", "snmp", "agent", "node_exporter" or "mock" (default monitoring.collector)
+	AgentToken   string              `yaml:"agent_token"`   // Bearer token for dashboard-agent pushes; devices with a token use the agent collector
+	NodeExporter *NodeExporterConfig `yaml:"node_exporter"` // Scrape node_exporter; devices with this block use the node_exporter collector
 }
 
 type SwitchConfig struct {
@@ -100,11 +171,44 @@ type SwitchConfig struct {
 	Enabled         bool   `yaml:"enabled"`
 	ControllerIP    string `yaml:"controller_ip"`    // SDN controller IP
 	OpenFlowVersion string `yaml:"openflow_version"` // Expected OpenFlow version
-	// SSH credentials specific to this switch (optional, falls back to global SSH config)
+	// SSH credentials specific to this switch (optional, falls back to the
+	// profile named by ssh_profile, then to global SSH config)
+	SSHProfile  string   `yaml:"ssh_profile"`  // Name of a profile in ssh.profiles
 	SSHUsername string   `yaml:"ssh_username"` // Switch-specific SSH username
 	SSHPassword string   `yaml:"ssh_password"` // Switch-specific SSH password
 	SSHKeyPath  string   `yaml:"ssh_key_path"` // Switch-specific SSH private key path
 	Tags        []string `yaml:"tags"`
+
+	SSHJumpHosts []SSHJumpHost `yaml:"ssh_jump_hosts"` // Overrides profile/global jump hosts; [] connects directly
+
+	Reachability *ReachabilityConfig `yaml:"reachability"` // Replaces monitoring.reachability for this device
+	Filesystems  *FilesystemConfig   `yaml:"filesystems"`  // Refines monitoring.filesystems for this device
+	Collector    string              `yaml:"collector"`    // Metrics collector: "ssh", "snmp", "agent" or "mock" (default monitoring.collector)
+	AgentToken   string              `yaml:"agent_token"`  // Bearer token for dashboard-agent pushes; devices with a token use the agent collector
+	SNMP         *SNMPConfig         `yaml:"snmp"`         // SNMP polling; switches with this block default to the snmp collector
+}
+
+// SNMPConfig configures SNMP polling of a device. Version "2c" uses the
+// community; version "3" uses the user-based security model, with auth and
+// priv enabled by setting their protocols.
+type SNMPConfig struct {
+	Version        string `yaml:"version"`         // "2c" (default) or "3"
+	Port           int    `yaml:"port"`            // Agent UDP port (default 161)
+	Community      string `yaml:"community"`       // v2c community (default "public")
+	Username       string `yaml:"username"`        // v3 security name
+	AuthProtocol   string `yaml:"auth_protocol"`   // v3: MD5, SHA, SHA224, SHA256, SHA384 or SHA512
+	AuthPassword   string `yaml:"auth_password"`   // v3 auth passphrase (at least 8 characters)
+	PrivProtocol   string `yaml:"priv_protocol"`   // v3: DES or AES (requires auth)
+	PrivPassword   string `yaml:"priv_password"`   // v3 priv passphrase (at least 8 characters)
+	ContextName    string `yaml:"context_name"`    // v3 context, usually empty
+	TimeoutSeconds int    `yaml:"timeout_seconds"` // Per request attempt (default 2)
+	Retries        int    `yaml:"retries"`         // Resends after a timeout (default 0)
+}
+
+// NodeExporterConfig configures scraping a Prometheus node_exporter
+type NodeExporterConfig struct {
+	URL            string `yaml:"url"`             // Default http://<ip_address>:9100/metrics
+	TimeoutSeconds int    `yaml:"timeout_seconds"` // Default 5
 }
 
 type SyntheticCheckConfig struct {
@@ -123,11 +227,154 @@ type SyntheticCheckConfig struct {
 
 type MonitoringConfig struct {
 	PingTimeoutSeconds   int  `yaml:"ping_timeout_seconds"`
-	DiskThresholdPercent int  `yaml:"disk_threshold_percent"`
+	DiskThresholdPercent int  `yaml:"disk_threshold_percent"` // Partitions above this are listed as full and alerted on (default 90)
 	CheckProcesses       bool `yaml:"check_processes"`
 	CheckDiskSpace       bool `yaml:"check_disk_space"`
 	CheckUptime          bool `yaml:"check_uptime"`
 	UseMockData          bool `yaml:"use_mock_data"`
+
+	// Scheduler settings; the interval itself is the top-level monitoring_interval
+	Workers           int  `yaml:"workers"`             // Concurrent device checks (default 8)
+	JitterPercent     *int `yaml:"jitter_percent"`      // Random spread applied to each device's interval (default 10, 0 disables it)
+	MaxBackoffSeconds int  `yaml:"max_backoff_seconds"` // Upper bound for unreachable-host backoff (default 300)
+
+	// Default reachability probes; devices can replace them with their own
+	Reachability ReachabilityConfig `yaml:"reachability"`
+
+	// Debouncing and flap detection for devices, VM streams and synthetic checks
+	Hysteresis HysteresisConfig `yaml:"hysteresis"`
+
+	// Default filesystem selection and full thresholds; devices can refine them
+	Filesystems FilesystemConfig `yaml:"filesystems"`
+
+	// systemd units to check on devices besides the failed ones
+	Services ServicesConfig `yaml:"services"`
+
+	// Default metrics collector for devices without their own "collector".
+	// Empty means "mock" with use_mock_data or SSH disabled, otherwise "ssh".
+	Collector string `yaml:"collector"`
+}
+
+// AlertingConfig holds the alert rules. Rules are evaluated against the
+// latest device and synthetic check results.
+type AlertingConfig struct {
+	Enabled                   bool              `yaml:"enabled"`
+	EvaluationIntervalSeconds int               `yaml:"evaluation_interval_seconds"` // Default 15
+	ResolvedRetentionHours    int               `yaml:"resolved_retention_hours"`    // Keep resolved alerts listed this long (default 24)
+	Rules                     []AlertRuleConfig `yaml:"rules"`
+}
+
+// AlertRuleConfig is one alert rule, e.g. expr "disk_percent > 85 for 5m"
+// or "synthetic.status == fail for 3 runs". Selectors narrow the devices
+// or synthetic checks it applies to; a rule without selectors applies to
+// all of them.
+type AlertRuleConfig struct {
+	Name     string   `yaml:"name"`
+	Expr     string   `yaml:"expr"`
+	Severity string   `yaml:"severity"` // info, warning (default) or critical
+	Summary  string   `yaml:"summary"`  // Shown instead of the generated description
+	Devices  []string `yaml:"devices"`  // Device IDs, or check IDs for synthetic.* rules
+	Tags     []string `yaml:"tags"`     // Targets with any of these tags
+	Types    []string `yaml:"types"`    // server, vm and/or switch
+}
+
+// NotificationsConfig holds the channels firing and resolved alerts are
+// sent to. Failed deliveries are retried with exponential backoff.
+type NotificationsConfig struct {
+	Enabled             bool                        `yaml:"enabled"`
+	DashboardURL        string                      `yaml:"dashboard_url"`         // Base URL for links in messages, e.g. https://dashboard.example.com
+	MaxAttempts         int                         `yaml:"max_attempts"`          // Per delivery (default 5)
+	RetryInitialSeconds int                         `yaml:"retry_initial_seconds"` // First retry delay, doubled each time (default 10)
+	RetryMaxSeconds     int                         `yaml:"retry_max_seconds"`     // Longest retry delay (default 300)
+	TimeoutSeconds      int                         `yaml:"timeout_seconds"`       // Per attempt (default 15)
+	Channels            []NotificationChannelConfig `yaml:"channels"`
+}
+
+// NotificationChannelConfig is one destination. Severities and tags route
+// alerts to it; templates are Go text/templates over the alert.
+type NotificationChannelConfig struct {
+	Name          string   `yaml:"name"`
+	Type          string   `yaml:"type"`           // webhook, slack, mattermost or email
+	Severities    []string `yaml:"severities"`     // Only these severities (default all)
+	Tags          []string `yaml:"tags"`           // Only alerts on targets with any of these tags (default all)
+	SkipResolved  bool     `yaml:"skip_resolved"`  // Do not send resolved notifications
+	TitleTemplate string   `yaml:"title_template"` // Subject or headline
+	BodyTemplate  string   `yaml:"body_template"`
+
+	// webhook, slack and mattermost
+	URL      string            `yaml:"url"`
+	Secret   string            `yaml:"secret"`   // webhook: HMAC-SHA256 signing key
+	Headers  map[string]string `yaml:"headers"`  // webhook: extra request headers
+	Channel  string            `yaml:"channel"`  // slack/mattermost: override the webhook's channel
+	Username string            `yaml:"username"` // slack/mattermost: override the posting name
+
+	// email
+	SMTP SMTPConfig `yaml:"smtp"`
+}
+
+// SMTPConfig is the relay an email channel sends through
+type SMTPConfig struct {
+	Host               string   `yaml:"host"`
+	Port               int      `yaml:"port"`     // Default 587, or 465 with tls
+	Security           string   `yaml:"security"` // starttls (default), tls or none
+	Username           string   `yaml:"username"`
+	Password           string   `yaml:"password"`
+	PasswordEnv        string   `yaml:"password_env"` // Env var holding the password
+	From               string   `yaml:"from"`
+	To                 []string `yaml:"to"`
+	InsecureSkipVerify bool     `yaml:"insecure_skip_verify"`
+}
+
+// ReachabilityConfig selects the probes used to decide whether a device is
+// reachable. Probes run in parallel and the device is online when any of
+// them succeeds.
+type ReachabilityConfig struct {
+	ICMP      bool     `yaml:"icmp"`       // ICMP echo over an unprivileged ping socket
+	ICMPCount int      `yaml:"icmp_count"` // Echo requests per check (default 3)
+	TCPPorts  []int    `yaml:"tcp_ports"`  // TCP connect probes
+	HTTPURLs  []string `yaml:"http_urls"`  // Health URLs; any status below 400 is healthy
+}
+
+// IsZero reports whether no probe is configured
+func (r ReachabilityConfig) IsZero() bool {
+	return !r.ICMP && len(r.TCPPorts) == 0 && len(r.HTTPURLs) == 0
+}
+
+// FilesystemConfig selects the mounts monitored on a device and when each
+// counts as full. Patterns are shell globs matched against the mountpoint,
+// e.g. "/srv/*"; a trailing "/**" also matches deeper mounts. A mount is monitored when it matches an include pattern (or
+// there are none), matches no exclude pattern and its type is not excluded.
+type FilesystemConfig struct {
+	Include        []string       `yaml:"include"`         // Mountpoint patterns to monitor; empty monitors every mount
+	Exclude        []string       `yaml:"exclude"`         // Mountpoint patterns to leave out, e.g. "/snap/**"
+	ExcludeFSTypes []string       `yaml:"exclude_fstypes"` // Types to leave out (default tmpfs, devtmpfs, squashfs and other in-memory or image filesystems)
+	Thresholds     map[string]int `yaml:"thresholds"`      // Full threshold percent by mountpoint pattern; the longest matching pattern wins (default disk_threshold_percent)
+}
+
+// ServicesConfig holds the watchlists of systemd units. Every watchlist
+// that selects a device adds its units to the ones checked there.
+type ServicesConfig struct {
+	Watch []ServiceWatchConfig `yaml:"watch"`
+}
+
+// ServiceWatchConfig is a list of units expected to be active and enabled,
+// e.g. "nginx.service". Selectors narrow the devices it applies to; a list
+// without selectors applies to all of them.
+type ServiceWatchConfig struct {
+	Units   []string `yaml:"units"`   // Unit names; names without a type are services, as with systemctl
+	Devices []string `yaml:"devices"` // Device IDs
+	Tags    []string `yaml:"tags"`    // Devices with any of these tags
+	Types   []string `yaml:"types"`   // server, vm and/or switch
+}
+
+// HysteresisConfig keeps single missed checks from changing a device,
+// stream or synthetic check between up and down, and marks ones that change
+// too often as flapping
+type HysteresisConfig struct {
+	FailuresBeforeOffline int `yaml:"failures_before_offline"` // Consecutive failed checks before going down (default 3)
+	SuccessesBeforeOnline int `yaml:"successes_before_online"` // Consecutive good checks before coming back up (default 2)
+	FlapThreshold         int `yaml:"flap_threshold"`          // State changes within the window that mark flapping; 0 disables
+	FlapWindowMinutes     int `yaml:"flap_window_minutes"`     // Default 30
 }
 
 type UIConfig struct {
@@ -145,6 +392,135 @@ type SSHConfig struct {
 	PrivateKeyPath string `yaml:"private_key_path"`
 	Password       string `yaml:"password"`
 	TimeoutSeconds int    `yaml:"timeout_seconds"`
+
+	// Key and agent authentication (also settable per profile and jump host)
+	PassphraseEnv   string `yaml:"private_key_passphrase_env"`  // Env var holding the key passphrase
+	PassphraseFile  string `yaml:"private_key_passphrase_file"` // File holding the key passphrase
+	CertificatePath string `yaml:"certificate_path"`            // OpenSSH certificate (default <key>-cert.pub if present)
+	UseAgent        bool   `yaml:"use_agent"`                   // Sign with the agent at SSH_AUTH_SOCK
+
+	// Host key verification: "strict" (default), "tofu" or "insecure"
+	HostKeyMode    string `yaml:"host_key_mode"`
+	KnownHostsFile string `yaml:"known_hosts_file"` // Default: <data_directory>/known_hosts
+
+	// Connection pool settings
+	MaxSessionsPerHost int `yaml:"max_sessions_per_host"` // Concurrent sessions on one pooled connection (default 4)
+	IdleTimeoutSeconds int `yaml:"idle_timeout_seconds"`  // Close pooled connections unused for this long (default 300)
+	KeepaliveSeconds   int `yaml:"keepalive_seconds"`     // Keepalive request interval (default 30)
+
+	// Named credential sets that devices reference with ssh_profile
+	Profiles map[string]SSHProfile `yaml:"profiles"`
+
+	// Jump hosts to tunnel through, in order, for every device
+	JumpHosts []SSHJumpHost `yaml:"jump_hosts"`
+}
+
+// SSHProfile is a named set of SSH credentials. Empty fields inherit from
+// the global SSH settings.
+type SSHProfile struct {
+	Username        string        `yaml:"username"`
+	PrivateKeyPath  string        `yaml:"private_key_path"`
+	Password        string        `yaml:"password"`
+	PassphraseEnv   string        `yaml:"private_key_passphrase_env"`
+	PassphraseFile  string        `yaml:"private_key_passphrase_file"`
+	CertificatePath string        `yaml:"certificate_path"`
+	UseAgent        bool          `yaml:"use_agent"`  // Adds agent signing; cannot turn off a global use_agent
+	JumpHosts       []SSHJumpHost `yaml:"jump_hosts"` // Replaces the global jump hosts when set
+}
+
+// SSHJumpHost is a bastion that connections are tunneled through. Empty
+// credentials inherit from the credentials used for the target device.
+type SSHJumpHost struct {
+	Host            string `yaml:"host"`
+	Port            int    `yaml:"port"` // Default 22
+	Username        string `yaml:"username"`
+	PrivateKeyPath  string `yaml:"private_key_path"`
+	Password        string `yaml:"password"`
+	PassphraseEnv   string `yaml:"private_key_passphrase_env"`
+	PassphraseFile  string `yaml:"private_key_passphrase_file"`
+	CertificatePath string `yaml:"certificate_path"`
+}
+
+// ResolveCredentials merges the global SSH settings, the named profile and
+// per-device overrides; the most specific non-empty value wins. An empty profile name skips
+// the profile; an unknown one is an error.
+func (c *SSHConfig) ResolveCredentials(profile string, override SSHProfile) (SSHProfile, error) {
+	resolved := SSHProfile{
+		Username:        c.Username,
+		PrivateKeyPath:  c.PrivateKeyPath,
+		Password:        c.Password,
+		PassphraseEnv:   c.PassphraseEnv,
+		PassphraseFile:  c.PassphraseFile,
+		CertificatePath: c.CertificatePath,
+		UseAgent:        c.UseAgent,
+		JumpHosts:       c.JumpHosts,
+	}
+	if profile != "" {
+		p, ok := c.Profiles[profile]
+		if !ok {
+			return SSHProfile{}, fmt.Errorf("unknown SSH profile %q", profile)
+		}
+		resolved.merge(p)
+	}
+	resolved.merge(override)
+	return resolved, nil
+}
+
+// Credentials returns the credentials for logging in to the jump host:
+// its own where set, otherwise those used for the target
+func (j SSHJumpHost) Credentials(target SSHProfile) SSHProfile {
+	creds := target
+	creds.JumpHosts = nil
+	if j.PrivateKeyPath != "" {
+		// A different key does not share the target key's passphrase or certificate
+		creds.PassphraseEnv, creds.PassphraseFile, creds.CertificatePath = "", "", ""
+	}
+	creds.merge(SSHProfile{
+		Username:        j.Username,
+		PrivateKeyPath:  j.PrivateKeyPath,
+		Password:        j.Password,
+		PassphraseEnv:   j.PassphraseEnv,
+		PassphraseFile:  j.PassphraseFile,
+		CertificatePath: j.CertificatePath,
+	})
+	return creds
+}
+
+// merge overwrites fields with the non-empty fields of o
+func (p *SSHProfile) merge(o SSHProfile) {
+	if o.Username != "" {
+		p.Username = o.Username
+	}
+	if o.PrivateKeyPath != "" {
+		p.PrivateKeyPath = o.PrivateKeyPath
+	}
+	if o.Password != "" {
+		p.Password = o.Password
+	}
+	if o.PassphraseEnv != "" {
+		p.PassphraseEnv = o.PassphraseEnv
+	}
+	if o.PassphraseFile != "" {
+		p.PassphraseFile = o.PassphraseFile
+	}
+	if o.CertificatePath != "" {
+		p.CertificatePath = o.CertificatePath
+	}
+	if o.UseAgent {
+		p.UseAgent = true
+	}
+	// A non-nil empty list (jump_hosts: []) deliberately disables jump hosts
+	if o.JumpHosts != nil {
+		p.JumpHosts = o.JumpHosts
+	}
+}
+
+// DataDir returns the directory for persistent state, defaulting to ./data
+func (c *Config) DataDir() string {
+	if c.DataDirectory == "" {
+		return "./data"
+	}
+	return c.DataDirectory
 }
 
 func LoadConfig(filePath string) (*Config, error) {
@@ -224,6 +600,10 @@ func applyEnvOverrides(cfg *Config) {
 		cfg.TLS.KeyFile = keyFile
 	}
 
+	if metricsToken := os.Getenv("METRICS_TOKEN"); metricsToken != "" {
+		cfg.Metrics.Token = metricsToken
+	}
+
 	// Monitoring options
 	if timeout := os.Getenv("MONITORING_TIMEOUT"); timeout != "" {
 		if t, err := strconv.Atoi(timeout); err == nil {
@@ -0,0 +1,234 @@
+// Package cron parses five-field cron expressions and finds the times they
+// match, for recurring schedules such as maintenance windows.
+//
+// The fields are minute, hour, day of month, month and day of week. Each
+// field is "*", a number, a range "a-b", a list "a,b,c" or any of those
+// with a step "/n". Months and weekdays also accept three-letter names
+// (jan, mon) and Sunday is both 0 and 7. As in classic cron, when both the
+// day of month and the day of week are restricted a day matches if either
+// does. The shortcuts @hourly, @daily, @weekly, @monthly and @yearly are
+// accepted too.
+//
+// Times are wall clock times in the location searched. A time skipped when
+// clocks go forward does not match that day, and a time repeated when they
+// go back matches only the first time.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression
+type Schedule struct {
+	expr                         string
+	minute, hour, dom, month     uint64 // Bit n set when value n matches
+	dow                          uint64
+	domRestricted, dowRestricted bool
+}
+
+var shortcuts = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var dayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// Parse parses a cron expression
+func Parse(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	spec := expr
+	if s, ok := shortcuts[strings.ToLower(spec)]; ok {
+		spec = s
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("cron expression %q: want 5 fields (minute hour day month weekday), got %d", expr, len(fields))
+	}
+
+	s := Schedule{expr: expr}
+	var err error
+	if s.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return Schedule{}, fmt.Errorf("cron minute: %w", err)
+	}
+	if s.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return Schedule{}, fmt.Errorf("cron hour: %w", err)
+	}
+	if s.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return Schedule{}, fmt.Errorf("cron day of month: %w", err)
+	}
+	if s.month, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return Schedule{}, fmt.Errorf("cron month: %w", err)
+	}
+	if s.dow, err = parseField(fields[4], 0, 7, dayNames); err != nil {
+		return Schedule{}, fmt.Errorf("cron day of week: %w", err)
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1 // 7 is Sunday too
+	}
+	s.domRestricted = !strings.HasPrefix(fields[2], "*")
+	s.dowRestricted = !strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+// parseField turns one comma-separated field into a bit set
+func parseField(field string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("bad step %q", stepPart)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			a, b, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = fieldValue(a, min, max, names); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = fieldValue(b, min, max, names); err != nil {
+					return 0, err
+				}
+				if hi < lo {
+					return 0, fmt.Errorf("bad range %q", rangePart)
+				}
+			} else if hasStep {
+				hi = max // "5/15" means from 5 to the end in steps of 15
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func fieldValue(s string, min, max int, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("bad value %q", s)
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", v, min, max)
+	}
+	return v, nil
+}
+
+// String returns the expression as it was written
+func (s Schedule) String() string {
+	return s.expr
+}
+
+// Matches reports whether the minute containing t is scheduled, in t's
+// location
+func (s Schedule) Matches(t time.Time) bool {
+	return s.minute&(1<<uint(t.Minute())) != 0 &&
+		s.hour&(1<<uint(t.Hour())) != 0 &&
+		s.month&(1<<uint(t.Month())) != 0 &&
+		s.dayMatches(t)
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// Next returns the first scheduled minute after t, looking no further than
+// limit ahead. The search runs in t's location.
+func (s Schedule) Next(t time.Time, limit time.Duration) (time.Time, bool) {
+	end := t.Add(limit)
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	for !t.After(end) {
+		y, mo, d := t.Date()
+		next := t.Add(time.Minute)
+		switch {
+		case s.month&(1<<uint(mo)) == 0:
+			next = time.Date(y, mo+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			next = time.Date(y, mo, d+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			// Counted in minutes, as the next hour may be a repeated one
+			next = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		case s.minute&(1<<uint(t.Minute())) == 0, repeated(t):
+		default:
+			return t, true
+		}
+		// time.Date moves times in a DST gap either way; step through the
+		// gap a minute at a time instead
+		if !next.After(t) {
+			next = t.Add(time.Minute)
+		}
+		t = next
+	}
+	return time.Time{}, false
+}
+
+// Last returns the latest scheduled minute at or before t, looking no
+// further than limit back. The search runs in t's location.
+func (s Schedule) Last(t time.Time, limit time.Duration) (time.Time, bool) {
+	start := t.Add(-limit)
+	loc := t.Location()
+	t = t.Truncate(time.Minute)
+	for !t.Before(start) {
+		y, mo, d := t.Date()
+		prev := t.Add(-time.Minute)
+		switch {
+		case s.month&(1<<uint(mo)) == 0:
+			prev = time.Date(y, mo, 1, 0, 0, 0, 0, loc).Add(-time.Minute)
+		case !s.dayMatches(t):
+			prev = time.Date(y, mo, d, 0, 0, 0, 0, loc).Add(-time.Minute)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			prev = t.Add(-time.Duration(t.Minute()+1) * time.Minute)
+		case s.minute&(1<<uint(t.Minute())) == 0, repeated(t):
+		default:
+			return t, true
+		}
+		if !prev.Before(t) {
+			prev = t.Add(-time.Minute)
+		}
+		t = prev
+	}
+	return time.Time{}, false
+}
+
+// repeated reports whether the wall clock already showed t's time earlier,
+// because clocks went back shortly before t
+func repeated(t time.Time) bool {
+	_, offset := t.Zone()
+	_, before := t.Add(-12 * time.Hour).Zone()
+	if before <= offset {
+		return false
+	}
+	_, earlier := t.Add(-time.Duration(before-offset) * time.Second).Zone()
+	return earlier == before
+}
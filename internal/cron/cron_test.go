@@ -0,0 +1,191 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"", "want 5 fields"},
+		{"* * * *", "want 5 fields"},
+		{"* * * * * *", "want 5 fields"},
+		{"@every 5m", "want 5 fields"},
+		{"60 * * * *", "cron minute: value 60 out of range 0-59"},
+		{"* 24 * * *", "cron hour: value 24 out of range 0-23"},
+		{"* * 0 * *", "cron day of month: value 0 out of range 1-31"},
+		{"* * * 13 *", "cron month: value 13 out of range 1-12"},
+		{"* * * foo *", `cron month: bad value "foo"`},
+		{"* * * * 8", "cron day of week: value 8 out of range 0-7"},
+		{"5-1 * * * *", `cron minute: bad range "5-1"`},
+		{"*/0 * * * *", `cron minute: bad step "0"`},
+		{"*/x * * * *", `cron minute: bad step "x"`},
+		{"1,,2 * * * *", `cron minute: bad value ""`},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.expr); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.expr, err, tt.want)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	// 2026-06-01 is a Monday
+	at := func(day, hour, minute int) time.Time { return time.Date(2026, 6, day, hour, minute, 0, 0, time.UTC) }
+	tests := []struct {
+		expr string
+		t    time.Time
+		want bool
+	}{
+		{"* * * * *", at(1, 0, 0), true},
+		{"*/15 9-17 * * *", at(1, 9, 45), true},
+		{"*/15 9-17 * * *", at(1, 9, 50), false},
+		{"*/15 9-17 * * *", at(1, 18, 0), false},
+		{"5/20 * * * *", at(1, 3, 45), true},
+		{"5/20 * * * *", at(1, 3, 0), false},
+		{"0 22 * * tue", at(2, 22, 0), true},
+		{"0 22 * * TUE", at(1, 22, 0), false},
+		{"0 0 * * 7", at(7, 0, 0), true},
+		{"0 0 * * 0", at(7, 0, 0), true},
+		{"0 0 * jun-aug mon-fri", at(5, 0, 0), true},
+		{"0 0 * jun-aug mon-fri", at(6, 0, 0), false},
+		// Day of month or day of week when both are restricted
+		{"0 0 15 * mon", at(15, 0, 0), true},
+		{"0 0 15 * mon", at(8, 0, 0), true},
+		{"0 0 15 * mon", at(9, 0, 0), false},
+		// Both when either is "*"
+		{"0 0 */2 * mon", at(8, 0, 0), false},
+		{"0 0 */2 * mon", at(15, 0, 0), true},
+		{"@monthly", at(1, 0, 0), true},
+		{"@weekly", at(7, 0, 0), true},
+		{"@weekly", at(1, 0, 0), false},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.Matches(tt.t); got != tt.want {
+			t.Errorf("%q matches %v = %v, want %v", tt.expr, tt.t.Format("Mon Jan 2 15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestNextAndLast(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	utc := func(y int, mo time.Month, d, h, m int) time.Time { return time.Date(y, mo, d, h, m, 0, 0, time.UTC) }
+	// Berlin switches to summer time at 01:00 UTC on 2026-03-29 and back at
+	// 01:00 UTC on 2026-10-25; New York at 07:00 UTC on 2026-03-08 and 06:00
+	// UTC on 2026-11-01
+	tests := []struct {
+		name     string
+		expr     string
+		from     time.Time
+		loc      *time.Location
+		next     time.Time // Zero for none within a year
+		last     time.Time // Zero for none within a year
+		lastFrom time.Time // Defaults to from
+	}{
+		{
+			name: "next day", expr: "0 22 * * *", from: utc(2026, 6, 1, 22, 0), loc: time.UTC,
+			next: utc(2026, 6, 2, 22, 0), last: utc(2026, 6, 1, 22, 0),
+		},
+		{
+			name: "month rollover", expr: "30 23 * * *", from: utc(2026, 1, 31, 23, 45), loc: time.UTC,
+			next: utc(2026, 2, 1, 23, 30), last: utc(2026, 1, 31, 23, 30),
+		},
+		{
+			name: "year rollover", expr: "0 0 1 * *", from: utc(2026, 12, 15, 0, 0), loc: time.UTC,
+			next: utc(2027, 1, 1, 0, 0), last: utc(2026, 12, 1, 0, 0),
+		},
+		{
+			name: "day missing from short months", expr: "0 12 31 * *", from: utc(2026, 3, 31, 13, 0), loc: time.UTC,
+			next: utc(2026, 5, 31, 12, 0), last: utc(2026, 3, 31, 12, 0), lastFrom: utc(2026, 4, 30, 23, 0),
+		},
+		{
+			name: "leap day", expr: "0 6 29 2 *", from: utc(2026, 1, 1, 0, 0), loc: time.UTC,
+			next: time.Time{}, last: time.Time{}, lastFrom: utc(2027, 12, 31, 0, 0),
+		},
+		{
+			name: "weekday across a month", expr: "0 9 * * mon", from: utc(2026, 6, 27, 0, 0), loc: time.UTC,
+			next: utc(2026, 6, 29, 9, 0), last: utc(2026, 6, 22, 9, 0),
+		},
+		{
+			name: "local time across spring forward", expr: "0 3 * * *", from: utc(2026, 3, 28, 12, 0), loc: berlin,
+			next: utc(2026, 3, 29, 1, 0), last: utc(2026, 3, 28, 2, 0),
+		},
+		{
+			name: "time skipped by spring forward", expr: "30 2 * * *", from: utc(2026, 3, 28, 12, 0), loc: berlin,
+			next: utc(2026, 3, 30, 0, 30), last: utc(2026, 3, 28, 1, 30), lastFrom: utc(2026, 3, 29, 12, 0),
+		},
+		{
+			name: "time skipped by spring forward in New York", expr: "30 2 * * *", from: utc(2026, 3, 8, 0, 0), loc: newYork,
+			next: utc(2026, 3, 9, 6, 30), last: utc(2026, 3, 7, 7, 30), lastFrom: utc(2026, 3, 8, 12, 0),
+		},
+		{
+			name: "time repeated by fall back", expr: "30 2 * * *", from: utc(2026, 10, 24, 12, 0), loc: berlin,
+			next: utc(2026, 10, 25, 0, 30), last: utc(2026, 10, 25, 0, 30), lastFrom: utc(2026, 10, 25, 1, 45),
+		},
+		{
+			name: "after the first of a repeated time", expr: "30 1 * * *", from: utc(2026, 11, 1, 5, 31), loc: newYork,
+			next: utc(2026, 11, 2, 6, 30), last: utc(2026, 11, 1, 5, 30), lastFrom: utc(2026, 11, 1, 6, 45),
+		},
+		{
+			name: "every 20 minutes through fall back", expr: "*/20 * * * *", from: utc(2026, 10, 25, 0, 50), loc: berlin,
+			next: utc(2026, 10, 25, 2, 0), last: utc(2026, 10, 25, 0, 40), lastFrom: utc(2026, 10, 25, 1, 50),
+		},
+	}
+	const year = 366 * 24 * time.Hour
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			next, ok := s.Next(tt.from.In(tt.loc), year)
+			if ok != !tt.next.IsZero() || !next.Equal(tt.next) {
+				t.Errorf("next after %v: %v, want %v", tt.from.In(tt.loc), next, tt.next.In(tt.loc))
+			}
+			lastFrom := tt.lastFrom
+			if lastFrom.IsZero() {
+				lastFrom = tt.from
+			}
+			last, ok := s.Last(lastFrom.In(tt.loc), year)
+			if ok != !tt.last.IsZero() || !last.Equal(tt.last) {
+				t.Errorf("last at %v: %v, want %v", lastFrom.In(tt.loc), last, tt.last.In(tt.loc))
+			}
+		})
+	}
+}
+
+func TestSearchLimit(t *testing.T) {
+	s, err := Parse("0 22 * * tue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2026-10-20 is a Tuesday
+	tue := time.Date(2026, 10, 20, 23, 30, 0, 0, time.UTC)
+	if last, ok := s.Last(tue, 90*time.Minute); !ok || last.Hour() != 22 {
+		t.Errorf("last within 90 minutes: %v, %v", last, ok)
+	}
+	if last, ok := s.Last(tue, 89*time.Minute); ok {
+		t.Errorf("last within 89 minutes: %v", last)
+	}
+	if next, ok := s.Next(tue, 7*24*time.Hour); !ok || !next.Equal(tue.Add(7*24*time.Hour-90*time.Minute)) {
+		t.Errorf("next within a week: %v, %v", next, ok)
+	}
+	if next, ok := s.Next(tue, 6*24*time.Hour); ok {
+		t.Errorf("next within six days: %v", next)
+	}
+}
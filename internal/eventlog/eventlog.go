@@ -0,0 +1,279 @@
+// Package eventlog is a durable, append-only log of state-transition
+// events.
+//
+// Events are written as JSON lines to one file per UTC day:
+//
+//	<dir>/<YYYY-MM-DD>.jsonl
+//
+// Events within the retention period are also kept in memory, so queries
+// never touch the disk. Retention deletes whole day files. Event IDs keep
+// increasing across restarts and serve as pagination cursors.
+package eventlog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"server-dashboard/internal/models"
+)
+
+// DefaultRetention applies when Options.Retention is zero
+const DefaultRetention = 30 * 24 * time.Hour
+
+// MaxLimit bounds the events returned by one query
+const MaxLimit = 1000
+
+// Options configures a log
+type Options struct {
+	Dir       string
+	Retention time.Duration // Default 30 days
+}
+
+// Log is an open event log. It is safe for concurrent use.
+type Log struct {
+	dir       string
+	retention time.Duration
+
+	mu      sync.RWMutex
+	events  []models.Event // Oldest ID first; times need not be in order
+	lastID  uint64
+	file    *os.File
+	fileDay string
+}
+
+// Open loads the events within the retention period from dir, creating it
+// if needed
+func Open(opts Options) (*Log, error) {
+	if opts.Retention <= 0 {
+		opts.Retention = DefaultRetention
+	}
+	if err := os.MkdirAll(opts.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("eventlog: %w", err)
+	}
+	l := &Log{dir: opts.Dir, retention: opts.Retention}
+
+	files, err := filepath.Glob(filepath.Join(opts.Dir, "*.jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("eventlog: %w", err)
+	}
+	sort.Strings(files)
+	cutoff := time.Now().Add(-l.retention)
+	for _, f := range files {
+		if err := l.load(f, cutoff); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(l.events, func(i, j int) bool { return l.events[i].ID < l.events[j].ID })
+	l.enforceRetention(time.Now())
+	return l, nil
+}
+
+// load reads one day file. A torn last line, left by a crash mid-write, is
+// skipped and terminated so appends start on a fresh line.
+func (l *Log) load(path string, cutoff time.Time) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("eventlog: %w", err)
+	}
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("eventlog: %w", err)
+		}
+		_, err = f.Write([]byte{'\n'})
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("eventlog: %w", err)
+		}
+	}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var e models.Event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			log.Printf("eventlog: skipping unreadable line in %s: %v", filepath.Base(path), err)
+			continue
+		}
+		if e.ID > l.lastID {
+			l.lastID = e.ID
+		}
+		if e.Time.After(cutoff) {
+			l.events = append(l.events, e)
+		}
+	}
+	return sc.Err()
+}
+
+// Close closes the current day file
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Append assigns the event an ID, and a time if it has none, and writes it.
+// The event is kept in memory even if writing fails.
+func (l *Log) Append(e models.Event) (models.Event, error) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lastID++
+	e.ID = l.lastID
+	l.events = append(l.events, e)
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return e, err
+	}
+	day := dayOf(e.Time)
+	if l.file == nil || day != l.fileDay {
+		if l.file != nil {
+			l.file.Close()
+			l.file = nil
+		}
+		f, err := os.OpenFile(filepath.Join(l.dir, day+".jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return e, fmt.Errorf("eventlog: %w", err)
+		}
+		l.file, l.fileDay = f, day
+		l.enforceRetention(e.Time)
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return e, fmt.Errorf("eventlog: %w", err)
+	}
+	return e, nil
+}
+
+// enforceRetention drops expired events and day files. Callers hold l.mu
+// or own l exclusively.
+func (l *Log) enforceRetention(now time.Time) {
+	cutoff := now.Add(-l.retention)
+	// Times follow the clock or the caller, so expired events can sit
+	// anywhere in the list
+	kept := make([]models.Event, 0, len(l.events))
+	for _, e := range l.events {
+		if e.Time.After(cutoff) {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) < len(l.events) {
+		l.events = kept
+	}
+
+	files, _ := filepath.Glob(filepath.Join(l.dir, "*.jsonl"))
+	oldest := dayOf(cutoff)
+	for _, f := range files {
+		if day := strings.TrimSuffix(filepath.Base(f), ".jsonl"); day < oldest {
+			if err := os.Remove(f); err != nil {
+				log.Printf("eventlog: %v", err)
+			}
+		}
+	}
+}
+
+// Query selects events, newest first. Empty fields match everything.
+type Query struct {
+	Types      []string
+	TargetKind string
+	TargetID   string
+	Since      time.Time
+	Until      time.Time
+	Cursor     string // From a previous page's next cursor
+	Limit      int    // Default 100, at most MaxLimit
+}
+
+// Page is one page of a query result
+type Page struct {
+	Events     []models.Event `json:"events"`
+	NextCursor string         `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// Query returns the newest events matching q, starting after q.Cursor
+func (l *Log) Query(q Query) (Page, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	before := uint64(0)
+	if q.Cursor != "" {
+		n, err := strconv.ParseUint(q.Cursor, 10, 64)
+		if err != nil || n == 0 {
+			return Page{}, fmt.Errorf("invalid cursor %q", q.Cursor)
+		}
+		before = n
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	page := Page{Events: []models.Event{}}
+	start := len(l.events)
+	if before > 0 {
+		start = sort.Search(len(l.events), func(i int) bool { return l.events[i].ID >= before })
+	}
+	for i := start - 1; i >= 0; i-- {
+		e := l.events[i]
+		if !matches(q, e) {
+			continue
+		}
+		if len(page.Events) == limit {
+			page.NextCursor = strconv.FormatUint(page.Events[limit-1].ID, 10)
+			break
+		}
+		page.Events = append(page.Events, e)
+	}
+	return page, nil
+}
+
+func matches(q Query, e models.Event) bool {
+	if len(q.Types) > 0 {
+		found := false
+		for _, t := range q.Types {
+			if t == e.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.TargetKind != "" && q.TargetKind != e.TargetKind {
+		return false
+	}
+	if q.TargetID != "" && q.TargetID != e.TargetID {
+		return false
+	}
+	if !q.Since.IsZero() && e.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.Time.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+func dayOf(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
@@ -0,0 +1,161 @@
+package eventlog
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"server-dashboard/internal/models"
+)
+
+func openTest(t *testing.T, dir string) *Log {
+	t.Helper()
+	l, err := Open(Options{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+// ids returns the IDs of a query's events
+func ids(t *testing.T, l *Log, q Query) []uint64 {
+	t.Helper()
+	p, err := l.Query(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []uint64
+	for _, e := range p.Events {
+		out = append(out, e.ID)
+	}
+	return out
+}
+
+func TestQueryPages(t *testing.T) {
+	l := openTest(t, t.TempDir())
+	for i := 0; i < 25; i++ {
+		typ := models.EventStatus
+		if i%2 == 1 {
+			typ = models.EventPing
+		}
+		l.Append(models.Event{Type: typ, TargetKind: "server", TargetID: "web" + strconv.Itoa(i%3)})
+	}
+
+	var got []uint64
+	q := Query{Types: []string{models.EventStatus}, Limit: 5}
+	for pages := 0; ; pages++ {
+		p, err := l.Query(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pages > 5 {
+			t.Fatal("pages never end")
+		}
+		for _, e := range p.Events {
+			got = append(got, e.ID)
+		}
+		if p.NextCursor == "" {
+			break
+		}
+		q.Cursor = p.NextCursor
+	}
+	if want := []uint64{25, 23, 21, 19, 17, 15, 13, 11, 9, 7, 5, 3, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("status events = %v, want %v", got, want)
+	}
+
+	if got := ids(t, l, Query{TargetKind: "server", TargetID: "web1", Limit: 4}); !reflect.DeepEqual(got, []uint64{23, 20, 17, 14}) {
+		t.Errorf("web1 events = %v", got)
+	}
+	if _, err := l.Query(Query{Cursor: "x"}); err == nil {
+		t.Error("invalid cursor accepted")
+	}
+}
+
+// Event times need not follow IDs: callers may set them and the clock can
+// step back. Time ranges still find every event in them.
+func TestQueryOutOfOrderTimes(t *testing.T) {
+	l := openTest(t, t.TempDir())
+	now := time.Now()
+	for _, ago := range []time.Duration{time.Hour, 2 * time.Hour, 10 * time.Minute, 3 * time.Hour, 5 * time.Minute} {
+		if _, err := l.Append(models.Event{Type: models.EventStatus, Time: now.Add(-ago)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		since, until time.Duration // Before now, zero for none
+		want         []uint64
+	}{
+		{0, 0, []uint64{5, 4, 3, 2, 1}},
+		{90 * time.Minute, 0, []uint64{5, 3, 1}},
+		{30 * time.Minute, 0, []uint64{5, 3}},
+		{4 * time.Hour, 90 * time.Minute, []uint64{4, 2}},
+		{2*time.Hour + time.Minute, 7 * time.Minute, []uint64{3, 2, 1}},
+	}
+	for _, tt := range tests {
+		q := Query{}
+		if tt.since > 0 {
+			q.Since = now.Add(-tt.since)
+		}
+		if tt.until > 0 {
+			q.Until = now.Add(-tt.until)
+		}
+		if got := ids(t, l, q); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("since %v until %v ago: %v, want %v", tt.since, tt.until, got, tt.want)
+		}
+	}
+}
+
+func TestRetention(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "2000-01-01.jsonl")
+	if err := os.WriteFile(old, []byte(`{"id":5,"time":"2000-01-01T00:00:00Z"}`+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	l := openTest(t, dir)
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("expired day file kept: %v", err)
+	}
+
+	// IDs continue after the expired ones, and an expired event appended
+	// between recent ones is dropped without them
+	now := time.Now()
+	for _, ago := range []time.Duration{time.Hour, 40 * 24 * time.Hour, time.Minute} {
+		l.Append(models.Event{Type: models.EventStatus, Time: now.Add(-ago)})
+	}
+	l.mu.Lock()
+	l.enforceRetention(now)
+	l.mu.Unlock()
+	if got := ids(t, l, Query{}); !reflect.DeepEqual(got, []uint64{8, 6}) {
+		t.Errorf("events = %v, want [8 6]", got)
+	}
+}
+
+// A line torn by a crash mid-write is skipped, and the next event starts on
+// a new line
+func TestTornLine(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().UTC().Format(time.RFC3339)
+	torn := `{"id":3,"time":"` + now + `"}` + "\n" + `{"id":4,"ti`
+	if err := os.WriteFile(filepath.Join(dir, dayOf(time.Now())+".jsonl"), []byte(torn), 0600); err != nil {
+		t.Fatal(err)
+	}
+	l, err := Open(Options{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := l.Append(models.Event{Type: models.EventStatus})
+	if err != nil || e.ID != 4 {
+		t.Fatalf("appended %d, %v; want ID 4", e.ID, err)
+	}
+	l.Close()
+
+	l = openTest(t, dir)
+	if got := ids(t, l, Query{}); !reflect.DeepEqual(got, []uint64{4, 3}) {
+		t.Errorf("events after reopening = %v, want [4 3]", got)
+	}
+}
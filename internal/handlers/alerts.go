@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/middleware"
+	"server-dashboard/internal/models"
+	"server-dashboard/internal/services"
+)
+
+// AlertsResponse is the body of /api/alerts
+type AlertsResponse struct {
+	Enabled bool               `json:"enabled"`
+	Alerts  []models.Alert     `json:"alerts"`
+	Rules   []models.AlertRule `json:"rules"`
+}
+
+// AlertRow is an alert with a link to its device or synthetic check
+type AlertRow struct {
+	models.Alert
+	Link string
+}
+
+// AlertsPageHandler lists firing, pending and recently resolved alerts and
+// the configured rules
+func AlertsPageHandler(cfg *config.Config, templates *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, _ := middleware.GetUsername(r)
+		servers, _ := services.GetAllServers()
+		vms, _ := services.GetAllVMs()
+		switches, _ := services.GetAllSwitches()
+
+		var rows []AlertRow
+		counts := map[string]int{}
+		for _, a := range services.GetAlerts() {
+			rows = append(rows, AlertRow{Alert: a, Link: services.TargetPath(a.TargetKind, a.TargetID)})
+			counts[a.State]++
+		}
+
+		data := map[string]interface{}{
+			"Username":    username,
+			"IsAdmin":     isAdminUser(cfg, username),
+			"ServerCount": len(servers),
+			"VMCount":     len(vms),
+			"SwitchCount": len(switches),
+			"Enabled":     services.AlertingEnabled(),
+			"Alerts":      rows,
+			"Rules":       services.GetAlertRules(),
+			"Firing":      counts[models.AlertFiring],
+			"Pending":     counts[models.AlertPending],
+			"Resolved":    counts[models.AlertResolved],
+		}
+		if err := templates.ExecuteTemplate(w, "alerts.html", data); err != nil {
+			log.Printf("Error rendering template: %v", err)
+		}
+	}
+}
+
+// AlertsAPIHandler serves /api/alerts. The state and severity parameters
+// narrow the list, e.g. ?state=firing&severity=critical.
+func AlertsAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		state, severity := q.Get("state"), q.Get("severity")
+
+		list := []models.Alert{}
+		for _, a := range services.GetAlerts() {
+			if (state == "" || a.State == state) && (severity == "" || a.Severity == severity) {
+				list = append(list, a)
+			}
+		}
+		rules := services.GetAlertRules()
+		if rules == nil {
+			rules = []models.AlertRule{}
+		}
+		writeJSON(w, http.StatusOK, AlertsResponse{Enabled: services.AlertingEnabled(), Alerts: list, Rules: rules})
+	}
+}
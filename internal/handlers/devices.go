@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+
+	"server-dashboard/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// DeviceResponse is a device's current state as served by /api/devices/{id}
+type DeviceResponse struct {
+	Kind   string      `json:"kind"`   // server, vm or switch
+	Device interface{} `json:"device"` // The server, VM or switch model, including its filesystems
+}
+
+// DeviceAPIHandler serves /api/devices/{id}: the server, VM or switch with
+// that ID as last checked
+func DeviceAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		if srv, ok := services.GetServer(id); ok {
+			writeJSON(w, http.StatusOK, DeviceResponse{Kind: services.KindServer, Device: srv})
+			return
+		}
+		if vm, ok := services.GetVM(id); ok {
+			writeJSON(w, http.StatusOK, DeviceResponse{Kind: services.KindVM, Device: vm})
+			return
+		}
+		if sw, ok := services.GetSwitch(id); ok {
+			writeJSON(w, http.StatusOK, DeviceResponse{Kind: services.KindSwitch, Device: sw})
+			return
+		}
+		http.Error(w, services.ErrUnknownDevice.Error(), http.StatusNotFound)
+	}
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/eventlog"
+	"server-dashboard/internal/middleware"
+	"server-dashboard/internal/models"
+	"server-dashboard/internal/services"
+)
+
+var errBadLimit = errors.New("limit must be a positive number")
+
+// eventTypes are the event types in the order the timeline filter lists them
+var eventTypes = []string{
+	models.EventStatus, models.EventPing, models.EventSynthetic,
+	models.EventFailedServices, models.EventStream, models.EventFlapping,
+}
+
+// timelineRanges are the time ranges the timeline filter offers
+var timelineRanges = []struct{ Value, Label string }{
+	{"-1h", "Last hour"}, {"-24h", "Last 24 hours"}, {"-168h", "Last 7 days"}, {"", "Everything kept"},
+}
+
+// EventRow is an event with a link to its target and badge classes for its
+// old and new states
+type EventRow struct {
+	models.Event
+	Link     string
+	OldClass string
+	NewClass string
+}
+
+// EventTarget is an option of the timeline's target filter
+type EventTarget struct {
+	Value string // kind/id
+	Label string
+}
+
+// eventQuery reads the filter parameters shared by the timeline page and
+// /api/events
+func eventQuery(q url.Values, now time.Time) (eventlog.Query, error) {
+	var eq eventlog.Query
+	for _, t := range q["type"] {
+		for _, v := range strings.Split(t, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				eq.Types = append(eq.Types, v)
+			}
+		}
+	}
+	eq.TargetKind = q.Get("kind")
+	eq.TargetID = q.Get("target")
+	eq.Cursor = q.Get("cursor")
+	var err error
+	if eq.Since, err = parseHistoryTime(q.Get("since"), time.Time{}, now); err != nil {
+		return eq, err
+	}
+	if eq.Until, err = parseHistoryTime(q.Get("until"), time.Time{}, now); err != nil {
+		return eq, err
+	}
+	if l := q.Get("limit"); l != "" {
+		if eq.Limit, err = strconv.Atoi(l); err != nil || eq.Limit <= 0 {
+			return eq, errBadLimit
+		}
+	}
+	return eq, nil
+}
+
+// eventStateClass picks a badge color for a state
+func eventStateClass(typ, state string) string {
+	switch state {
+	case models.StatusOnline, "running", "ok", "up", "stable":
+		return "bg-success"
+	case models.StatusOffline, "fail", "down":
+		return "bg-danger"
+	case "flapping":
+		return "bg-warning text-dark"
+	case "unknown", "":
+		return "bg-secondary"
+	}
+	if typ == models.EventFailedServices {
+		if state == "0" {
+			return "bg-success"
+		}
+		return "bg-warning text-dark"
+	}
+	return models.StatusBadgeClass(state)
+}
+
+// EventsPageHandler shows the timeline of state transitions, newest first
+func EventsPageHandler(cfg *config.Config, templates *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, _ := middleware.GetUsername(r)
+		servers, _ := services.GetAllServers()
+		vms, _ := services.GetAllVMs()
+		switches, _ := services.GetAllSwitches()
+
+		q := r.URL.Query()
+		if _, ok := q["since"]; !ok {
+			q.Set("since", "-24h")
+		}
+		// The page's target filter carries kind and ID in one value
+		if kind, id, ok := strings.Cut(q.Get("device"), "/"); ok {
+			q.Set("kind", kind)
+			q.Set("target", id)
+		}
+		errMsg := ""
+		var page eventlog.Page
+		eq, err := eventQuery(q, time.Now())
+		if err == nil {
+			page, err = services.QueryEvents(eq)
+		}
+		if err != nil {
+			errMsg = err.Error()
+		}
+
+		rows := make([]EventRow, 0, len(page.Events))
+		for _, e := range page.Events {
+			rows = append(rows, EventRow{
+				Event:    e,
+				Link:     services.TargetPath(e.TargetKind, e.TargetID),
+				OldClass: eventStateClass(e.Type, e.Old),
+				NewClass: eventStateClass(e.Type, e.New),
+			})
+		}
+
+		var targets []EventTarget
+		for _, s := range servers {
+			targets = append(targets, EventTarget{Value: services.KindServer + "/" + s.ID, Label: "Server: " + s.Name})
+		}
+		for _, vm := range vms {
+			targets = append(targets, EventTarget{Value: services.KindVM + "/" + vm.ID, Label: "VM: " + vm.Name})
+		}
+		for _, sw := range switches {
+			targets = append(targets, EventTarget{Value: services.KindSwitch + "/" + sw.ID, Label: "Switch: " + sw.Name})
+		}
+		for _, c := range services.GetSyntheticResults() {
+			targets = append(targets, EventTarget{Value: services.KindSynthetic + "/" + c.ID, Label: "Synthetic: " + c.Name})
+		}
+
+		older := ""
+		if page.NextCursor != "" {
+			next := r.URL.Query()
+			next.Set("cursor", page.NextCursor)
+			older = "/events?" + next.Encode()
+		}
+
+		data := map[string]interface{}{
+			"Username":    username,
+			"IsAdmin":     isAdminUser(cfg, username),
+			"ServerCount": len(servers),
+			"VMCount":     len(vms),
+			"SwitchCount": len(switches),
+			"Enabled":     services.EventsEnabled(),
+			"Events":      rows,
+			"Types":       eventTypes,
+			"Ranges":      timelineRanges,
+			"Targets":     targets,
+			"Type":        q.Get("type"),
+			"Device":      q.Get("device"),
+			"Since":       q.Get("since"),
+			"Paged":       q.Get("cursor") != "",
+			"OlderLink":   older,
+			"Error":       errMsg,
+		}
+		if err := templates.ExecuteTemplate(w, "events.html", data); err != nil {
+			log.Printf("Error rendering template: %v", err)
+		}
+	}
+}
+
+// EventsAPIHandler serves /api/events, newest first. Filters: type (comma
+// separated), kind, target (device or check ID), since and until (RFC 3339,
+// Unix seconds or a duration such as "-24h") and limit (default 100, at
+// most 1000). Pass a page's next_cursor as cursor to get the next page.
+func EventsAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		eq, err := eventQuery(r.URL.Query(), time.Now())
+		if err != nil {
+			http.Error(w, "invalid query: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		page, err := services.QueryEvents(eq)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusOK, page)
+	}
+}
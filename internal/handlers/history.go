@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"server-dashboard/internal/services"
+	"server-dashboard/internal/tsdb"
+
+	"github.com/gorilla/mux"
+)
+
+// DeviceMetricsResponse is one metric of a device over a time range
+type DeviceMetricsResponse struct {
+	DeviceID    string       `json:"device_id"`
+	Name        string       `json:"name"`
+	From        time.Time    `json:"from"`
+	To          time.Time    `json:"to"`
+	StepSeconds float64      `json:"step_seconds"`
+	Tier        string       `json:"tier"` // raw, 1m or 1h
+	Points      []tsdb.Point `json:"points"`
+}
+
+// DeviceMetricNamesResponse lists the metrics recorded for a device
+type DeviceMetricNamesResponse struct {
+	DeviceID string   `json:"device_id"`
+	Metrics  []string `json:"metrics"`
+}
+
+// DeviceMetricsHandler serves /api/devices/{id}/metrics. With a name
+// parameter it returns that metric between from and to (default the last
+// 24 hours) in steps; without one it lists the recorded metric names.
+//
+// from and to accept RFC 3339 times, Unix seconds or a duration relative to
+// now such as "-6h"; step accepts a duration ("5m") or seconds.
+func DeviceMetricsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		q := r.URL.Query()
+		name := q.Get("name")
+
+		if name == "" {
+			names, err := services.HistoryMetricNames(id)
+			if err != nil {
+				writeHistoryError(w, err)
+				return
+			}
+			if names == nil {
+				names = []string{}
+			}
+			writeJSON(w, http.StatusOK, DeviceMetricNamesResponse{DeviceID: id, Metrics: names})
+			return
+		}
+
+		now := time.Now()
+		to, err := parseHistoryTime(q.Get("to"), now, now)
+		if err != nil {
+			http.Error(w, "invalid to: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		from, err := parseHistoryTime(q.Get("from"), to.Add(-24*time.Hour), now)
+		if err != nil {
+			http.Error(w, "invalid from: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		step, err := parseHistoryStep(q.Get("step"))
+		if err != nil {
+			http.Error(w, "invalid step: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		res, err := services.QueryHistory(id, name, from, to, step)
+		if err != nil {
+			writeHistoryError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, DeviceMetricsResponse{
+			DeviceID:    id,
+			Name:        name,
+			From:        from,
+			To:          to,
+			StepSeconds: res.Step.Seconds(),
+			Tier:        res.Tier,
+			Points:      res.Points,
+		})
+	}
+}
+
+func writeHistoryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownDevice):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrHistoryDisabled):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// writeJSON writes v as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// parseHistoryTime parses an RFC 3339 time, Unix seconds or a duration
+// relative to now
+func parseHistoryTime(s string, def, now time.Time) (time.Time, error) {
+	if s == "" {
+		return def, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	if d, err := time.ParseDuration(strings.TrimPrefix(s, "now")); err == nil {
+		return now.Add(d), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a time, Unix timestamp or relative duration", s)
+}
+
+// parseHistoryStep parses a duration or a number of seconds
+func parseHistoryStep(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if secs, err := strconv.ParseFloat(s, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%q is not a positive duration", s)
+	}
+	return d, nil
+}
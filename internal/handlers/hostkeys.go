@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/middleware"
+	"server-dashboard/internal/services"
+)
+
+// HostKeysPageHandler lists SSH host keys awaiting review and lets admins
+// accept or reject them
+func HostKeysPageHandler(cfg *config.Config, templates *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := middleware.GetUsername(r)
+		if !ok {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		if !isAdminUser(cfg, username) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		store := services.GetHostKeyStore()
+
+		if r.Method == http.MethodGet {
+			renderHostKeys(templates, cfg, username, store, "", w)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		if store == nil {
+			renderHostKeys(templates, cfg, username, store, "Host key verification is not active", w)
+			return
+		}
+
+		host := r.FormValue("host")
+		fingerprint := r.FormValue("fingerprint")
+		var err error
+		switch r.FormValue("action") {
+		case "accept":
+			err = store.Accept(host, fingerprint)
+			if err == nil {
+				log.Printf("User %s accepted SSH host key %s for %s", username, fingerprint, host)
+			}
+		case "reject":
+			err = store.Reject(host, fingerprint)
+			if err == nil {
+				log.Printf("User %s rejected SSH host key %s for %s", username, fingerprint, host)
+			}
+		default:
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			renderHostKeys(templates, cfg, username, store, err.Error(), w)
+			return
+		}
+		http.Redirect(w, r, "/admin/host-keys", http.StatusFound)
+	}
+}
+
+func renderHostKeys(t *template.Template, cfg *config.Config, username string, store *services.HostKeyStore, msg string, w http.ResponseWriter) {
+	data := map[string]interface{}{
+		"Username": username,
+		"IsAdmin":  true,
+		"Error":    msg,
+		"Enabled":  store != nil,
+		"Mode":     cfg.SSH.HostKeyMode,
+	}
+	if data["Mode"] == "" {
+		data["Mode"] = services.HostKeyModeStrict
+	}
+	if store != nil {
+		data["Mode"] = store.Mode()
+		data["KnownHostsPath"] = store.KnownHostsPath()
+		data["Records"] = store.Records()
+	}
+	t.ExecuteTemplate(w, "host-keys.html", data)
+}
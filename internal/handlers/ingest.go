@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/models"
+	"server-dashboard/internal/services"
+)
+
+// maxIngestBytes bounds the size of one agent report
+const maxIngestBytes = 1 << 20
+
+// IngestResponse acknowledges an agent report
+type IngestResponse struct {
+	Accepted bool   `json:"accepted"`
+	Message  string `json:"message,omitempty"`
+}
+
+// IngestHandler accepts metrics pushed by dashboard-agent. It sits outside
+// session auth; agents authenticate with their device's agent_token.
+func IngestHandler(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if r.TLS == nil && !cfg.Ingest.AllowHTTP {
+			writeIngestResponse(w, http.StatusForbidden, "ingest requires HTTPS (set ingest.allow_http behind a TLS proxy)")
+			return
+		}
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || token == r.Header.Get("Authorization") {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="ingest"`)
+			writeIngestResponse(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		var report models.AgentReport
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxIngestBytes))
+		if err := decoder.Decode(&report); err != nil {
+			writeIngestResponse(w, http.StatusBadRequest, "invalid report: "+err.Error())
+			return
+		}
+
+		kind, ok := services.AuthenticateAgent(report.DeviceID, token)
+		if !ok {
+			log.Printf("Rejected agent report for %q from %s: invalid token", report.DeviceID, r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="ingest"`)
+			writeIngestResponse(w, http.StatusUnauthorized, "unknown device or invalid token")
+			return
+		}
+
+		services.RecordAgentReport(kind, report.DeviceID, report, r.RemoteAddr)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(IngestResponse{Accepted: true})
+	}
+}
+
+func writeIngestResponse(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(IngestResponse{Accepted: false, Message: message})
+}
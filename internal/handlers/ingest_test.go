@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/models"
+	"server-dashboard/internal/services"
+)
+
+// withIngestConfig installs a config with two agent-monitored servers
+func withIngestConfig(t *testing.T, allowHTTP bool) *config.Config {
+	t.Helper()
+	cfg := &config.Config{
+		Ingest: config.IngestConfig{AllowHTTP: allowHTTP},
+		Servers: []config.ServerConfig{
+			{ID: "web1", AgentToken: "web1-token"},
+			{ID: "db1", AgentToken: "db1-token"},
+			{ID: "legacy"},
+		},
+	}
+	old := services.Config
+	services.Config = cfg
+	t.Cleanup(func() { services.Config = old })
+	return cfg
+}
+
+func ingestRequest(t *testing.T, url, token string, report models.AgentReport) *http.Request {
+	t.Helper()
+	body, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+func TestIngestHandlerRejects(t *testing.T) {
+	report := models.AgentReport{DeviceID: "web1", Metrics: models.HostMetrics{KernelVersion: "6.1.0"}}
+	tests := []struct {
+		name      string
+		allowHTTP bool
+		url       string
+		token     string
+		report    models.AgentReport
+		want      int
+	}{
+		{"plain HTTP", false, "http://dashboard/api/ingest", "web1-token", report, http.StatusForbidden},
+		{"no token", false, "https://dashboard/api/ingest", "", report, http.StatusUnauthorized},
+		{"bad token", false, "https://dashboard/api/ingest", "guess", report, http.StatusUnauthorized},
+		{"another device's token", false, "https://dashboard/api/ingest", "db1-token", report, http.StatusUnauthorized},
+		{"device without a token", true, "http://dashboard/api/ingest", "web1-token", models.AgentReport{DeviceID: "legacy"}, http.StatusUnauthorized},
+		{"unknown device", true, "http://dashboard/api/ingest", "web1-token", models.AgentReport{DeviceID: "web2"}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withIngestConfig(t, tt.allowHTTP)
+			w := httptest.NewRecorder()
+			IngestHandler(services.Config)(w, ingestRequest(t, tt.url, tt.token, tt.report))
+			if w.Code != tt.want {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			var resp IngestResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Accepted || resp.Message == "" {
+				t.Errorf("response %s, err %v", w.Body, err)
+			}
+			if tt.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("no WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestIngestHandlerStoresReport(t *testing.T) {
+	cfg := withIngestConfig(t, true)
+	agent, ok := services.GetCollector(services.CollectorAgent)
+	if !ok {
+		t.Fatal("agent collector not registered")
+	}
+	target := services.CollectTarget{Kind: services.KindServer, ID: "db1"}
+
+	collectedAt := time.Now().UTC().Truncate(time.Second)
+	report := models.AgentReport{DeviceID: "db1", CollectedAt: collectedAt, Metrics: models.HostMetrics{KernelVersion: "6.1.0", Processes: 42}}
+	w := httptest.NewRecorder()
+	IngestHandler(cfg)(w, ingestRequest(t, "http://dashboard/api/ingest", "db1-token", report))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	m, _, err := agent.Collect(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.KernelVersion != "6.1.0" || m.Processes != 42 || !m.SampledAt.Equal(collectedAt) {
+		t.Errorf("stored metrics: %+v", m)
+	}
+}
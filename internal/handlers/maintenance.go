@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/middleware"
+	"server-dashboard/internal/models"
+	"server-dashboard/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// datetimeLocalLayout is the value format of <input type="datetime-local">
+const datetimeLocalLayout = "2006-01-02T15:04"
+
+// MaintenanceResponse is the body of /api/maintenance
+type MaintenanceResponse struct {
+	Windows  []models.MaintenanceWindow `json:"windows"`
+	Silences []models.Silence           `json:"silences"`
+	Targets  []models.MaintenanceTarget `json:"in_maintenance"` // Devices and checks in an active window
+}
+
+// SilenceRequest is the body of POST /api/maintenance/silences. ExpiresIn,
+// a duration such as "2h", may be given instead of expires_at.
+type SilenceRequest struct {
+	models.Silence
+	ExpiresIn string `json:"expires_in"`
+}
+
+// MaintenancePageHandler lists maintenance windows and silences and lets
+// admins add and remove them
+func MaintenancePageHandler(cfg *config.Config, templates *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := middleware.GetUsername(r)
+		if !ok {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		isAdmin := isAdminUser(cfg, username)
+
+		errMsg := ""
+		if r.Method == http.MethodPost {
+			if !isAdmin {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, "Bad Request", http.StatusBadRequest)
+				return
+			}
+			if err := maintenanceFormAction(r, username); err != nil {
+				errMsg = err.Error()
+			} else {
+				http.Redirect(w, r, "/maintenance", http.StatusFound)
+				return
+			}
+		}
+
+		servers, _ := services.GetAllServers()
+		vms, _ := services.GetAllVMs()
+		switches, _ := services.GetAllSwitches()
+		data := map[string]interface{}{
+			"Username":    username,
+			"IsAdmin":     isAdmin,
+			"ServerCount": len(servers),
+			"VMCount":     len(vms),
+			"SwitchCount": len(switches),
+			"Servers":     servers,
+			"VMs":         vms,
+			"Switches":    switches,
+			"Synthetics":  services.GetSyntheticResults(),
+			"Windows":     services.GetMaintenanceWindows(),
+			"Silences":    services.GetSilences(),
+			"Targets":     services.MaintenanceTargets(),
+			"Rules":       services.GetAlertRules(),
+			"Timezone":    time.Now().Format("MST"),
+			"Error":       errMsg,
+		}
+		if err := templates.ExecuteTemplate(w, "maintenance.html", data); err != nil {
+			log.Printf("Error rendering template: %v", err)
+		}
+	}
+}
+
+// maintenanceFormAction applies one form submission from the maintenance page
+func maintenanceFormAction(r *http.Request, username string) error {
+	switch r.FormValue("action") {
+	case "add-window":
+		win := models.MaintenanceWindow{
+			Name:             r.FormValue("name"),
+			Comment:          strings.TrimSpace(r.FormValue("comment")),
+			MaintenanceScope: formScope(r),
+		}
+		if r.FormValue("kind") == "recurring" {
+			win.Schedule = strings.TrimSpace(r.FormValue("schedule"))
+			win.DurationMinutes, _ = strconv.Atoi(r.FormValue("duration_minutes"))
+			win.Timezone = strings.TrimSpace(r.FormValue("timezone"))
+		} else {
+			var err error
+			if win.Start, err = time.ParseInLocation(datetimeLocalLayout, r.FormValue("start"), time.Local); err != nil {
+				return errors.New("start must be a date and time")
+			}
+			if win.End, err = time.ParseInLocation(datetimeLocalLayout, r.FormValue("end"), time.Local); err != nil {
+				return errors.New("end must be a date and time")
+			}
+		}
+		added, err := services.AddMaintenanceWindow(win, username)
+		if err == nil {
+			log.Printf("User %s added maintenance window %q (%s)", username, added.Name, added.ID)
+		}
+		return err
+	case "delete-window":
+		deleted, err := services.DeleteMaintenanceWindow(r.FormValue("id"))
+		if err == nil {
+			log.Printf("User %s deleted maintenance window %q (%s)", username, deleted.Name, deleted.ID)
+		}
+		return err
+	case "add-silence":
+		d, err := time.ParseDuration(r.FormValue("expires_in"))
+		if err != nil || d <= 0 {
+			return errors.New("expires in must be a duration such as 2h or 30m")
+		}
+		sl := models.Silence{
+			Comment:          r.FormValue("comment"),
+			Rules:            r.Form["rules"],
+			MaintenanceScope: formScope(r),
+			ExpiresAt:        time.Now().Add(d).UTC(),
+		}
+		added, err := services.AddSilence(sl, username)
+		if err == nil {
+			log.Printf("User %s added silence %s until %s: %s", username, added.ID, added.ExpiresAt.Format(time.RFC3339), added.Comment)
+		}
+		return err
+	case "expire-silence":
+		expired, err := services.ExpireSilence(r.FormValue("id"))
+		if err == nil {
+			log.Printf("User %s expired silence %s", username, expired.ID)
+		}
+		return err
+	}
+	return errors.New("unknown action")
+}
+
+// formScope reads the device and synthetic selections and the
+// comma-separated tags of a maintenance form
+func formScope(r *http.Request) models.MaintenanceScope {
+	return models.MaintenanceScope{
+		Devices:    r.Form["devices"],
+		Tags:       strings.Split(r.FormValue("tags"), ","),
+		Synthetics: r.Form["synthetics"],
+	}
+}
+
+// MaintenanceAPIHandler serves GET /api/maintenance
+func MaintenanceAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := MaintenanceResponse{
+			Windows:  services.GetMaintenanceWindows(),
+			Silences: services.GetSilences(),
+			Targets:  services.MaintenanceTargets(),
+		}
+		if resp.Windows == nil {
+			resp.Windows = []models.MaintenanceWindow{}
+		}
+		if resp.Silences == nil {
+			resp.Silences = []models.Silence{}
+		}
+		if resp.Targets == nil {
+			resp.Targets = []models.MaintenanceTarget{}
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// MaintenanceWindowAPIHandler serves POST /api/maintenance/windows and
+// DELETE /api/maintenance/windows/{id} for admins
+func MaintenanceWindowAPIHandler(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, _ := middleware.GetUsername(r)
+		if !isAdminUser(cfg, username) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		if r.Method == http.MethodDelete {
+			deleted, err := services.DeleteMaintenanceWindow(mux.Vars(r)["id"])
+			if err != nil {
+				maintenanceError(w, err)
+				return
+			}
+			log.Printf("User %s deleted maintenance window %q (%s)", username, deleted.Name, deleted.ID)
+			writeJSON(w, http.StatusOK, deleted)
+			return
+		}
+
+		var win models.MaintenanceWindow
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&win); err != nil {
+			http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		added, err := services.AddMaintenanceWindow(win, username)
+		if err != nil {
+			maintenanceError(w, err)
+			return
+		}
+		log.Printf("User %s added maintenance window %q (%s)", username, added.Name, added.ID)
+		writeJSON(w, http.StatusCreated, added)
+	}
+}
+
+// SilenceAPIHandler serves POST /api/maintenance/silences and
+// DELETE /api/maintenance/silences/{id} for admins. Deleting a silence
+// expires it.
+func SilenceAPIHandler(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, _ := middleware.GetUsername(r)
+		if !isAdminUser(cfg, username) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		if r.Method == http.MethodDelete {
+			expired, err := services.ExpireSilence(mux.Vars(r)["id"])
+			if err != nil {
+				maintenanceError(w, err)
+				return
+			}
+			log.Printf("User %s expired silence %s", username, expired.ID)
+			writeJSON(w, http.StatusOK, expired)
+			return
+		}
+
+		var req SilenceRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.ExpiresIn != "" {
+			d, err := time.ParseDuration(req.ExpiresIn)
+			if err != nil || d <= 0 {
+				http.Error(w, "expires_in must be a positive duration such as 2h", http.StatusBadRequest)
+				return
+			}
+			req.ExpiresAt = time.Now().Add(d).UTC()
+		}
+		added, err := services.AddSilence(req.Silence, username)
+		if err != nil {
+			maintenanceError(w, err)
+			return
+		}
+		log.Printf("User %s added silence %s until %s: %s", username, added.ID, added.ExpiresAt.Format(time.RFC3339), added.Comment)
+		writeJSON(w, http.StatusCreated, added)
+	}
+}
+
+func maintenanceError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrMaintenanceNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
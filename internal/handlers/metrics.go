@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/middleware"
+	"server-dashboard/internal/promtext"
+	"server-dashboard/internal/services"
+)
+
+// MetricsHandler serves the Prometheus exposition. It sits outside session
+// auth so scrapers can present metrics.token instead of a session cookie.
+func MetricsHandler(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.Metrics.Enabled {
+			http.NotFound(w, r)
+			return
+		}
+		if !metricsAuthorized(cfg, r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", promtext.ContentType)
+		if err := services.WriteExposition(w); err != nil {
+			log.Printf("Failed to write metrics: %v", err)
+		}
+	}
+}
+
+// metricsAuthorized accepts the configured bearer token or a logged-in
+// session. Without a token and with auth disabled the endpoint is open.
+func metricsAuthorized(cfg *config.Config, r *http.Request) bool {
+	if cfg.Metrics.Token != "" {
+		header := r.Header.Get("Authorization")
+		if token := strings.TrimPrefix(header, "Bearer "); token != header &&
+			subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Metrics.Token)) == 1 {
+			return true
+		}
+	}
+	if _, ok := middleware.GetUsername(r); ok {
+		return true
+	}
+	return cfg.Metrics.Token == "" && !cfg.Auth.Enabled
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/promtext"
+)
+
+func TestMetricsHandlerAccess(t *testing.T) {
+	tests := []struct {
+		name    string
+		metrics config.MetricsConfig
+		auth    bool
+		header  string
+		want    int
+	}{
+		{"disabled", config.MetricsConfig{Token: "scrape"}, false, "Bearer scrape", http.StatusNotFound},
+		{"open without token or auth", config.MetricsConfig{Enabled: true}, false, "", http.StatusOK},
+		{"session needed with auth", config.MetricsConfig{Enabled: true}, true, "", http.StatusUnauthorized},
+		{"token", config.MetricsConfig{Enabled: true, Token: "scrape"}, true, "Bearer scrape", http.StatusOK},
+		{"missing token", config.MetricsConfig{Enabled: true, Token: "scrape"}, false, "", http.StatusUnauthorized},
+		{"wrong token", config.MetricsConfig{Enabled: true, Token: "scrape"}, false, "Bearer scrap", http.StatusUnauthorized},
+		{"token without Bearer", config.MetricsConfig{Enabled: true, Token: "scrape"}, false, "scrape", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		cfg := &config.Config{Metrics: tt.metrics}
+		cfg.Auth.Enabled = tt.auth
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		MetricsHandler(cfg)(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.want)
+		}
+		if tt.want == http.StatusOK && w.Header().Get("Content-Type") != promtext.ContentType {
+			t.Errorf("%s: content type %q", tt.name, w.Header().Get("Content-Type"))
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/middleware"
+	"server-dashboard/internal/notify"
+	"server-dashboard/internal/services"
+)
+
+// NotificationsResponse is the body of /api/notifications
+type NotificationsResponse struct {
+	Enabled    bool                 `json:"enabled"`
+	Channels   []notify.ChannelInfo `json:"channels"`
+	Deliveries []notify.Delivery    `json:"deliveries"` // Newest first
+}
+
+// NotificationsPageHandler shows the notification channels and delivery
+// log and lets admins send a test message through a channel
+func NotificationsPageHandler(cfg *config.Config, templates *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, ok := middleware.GetUsername(r)
+		if !ok {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		if !isAdminUser(cfg, username) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		errMsg := ""
+		if r.Method == http.MethodPost {
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, "Bad Request", http.StatusBadRequest)
+				return
+			}
+			channel := r.FormValue("channel")
+			if err := services.SendTestNotification(channel); err != nil {
+				errMsg = err.Error()
+			} else {
+				log.Printf("User %s sent a test notification to %s", username, channel)
+				http.Redirect(w, r, "/admin/notifications?sent="+url.QueryEscape(channel), http.StatusFound)
+				return
+			}
+		}
+
+		servers, _ := services.GetAllServers()
+		vms, _ := services.GetAllVMs()
+		switches, _ := services.GetAllSwitches()
+		data := map[string]interface{}{
+			"Username":    username,
+			"IsAdmin":     true,
+			"ServerCount": len(servers),
+			"VMCount":     len(vms),
+			"SwitchCount": len(switches),
+			"Enabled":     services.NotificationsEnabled(),
+			"Channels":    services.NotificationChannels(),
+			"Deliveries":  services.NotificationDeliveries(),
+			"Sent":        r.URL.Query().Get("sent"),
+			"Error":       errMsg,
+		}
+		if err := templates.ExecuteTemplate(w, "notifications.html", data); err != nil {
+			log.Printf("Error rendering template: %v", err)
+		}
+	}
+}
+
+// NotificationsAPIHandler serves /api/notifications
+func NotificationsAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := NotificationsResponse{
+			Enabled:    services.NotificationsEnabled(),
+			Channels:   services.NotificationChannels(),
+			Deliveries: services.NotificationDeliveries(),
+		}
+		if resp.Channels == nil {
+			resp.Channels = []notify.ChannelInfo{}
+		}
+		if resp.Deliveries == nil {
+			resp.Deliveries = []notify.Delivery{}
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// NotificationTestHandler serves POST /api/notifications/test?channel=name
+// for admins. The message is queued; its outcome shows in the delivery log.
+func NotificationTestHandler(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username, _ := middleware.GetUsername(r)
+		if !isAdminUser(cfg, username) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		channel := r.URL.Query().Get("channel")
+		if err := services.SendTestNotification(channel); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("User %s sent a test notification to %s", username, channel)
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "queued", "channel": channel})
+	}
+}
@@ -75,10 +75,10 @@ func ServerDetailHandlerWithTemplates(cfg *config.Config, templates *template.Te
 		vars := mux.Vars(r)
 		serverID := vars["id"]
 
-		// Get all servers
-		servers, err := services.GetAllServers()
-		if err != nil {
-			http.Error(w, "Error fetching servers: "+err.Error(), http.StatusInternalServerError)
+		// Find the specific server
+		server, found := services.GetServer(serverID)
+		if !found {
+			http.Error(w, "Server not found", http.StatusNotFound)
 			return
 		}
 
@@ -88,20 +88,6 @@ func ServerDetailHandlerWithTemplates(cfg *config.Config, templates *template.Te
 			return
 		}
 
-		// Find the specific server
-		var server *models.Server
-		for i := range servers {
-			if servers[i].ID == serverID {
-				server = &servers[i]
-				break
-			}
-		}
-
-		if server == nil {
-			http.Error(w, "Server not found", http.StatusNotFound)
-			return
-		}
-
 		w.Header().Set("Content-Type", "text/html")
 
 		// Attach VMs assigned to this server (if any)
@@ -113,10 +99,12 @@ func ServerDetailHandlerWithTemplates(cfg *config.Config, templates *template.Te
 		}
 
 		data := map[string]interface{}{
-			"server":   server,
-			"vms":      attachedVMs,
-			"IsAdmin":  isAdminUser(cfg, username),
-			"Username": username,
+			"server":     &server,
+			"vms":        attachedVMs,
+			"dependsOn":  services.DependencyParents(services.KindServer, server.ID),
+			"dependents": services.DependencyChildren(services.KindServer, server.ID),
+			"IsAdmin":    isAdminUser(cfg, username),
+			"Username":   username,
 		}
 
 		// Template is defined as "server-detail" in server-detail.html
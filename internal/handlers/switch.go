@@ -46,30 +46,17 @@ func SwitchDetailHandler(cfg *config.Config, templates *template.Template) http.
 			return
 		}
 
-		switches, err := services.GetAllSwitches()
-		if err != nil {
-			http.Error(w, "Failed to retrieve switches", http.StatusInternalServerError)
-			return
-		}
-
-		var targetSwitch *interface{}
-		for i := range switches {
-			if switches[i].ID == switchID {
-				var iface interface{} = switches[i]
-				targetSwitch = &iface
-				break
-			}
-		}
-
-		if targetSwitch == nil {
+		targetSwitch, found := services.GetSwitch(switchID)
+		if !found {
 			http.Error(w, "Switch not found", http.StatusNotFound)
 			return
 		}
 
 		data := map[string]interface{}{
-			"switch":   *targetSwitch,
-			"IsAdmin":  isAdminUser(cfg, username),
-			"Username": username,
+			"switch":     targetSwitch,
+			"dependents": services.DependencyChildren(services.KindSwitch, targetSwitch.ID),
+			"IsAdmin":    isAdminUser(cfg, username),
+			"Username":   username,
 		}
 
 		if err := templates.ExecuteTemplate(w, "switch-detail.html", data); err != nil {
@@ -72,29 +72,9 @@ func VMDetailHandlerWithTemplates(cfg *config.Config, templates *template.Templa
 		vars := mux.Vars(r)
 		vmID := vars["id"]
 
-		// Get all VMs
-		vms, err := services.GetAllVMs()
-		if err != nil {
-			http.Error(w, "Error fetching VMs: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		servers, err := services.GetAllServers()
-		if err != nil {
-			http.Error(w, "Error fetching servers: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
 		// Find the specific VM
-		var vm *models.VM
-		for i := range vms {
-			if vms[i].ID == vmID {
-				vm = &vms[i]
-				break
-			}
-		}
-
-		if vm == nil {
+		vm, found := services.GetVM(vmID)
+		if !found {
 			http.Error(w, "VM not found", http.StatusNotFound)
 			return
 		}
@@ -104,17 +84,15 @@ func VMDetailHandlerWithTemplates(cfg *config.Config, templates *template.Templa
 		// Identify host server (if linked)
 		var hostServer *models.Server
 		if vm.HostServerID != "" {
-			for i := range servers {
-				if servers[i].ID == vm.HostServerID {
-					hostServer = &servers[i]
-					break
-				}
+			if srv, ok := services.GetServer(vm.HostServerID); ok {
+				hostServer = &srv
 			}
 		}
 
 		data := map[string]interface{}{
-			"vm":         vm,
+			"vm":         &vm,
 			"hostServer": hostServer,
+			"dependsOn":  services.DependencyParents(services.KindVM, vm.ID),
 			"IsAdmin":    isAdminUser(cfg, username),
 			"Username":   username,
 		}
@@ -92,8 +92,9 @@ func AuthRequired(enabled bool) func(http.Handler) http.Handler {
                 return
             }
             path := r.URL.Path
-            // Allow unauthenticated access to login, health, static
-            if path == "/login" || path == "/logout" || path == "/health" || strings.HasPrefix(path, "/static/") || strings.HasPrefix(path, "/debug/pprof/") {
+            // Allow unauthenticated access to login, health, static. The
+            // ingest and metrics endpoints check tokens themselves.
+            if path == "/login" || path == "/logout" || path == "/health" || path == "/api/ingest" || path == "/metrics" || strings.HasPrefix(path, "/static/") || strings.HasPrefix(path, "/debug/pprof/") {
                 next.ServeHTTP(w, r)
                 return
             }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// AgentReport is the payload dashboard-agent pushes to the ingest endpoint
+type AgentReport struct {
+	DeviceID     string      `json:"device_id"`
+	Hostname     string      `json:"hostname"`
+	AgentVersion string      `json:"agent_version"`
+	CollectedAt  time.Time   `json:"collected_at"`
+	Metrics      HostMetrics `json:"metrics"`
+	// Metrics that could not be read, e.g. "memory.available_kb: missing"
+	MetricErrors []string `json:"metric_errors,omitempty"`
+}
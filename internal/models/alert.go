@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Alert states. A pending alert fires once its condition has held long
+// enough; a firing alert resolves when the condition clears.
+const (
+	AlertPending  = "pending"
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// Alert severities, from least to most severe
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Alert is one rule matching one device or synthetic check
+type Alert struct {
+	ID          string    `json:"id"` // rule/kind/target
+	Rule        string    `json:"rule"`
+	Expr        string    `json:"expr"`
+	Severity    string    `json:"severity"`
+	State       string    `json:"state"`
+	Summary     string    `json:"summary"`
+	TargetKind  string    `json:"target_kind"` // server, vm, switch or synthetic
+	TargetID    string    `json:"target_id"`
+	TargetName  string    `json:"target_name"`
+	Tags        []string  `json:"tags"`
+	Value       string    `json:"value"` // Metric value at the last evaluation
+	ActiveSince time.Time `json:"active_since"`
+	FiredAt     time.Time `json:"fired_at"`
+	ResolvedAt  time.Time `json:"resolved_at"`
+	LastEval    time.Time `json:"last_evaluated"`
+	Suppressed  string    `json:"suppressed,omitempty"` // Why notifications are held back: maintenance window or silence
+}
+
+// AlertRule describes a configured rule and whether it could be loaded
+type AlertRule struct {
+	Name     string   `json:"name"`
+	Expr     string   `json:"expr"`
+	Severity string   `json:"severity"`
+	Devices  []string `json:"devices,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Types    []string `json:"types,omitempty"`
+	Error    string   `json:"error,omitempty"` // Why the rule is not evaluated
+}
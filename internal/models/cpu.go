@@ -0,0 +1,225 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CPUTimes are the cumulative times all cores spent in each state since
+// boot, in seconds, as in the "cpu" line of /proc/stat. Guest time is part
+// of user and nice time there, so it is not listed separately.
+type CPUTimes struct {
+	User    float64 `json:"user"`
+	Nice    float64 `json:"nice"`
+	System  float64 `json:"system"`
+	Idle    float64 `json:"idle"`
+	IOWait  float64 `json:"iowait"`
+	IRQ     float64 `json:"irq"`
+	SoftIRQ float64 `json:"softirq"`
+	Steal   float64 `json:"steal"` // Time a VM was ready to run but the hypervisor ran something else
+}
+
+func (t CPUTimes) total() float64 {
+	return t.User + t.Nice + t.System + t.Idle + t.IOWait + t.IRQ + t.SoftIRQ + t.Steal
+}
+
+// clone returns a copy of the times, nil for nil
+func (t *CPUTimes) clone() *CPUTimes {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+// CPUUsage is the share of CPU time spent in each state between two samples
+type CPUUsage struct {
+	UserPercent   float64 `json:"user_percent"`   // Including nice
+	SystemPercent float64 `json:"system_percent"` // Including hard and soft interrupts
+	IOWaitPercent float64 `json:"iowait_percent"`
+	StealPercent  float64 `json:"steal_percent"`
+	IdlePercent   float64 `json:"idle_percent"`
+	UsedPercent   float64 `json:"used_percent"` // Everything but idle and iowait
+}
+
+// clone returns a copy of the usage, nil for nil
+func (u *CPUUsage) clone() *CPUUsage {
+	if u == nil {
+		return nil
+	}
+	c := *u
+	return &c
+}
+
+// CPUUsageSince returns the CPU usage between the previous sample's times and
+// the current ones. Times equal to the previous ones are the same sample
+// read again, such as an agent report that has not been replaced yet, and
+// keep the previous usage. There is no usage for the first sample or after
+// the times went backwards, e.g. because the host rebooted.
+func CPUUsageSince(prevTimes *CPUTimes, prevUsage *CPUUsage, cur *CPUTimes) *CPUUsage {
+	if cur == nil || prevTimes == nil {
+		return nil
+	}
+	if *cur == *prevTimes {
+		return prevUsage
+	}
+	d := CPUTimes{
+		User: cur.User - prevTimes.User, Nice: cur.Nice - prevTimes.Nice,
+		System: cur.System - prevTimes.System, Idle: cur.Idle - prevTimes.Idle,
+		IOWait: cur.IOWait - prevTimes.IOWait, IRQ: cur.IRQ - prevTimes.IRQ,
+		SoftIRQ: cur.SoftIRQ - prevTimes.SoftIRQ, Steal: cur.Steal - prevTimes.Steal,
+	}
+	// iowait is not guaranteed to be monotonic per core and may dip slightly
+	if d.IOWait < 0 {
+		d.IOWait = 0
+	}
+	total := d.total()
+	if total <= 0 || d.User < 0 || d.Nice < 0 || d.System < 0 || d.Idle < 0 || d.IRQ < 0 || d.SoftIRQ < 0 || d.Steal < 0 {
+		return nil
+	}
+	pct := func(v float64) float64 { return v / total * 100 }
+	u := &CPUUsage{
+		UserPercent:   pct(d.User + d.Nice),
+		SystemPercent: pct(d.System + d.IRQ + d.SoftIRQ),
+		IOWaitPercent: pct(d.IOWait),
+		StealPercent:  pct(d.Steal),
+		IdlePercent:   pct(d.Idle),
+	}
+	u.UsedPercent = 100 - u.IdlePercent - u.IOWaitPercent
+	return u
+}
+
+// PressureStall is one line of a /proc/pressure file: the share of time in
+// percent that tasks were stalled on a resource, averaged over 10 seconds,
+// 1 minute and 5 minutes
+type PressureStall struct {
+	Avg10  float64 `json:"avg10"`
+	Avg60  float64 `json:"avg60"`
+	Avg300 float64 `json:"avg300"`
+}
+
+// Pressure is Linux pressure stall information (PSI, kernel 4.20 and later).
+// "Some" is the time at least one task was stalled; "full" the time all
+// non-idle tasks were stalled at once, which kernels before 5.13 do not
+// report for the CPU.
+type Pressure struct {
+	CPUSome    *PressureStall `json:"cpu_some,omitempty"`
+	CPUFull    *PressureStall `json:"cpu_full,omitempty"`
+	MemorySome *PressureStall `json:"memory_some,omitempty"`
+	MemoryFull *PressureStall `json:"memory_full,omitempty"`
+	IOSome     *PressureStall `json:"io_some,omitempty"`
+	IOFull     *PressureStall `json:"io_full,omitempty"`
+}
+
+// clone returns a copy of the pressure that shares none of its lines,
+// nil for nil
+func (p *Pressure) clone() *Pressure {
+	if p == nil {
+		return nil
+	}
+	c := &Pressure{}
+	for _, resource := range PressureResources {
+		for _, kind := range PressureKinds {
+			if s := p.Stall(resource, kind); s != nil {
+				c.SetStall(resource, kind, *s)
+			}
+		}
+	}
+	return c
+}
+
+// Stall returns the line for a resource ("cpu", "memory" or "io") and kind
+// ("some" or "full"), or nil when the host did not report it
+func (p *Pressure) Stall(resource, kind string) *PressureStall {
+	if p == nil {
+		return nil
+	}
+	switch resource + "_" + kind {
+	case "cpu_some":
+		return p.CPUSome
+	case "cpu_full":
+		return p.CPUFull
+	case "memory_some":
+		return p.MemorySome
+	case "memory_full":
+		return p.MemoryFull
+	case "io_some":
+		return p.IOSome
+	case "io_full":
+		return p.IOFull
+	}
+	return nil
+}
+
+// SetStall stores the line for a resource and kind; unknown ones are ignored
+func (p *Pressure) SetStall(resource, kind string, s PressureStall) {
+	switch resource + "_" + kind {
+	case "cpu_some":
+		p.CPUSome = &s
+	case "cpu_full":
+		p.CPUFull = &s
+	case "memory_some":
+		p.MemorySome = &s
+	case "memory_full":
+		p.MemoryFull = &s
+	case "io_some":
+		p.IOSome = &s
+	case "io_full":
+		p.IOFull = &s
+	}
+}
+
+// PressureResources and PressureKinds list what a Pressure can hold
+var (
+	PressureResources = []string{"cpu", "memory", "io"}
+	PressureKinds     = []string{"some", "full"}
+)
+
+// ParsePressureStall parses the averages of a /proc/pressure line, e.g.
+// "some avg10=0.00 avg60=1.25 avg300=0.40 total=123456". It returns the
+// kind ("some" or "full") with the averages.
+func ParsePressureStall(line string) (string, PressureStall, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || (fields[0] != "some" && fields[0] != "full") {
+		return "", PressureStall{}, false
+	}
+	var s PressureStall
+	found := 0
+	for _, field := range fields[1:] {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", PressureStall{}, false
+		}
+		switch key {
+		case "avg10":
+			s.Avg10, found = v, found+1
+		case "avg60":
+			s.Avg60, found = v, found+1
+		case "avg300":
+			s.Avg300, found = v, found+1
+		}
+	}
+	return fields[0], s, found == 3
+}
+
+// ParseLoadAverage parses a "1.00 0.50 0.25" load average into the 1, 5 and
+// 15 minute values
+func ParseLoadAverage(load string) (load1, load5, load15 float64, ok bool) {
+	fields := strings.Fields(load)
+	if len(fields) != 3 {
+		return 0, 0, 0, false
+	}
+	var v [3]float64
+	for i, f := range fields {
+		n, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return 0, 0, 0, false
+		}
+		v[i] = n
+	}
+	return v[0], v[1], v[2], true
+}
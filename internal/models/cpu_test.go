@@ -0,0 +1,109 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCPUUsageSince(t *testing.T) {
+	prev := &CPUTimes{User: 100, Nice: 10, System: 50, Idle: 800, IOWait: 20, IRQ: 5, SoftIRQ: 5, Steal: 10}
+	prevUsage := &CPUUsage{UsedPercent: 33}
+	tests := []struct {
+		name      string
+		prevTimes *CPUTimes
+		cur       *CPUTimes
+		want      *CPUUsage // Nil for no usage
+	}{
+		{
+			name:      "shares of the interval",
+			prevTimes: prev,
+			cur:       &CPUTimes{User: 130, Nice: 20, System: 60, Idle: 840, IOWait: 25, IRQ: 5, SoftIRQ: 10, Steal: 10},
+			want:      &CPUUsage{UserPercent: 40, SystemPercent: 15, IOWaitPercent: 5, IdlePercent: 40, UsedPercent: 55},
+		},
+		{
+			name:      "steal",
+			prevTimes: prev,
+			cur:       &CPUTimes{User: 110, Nice: 10, System: 50, Idle: 880, IOWait: 20, IRQ: 5, SoftIRQ: 5, Steal: 20},
+			want:      &CPUUsage{UserPercent: 10, StealPercent: 10, IdlePercent: 80, UsedPercent: 20},
+		},
+		{
+			name:      "iowait dipping counts as none",
+			prevTimes: prev,
+			cur:       &CPUTimes{User: 150, Nice: 10, System: 50, Idle: 850, IOWait: 18, IRQ: 5, SoftIRQ: 5, Steal: 10},
+			want:      &CPUUsage{UserPercent: 50, IdlePercent: 50, UsedPercent: 50},
+		},
+		{
+			name:      "counter reset after a reboot",
+			prevTimes: prev,
+			cur:       &CPUTimes{User: 20, Nice: 1, System: 10, Idle: 300, IOWait: 2, IRQ: 1, SoftIRQ: 1},
+		},
+		{
+			name:      "one counter going back",
+			prevTimes: prev,
+			cur:       &CPUTimes{User: 200, Nice: 10, System: 40, Idle: 900, IOWait: 20, IRQ: 5, SoftIRQ: 5, Steal: 10},
+		},
+		{
+			name:      "same sample read again keeps its usage",
+			prevTimes: prev,
+			cur:       &CPUTimes{User: 100, Nice: 10, System: 50, Idle: 800, IOWait: 20, IRQ: 5, SoftIRQ: 5, Steal: 10},
+			want:      prevUsage,
+		},
+		{name: "first sample", cur: prev},
+		{name: "no times", prevTimes: prev},
+	}
+	for _, tt := range tests {
+		got := CPUUsageSince(tt.prevTimes, prevUsage, tt.cur)
+		if (got == nil) != (tt.want == nil) || got != nil && !sameUsage(*got, *tt.want) {
+			t.Errorf("%s: usage %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// sameUsage compares usage to within rounding
+func sameUsage(a, b CPUUsage) bool {
+	near := func(x, y float64) bool { return math.Abs(x-y) < 1e-9 }
+	return near(a.UsedPercent, b.UsedPercent) && near(a.UserPercent, b.UserPercent) &&
+		near(a.SystemPercent, b.SystemPercent) && near(a.IOWaitPercent, b.IOWaitPercent) &&
+		near(a.StealPercent, b.StealPercent) && near(a.IdlePercent, b.IdlePercent)
+}
+
+func TestParsePressureStall(t *testing.T) {
+	tests := []struct {
+		line string
+		kind string
+		want PressureStall
+		ok   bool
+	}{
+		{"some avg10=0.00 avg60=1.25 avg300=0.40 total=123456", "some", PressureStall{Avg60: 1.25, Avg300: 0.40}, true},
+		{"full avg10=1.50 avg60=0.25 avg300=0.00 total=12", "full", PressureStall{Avg10: 1.5, Avg60: 0.25}, true},
+		{"some avg10=0.00 avg60=1.25 total=1", "", PressureStall{}, false},
+		{"some avg10=x avg60=1.25 avg300=0.40 total=1", "", PressureStall{}, false},
+		{"most avg10=0.00 avg60=1.25 avg300=0.40 total=1", "", PressureStall{}, false},
+	}
+	for _, tt := range tests {
+		kind, got, ok := ParsePressureStall(tt.line)
+		if ok != tt.ok || ok && (kind != tt.kind || got != tt.want) {
+			t.Errorf("%q = %q, %+v, %v", tt.line, kind, got, ok)
+		}
+	}
+}
+
+func TestParseLoadAverage(t *testing.T) {
+	tests := []struct {
+		load                 string
+		load1, load5, load15 float64
+		ok                   bool
+	}{
+		{"0.52 0.41 0.30", 0.52, 0.41, 0.30, true},
+		{"  1 2.5   10 ", 1, 2.5, 10, true},
+		{"0.52 0.41", 0, 0, 0, false},
+		{"0.52 n/a 0.30", 0, 0, 0, false},
+		{"", 0, 0, 0, false},
+	}
+	for _, tt := range tests {
+		l1, l5, l15, ok := ParseLoadAverage(tt.load)
+		if l1 != tt.load1 || l5 != tt.load5 || l15 != tt.load15 || ok != tt.ok {
+			t.Errorf("%q = %v %v %v, %v", tt.load, l1, l5, l15, ok)
+		}
+	}
+}
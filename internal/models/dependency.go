@@ -0,0 +1,19 @@
+package models
+
+// Dependency relations between a device and the one it depends on
+const (
+	DependencyHost   = "host"   // A VM's host server
+	DependencyUplink = "uplink" // The switch a server or VM is connected through
+)
+
+// DependencyNode is a device in a dependency tree. Depending on the tree,
+// Children are the devices it depends on or the devices depending on it.
+type DependencyNode struct {
+	Kind     string           `json:"kind"`
+	ID       string           `json:"id"`
+	Name     string           `json:"name"`
+	Status   string           `json:"status"`
+	Relation string           `json:"relation"` // DependencyHost or DependencyUplink
+	Link     string           `json:"link"`
+	Children []DependencyNode `json:"children,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Event types: what changed state
+const (
+	EventStatus         = "status"          // Device status, e.g. online to offline
+	EventPing           = "ping"            // Device reachability
+	EventSynthetic      = "synthetic"       // Synthetic check ok/fail
+	EventFailedServices = "failed_services" // Count of failed systemd services
+	EventStream         = "stream"          // VM stream port up/down
+	EventFlapping       = "flapping"        // Device, stream or synthetic check started or stopped flapping
+)
+
+// Event records one state transition of a device, stream or synthetic check
+type Event struct {
+	ID         uint64    `json:"id"` // Increases with every event; used as the pagination cursor
+	Time       time.Time `json:"time"`
+	Type       string    `json:"type"`
+	TargetKind string    `json:"target_kind"` // server, vm, switch or synthetic
+	TargetID   string    `json:"target_id"`
+	TargetName string    `json:"target_name"`
+	Subject    string    `json:"subject,omitempty"` // Part of the target that changed, e.g. "port 8554"
+	Old        string    `json:"old"`
+	New        string    `json:"new"`
+	Reason     string    `json:"reason,omitempty"`
+	Error      string    `json:"error,omitempty"` // Collector error at the time, if any
+}
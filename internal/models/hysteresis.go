@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// Hysteresis says how many consecutive check results it takes to change
+// between up and down, and how many changes within a window count as
+// flapping
+type Hysteresis struct {
+	FailuresBeforeDown int           // At least 1
+	SuccessesBeforeUp  int           // At least 1
+	FlapWindow         time.Duration // How far back state changes are counted
+	FlapThreshold      int           // State changes within FlapWindow that mark flapping; 0 disables
+}
+
+// StateTracker debounces the up/down state of a device, stream or
+// synthetic check and detects flapping. It is embedded in each of them.
+type StateTracker struct {
+	Known        bool        `json:"-"`
+	Up           bool        `json:"-"`
+	Pending      int         `json:"pending_checks"` // Consecutive results disagreeing with the current state
+	Flapping     bool        `json:"flapping"`
+	StateChanges []time.Time `json:"state_changes"` // Changes within the flap window, oldest first
+}
+
+// ObserveState records one check result and returns the debounced state.
+// The first result is taken as is. StateChanges is replaced rather than
+// modified, so trackers copied along with their device stay independent.
+func (t *StateTracker) ObserveState(up bool, now time.Time, h Hysteresis) bool {
+	need := h.FailuresBeforeDown
+	if up {
+		need = h.SuccessesBeforeUp
+	}
+	changed := false
+	switch {
+	case !t.Known:
+		t.Known, t.Up, t.Pending = true, up, 0
+	case up == t.Up:
+		t.Pending = 0
+	default:
+		t.Pending++
+		if t.Pending >= need {
+			t.Up, t.Pending, changed = up, 0, true
+		}
+	}
+
+	if h.FlapThreshold <= 0 {
+		t.StateChanges, t.Flapping = nil, false
+		return t.Up
+	}
+	cutoff := now.Add(-h.FlapWindow)
+	changes := make([]time.Time, 0, len(t.StateChanges)+1)
+	for _, c := range t.StateChanges {
+		if c.After(cutoff) {
+			changes = append(changes, c)
+		}
+	}
+	if changed {
+		changes = append(changes, now)
+	}
+	t.StateChanges = changes
+	t.Flapping = len(changes) >= h.FlapThreshold
+	return t.Up
+}
+
+// FlapState names the flapping state for events
+func FlapState(flapping bool) string {
+	if flapping {
+		return "flapping"
+	}
+	return "stable"
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestObserveState(t *testing.T) {
+	debounce := Hysteresis{FailuresBeforeDown: 3, SuccessesBeforeUp: 2}
+	flaps := Hysteresis{FailuresBeforeDown: 1, SuccessesBeforeUp: 1, FlapWindow: time.Minute, FlapThreshold: 3}
+	// Results and states are written as U for up and D for down, one per
+	// check ten seconds apart
+	tests := []struct {
+		name     string
+		h        Hysteresis
+		results  string
+		want     string
+		pending  int
+		flapping bool
+		changes  int
+	}{
+		{"first result taken as is", debounce, "D", "D", 0, false, 0},
+		{"single failures suppressed", debounce, "UDUDDUDDU", "UUUUUUUUU", 0, false, 0},
+		{"down after three failures", debounce, "UDDDD", "UUUDD", 0, false, 0},
+		{"pending count", debounce, "UDD", "UUU", 2, false, 0},
+		{"up after two successes", debounce, "DUDUU", "DDDDU", 0, false, 0},
+		{"flapping", flaps, "UDUD", "UDUD", 0, true, 3},
+		{"below the flap threshold", flaps, "UDU", "UDU", 0, false, 2},
+		{"flap threshold 0 disables detection", Hysteresis{FailuresBeforeDown: 1, SuccessesBeforeUp: 1, FlapWindow: time.Minute}, "UDUDUD", "UDUDUD", 0, false, 0},
+		// Changes older than the window no longer count
+		{"settles after the flap window", flaps, "UDUDUUUUUUU", "UDUDUUUUUUU", 0, false, 0},
+		{"suppressed results are no state changes", Hysteresis{FailuresBeforeDown: 2, SuccessesBeforeUp: 1, FlapWindow: time.Minute, FlapThreshold: 2}, "UDUDUD", "UUUUUU", 1, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var st StateTracker
+			now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+			got := ""
+			for _, r := range tt.results {
+				now = now.Add(10 * time.Second)
+				if st.ObserveState(r == 'U', now, tt.h) {
+					got += "U"
+				} else {
+					got += "D"
+				}
+			}
+			if got != tt.want || st.Pending != tt.pending || st.Flapping != tt.flapping || len(st.StateChanges) != tt.changes {
+				t.Errorf("states %s, pending %d, flapping %v, changes %v; want %s, %d, %v, %d",
+					got, st.Pending, st.Flapping, st.StateChanges, tt.want, tt.pending, tt.flapping, tt.changes)
+			}
+		})
+	}
+}
+
+// Devices are copied with their tracker; the copies must not share changes
+func TestObserveStateCopies(t *testing.T) {
+	h := Hysteresis{FailuresBeforeDown: 1, SuccessesBeforeUp: 1, FlapWindow: time.Minute, FlapThreshold: 3}
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	var st StateTracker
+	st.ObserveState(true, now, h)
+	st.ObserveState(false, now.Add(time.Second), h)
+
+	cp := st
+	cp.ObserveState(true, now.Add(2*time.Second), h)
+	if len(st.StateChanges) != 1 || len(cp.StateChanges) != 2 {
+		t.Errorf("original has %d changes, copy %d", len(st.StateChanges), len(cp.StateChanges))
+	}
+}
@@ -0,0 +1,173 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// NetworkInterface is one network interface in a sample. Counters are
+// cumulative as the host reports them; Rates are filled in by the dashboard
+// from the previous sample.
+type NetworkInterface struct {
+	Name      string    `json:"name"`
+	OperState string    `json:"operstate"`  // As in /sys/class/net: up, down, lowerlayerdown, dormant, unknown, ...
+	SpeedMbps int64     `json:"speed_mbps"` // Link speed; 0 when unknown, e.g. for virtual or down links
+	RxBytes   uint64    `json:"rx_bytes"`
+	TxBytes   uint64    `json:"tx_bytes"`
+	RxPackets uint64    `json:"rx_packets"`
+	TxPackets uint64    `json:"tx_packets"`
+	RxErrors  uint64    `json:"rx_errors"`
+	TxErrors  uint64    `json:"tx_errors"`
+	RxDropped uint64    `json:"rx_dropped"`
+	TxDropped uint64    `json:"tx_dropped"`
+	Counter32 bool      `json:"counter32,omitempty"` // Counters wrap at 2^32, as on SNMP agents without 64-bit counters
+	SampledAt time.Time `json:"-"`                   // When the counters were read, set by WithInterfaceRates
+
+	// Rates since the previous sample; nil for the first sample of an
+	// interface and after its counters were reset, e.g. by a reboot
+	Rates *InterfaceRates `json:"rates,omitempty"`
+}
+
+// InterfaceRates are per-second rates between two samples of an interface
+type InterfaceRates struct {
+	RxBytesPerSec      float64 `json:"rx_bytes_per_sec"`
+	TxBytesPerSec      float64 `json:"tx_bytes_per_sec"`
+	RxPacketsPerSec    float64 `json:"rx_packets_per_sec"`
+	TxPacketsPerSec    float64 `json:"tx_packets_per_sec"`
+	RxErrorsPerSec     float64 `json:"rx_errors_per_sec"`
+	TxErrorsPerSec     float64 `json:"tx_errors_per_sec"`
+	RxDroppedPerSec    float64 `json:"rx_dropped_per_sec"`
+	TxDroppedPerSec    float64 `json:"tx_dropped_per_sec"`
+	UtilizationPercent float64 `json:"utilization_percent"` // Busier direction as a share of link speed; 0 when the speed is unknown
+	IntervalSeconds    float64 `json:"interval_seconds"`
+}
+
+// Up reports whether the interface is operationally up
+func (i NetworkInterface) Up() bool {
+	return i.OperState == "up"
+}
+
+// Errors is the number of receive and transmit errors
+func (i NetworkInterface) Errors() uint64 {
+	return i.RxErrors + i.TxErrors
+}
+
+// Dropped is the number of packets dropped on receive and transmit
+func (i NetworkInterface) Dropped() uint64 {
+	return i.RxDropped + i.TxDropped
+}
+
+// SpeedLabel renders the link speed like "10 Gbit/s", or "-" when unknown
+func (i NetworkInterface) SpeedLabel() string {
+	switch {
+	case i.SpeedMbps <= 0:
+		return "-"
+	case i.SpeedMbps%1000 == 0:
+		return fmt.Sprintf("%d Gbit/s", i.SpeedMbps/1000)
+	default:
+		return fmt.Sprintf("%d Mbit/s", i.SpeedMbps)
+	}
+}
+
+// cloneInterfaces copies an interface list including each interface's
+// rates, nil for nil
+func cloneInterfaces(ifaces []NetworkInterface) []NetworkInterface {
+	if ifaces == nil {
+		return nil
+	}
+	out := append([]NetworkInterface(nil), ifaces...)
+	for i := range out {
+		if r := out[i].Rates; r != nil {
+			rates := *r
+			out[i].Rates = &rates
+		}
+	}
+	return out
+}
+
+// FormatThroughput renders a rate in bytes per second as bits per second,
+// like "12.4 Mbit/s"
+func FormatThroughput(bytesPerSec float64) string {
+	bits := bytesPerSec * 8
+	for _, unit := range []string{"bit/s", "kbit/s", "Mbit/s", "Gbit/s"} {
+		if bits < 1000 {
+			return fmt.Sprintf("%.1f %s", bits, unit)
+		}
+		bits /= 1000
+	}
+	return fmt.Sprintf("%.1f Tbit/s", bits)
+}
+
+// WithInterfaceRates returns the interfaces of a sample taken at sampledAt
+// with their rates computed against the previous sample. A sample taken at
+// the same time as the previous one is the same sample read again, such as
+// an agent report that has not been replaced yet, and keeps its rates.
+func WithInterfaceRates(prev, cur []NetworkInterface, sampledAt time.Time) []NetworkInterface {
+	if cur == nil {
+		return nil
+	}
+	previous := make(map[string]NetworkInterface, len(prev))
+	for _, p := range prev {
+		previous[p.Name] = p
+	}
+	out := make([]NetworkInterface, len(cur))
+	for i, iface := range cur {
+		iface.SampledAt, iface.Rates = sampledAt, nil
+		if p, ok := previous[iface.Name]; ok && !p.SampledAt.IsZero() {
+			switch elapsed := sampledAt.Sub(p.SampledAt); {
+			case elapsed == 0:
+				iface.Rates = p.Rates
+			case elapsed > 0:
+				iface.Rates = interfaceRates(p, iface, elapsed.Seconds())
+			}
+		}
+		out[i] = iface
+	}
+	return out
+}
+
+// interfaceRates returns the rates between two samples of an interface, or
+// nil when its counters went backwards for any other reason than a 32-bit
+// wrap. A wrap that would mean more traffic than the link can carry is a
+// reset too: a rebooted host restarts its counters near zero.
+func interfaceRates(prev, cur NetworkInterface, secs float64) *InterfaceRates {
+	wrap := cur.Counter32 && prev.Counter32
+	deltas := make([]float64, 8)
+	prevCounters := []uint64{prev.RxBytes, prev.TxBytes, prev.RxPackets, prev.TxPackets, prev.RxErrors, prev.TxErrors, prev.RxDropped, prev.TxDropped}
+	curCounters := []uint64{cur.RxBytes, cur.TxBytes, cur.RxPackets, cur.TxPackets, cur.RxErrors, cur.TxErrors, cur.RxDropped, cur.TxDropped}
+	for i := range deltas {
+		d, ok := counterDelta(prevCounters[i], curCounters[i], wrap)
+		if !ok {
+			return nil
+		}
+		deltas[i] = float64(d) / secs
+	}
+
+	r := &InterfaceRates{
+		RxBytesPerSec: deltas[0], TxBytesPerSec: deltas[1],
+		RxPacketsPerSec: deltas[2], TxPacketsPerSec: deltas[3],
+		RxErrorsPerSec: deltas[4], TxErrorsPerSec: deltas[5],
+		RxDroppedPerSec: deltas[6], TxDroppedPerSec: deltas[7],
+		IntervalSeconds: secs,
+	}
+	if cur.SpeedMbps > 0 {
+		r.UtilizationPercent = math.Max(r.RxBytesPerSec, r.TxBytesPerSec) * 8 / (float64(cur.SpeedMbps) * 1e6) * 100
+		if wrap && r.UtilizationPercent > 100 && (cur.RxBytes < prev.RxBytes || cur.TxBytes < prev.TxBytes) {
+			return nil
+		}
+	}
+	return r
+}
+
+// counterDelta returns how far a counter advanced. Counters that went
+// backwards were reset, unless they are 32-bit and wrapped.
+func counterDelta(prev, cur uint64, counter32 bool) (uint64, bool) {
+	if cur >= prev {
+		return cur - prev, true
+	}
+	if counter32 && prev <= math.MaxUint32 && cur <= math.MaxUint32 {
+		return cur + (math.MaxUint32 + 1) - prev, true
+	}
+	return 0, false
+}
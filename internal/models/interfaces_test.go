@@ -0,0 +1,133 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCounterDelta(t *testing.T) {
+	tests := []struct {
+		name      string
+		prev, cur uint64
+		counter32 bool
+		want      uint64
+		ok        bool
+	}{
+		{"advanced", 100, 250, false, 150, true},
+		{"unchanged", 100, 100, true, 0, true},
+		{"64-bit reset", 1 << 40, 10, false, 0, false},
+		{"32-bit wrap", math.MaxUint32 - 9, 10, true, 20, true},
+		{"32-bit wrap to zero", math.MaxUint32, 0, true, 1, true},
+		{"64-bit counter going back", 1000, 10, false, 0, false},
+		// A counter over 32 bits cannot have wrapped at 2^32
+		{"32-bit flag with a larger value", math.MaxUint32 + 10, 5, true, 0, false},
+	}
+	for _, tt := range tests {
+		if got, ok := counterDelta(tt.prev, tt.cur, tt.counter32); got != tt.want || ok != tt.ok {
+			t.Errorf("%s: counterDelta(%d, %d, %v) = %d, %v; want %d, %v", tt.name, tt.prev, tt.cur, tt.counter32, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestWithInterfaceRates(t *testing.T) {
+	t0 := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	rates := &InterfaceRates{RxBytesPerSec: 7}
+	tests := []struct {
+		name    string
+		prev    NetworkInterface
+		elapsed time.Duration
+		cur     NetworkInterface
+		want    *InterfaceRates // Nil for no rates
+	}{
+		{
+			name:    "rates and utilization",
+			prev:    NetworkInterface{RxBytes: 1000, TxBytes: 500, RxPackets: 10, SpeedMbps: 1},
+			elapsed: 10 * time.Second,
+			cur:     NetworkInterface{RxBytes: 11000, TxBytes: 2500, RxPackets: 30, RxDropped: 5, SpeedMbps: 1},
+			want: &InterfaceRates{RxBytesPerSec: 1000, TxBytesPerSec: 200, RxPacketsPerSec: 2, RxDroppedPerSec: 0.5,
+				IntervalSeconds: 10, UtilizationPercent: 0.8},
+		},
+		{
+			name:    "unknown speed",
+			prev:    NetworkInterface{RxBytes: 1000},
+			elapsed: 10 * time.Second,
+			cur:     NetworkInterface{RxBytes: 2000},
+			want:    &InterfaceRates{RxBytesPerSec: 100, IntervalSeconds: 10},
+		},
+		{
+			name:    "32-bit wrap",
+			prev:    NetworkInterface{RxBytes: math.MaxUint32 - 99, Counter32: true, SpeedMbps: 1000},
+			elapsed: 10 * time.Second,
+			cur:     NetworkInterface{RxBytes: 900, Counter32: true, SpeedMbps: 1000},
+			want:    &InterfaceRates{RxBytesPerSec: 100, IntervalSeconds: 10, UtilizationPercent: 0.00008},
+		},
+		{
+			name:    "reset of a 64-bit counter",
+			prev:    NetworkInterface{RxBytes: 1 << 33},
+			elapsed: 10 * time.Second,
+			cur:     NetworkInterface{RxBytes: 100},
+		},
+		{
+			name:    "reset that would be a wrap faster than the link",
+			prev:    NetworkInterface{RxBytes: 1 << 20, Counter32: true, SpeedMbps: 10},
+			elapsed: 10 * time.Second,
+			cur:     NetworkInterface{RxBytes: 100, Counter32: true, SpeedMbps: 10},
+		},
+		{
+			name:    "32-bit counter of only one sample",
+			prev:    NetworkInterface{RxBytes: math.MaxUint32 - 99},
+			elapsed: 10 * time.Second,
+			cur:     NetworkInterface{RxBytes: 900, Counter32: true},
+		},
+		{
+			name:    "same sample read again keeps its rates",
+			prev:    NetworkInterface{RxBytes: 1000, Rates: rates},
+			elapsed: 0,
+			cur:     NetworkInterface{RxBytes: 1000},
+			want:    rates,
+		},
+		{
+			name:    "older sample",
+			prev:    NetworkInterface{RxBytes: 1000, Rates: rates},
+			elapsed: -time.Second,
+			cur:     NetworkInterface{RxBytes: 2000},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.prev.Name, tt.cur.Name = "eth0", "eth0"
+			tt.prev.SampledAt = t0
+			got := WithInterfaceRates([]NetworkInterface{tt.prev}, []NetworkInterface{tt.cur}, t0.Add(tt.elapsed))
+			if len(got) != 1 || !got[0].SampledAt.Equal(t0.Add(tt.elapsed)) {
+				t.Fatalf("interfaces %+v", got)
+			}
+			r := got[0].Rates
+			if (r == nil) != (tt.want == nil) || r != nil && !sameRates(*r, *tt.want) {
+				t.Errorf("rates %+v, want %+v", r, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithInterfaceRatesFirstSample(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	prev := []NetworkInterface{{Name: "eth0", RxBytes: 10, SampledAt: now.Add(-10 * time.Second)}}
+	got := WithInterfaceRates(prev, []NetworkInterface{{Name: "eth0", RxBytes: 20}, {Name: "eth1", RxBytes: 5}}, now)
+	if got[0].Rates == nil || got[1].Rates != nil {
+		t.Errorf("rates %+v and %+v, want only eth0's", got[0].Rates, got[1].Rates)
+	}
+	if WithInterfaceRates(prev, nil, now) != nil {
+		t.Error("rates for a sample without interfaces")
+	}
+}
+
+// sameRates compares rates to within rounding
+func sameRates(a, b InterfaceRates) bool {
+	near := func(x, y float64) bool { return math.Abs(x-y) < 1e-9 }
+	return near(a.RxBytesPerSec, b.RxBytesPerSec) && near(a.TxBytesPerSec, b.TxBytesPerSec) &&
+		near(a.RxPacketsPerSec, b.RxPacketsPerSec) && near(a.TxPacketsPerSec, b.TxPacketsPerSec) &&
+		near(a.RxErrorsPerSec, b.RxErrorsPerSec) && near(a.TxErrorsPerSec, b.TxErrorsPerSec) &&
+		near(a.RxDroppedPerSec, b.RxDroppedPerSec) && near(a.TxDroppedPerSec, b.TxDroppedPerSec) &&
+		near(a.IntervalSeconds, b.IntervalSeconds) && near(a.UtilizationPercent, b.UtilizationPercent)
+}
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// MaintenanceScope selects the devices and synthetic checks a maintenance
+// window or silence covers
+type MaintenanceScope struct {
+	Devices    []string `json:"devices,omitempty"`    // Server, VM or switch IDs
+	Tags       []string `json:"tags,omitempty"`       // Devices and checks with any of these tags
+	Synthetics []string `json:"synthetics,omitempty"` // Synthetic check IDs
+}
+
+// MaintenanceWindow is planned work during which devices show a maintenance
+// badge and their alert notifications are held back. A window is either
+// one-off (Start to End) or recurring (Schedule, lasting DurationMinutes).
+type MaintenanceWindow struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Comment string `json:"comment,omitempty"`
+	MaintenanceScope
+	Start           time.Time `json:"start"`                      // One-off windows
+	End             time.Time `json:"end"`                        // One-off windows
+	Schedule        string    `json:"schedule,omitempty"`         // Recurring windows: cron expression
+	DurationMinutes int       `json:"duration_minutes,omitempty"` // Recurring windows
+	Timezone        string    `json:"timezone,omitempty"`         // For Schedule; server local time if empty
+	CreatedBy       string    `json:"created_by"`
+	CreatedAt       time.Time `json:"created_at"`
+
+	Active    bool      `json:"active"`     // In effect now
+	ActiveEnd time.Time `json:"active_end"` // When the current occurrence ends
+	NextStart time.Time `json:"next_start"` // Next occurrence, if any
+}
+
+// Recurring reports whether the window repeats on a schedule
+func (w MaintenanceWindow) Recurring() bool {
+	return w.Schedule != ""
+}
+
+// Silence holds back notifications for matching alerts until it expires.
+// An alert matches when its rule is listed (or Rules is empty) and its
+// target is in scope (or the scope is empty).
+type Silence struct {
+	ID      string   `json:"id"`
+	Comment string   `json:"comment"`
+	Rules   []string `json:"rules,omitempty"` // Alert rule names
+	MaintenanceScope
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	Active bool `json:"active"`
+}
+
+// MaintenanceTarget is a device or synthetic check covered by an active
+// maintenance window
+type MaintenanceTarget struct {
+	Kind   string `json:"kind"` // server, vm, switch or synthetic
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Window string `json:"window"` // Window name
+}
@@ -0,0 +1,353 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FullPartitionPercent is the default usage above which a partition is
+// listed in FullPartitions; the dashboard uses disk_threshold_percent when set
+const FullPartitionPercent = 90.0
+
+// HostMetrics is one sample of host metrics, independent of how it was
+// collected. Collectors fill it and the device models copy it in.
+type HostMetrics struct {
+	Uptime         string             `json:"uptime"`
+	Processes      int                `json:"processes"`
+	DiskUsage      float64            `json:"disk_usage"`          // Root partition used in GB
+	DiskTotal      float64            `json:"disk_total"`          // Root partition size in GB
+	DiskPercent    float64            `json:"disk_percent"`        // Root partition usage percentage
+	FullPartitions []string           `json:"full_partitions"`     // Partitions over the threshold, e.g. "/var 95%"
+	Filesystems    []Filesystem       `json:"filesystems"`         // Every mounted filesystem with blocks, nil from collectors that cannot list mounts
+	MemoryUsed     float64            `json:"memory_used"`         // Memory used in MB
+	MemoryTotal    float64            `json:"memory_total"`        // Total memory in MB
+	LoadAverage    string             `json:"load_average"`        // 1, 5, 15 minute load averages
+	CPUCores       int                `json:"cpu_cores"`           // Logical CPUs, 0 when unknown
+	CPUTimes       *CPUTimes          `json:"cpu_times,omitempty"` // Nil from collectors that cannot read CPU times
+	Pressure       *Pressure          `json:"pressure,omitempty"`  // Nil when the kernel has no PSI
+	FailedServices int                `json:"failed_services"`     // Count of failed systemd services
+	Units          []SystemdUnit      `json:"units"`               // Service units and failed units, nil from collectors that cannot list units
+	FailedUnits    []SystemdUnit      `json:"failed_units"`        // Set by the dashboard from Units
+	Services       []ServiceHealth    `json:"services"`            // Watched units, set by the dashboard from Units
+	InodeUsed      int64              `json:"inode_used"`
+	InodeTotal     int64              `json:"inode_total"`
+	InodePercent   float64            `json:"inode_percent"`
+	NetworkRxMB    float64            `json:"network_rx_mb"` // Cumulative MB received since boot
+	NetworkTxMB    float64            `json:"network_tx_mb"` // Cumulative MB transmitted since boot
+	Interfaces     []NetworkInterface `json:"interfaces"`    // Per-interface counters, nil from collectors that cannot list interfaces
+	KernelVersion  string             `json:"kernel_version"`
+	PortCount      int                `json:"port_count,omitempty"` // Physical ports, from collectors that see the interface table
+
+	// OpenFlow is only set when Open vSwitch tools are present on the host
+	OpenFlow *OpenFlowMetrics `json:"openflow,omitempty"`
+
+	// SampledAt is when the host took the sample, if not at collection time
+	SampledAt time.Time `json:"-"`
+}
+
+// Filesystem is one mounted filesystem. Collectors report every mount with
+// blocks; the dashboard then applies the device's include/exclude patterns
+// and sets the threshold and full flag.
+type Filesystem struct {
+	Mountpoint       string  `json:"mountpoint"`
+	FSType           string  `json:"fstype"` // Empty when the collector cannot tell, e.g. SNMP
+	Device           string  `json:"device"`
+	SizeBytes        uint64  `json:"size_bytes"`
+	UsedBytes        uint64  `json:"used_bytes"`
+	AvailBytes       uint64  `json:"avail_bytes"`  // Available to unprivileged users
+	UsedPercent      float64 `json:"used_percent"` // As df reports it: reserved blocks count as unavailable
+	InodesTotal      uint64  `json:"inodes_total"` // 0 for filesystems without a fixed inode table
+	InodesUsed       uint64  `json:"inodes_used"`
+	InodePercent     float64 `json:"inode_percent"`
+	ReadOnly         bool    `json:"read_only"`
+	ThresholdPercent float64 `json:"threshold_percent"` // Usage above which the mount counts as full
+	Full             bool    `json:"full"`
+}
+
+// FilesystemUsage returns the used percentage the way df computes it, where
+// blocks reserved for root count as unavailable
+func FilesystemUsage(used, avail uint64) float64 {
+	if used+avail == 0 {
+		return 0
+	}
+	return float64(used) / float64(used+avail) * 100
+}
+
+// InodeUsage returns the percentage of inodes in use, 0 without an inode table
+func InodeUsage(used, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(used) / float64(total) * 100
+}
+
+// SizeGB returns the filesystem size in GB for display
+func (f Filesystem) SizeGB() float64 { return float64(f.SizeBytes) / (1024 * 1024 * 1024) }
+
+// UsedGB returns the used space in GB for display
+func (f Filesystem) UsedGB() float64 { return float64(f.UsedBytes) / (1024 * 1024 * 1024) }
+
+// OpenFlowMetrics holds Open vSwitch state reported by a switch
+type OpenFlowMetrics struct {
+	Controller string `json:"controller"` // e.g. "tcp:192.168.1.250:6653"
+	FlowCount  int    `json:"flow_count"`
+	PortCount  int    `json:"port_count"`
+	Version    string `json:"version"`
+}
+
+// UnescapeMount decodes the octal escapes /proc/mounts uses in mountpoints
+// and devices, such as \040 for a space
+func UnescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// FormatUptime renders seconds since boot like "5 days 3 hours"
+func FormatUptime(secs int64) string {
+	days := secs / 86400
+	hours := (secs % 86400) / 3600
+	minutes := (secs % 3600) / 60
+	if days > 0 {
+		return fmt.Sprintf("%d days %d hours", days, hours)
+	}
+	if hours > 0 {
+		return fmt.Sprintf("%d hours %d minutes", hours, minutes)
+	}
+	return fmt.Sprintf("%d minutes", minutes)
+}
+
+// MemoryPercent returns memory usage as a percentage of total memory
+func (m *HostMetrics) MemoryPercent() float64 {
+	if m.MemoryTotal <= 0 {
+		return 0
+	}
+	return (m.MemoryUsed / m.MemoryTotal) * 100
+}
+
+// ApplyHostMetrics copies a collected sample onto the server
+func (s *Server) ApplyHostMetrics(m *HostMetrics) {
+	s.Uptime = m.Uptime
+	s.Processes = m.Processes
+	s.DiskUsage = m.DiskUsage
+	s.DiskTotal = m.DiskTotal
+	s.DiskPercent = m.DiskPercent
+	s.DiskPartition = "/"
+	s.FullPartitions = append([]string{}, m.FullPartitions...)
+	s.Filesystems = append([]Filesystem(nil), m.Filesystems...)
+	s.MemoryUsed = m.MemoryUsed
+	s.MemoryTotal = m.MemoryTotal
+	s.MemoryPercent = m.MemoryPercent()
+	s.LoadAverage = m.LoadAverage
+	s.Load1, s.Load5, s.Load15, _ = ParseLoadAverage(m.LoadAverage)
+	s.CPUCores = m.CPUCores
+	s.CPUTimes = m.CPUTimes
+	s.Pressure = m.Pressure
+	s.FailedServices = m.FailedServices
+	s.FailedUnits = copyUnits(m.FailedUnits)
+	s.WatchedServices = copyServices(m.Services)
+	s.InodeUsed = m.InodeUsed
+	s.InodeTotal = m.InodeTotal
+	s.InodePercent = m.InodePercent
+	s.NetworkRxMB = m.NetworkRxMB
+	s.NetworkTxMB = m.NetworkTxMB
+	s.Interfaces = append([]NetworkInterface(nil), m.Interfaces...)
+	s.KernelVersion = m.KernelVersion
+}
+
+// ApplyHostMetrics copies a collected sample onto the VM
+func (vm *VM) ApplyHostMetrics(m *HostMetrics) {
+	vm.Uptime = m.Uptime
+	vm.Processes = m.Processes
+	vm.DiskUsage = m.DiskUsage
+	vm.DiskTotal = m.DiskTotal
+	vm.DiskPercent = m.DiskPercent
+	vm.DiskPartition = "/"
+	vm.FullPartitions = append([]string{}, m.FullPartitions...)
+	vm.Filesystems = append([]Filesystem(nil), m.Filesystems...)
+	vm.MemoryUsed = m.MemoryUsed
+	vm.MemoryTotal = m.MemoryTotal
+	vm.MemoryPercent = m.MemoryPercent()
+	vm.LoadAverage = m.LoadAverage
+	vm.Load1, vm.Load5, vm.Load15, _ = ParseLoadAverage(m.LoadAverage)
+	vm.CPUCores = m.CPUCores
+	vm.CPUTimes = m.CPUTimes
+	vm.Pressure = m.Pressure
+	vm.FailedServices = m.FailedServices
+	vm.FailedUnits = copyUnits(m.FailedUnits)
+	vm.WatchedServices = copyServices(m.Services)
+	vm.InodeUsed = m.InodeUsed
+	vm.InodeTotal = m.InodeTotal
+	vm.InodePercent = m.InodePercent
+	vm.NetworkRxMB = m.NetworkRxMB
+	vm.NetworkTxMB = m.NetworkTxMB
+	vm.Interfaces = append([]NetworkInterface(nil), m.Interfaces...)
+	vm.KernelVersion = m.KernelVersion
+}
+
+// ApplyHostMetrics copies a collected sample onto the switch, including
+// OpenFlow state when the switch reported it
+func (sw *Switch) ApplyHostMetrics(m *HostMetrics) {
+	sw.Uptime = m.Uptime
+	sw.Processes = m.Processes
+	sw.DiskUsage = m.DiskUsage
+	sw.DiskTotal = m.DiskTotal
+	sw.DiskPercent = m.DiskPercent
+	sw.DiskPartition = "/"
+	sw.FullPartitions = append([]string{}, m.FullPartitions...)
+	sw.Filesystems = append([]Filesystem(nil), m.Filesystems...)
+	sw.MemoryUsed = m.MemoryUsed
+	sw.MemoryTotal = m.MemoryTotal
+	sw.MemoryPercent = m.MemoryPercent()
+	sw.LoadAverage = m.LoadAverage
+	sw.Load1, sw.Load5, sw.Load15, _ = ParseLoadAverage(m.LoadAverage)
+	sw.CPUCores = m.CPUCores
+	sw.CPUTimes = m.CPUTimes
+	sw.Pressure = m.Pressure
+	sw.FailedServices = m.FailedServices
+	sw.FailedUnits = copyUnits(m.FailedUnits)
+	sw.WatchedServices = copyServices(m.Services)
+	sw.InodeUsed = m.InodeUsed
+	sw.InodeTotal = m.InodeTotal
+	sw.InodePercent = m.InodePercent
+	sw.NetworkRxMB = m.NetworkRxMB
+	sw.NetworkTxMB = m.NetworkTxMB
+	sw.Interfaces = append([]NetworkInterface(nil), m.Interfaces...)
+	sw.KernelVersion = m.KernelVersion
+	if m.PortCount > 0 {
+		sw.PortCount = m.PortCount
+	}
+
+	if m.OpenFlow == nil {
+		sw.OpenFlowStatus = "not_installed"
+		return
+	}
+	sw.FlowCount = m.OpenFlow.FlowCount
+	sw.PortCount = m.OpenFlow.PortCount
+	if m.OpenFlow.Version != "" {
+		sw.OpenFlowVersion = m.OpenFlow.Version
+	}
+	controller := m.OpenFlow.Controller
+	if controller == "" {
+		sw.OpenFlowStatus = "inactive"
+		return
+	}
+	sw.OpenFlowStatus = "active"
+	// Extract IP from tcp:192.168.1.250:6653 format
+	if parts := strings.Split(controller, ":"); len(parts) >= 2 {
+		sw.ControllerIP = parts[1]
+	}
+}
+
+// copyUnits copies a unit list, keeping nil (not reported) apart from empty
+func copyUnits(units []SystemdUnit) []SystemdUnit {
+	if units == nil {
+		return nil
+	}
+	return append([]SystemdUnit{}, units...)
+}
+
+// copyServices copies a service health list, keeping nil apart from empty
+func copyServices(services []ServiceHealth) []ServiceHealth {
+	if services == nil {
+		return nil
+	}
+	return append([]ServiceHealth{}, services...)
+}
+
+// RecordCollection applies a successful collection to the server. Interface
+// rates and CPU usage are computed against the previous collection.
+func (s *Server) RecordCollection(collector string, m *HostMetrics, metricErrors []string) {
+	now := time.Now()
+	sampledAt := m.SampledAt
+	if sampledAt.IsZero() {
+		sampledAt = now
+	}
+	interfaces := WithInterfaceRates(s.Interfaces, m.Interfaces, sampledAt)
+	cpu := CPUUsageSince(s.CPUTimes, s.CPU, m.CPUTimes)
+	s.ApplyHostMetrics(m)
+	s.Interfaces = interfaces
+	s.CPU = cpu
+	s.MetricErrors = metricErrors
+	s.Collector = collector
+	s.MetricsCollectedAt = now
+	s.MetricsStale = false
+	s.CollectionError = ""
+}
+
+// RecordCollectionFailure marks the server's metrics as stale. The last
+// collected values are kept as they are, never replaced with made-up ones.
+func (s *Server) RecordCollectionFailure(collector, status string, err error) {
+	s.Status = status
+	s.Collector = collector
+	s.MetricsStale = true
+	s.CollectionError = err.Error()
+}
+
+// RecordCollection applies a successful collection to the VM
+func (vm *VM) RecordCollection(collector string, m *HostMetrics, metricErrors []string) {
+	now := time.Now()
+	sampledAt := m.SampledAt
+	if sampledAt.IsZero() {
+		sampledAt = now
+	}
+	interfaces := WithInterfaceRates(vm.Interfaces, m.Interfaces, sampledAt)
+	cpu := CPUUsageSince(vm.CPUTimes, vm.CPU, m.CPUTimes)
+	vm.ApplyHostMetrics(m)
+	vm.Interfaces = interfaces
+	vm.CPU = cpu
+	vm.MetricErrors = metricErrors
+	vm.Collector = collector
+	vm.MetricsCollectedAt = now
+	vm.MetricsStale = false
+	vm.CollectionError = ""
+}
+
+// RecordCollectionFailure marks the VM's metrics as stale
+func (vm *VM) RecordCollectionFailure(collector, status string, err error) {
+	vm.Status = status
+	vm.Collector = collector
+	vm.MetricsStale = true
+	vm.CollectionError = err.Error()
+}
+
+// RecordCollection applies a successful collection to the switch
+func (sw *Switch) RecordCollection(collector string, m *HostMetrics, metricErrors []string) {
+	now := time.Now()
+	sampledAt := m.SampledAt
+	if sampledAt.IsZero() {
+		sampledAt = now
+	}
+	interfaces := WithInterfaceRates(sw.Interfaces, m.Interfaces, sampledAt)
+	cpu := CPUUsageSince(sw.CPUTimes, sw.CPU, m.CPUTimes)
+	sw.ApplyHostMetrics(m)
+	sw.Interfaces = interfaces
+	sw.CPU = cpu
+	sw.MetricErrors = metricErrors
+	sw.Collector = collector
+	sw.MetricsCollectedAt = now
+	sw.MetricsStale = false
+	sw.CollectionError = ""
+}
+
+// RecordCollectionFailure marks the switch's metrics as stale
+func (sw *Switch) RecordCollectionFailure(collector, status string, err error) {
+	sw.Status = status
+	sw.Collector = collector
+	sw.MetricsStale = true
+	sw.CollectionError = err.Error()
+}
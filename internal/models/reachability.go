@@ -0,0 +1,81 @@
+package models
+
+// Probe types
+const (
+	ProbeICMP  = "icmp"
+	ProbeTCP   = "tcp"
+	ProbeHTTP  = "http"
+	ProbeJump  = "ssh-jump" // TCP connect to the SSH port from the last jump host
+	ProbeAgent = "agent"    // A recent push from dashboard-agent
+)
+
+// ProbeResult is the outcome of one reachability probe
+type ProbeResult struct {
+	Type       string  `json:"type"`
+	Target     string  `json:"target"` // address, host:port or URL
+	Success    bool    `json:"success"`
+	RTTMillis  float64 `json:"rtt_ms"`                        // Average round trip of successful attempts
+	PacketLoss float64 `json:"packet_loss_percent,omitempty"` // ICMP only
+	Error      string  `json:"error,omitempty"`
+}
+
+// Reachability summarizes the probes run for one device check
+type Reachability struct {
+	Status     string        // StatusOnline when any probe succeeded
+	RTTMillis  float64       // ICMP round trip if available, else the fastest successful probe
+	PacketLoss float64       // ICMP packet loss percentage (0 when ICMP was not probed)
+	Probes     []ProbeResult // Individual results in configured order
+}
+
+// NewReachability derives the summary from individual probe results
+func NewReachability(probes []ProbeResult) Reachability {
+	r := Reachability{Status: StatusOffline, Probes: probes}
+	var icmpRTT, fastest float64
+	haveICMP := false
+	for _, p := range probes {
+		if p.Type == ProbeICMP {
+			r.PacketLoss = p.PacketLoss
+		}
+		if !p.Success {
+			continue
+		}
+		r.Status = StatusOnline
+		if p.Type == ProbeAgent {
+			continue // no round trip to measure
+		}
+		if p.Type == ProbeICMP {
+			icmpRTT, haveICMP = p.RTTMillis, true
+		} else if fastest == 0 || p.RTTMillis < fastest {
+			fastest = p.RTTMillis
+		}
+	}
+	r.RTTMillis = fastest
+	if haveICMP {
+		r.RTTMillis = icmpRTT
+	}
+	return r
+}
+
+// ApplyReachability copies probe results onto the server
+func (s *Server) ApplyReachability(r Reachability) {
+	s.PingStatus = r.Status
+	s.RTTMillis = r.RTTMillis
+	s.PacketLoss = r.PacketLoss
+	s.Probes = append([]ProbeResult{}, r.Probes...)
+}
+
+// ApplyReachability copies probe results onto the VM
+func (vm *VM) ApplyReachability(r Reachability) {
+	vm.PingStatus = r.Status
+	vm.RTTMillis = r.RTTMillis
+	vm.PacketLoss = r.PacketLoss
+	vm.Probes = append([]ProbeResult{}, r.Probes...)
+}
+
+// ApplyReachability copies probe results onto the switch
+func (sw *Switch) ApplyReachability(r Reachability) {
+	sw.PingStatus = r.Status
+	sw.RTTMillis = r.RTTMillis
+	sw.PacketLoss = r.PacketLoss
+	sw.Probes = append([]ProbeResult{}, r.Probes...)
+}
@@ -19,16 +19,28 @@ type Server struct {
 	DiskTotal      float64   `json:"disk_total"`
 	DiskPercent    float64   `json:"disk_percent"`
 	DiskPartition  string    `json:"disk_partition"`  // Primary partition being monitored (usually /)
-	FullPartitions []string  `json:"full_partitions"` // List of partitions over 90% full
+	FullPartitions []string  `json:"full_partitions"` // Mounts over their full threshold, e.g. "/var 95%"
+	Filesystems    []Filesystem `json:"filesystems"`     // Every monitored mount, see Filesystem
 	Tags           []string  `json:"tags"`
+	UplinkSwitchID string    `json:"uplink_switch_id,omitempty"` // Switch the server is connected through
 	// Memory metrics
 	MemoryUsed     float64   `json:"memory_used"`     // Memory used in MB
 	MemoryTotal    float64   `json:"memory_total"`    // Total memory in MB
 	MemoryPercent  float64   `json:"memory_percent"`  // Memory usage percentage
 	// Load average
 	LoadAverage    string    `json:"load_average"`    // 1, 5, 15 minute load averages
+	Load1          float64   `json:"load1"`           // Load averages as numbers; 0 when LoadAverage is empty
+	Load5          float64   `json:"load5"`
+	Load15         float64   `json:"load15"`
+	// CPU
+	CPUCores       int       `json:"cpu_cores"`       // Logical CPUs, 0 when unknown
+	CPU            *CPUUsage `json:"cpu,omitempty"`   // Usage since the previous sample
+	CPUTimes       *CPUTimes `json:"cpu_times,omitempty"` // Cumulative CPU times of the last sample
+	Pressure       *Pressure `json:"pressure,omitempty"`  // Nil when the kernel has no PSI
 	// Failed services
 	FailedServices int       `json:"failed_services"` // Count of failed systemd services
+	FailedUnits    []SystemdUnit `json:"failed_units"`     // Failed units with their result, nil when the collector cannot list units
+	WatchedServices []ServiceHealth `json:"watched_services"` // Units from the device's watchlist
 	// Inode usage
 	InodeUsed      int64     `json:"inode_used"`      // Inodes used on root partition
 	InodeTotal     int64     `json:"inode_total"`     // Total inodes on root partition
@@ -36,9 +48,25 @@ type Server struct {
 	// Network stats
 	NetworkRxMB    float64   `json:"network_rx_mb"`   // Network received in MB
 	NetworkTxMB    float64   `json:"network_tx_mb"`   // Network transmitted in MB
+	Interfaces     []NetworkInterface `json:"interfaces"` // Per-interface counters and rates
 	// System info
 	KernelVersion  string    `json:"kernel_version"`  // Linux kernel version
 	LastChecked    time.Time `json:"last_checked"`
+	// Collection problems from the last check, e.g. "memory.available_kb: missing"
+	MetricErrors []string `json:"metric_errors"`
+	// Reachability probe results from the last check
+	RTTMillis  float64       `json:"rtt_ms"`
+	PacketLoss float64       `json:"packet_loss_percent"`
+	Probes     []ProbeResult `json:"probes"`
+	// Debounced reachability and flap detection
+	StateTracker
+	// Parent that is down while the status is "unreachable", e.g. "uplink switch Core Switch 1"
+	ParentDown string `json:"parent_down,omitempty"`
+	// Metrics collection state
+	Collector          string    `json:"collector"`            // Collector that produced the metrics
+	MetricsCollectedAt time.Time `json:"metrics_collected_at"` // Zero until the first successful collection
+	MetricsStale       bool      `json:"metrics_stale"`        // Last collection failed; metrics are from MetricsCollectedAt
+	CollectionError    string    `json:"collection_error,omitempty"`
 }
 
 // NewServer creates a new Server instance.
@@ -60,6 +88,24 @@ func NewServer(id, name, ipAddress, hostname string, port int) *Server {
 	}
 }
 
+// Clone returns a deep copy of the server so the copy can be modified
+// without affecting the slices and pointers of the original.
+func (s Server) Clone() Server {
+	s.FullPartitions = append([]string(nil), s.FullPartitions...)
+	s.Filesystems = append([]Filesystem(nil), s.Filesystems...)
+	s.Interfaces = cloneInterfaces(s.Interfaces)
+	s.CPU = s.CPU.clone()
+	s.CPUTimes = s.CPUTimes.clone()
+	s.Pressure = s.Pressure.clone()
+	s.FailedUnits = copyUnits(s.FailedUnits)
+	s.WatchedServices = copyServices(s.WatchedServices)
+	s.Tags = append([]string(nil), s.Tags...)
+	s.MetricErrors = append([]string(nil), s.MetricErrors...)
+	s.Probes = append([]ProbeResult(nil), s.Probes...)
+	s.StateChanges = append([]time.Time(nil), s.StateChanges...)
+	return s
+}
+
 // CheckStatus updates the server's status.
 func (s *Server) CheckStatus(status string) {
 	s.Status = status
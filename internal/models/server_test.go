@@ -0,0 +1,25 @@
+package models
+
+import "testing"
+
+func TestCloneSharesNoPointers(t *testing.T) {
+	orig := Server{
+		CPU:        &CPUUsage{UsedPercent: 10},
+		CPUTimes:   &CPUTimes{User: 1},
+		Pressure:   &Pressure{CPUSome: &PressureStall{Avg10: 1}},
+		Interfaces: []NetworkInterface{{Name: "eth0", Rates: &InterfaceRates{RxBytesPerSec: 1}}},
+	}
+	c := orig.Clone()
+	c.CPU.UsedPercent = 99
+	c.CPUTimes.User = 99
+	c.Pressure.CPUSome.Avg10 = 99
+	c.Interfaces[0].Rates.RxBytesPerSec = 99
+	if orig.CPU.UsedPercent != 10 || orig.CPUTimes.User != 1 || orig.Pressure.CPUSome.Avg10 != 1 || orig.Interfaces[0].Rates.RxBytesPerSec != 1 {
+		t.Fatalf("clone shares state with the original: %+v", orig)
+	}
+
+	var empty Server
+	if c := empty.Clone(); c.CPU != nil || c.CPUTimes != nil || c.Pressure != nil || c.Interfaces != nil {
+		t.Fatalf("nil fields not kept nil: %+v", c)
+	}
+}
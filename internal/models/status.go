@@ -0,0 +1,54 @@
+package models
+
+// Device status values. Servers and switches report "online", VMs report
+// "running"; the remaining values apply to every device type.
+const (
+	StatusOnline          = "online"
+	StatusRunning         = "running"
+	StatusOffline         = "offline"
+	StatusUnknown         = "unknown"          // reachable, but metrics have never been collected
+	StatusStale           = "stale"            // reachable, but the last metrics collection failed
+	StatusHostKeyMismatch = "hostkey-mismatch" // SSH host key changed or was rejected
+	StatusHostKeyPending  = "hostkey-pending"  // SSH host key not yet trusted
+	StatusUnreachable     = "unreachable"      // down while a host or uplink it depends on is down
+)
+
+// StatusLabel returns the human readable label for a device status
+func StatusLabel(status string) string {
+	switch status {
+	case StatusOnline:
+		return "Online"
+	case StatusRunning:
+		return "Running"
+	case StatusOffline:
+		return "Offline"
+	case StatusStale:
+		return "Stale"
+	case StatusHostKeyMismatch:
+		return "Host Key Mismatch"
+	case StatusHostKeyPending:
+		return "Host Key Pending"
+	case StatusUnreachable:
+		return "Unreachable (parent down)"
+	default:
+		return "Unknown"
+	}
+}
+
+// StatusBadgeClass returns the Bootstrap badge classes for a device status
+func StatusBadgeClass(status string) string {
+	switch status {
+	case StatusOnline, StatusRunning:
+		return "bg-success"
+	case StatusOffline:
+		return "bg-danger"
+	case StatusHostKeyMismatch:
+		return "bg-danger"
+	case StatusHostKeyPending, StatusStale:
+		return "bg-warning text-dark"
+	case StatusUnreachable:
+		return "bg-dark"
+	default:
+		return "bg-secondary"
+	}
+}
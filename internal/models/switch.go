@@ -19,7 +19,8 @@ type Switch struct {
 	DiskTotal      float64   `json:"disk_total"`
 	DiskPercent    float64   `json:"disk_percent"`
 	DiskPartition  string    `json:"disk_partition"`  // Primary partition being monitored (usually /)
-	FullPartitions []string  `json:"full_partitions"` // List of partitions over 90% full
+	FullPartitions []string  `json:"full_partitions"` // Mounts over their full threshold, e.g. "/var 95%"
+	Filesystems    []Filesystem `json:"filesystems"`     // Every monitored mount, see Filesystem
 	Tags           []string  `json:"tags"`
 	// Memory metrics
 	MemoryUsed     float64   `json:"memory_used"`     // Memory used in MB
@@ -27,8 +28,18 @@ type Switch struct {
 	MemoryPercent  float64   `json:"memory_percent"`  // Memory usage percentage
 	// Load average
 	LoadAverage    string    `json:"load_average"`    // 1, 5, 15 minute load averages
+	Load1          float64   `json:"load1"`           // Load averages as numbers; 0 when LoadAverage is empty
+	Load5          float64   `json:"load5"`
+	Load15         float64   `json:"load15"`
+	// CPU
+	CPUCores       int       `json:"cpu_cores"`       // Logical CPUs, 0 when unknown
+	CPU            *CPUUsage `json:"cpu,omitempty"`   // Usage since the previous sample
+	CPUTimes       *CPUTimes `json:"cpu_times,omitempty"` // Cumulative CPU times of the last sample
+	Pressure       *Pressure `json:"pressure,omitempty"`  // Nil when the kernel has no PSI
 	// Failed services
 	FailedServices int       `json:"failed_services"` // Count of failed systemd services
+	FailedUnits    []SystemdUnit `json:"failed_units"`     // Failed units with their result, nil when the collector cannot list units
+	WatchedServices []ServiceHealth `json:"watched_services"` // Units from the device's watchlist
 	// Inode usage
 	InodeUsed      int64     `json:"inode_used"`      // Inodes used on root partition
 	InodeTotal     int64     `json:"inode_total"`     // Total inodes on root partition
@@ -36,6 +47,7 @@ type Switch struct {
 	// Network stats
 	NetworkRxMB    float64   `json:"network_rx_mb"`   // Network received in MB
 	NetworkTxMB    float64   `json:"network_tx_mb"`   // Network transmitted in MB
+	Interfaces     []NetworkInterface `json:"interfaces"` // Per-interface counters and rates
 	// System info
 	KernelVersion  string    `json:"kernel_version"`  // Linux kernel version
 	// OpenFlow specific
@@ -45,6 +57,19 @@ type Switch struct {
 	FlowCount       int      `json:"flow_count"`       // Number of active flow rules
 	PortCount       int      `json:"port_count"`       // Number of switch ports
 	LastChecked     time.Time `json:"last_checked"`
+	// Collection problems from the last check, e.g. "memory.available_kb: missing"
+	MetricErrors []string `json:"metric_errors"`
+	// Reachability probe results from the last check
+	RTTMillis  float64       `json:"rtt_ms"`
+	PacketLoss float64       `json:"packet_loss_percent"`
+	Probes     []ProbeResult `json:"probes"`
+	// Debounced reachability and flap detection
+	StateTracker
+	// Metrics collection state
+	Collector          string    `json:"collector"`            // Collector that produced the metrics
+	MetricsCollectedAt time.Time `json:"metrics_collected_at"` // Zero until the first successful collection
+	MetricsStale       bool      `json:"metrics_stale"`        // Last collection failed; metrics are from MetricsCollectedAt
+	CollectionError    string    `json:"collection_error,omitempty"`
 }
 
 // NewSwitch creates a new Switch instance
@@ -71,6 +96,24 @@ func NewSwitch(id, name, ipAddress, hostname string, port int) *Switch {
 	}
 }
 
+// Clone returns a deep copy of the switch so the copy can be modified
+// without affecting the slices and pointers of the original.
+func (sw Switch) Clone() Switch {
+	sw.FullPartitions = append([]string(nil), sw.FullPartitions...)
+	sw.Filesystems = append([]Filesystem(nil), sw.Filesystems...)
+	sw.Interfaces = cloneInterfaces(sw.Interfaces)
+	sw.CPU = sw.CPU.clone()
+	sw.CPUTimes = sw.CPUTimes.clone()
+	sw.Pressure = sw.Pressure.clone()
+	sw.FailedUnits = copyUnits(sw.FailedUnits)
+	sw.WatchedServices = copyServices(sw.WatchedServices)
+	sw.Tags = append([]string(nil), sw.Tags...)
+	sw.MetricErrors = append([]string(nil), sw.MetricErrors...)
+	sw.Probes = append([]ProbeResult(nil), sw.Probes...)
+	sw.StateChanges = append([]time.Time(nil), sw.StateChanges...)
+	return sw
+}
+
 // CheckStatus updates the switch's status
 func (sw *Switch) CheckStatus(status string) {
 	sw.Status = status
@@ -13,4 +13,5 @@ type SyntheticCheckResult struct {
 	LastRun     time.Time `json:"last_run"`
 	Message     string    `json:"message"`
 	Tags        []string  `json:"tags"`
+	StateTracker
 }
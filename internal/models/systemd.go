@@ -0,0 +1,157 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// SystemdUnit is one systemd unit as a collector reported it. Collectors
+// report every service unit and failed units of any type; the dashboard
+// then picks out the failed ones and those on the device's watchlist.
+type SystemdUnit struct {
+	Name          string    `json:"name"`                      // e.g. "nginx.service"
+	LoadState     string    `json:"load_state"`                // loaded, not-found, masked, ...
+	ActiveState   string    `json:"active_state"`              // active, inactive, failed, activating, ...
+	SubState      string    `json:"sub_state"`                 // running, exited, dead, failed, ...
+	UnitFileState string    `json:"unit_file_state,omitempty"` // enabled, disabled, static, masked, ...; empty when unknown
+	Result        string    `json:"result,omitempty"`          // Why a failed unit failed: exit-code, signal, timeout, ...
+	FailedAt      time.Time `json:"failed_at"`                 // When a failed unit entered the failed state; zero when unknown
+}
+
+// Failed reports whether the unit is in the failed state
+func (u SystemdUnit) Failed() bool {
+	return u.ActiveState == "failed"
+}
+
+// State renders the active and sub state like "active (running)"
+func (u SystemdUnit) State() string {
+	if u.SubState == "" || u.SubState == u.ActiveState {
+		return u.ActiveState
+	}
+	return u.ActiveState + " (" + u.SubState + ")"
+}
+
+// ServiceHealth is a unit from a device's watchlist, checked to be active
+// and not disabled
+type ServiceHealth struct {
+	SystemdUnit
+	Healthy bool   `json:"healthy"`
+	Problem string `json:"problem,omitempty"` // e.g. "inactive (dead)", "disabled" or "not found"
+}
+
+// CheckService checks a watched unit; unit is nil when the host did not
+// report it, i.e. no such unit is installed
+func CheckService(name string, unit *SystemdUnit) ServiceHealth {
+	if unit == nil || unit.LoadState == "not-found" {
+		return ServiceHealth{SystemdUnit: SystemdUnit{Name: name, LoadState: "not-found"}, Problem: "not found"}
+	}
+	h := ServiceHealth{SystemdUnit: *unit}
+	switch {
+	case unit.LoadState == "masked" || unit.UnitFileState == "masked":
+		h.Problem = "masked"
+	case unit.ActiveState != "active":
+		h.Problem = unit.State()
+	case unit.UnitFileState == "disabled":
+		h.Problem = "disabled" // Running now, but will not start after a reboot
+	default:
+		h.Healthy = true
+	}
+	return h
+}
+
+// SystemdUnitName completes a unit name without a type the way systemctl
+// does, e.g. "nginx" to "nginx.service"
+func SystemdUnitName(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return name
+	}
+	if i := strings.LastIndex(name, "."); i >= 0 && systemdUnitTypes[name[i+1:]] {
+		return name
+	}
+	return name + ".service"
+}
+
+var systemdUnitTypes = map[string]bool{
+	"service": true, "socket": true, "device": true, "mount": true, "automount": true, "swap": true,
+	"target": true, "path": true, "timer": true, "slice": true, "scope": true,
+}
+
+// ReportedSystemdUnit reports whether collectors list a unit: every service
+// unit, and units of other types only while they are failed
+func ReportedSystemdUnit(u SystemdUnit) bool {
+	return strings.HasSuffix(u.Name, ".service") || u.Failed()
+}
+
+// ParseSystemdUnit parses a line of "systemctl list-units --plain
+// --no-legend": "<unit> <load> <active> <sub> <description>". Older
+// versions mark failed units with a bullet even in plain output.
+func ParseSystemdUnit(line string) (SystemdUnit, bool) {
+	fields := strings.Fields(line)
+	if len(fields) > 0 && fields[0] == "●" {
+		fields = fields[1:]
+	}
+	if len(fields) < 4 {
+		return SystemdUnit{}, false
+	}
+	return SystemdUnit{Name: fields[0], LoadState: fields[1], ActiveState: fields[2], SubState: fields[3]}, true
+}
+
+// ParseUnitFile parses a line of "systemctl list-unit-files --no-legend":
+// "<unit> <state> [<preset>]"
+func ParseUnitFile(line string) (name, state string, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return "", "", false
+	}
+	return fields[0], fields[1], true
+}
+
+// WithUnitFileStates sets the unit file state of the units from a map of
+// unit file states by name, and returns them sorted by name. Services that
+// are installed but not loaded are added as inactive, as systemd only loads
+// units while something refers to them. Templates such as
+// "getty@.service" are not units themselves and are left out.
+func WithUnitFileStates(units []SystemdUnit, states map[string]string) []SystemdUnit {
+	listed := make(map[string]bool, len(units))
+	for i := range units {
+		listed[units[i].Name] = true
+		if state, ok := states[units[i].Name]; ok {
+			units[i].UnitFileState = state
+		}
+	}
+	for name, state := range states {
+		if listed[name] || strings.HasSuffix(name, "@.service") || !strings.HasSuffix(name, ".service") {
+			continue
+		}
+		loadState := "loaded"
+		if state == "masked" {
+			loadState = "masked"
+		}
+		units = append(units, SystemdUnit{Name: name, LoadState: loadState, ActiveState: "inactive", SubState: "dead", UnitFileState: state})
+	}
+	sort.Slice(units, func(i, j int) bool { return units[i].Name < units[j].Name })
+	return units
+}
+
+// SystemdTimestamp converts a systemd *TimestampMonotonic property, in
+// microseconds since boot, to wall-clock time given the boot time from the
+// btime line of /proc/stat. Zero stays zero, as systemd reports it for
+// events that never happened.
+func SystemdTimestamp(bootTime int64, monotonicUsec uint64) time.Time {
+	if bootTime <= 0 || monotonicUsec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(bootTime, 0).Add(time.Duration(monotonicUsec) * time.Microsecond)
+}
+
+// ReportsUnits reports whether the server's collector lists systemd units,
+// as opposed to only counting the failed ones
+func (s Server) ReportsUnits() bool { return s.FailedUnits != nil }
+
+// ReportsUnits reports whether the VM's collector lists systemd units
+func (vm VM) ReportsUnits() bool { return vm.FailedUnits != nil }
+
+// ReportsUnits reports whether the switch's collector lists systemd units
+func (sw Switch) ReportsUnits() bool { return sw.FailedUnits != nil }
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSystemdUnit(t *testing.T) {
+	tests := []struct {
+		line string
+		want SystemdUnit
+		ok   bool
+	}{
+		{"nginx.service loaded active running A high performance web server",
+			SystemdUnit{Name: "nginx.service", LoadState: "loaded", ActiveState: "active", SubState: "running"}, true},
+		{"● backup.service loaded failed failed Nightly backup",
+			SystemdUnit{Name: "backup.service", LoadState: "loaded", ActiveState: "failed", SubState: "failed"}, true},
+		{"ghost.service not-found inactive dead ghost.service",
+			SystemdUnit{Name: "ghost.service", LoadState: "not-found", ActiveState: "inactive", SubState: "dead"}, true},
+		{"nginx.service loaded active", SystemdUnit{}, false},
+		{"●", SystemdUnit{}, false},
+		{"", SystemdUnit{}, false},
+	}
+	for _, tt := range tests {
+		if got, ok := ParseSystemdUnit(tt.line); got != tt.want || ok != tt.ok {
+			t.Errorf("%q = %+v, %v", tt.line, got, ok)
+		}
+	}
+
+	for _, tt := range []struct {
+		line, name, state string
+		ok                bool
+	}{
+		{"nginx.service enabled enabled", "nginx.service", "enabled", true},
+		{"getty@.service enabled", "getty@.service", "enabled", true},
+		{"nginx.service", "", "", false},
+	} {
+		if name, state, ok := ParseUnitFile(tt.line); name != tt.name || state != tt.state || ok != tt.ok {
+			t.Errorf("unit file %q = %q, %q, %v", tt.line, name, state, ok)
+		}
+	}
+}
+
+func TestWithUnitFileStates(t *testing.T) {
+	units := []SystemdUnit{
+		{Name: "nginx.service", LoadState: "loaded", ActiveState: "active", SubState: "running"},
+		{Name: "backup.service", LoadState: "loaded", ActiveState: "failed", SubState: "failed"},
+	}
+	states := map[string]string{
+		"nginx.service":      "enabled",
+		"postgresql.service": "disabled",
+		"legacy.service":     "masked",
+		"getty@.service":     "enabled",
+		"docker.socket":      "enabled",
+	}
+	want := []SystemdUnit{
+		{Name: "backup.service", LoadState: "loaded", ActiveState: "failed", SubState: "failed"},
+		{Name: "legacy.service", LoadState: "masked", ActiveState: "inactive", SubState: "dead", UnitFileState: "masked"},
+		{Name: "nginx.service", LoadState: "loaded", ActiveState: "active", SubState: "running", UnitFileState: "enabled"},
+		{Name: "postgresql.service", LoadState: "loaded", ActiveState: "inactive", SubState: "dead", UnitFileState: "disabled"},
+	}
+	if got := WithUnitFileStates(units, states); !reflect.DeepEqual(got, want) {
+		t.Errorf("units\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestCheckService(t *testing.T) {
+	unit := func(load, active, sub, file string) *SystemdUnit {
+		return &SystemdUnit{Name: "nginx.service", LoadState: load, ActiveState: active, SubState: sub, UnitFileState: file}
+	}
+	tests := []struct {
+		name    string
+		unit    *SystemdUnit
+		problem string // Empty when healthy
+	}{
+		{"running and enabled", unit("loaded", "active", "running", "enabled"), ""},
+		{"static", unit("loaded", "active", "exited", "static"), ""},
+		{"unit file state unknown", unit("loaded", "active", "running", ""), ""},
+		{"disabled", unit("loaded", "active", "running", "disabled"), "disabled"},
+		{"stopped", unit("loaded", "inactive", "dead", "enabled"), "inactive (dead)"},
+		{"failed", unit("loaded", "failed", "failed", "enabled"), "failed"},
+		{"masked", unit("masked", "inactive", "dead", "masked"), "masked"},
+		{"not loaded", unit("not-found", "inactive", "dead", ""), "not found"},
+		{"missing from the sample", nil, "not found"},
+	}
+	for _, tt := range tests {
+		h := CheckService("nginx.service", tt.unit)
+		if h.Healthy != (tt.problem == "") || h.Problem != tt.problem || h.Name != "nginx.service" {
+			t.Errorf("%s: %+v", tt.name, h)
+		}
+	}
+}
+
+func TestSystemdUnitName(t *testing.T) {
+	for name, want := range map[string]string{
+		"nginx":          "nginx.service",
+		" nginx ":        "nginx.service",
+		"nginx.service":  "nginx.service",
+		"backup.timer":   "backup.timer",
+		"srv-data.mount": "srv-data.mount",
+		"node.js":        "node.js.service",
+		"getty@tty1":     "getty@tty1.service",
+		"":               "",
+	} {
+		if got := SystemdUnitName(name); got != want {
+			t.Errorf("SystemdUnitName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestSystemdTimestamp(t *testing.T) {
+	if got := SystemdTimestamp(1700000000, 60000000); !got.Equal(time.Unix(1700000060, 0)) {
+		t.Errorf("timestamp %v", got)
+	}
+	if !SystemdTimestamp(1700000000, 0).IsZero() || !SystemdTimestamp(0, 60000000).IsZero() {
+		t.Error("timestamp without a monotonic time or boot time")
+	}
+}
@@ -7,6 +7,7 @@ import (
 type StreamStatus struct {
 	Port   int  `json:"port"`
 	Active bool `json:"active"`
+	StateTracker
 }
 
 type VM struct {
@@ -16,6 +17,7 @@ type VM struct {
 	Hostname       string         `json:"hostname"`
 	Port           int            `json:"port"`
 	HostServerID   string         `json:"host_server_id"`
+	UplinkSwitchID string         `json:"uplink_switch_id,omitempty"` // Switch the VM is connected through, if not just its host's
 	StreamPorts    []int          `json:"stream_ports"`    // Ports for video/media streaming
 	Streams        []StreamStatus `json:"streams"`         // Status of each stream port
 	Status         string         `json:"status"`
@@ -26,7 +28,8 @@ type VM struct {
 	DiskTotal      float64        `json:"disk_total"`
 	DiskPercent    float64        `json:"disk_percent"`
 	DiskPartition  string         `json:"disk_partition"`  // Primary partition being monitored (usually /)
-	FullPartitions []string       `json:"full_partitions"` // List of partitions over 90% full
+	FullPartitions []string       `json:"full_partitions"` // Mounts over their full threshold, e.g. "/var 95%"
+	Filesystems    []Filesystem   `json:"filesystems"`     // Every monitored mount, see Filesystem
 	Tags           []string       `json:"tags"`
 	// Memory metrics
 	MemoryUsed     float64        `json:"memory_used"`     // Memory used in MB
@@ -34,8 +37,18 @@ type VM struct {
 	MemoryPercent  float64        `json:"memory_percent"`  // Memory usage percentage
 	// Load average
 	LoadAverage    string         `json:"load_average"`    // 1, 5, 15 minute load averages
+	Load1          float64        `json:"load1"`           // Load averages as numbers; 0 when LoadAverage is empty
+	Load5          float64        `json:"load5"`
+	Load15         float64        `json:"load15"`
+	// CPU
+	CPUCores       int            `json:"cpu_cores"`       // Logical CPUs, 0 when unknown
+	CPU            *CPUUsage      `json:"cpu,omitempty"`   // Usage since the previous sample
+	CPUTimes       *CPUTimes      `json:"cpu_times,omitempty"` // Cumulative CPU times of the last sample
+	Pressure       *Pressure      `json:"pressure,omitempty"`  // Nil when the kernel has no PSI
 	// Failed services
 	FailedServices int            `json:"failed_services"` // Count of failed systemd services
+	FailedUnits    []SystemdUnit `json:"failed_units"`     // Failed units with their result, nil when the collector cannot list units
+	WatchedServices []ServiceHealth `json:"watched_services"` // Units from the device's watchlist
 	// Inode usage
 	InodeUsed      int64          `json:"inode_used"`      // Inodes used on root partition
 	InodeTotal     int64          `json:"inode_total"`     // Total inodes on root partition
@@ -43,9 +56,25 @@ type VM struct {
 	// Network stats
 	NetworkRxMB    float64        `json:"network_rx_mb"`   // Network received in MB
 	NetworkTxMB    float64        `json:"network_tx_mb"`   // Network transmitted in MB
+	Interfaces     []NetworkInterface `json:"interfaces"` // Per-interface counters and rates
 	// System info
 	KernelVersion  string         `json:"kernel_version"`  // Linux kernel version
 	LastChecked    time.Time      `json:"last_checked"`
+	// Collection problems from the last check, e.g. "memory.available_kb: missing"
+	MetricErrors []string `json:"metric_errors"`
+	// Reachability probe results from the last check
+	RTTMillis  float64       `json:"rtt_ms"`
+	PacketLoss float64       `json:"packet_loss_percent"`
+	Probes     []ProbeResult `json:"probes"`
+	// Debounced reachability and flap detection
+	StateTracker
+	// Parent that is down while the status is "unreachable", e.g. "host server Web Server 1"
+	ParentDown string `json:"parent_down,omitempty"`
+	// Metrics collection state
+	Collector          string    `json:"collector"`            // Collector that produced the metrics
+	MetricsCollectedAt time.Time `json:"metrics_collected_at"` // Zero until the first successful collection
+	MetricsStale       bool      `json:"metrics_stale"`        // Last collection failed; metrics are from MetricsCollectedAt
+	CollectionError    string    `json:"collection_error,omitempty"`
 }
 
 func NewVM(id, name, ipAddress, hostname string, port int, hostServerID string) *VM {
@@ -67,6 +96,26 @@ func NewVM(id, name, ipAddress, hostname string, port int, hostServerID string)
 	}
 }
 
+// Clone returns a deep copy of the VM so the copy can be modified
+// without affecting the slices and pointers of the original.
+func (vm VM) Clone() VM {
+	vm.StreamPorts = append([]int(nil), vm.StreamPorts...)
+	vm.Streams = append([]StreamStatus(nil), vm.Streams...)
+	vm.FullPartitions = append([]string(nil), vm.FullPartitions...)
+	vm.Filesystems = append([]Filesystem(nil), vm.Filesystems...)
+	vm.Interfaces = cloneInterfaces(vm.Interfaces)
+	vm.CPU = vm.CPU.clone()
+	vm.CPUTimes = vm.CPUTimes.clone()
+	vm.Pressure = vm.Pressure.clone()
+	vm.FailedUnits = copyUnits(vm.FailedUnits)
+	vm.WatchedServices = copyServices(vm.WatchedServices)
+	vm.Tags = append([]string(nil), vm.Tags...)
+	vm.MetricErrors = append([]string(nil), vm.MetricErrors...)
+	vm.Probes = append([]ProbeResult(nil), vm.Probes...)
+	vm.StateChanges = append([]time.Time(nil), vm.StateChanges...)
+	return vm
+}
+
 func (vm *VM) CheckStatus() string {
 	return vm.Status
 }
@@ -0,0 +1,370 @@
+// Package notify delivers alert notifications to webhooks, chat incoming
+// webhooks (Slack and Mattermost) and SMTP email.
+//
+// A Dispatcher routes each alert to the channels whose severity and tag
+// filters match, renders the channel's title and body templates, and
+// delivers in the background, retrying failures with exponential backoff.
+// Every delivery is kept in a bounded log.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"server-dashboard/internal/models"
+)
+
+// Notification statuses
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+	StatusTest     = "test"
+)
+
+// Delivery states
+const (
+	DeliveryQueued   = "queued"
+	DeliveryRetrying = "retrying"
+	DeliverySent     = "sent"
+	DeliveryFailed   = "failed"
+)
+
+// DefaultTitleTemplate and DefaultBodyTemplate are used by channels that
+// do not set their own
+const (
+	DefaultTitleTemplate = `[{{ .Status | upper }}] {{ .Alert.Severity }}: {{ .Alert.Rule }} on {{ .Alert.TargetName }}`
+	DefaultBodyTemplate  = `{{ .Alert.Summary }}
+Target: {{ .Alert.TargetName }} ({{ .Alert.TargetKind }} {{ .Alert.TargetID }})
+Rule: {{ .Alert.Rule }}{{ if .Alert.Expr }} ({{ .Alert.Expr }}){{ end }}
+Value: {{ .Alert.Value }}
+{{ if eq .Status "resolved" }}Resolved: {{ .Alert.ResolvedAt.Format "2006-01-02 15:04:05 MST" }}{{ else }}Since: {{ .Alert.ActiveSince.Format "2006-01-02 15:04:05 MST" }}{{ end }}
+{{- if .URL }}
+{{ .URL }}{{ end }}`
+)
+
+// maxDeliveries bounds the delivery log
+const maxDeliveries = 500
+
+// Message is one notification. Templates are executed with it; Title and
+// Body hold the rendered result when it is handed to a channel.
+type Message struct {
+	Status string // firing, resolved or test
+	Alert  models.Alert
+	URL    string // Link to the alert's target, when a dashboard URL is configured
+	Title  string
+	Body   string
+}
+
+// Channel delivers rendered messages to one destination
+type Channel interface {
+	Name() string
+	Type() string
+	// Target describes the destination without secrets, e.g. a host name
+	Target() string
+	Send(ctx context.Context, m Message) error
+}
+
+// permanentError marks a failure that retrying cannot fix
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the dispatcher gives up without retrying
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+// Route selects the alerts a channel receives. Empty lists match
+// everything; tags match when the alert's target has any of them.
+type Route struct {
+	Severities   []string
+	Tags         []string
+	SkipResolved bool // Only notify when alerts fire
+}
+
+func (r Route) matches(status string, a models.Alert) bool {
+	if status == StatusResolved && r.SkipResolved {
+		return false
+	}
+	if len(r.Severities) > 0 && !contains(r.Severities, a.Severity) {
+		return false
+	}
+	if len(r.Tags) == 0 {
+		return true
+	}
+	for _, tag := range a.Tags {
+		if contains(r.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Options tune delivery retries
+type Options struct {
+	MaxAttempts  int           // Default 5
+	RetryInitial time.Duration // Wait before the first retry, doubled each time (default 10s)
+	RetryMax     time.Duration // Longest wait between retries (default 5m)
+	Timeout      time.Duration // Per attempt (default 15s)
+}
+
+// Delivery is one message to one channel, as shown in the delivery log
+type Delivery struct {
+	ID        uint64    `json:"id"`
+	Channel   string    `json:"channel"`
+	Type      string    `json:"type"`
+	Status    string    `json:"status"` // firing, resolved or test
+	AlertID   string    `json:"alert_id"`
+	Title     string    `json:"title"`
+	State     string    `json:"state"` // queued, retrying, sent or failed
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ChannelInfo describes a configured channel
+type ChannelInfo struct {
+	Name         string   `json:"name"`
+	Type         string   `json:"type"`
+	Target       string   `json:"target"`
+	Severities   []string `json:"severities,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	SkipResolved bool     `json:"skip_resolved,omitempty"`
+}
+
+type route struct {
+	channel     Channel
+	route       Route
+	title, body *template.Template
+}
+
+// Dispatcher routes alerts to channels and delivers them
+type Dispatcher struct {
+	opts   Options
+	routes []*route
+
+	mu         sync.Mutex
+	nextID     uint64
+	deliveries []*Delivery // Oldest first
+}
+
+// NewDispatcher returns a dispatcher without channels
+func NewDispatcher(opts Options) *Dispatcher {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.RetryInitial <= 0 {
+		opts.RetryInitial = 10 * time.Second
+	}
+	if opts.RetryMax <= 0 {
+		opts.RetryMax = 5 * time.Minute
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 15 * time.Second
+	}
+	return &Dispatcher{opts: opts}
+}
+
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  strings.Join,
+}
+
+// Add registers a channel. Empty templates use the defaults.
+func (d *Dispatcher) Add(ch Channel, r Route, titleTemplate, bodyTemplate string) error {
+	if titleTemplate == "" {
+		titleTemplate = DefaultTitleTemplate
+	}
+	if bodyTemplate == "" {
+		bodyTemplate = DefaultBodyTemplate
+	}
+	title, err := template.New("title").Funcs(templateFuncs).Option("missingkey=zero").Parse(titleTemplate)
+	if err != nil {
+		return fmt.Errorf("title template: %w", err)
+	}
+	body, err := template.New("body").Funcs(templateFuncs).Option("missingkey=zero").Parse(bodyTemplate)
+	if err != nil {
+		return fmt.Errorf("body template: %w", err)
+	}
+	for _, existing := range d.routes {
+		if existing.channel.Name() == ch.Name() {
+			return fmt.Errorf("duplicate channel name %q", ch.Name())
+		}
+	}
+	d.routes = append(d.routes, &route{channel: ch, route: r, title: title, body: body})
+	return nil
+}
+
+// Notify sends an alert to every matching channel in the background
+func (d *Dispatcher) Notify(status, url string, a models.Alert) {
+	for _, r := range d.routes {
+		if r.route.matches(status, a) {
+			d.enqueue(r, Message{Status: status, Alert: a, URL: url})
+		}
+	}
+}
+
+// Test sends a test message to one channel, ignoring its route
+func (d *Dispatcher) Test(channel, url string) error {
+	for _, r := range d.routes {
+		if r.channel.Name() != channel {
+			continue
+		}
+		now := time.Now()
+		d.enqueue(r, Message{Status: StatusTest, URL: url, Alert: models.Alert{
+			ID:          "test",
+			Rule:        "test",
+			Severity:    models.SeverityInfo,
+			State:       models.AlertFiring,
+			Summary:     "Test notification from the server dashboard",
+			TargetKind:  "dashboard",
+			TargetID:    "dashboard",
+			TargetName:  "Server Dashboard",
+			Value:       "n/a",
+			ActiveSince: now,
+			FiredAt:     now,
+			LastEval:    now,
+		}})
+		return nil
+	}
+	return fmt.Errorf("unknown channel %q", channel)
+}
+
+// Channels describes the configured channels
+func (d *Dispatcher) Channels() []ChannelInfo {
+	out := make([]ChannelInfo, 0, len(d.routes))
+	for _, r := range d.routes {
+		out = append(out, ChannelInfo{
+			Name:         r.channel.Name(),
+			Type:         r.channel.Type(),
+			Target:       r.channel.Target(),
+			Severities:   r.route.Severities,
+			Tags:         r.route.Tags,
+			SkipResolved: r.route.SkipResolved,
+		})
+	}
+	return out
+}
+
+// Deliveries returns the delivery log, newest first
+func (d *Dispatcher) Deliveries() []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]Delivery, 0, len(d.deliveries))
+	for i := len(d.deliveries) - 1; i >= 0; i-- {
+		out = append(out, *d.deliveries[i])
+	}
+	return out
+}
+
+// enqueue renders a message and starts delivering it
+func (d *Dispatcher) enqueue(r *route, m Message) {
+	now := time.Now()
+	title, err := render(r.title, m)
+	body, bodyErr := render(r.body, m)
+	if err == nil {
+		err = bodyErr
+	}
+	m.Title, m.Body = title, body
+
+	d.mu.Lock()
+	d.nextID++
+	del := &Delivery{
+		ID:        d.nextID,
+		Channel:   r.channel.Name(),
+		Type:      r.channel.Type(),
+		Status:    m.Status,
+		AlertID:   m.Alert.ID,
+		Title:     title,
+		State:     DeliveryQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	d.deliveries = append(d.deliveries, del)
+	if len(d.deliveries) > maxDeliveries {
+		d.deliveries = append([]*Delivery(nil), d.deliveries[len(d.deliveries)-maxDeliveries:]...)
+	}
+	d.mu.Unlock()
+
+	if err != nil {
+		d.update(del, DeliveryFailed, fmt.Errorf("template: %w", err))
+		return
+	}
+	go d.deliver(r.channel, del, m)
+}
+
+// sleep waits between delivery attempts; tests replace it to observe the
+// backoff without waiting
+var sleep = time.Sleep
+
+// deliver sends with retries, backing off exponentially between attempts
+func (d *Dispatcher) deliver(ch Channel, del *Delivery, m Message) {
+	wait := d.opts.RetryInitial
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), d.opts.Timeout)
+		err := ch.Send(ctx, m)
+		cancel()
+
+		d.mu.Lock()
+		del.Attempts = attempt
+		d.mu.Unlock()
+		if err == nil {
+			d.update(del, DeliverySent, nil)
+			return
+		}
+		if IsPermanent(err) || attempt >= d.opts.MaxAttempts {
+			d.update(del, DeliveryFailed, err)
+			return
+		}
+		d.update(del, DeliveryRetrying, err)
+		sleep(wait)
+		if wait *= 2; wait > d.opts.RetryMax {
+			wait = d.opts.RetryMax
+		}
+	}
+}
+
+func (d *Dispatcher) update(del *Delivery, state string, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	del.State = state
+	del.UpdatedAt = time.Now()
+	if err != nil {
+		del.LastError = err.Error()
+	}
+}
+
+func render(t *template.Template, m Message) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, m); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,262 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"server-dashboard/internal/models"
+)
+
+// fakeChannel hands each message to the test and returns the error the
+// test sends back
+type fakeChannel struct {
+	name    string
+	sent    chan Message
+	results chan error
+}
+
+func newFakeChannel(name string) *fakeChannel {
+	return &fakeChannel{name: name, sent: make(chan Message, 100), results: make(chan error, 100)}
+}
+
+func (f *fakeChannel) Name() string   { return f.name }
+func (f *fakeChannel) Type() string   { return "fake" }
+func (f *fakeChannel) Target() string { return "test" }
+
+func (f *fakeChannel) Send(ctx context.Context, m Message) error {
+	f.sent <- m
+	return <-f.results
+}
+
+// withSleep replaces the backoff sleep for one test and returns the waits
+// seen so far
+func withSleep(t *testing.T, fn func(time.Duration)) func() []time.Duration {
+	t.Helper()
+	var mu sync.Mutex
+	var waits []time.Duration
+	sleep = func(d time.Duration) {
+		mu.Lock()
+		waits = append(waits, d)
+		mu.Unlock()
+		if fn != nil {
+			fn(d)
+		}
+	}
+	t.Cleanup(func() { sleep = time.Sleep })
+	return func() []time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]time.Duration(nil), waits...)
+	}
+}
+
+// waitForState polls the delivery log until the delivery with the given ID
+// reaches a state
+func waitForState(t *testing.T, d *Dispatcher, id uint64, state string) Delivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		for _, del := range d.Deliveries() {
+			if del.ID == id && del.State == state {
+				return del
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("delivery %d never became %s: %+v", id, state, d.Deliveries())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func testAlert() models.Alert {
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return models.Alert{
+		ID: "disk_threshold/server/web1", Rule: "disk_threshold", Severity: models.SeverityCritical,
+		Summary: "Disk 95% full", TargetKind: "server", TargetID: "web1", TargetName: "Web 1",
+		Value: "95", Tags: []string{"prod"}, ActiveSince: since, FiredAt: since,
+	}
+}
+
+func TestDeliveryStates(t *testing.T) {
+	retry := make(chan struct{})
+	withSleep(t, func(time.Duration) { <-retry })
+	ch := newFakeChannel("ops")
+	d := NewDispatcher(Options{})
+	if err := d.Add(ch, Route{}, "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	d.Notify(StatusFiring, "", testAlert())
+	m := <-ch.sent
+	if m.Title != "[FIRING] critical: disk_threshold on Web 1" {
+		t.Errorf("title = %q", m.Title)
+	}
+	if del := d.Deliveries()[0]; del.State != DeliveryQueued || del.Attempts != 0 || del.AlertID != testAlert().ID || del.Status != StatusFiring {
+		t.Errorf("while sending: %+v", del)
+	}
+
+	ch.results <- errors.New("connection refused")
+	del := waitForState(t, d, 1, DeliveryRetrying)
+	if del.Attempts != 1 || del.LastError != "connection refused" {
+		t.Errorf("after a failed attempt: %+v", del)
+	}
+
+	close(retry)
+	<-ch.sent
+	ch.results <- nil
+	del = waitForState(t, d, 1, DeliverySent)
+	if del.Attempts != 2 {
+		t.Errorf("after sending: %+v", del)
+	}
+}
+
+func TestDeliveryBacksOffExponentially(t *testing.T) {
+	waits := withSleep(t, nil)
+	ch := newFakeChannel("ops")
+	d := NewDispatcher(Options{MaxAttempts: 6, RetryInitial: 10 * time.Second, RetryMax: 25 * time.Second})
+	d.Add(ch, Route{}, "", "")
+	for i := 0; i < 4; i++ {
+		ch.results <- errors.New("HTTP 503: unavailable")
+	}
+	ch.results <- nil
+
+	d.Notify(StatusFiring, "", testAlert())
+	del := waitForState(t, d, 1, DeliverySent)
+	if del.Attempts != 5 {
+		t.Errorf("attempts = %d, want 5", del.Attempts)
+	}
+	want := []time.Duration{10 * time.Second, 20 * time.Second, 25 * time.Second, 25 * time.Second}
+	if got := waits(); !reflect.DeepEqual(got, want) {
+		t.Errorf("waits = %v, want %v", got, want)
+	}
+}
+
+func TestDeliveryGivesUp(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		attempts int
+		waits    int
+	}{
+		{"after max attempts", errors.New("HTTP 502: bad gateway"), 3, 2},
+		{"on a permanent error", Permanent(errors.New("HTTP 404: not found")), 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waits := withSleep(t, nil)
+			ch := newFakeChannel("ops")
+			d := NewDispatcher(Options{MaxAttempts: 3})
+			d.Add(ch, Route{}, "", "")
+			for i := 0; i < 3; i++ {
+				ch.results <- tt.err
+			}
+
+			d.Notify(StatusFiring, "", testAlert())
+			del := waitForState(t, d, 1, DeliveryFailed)
+			if del.Attempts != tt.attempts || del.LastError != tt.err.Error() {
+				t.Errorf("got %+v, want %d attempts", del, tt.attempts)
+			}
+			if got := len(waits()); got != tt.waits {
+				t.Errorf("waited %d times, want %d", got, tt.waits)
+			}
+		})
+	}
+}
+
+func TestTemplateErrorFailsDelivery(t *testing.T) {
+	ch := newFakeChannel("ops")
+	d := NewDispatcher(Options{})
+	if err := d.Add(ch, Route{}, "{{ .Alert.Missing }}", ""); err != nil {
+		t.Fatal(err)
+	}
+	d.Notify(StatusFiring, "", testAlert())
+	del := d.Deliveries()[0]
+	if del.State != DeliveryFailed || del.Attempts != 0 || del.LastError == "" {
+		t.Errorf("got %+v", del)
+	}
+	if len(ch.sent) != 0 {
+		t.Error("a message that failed to render was sent")
+	}
+
+	if err := d.Add(newFakeChannel("bad"), Route{}, "{{ .Status", ""); err == nil {
+		t.Error("unparsable template accepted")
+	}
+	if err := d.Add(newFakeChannel("ops"), Route{}, "", ""); err == nil {
+		t.Error("duplicate channel name accepted")
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	d := NewDispatcher(Options{})
+	channels := map[string]*fakeChannel{}
+	for name, r := range map[string]Route{
+		"all":      {},
+		"critical": {Severities: []string{models.SeverityCritical}},
+		"db":       {Tags: []string{"db"}},
+		"firing":   {SkipResolved: true},
+	} {
+		channels[name] = newFakeChannel(name)
+		channels[name].results <- nil
+		d.Add(channels[name], r, "", "")
+	}
+	got := func() []string {
+		var names []string
+		for _, name := range []string{"all", "critical", "db", "firing"} {
+			select {
+			case <-channels[name].sent:
+				names = append(names, name)
+				channels[name].results <- nil
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+		return names
+	}
+
+	a := testAlert()
+	d.Notify(StatusFiring, "", a)
+	if names := got(); !reflect.DeepEqual(names, []string{"all", "critical", "firing"}) {
+		t.Errorf("critical prod alert firing went to %v", names)
+	}
+	a.Severity, a.Tags = models.SeverityWarning, []string{"db", "prod"}
+	d.Notify(StatusResolved, "", a)
+	if names := got(); !reflect.DeepEqual(names, []string{"all", "db"}) {
+		t.Errorf("warning db alert resolved went to %v", names)
+	}
+
+	// Tests ignore the route
+	if err := d.Test("db", "http://dashboard/"); err != nil {
+		t.Fatal(err)
+	}
+	if names := got(); !reflect.DeepEqual(names, []string{"db"}) {
+		t.Errorf("test went to %v", names)
+	}
+	if err := d.Test("nope", ""); err == nil {
+		t.Error("test of an unknown channel accepted")
+	}
+}
+
+func TestDeliveryLogIsBounded(t *testing.T) {
+	ch := newFakeChannel("ops")
+	ch.sent = make(chan Message, maxDeliveries+10)
+	ch.results = make(chan error, maxDeliveries+10)
+	for i := 0; i < maxDeliveries+10; i++ {
+		ch.results <- nil
+	}
+	d := NewDispatcher(Options{})
+	d.Add(ch, Route{}, "", "")
+	for i := 0; i < maxDeliveries+10; i++ {
+		d.Notify(StatusFiring, "", testAlert())
+	}
+	log := d.Deliveries()
+	if len(log) != maxDeliveries {
+		t.Fatalf("log has %d deliveries, want %d", len(log), maxDeliveries)
+	}
+	if log[0].ID != maxDeliveries+10 || log[len(log)-1].ID != 11 {
+		t.Errorf("log holds deliveries %d to %d, want newest first from %d to 11", log[0].ID, log[len(log)-1].ID, maxDeliveries+10)
+	}
+	waitForState(t, d, maxDeliveries+10, DeliverySent)
+}
@@ -0,0 +1,168 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTP transport security modes
+const (
+	SMTPStartTLS = "starttls" // Upgrade a plain connection; fail if the server cannot
+	SMTPTLS      = "tls"      // Implicit TLS, usually port 465
+	SMTPNone     = "none"     // Plain text; authentication is refused except to localhost
+)
+
+// Email sends plain-text mail through an SMTP relay
+type Email struct {
+	ChannelName        string
+	Host               string
+	Port               int // Default 587, or 465 with implicit TLS
+	Username           string
+	Password           string
+	From               string
+	To                 []string
+	Security           string // starttls (default), tls or none
+	InsecureSkipVerify bool
+}
+
+func (e *Email) Name() string { return e.ChannelName }
+func (e *Email) Type() string { return "email" }
+func (e *Email) Target() string {
+	return strings.Join(e.To, ", ") + " via " + net.JoinHostPort(e.Host, strconv.Itoa(e.port()))
+}
+
+func (e *Email) security() string {
+	if e.Security == "" {
+		return SMTPStartTLS
+	}
+	return e.Security
+}
+
+func (e *Email) port() int {
+	switch {
+	case e.Port > 0:
+		return e.Port
+	case e.security() == SMTPTLS:
+		return 465
+	}
+	return 587
+}
+
+func (e *Email) Send(ctx context.Context, m Message) error {
+	if len(e.To) == 0 || e.From == "" {
+		return Permanent(errors.New("email channel needs from and to addresses"))
+	}
+	addr := net.JoinHostPort(e.Host, strconv.Itoa(e.port()))
+	tlsConfig := &tls.Config{ServerName: e.Host, InsecureSkipVerify: e.InsecureSkipVerify}
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if e.security() == SMTPTLS {
+		conn = tls.Client(conn, tlsConfig)
+	}
+	c, err := smtp.NewClient(conn, e.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "localhost"
+	}
+	if err := c.Hello(hostname); err != nil {
+		return smtpError(err)
+	}
+	switch e.security() {
+	case SMTPStartTLS:
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return Permanent(fmt.Errorf("%s does not offer STARTTLS", addr))
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	case SMTPTLS, SMTPNone:
+	default:
+		return Permanent(fmt.Errorf("unknown SMTP security %q", e.Security))
+	}
+	if e.Username != "" {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return Permanent(fmt.Errorf("%s does not offer authentication", addr))
+		}
+		// PlainAuth refuses to send credentials without TLS except to localhost
+		if err := c.Auth(smtp.PlainAuth("", e.Username, e.Password, e.Host)); err != nil {
+			return smtpError(err)
+		}
+	}
+
+	if err := c.Mail(e.From); err != nil {
+		return smtpError(err)
+	}
+	for _, to := range e.To {
+		if err := c.Rcpt(to); err != nil {
+			return smtpError(err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return smtpError(err)
+	}
+	if _, err := w.Write(e.message(m, hostname)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return smtpError(err)
+	}
+	return c.Quit()
+}
+
+// message builds the RFC 5322 message
+func (e *Email) message(m Message, hostname string) []byte {
+	id := make([]byte, 12)
+	rand.Read(id)
+
+	var b bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&b, "%s: %s\r\n", k, v) }
+	header("From", e.From)
+	header("To", strings.Join(e.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Title))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", "<"+hex.EncodeToString(id)+"@"+hostname+">")
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "8bit")
+	b.WriteString("\r\n")
+	body := strings.ReplaceAll(m.Body, "\r\n", "\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+// smtpError marks 5xx replies, which will not succeed on retry, as permanent
+func smtpError(err error) error {
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,290 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpMail is one message a smtpServer accepted
+type smtpMail struct {
+	from string
+	to   []string
+	data string
+	tls  bool
+	user string
+}
+
+// smtpServer is a minimal ESMTP server for one test. It offers STARTTLS
+// when tlsConfig is set and AUTH PLAIN when password is set.
+type smtpServer struct {
+	ln        net.Listener
+	tlsConfig *tls.Config
+	user      string
+	password  string
+	rcptReply string // Reply to RCPT TO, default "250 OK"
+
+	mu    sync.Mutex
+	mails []smtpMail
+}
+
+func startSMTPServer(t *testing.T, s *smtpServer) *smtpServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.ln = ln
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpServer) received() []smtpMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpMail(nil), s.mails...)
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	tp := textproto.NewConn(conn)
+	var mail smtpMail
+	tp.PrintfLine("220 test ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			ext := []string{"test"}
+			if s.tlsConfig != nil && !mail.tls {
+				ext = append(ext, "STARTTLS")
+			}
+			if s.password != "" {
+				ext = append(ext, "AUTH PLAIN")
+			}
+			ext = append(ext, "8BITMIME")
+			for i, e := range ext {
+				sep := "-"
+				if i == len(ext)-1 {
+					sep = " "
+				}
+				tp.PrintfLine("250%s%s", sep, e)
+			}
+		case "STARTTLS":
+			tp.PrintfLine("220 ready")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, tp, mail.tls = tlsConn, textproto.NewConn(tlsConn), true
+		case "AUTH":
+			_, resp, _ := strings.Cut(arg, " ")
+			creds, _ := base64.StdEncoding.DecodeString(resp)
+			if string(creds) != "\x00"+s.user+"\x00"+s.password {
+				tp.PrintfLine("535 5.7.8 authentication failed")
+				continue
+			}
+			mail.user = s.user
+			tp.PrintfLine("235 2.7.0 authenticated")
+		case "MAIL":
+			mail.from = mailbox(arg)
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			if s.rcptReply != "" {
+				tp.PrintfLine("%s", s.rcptReply)
+				continue
+			}
+			mail.to = append(mail.to, mailbox(arg))
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			lines, err := tp.ReadDotLines()
+			if err != nil {
+				return
+			}
+			mail.data = strings.Join(lines, "\n")
+			s.mu.Lock()
+			s.mails = append(s.mails, mail)
+			s.mu.Unlock()
+			tp.PrintfLine("250 OK queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("250 OK")
+		}
+	}
+}
+
+// mailbox extracts the address from e.g. "FROM:<a@example.com> BODY=8BITMIME"
+func mailbox(arg string) string {
+	_, rest, _ := strings.Cut(arg, "<")
+	addr, _, _ := strings.Cut(rest, ">")
+	return addr
+}
+
+// selfSignedTLS returns a server config with a certificate for 127.0.0.1
+func selfSignedTLS(t *testing.T) *tls.Config {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+}
+
+func sendEmail(e *Email) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return e.Send(ctx, Message{Status: StatusFiring, Alert: testAlert(), Title: "Disk ≥ 95% on Web 1", Body: "Disk 95% full\non Web 1"})
+}
+
+func TestEmailStartTLSAndAuth(t *testing.T) {
+	srv := startSMTPServer(t, &smtpServer{tlsConfig: selfSignedTLS(t), user: "alerts", password: "s3cret"})
+	e := &Email{
+		ChannelName: "mail", Host: "127.0.0.1", Port: srv.port(),
+		Username: "alerts", Password: "s3cret", InsecureSkipVerify: true,
+		From: "dashboard@example.com", To: []string{"ops@example.com", "oncall@example.com"},
+	}
+	if err := sendEmail(e); err != nil {
+		t.Fatal(err)
+	}
+	mails := srv.received()
+	if len(mails) != 1 {
+		t.Fatalf("received %d mails", len(mails))
+	}
+	m := mails[0]
+	if !m.tls || m.user != "alerts" || m.from != "dashboard@example.com" || strings.Join(m.to, ",") != "ops@example.com,oncall@example.com" {
+		t.Errorf("envelope: tls %v, user %q, from %q, to %v", m.tls, m.user, m.from, m.to)
+	}
+
+	msg, err := textproto.NewReader(bufio.NewReader(strings.NewReader(m.data + "\n"))).ReadMIMEHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Get("Subject") != "=?utf-8?q?Disk_=E2=89=A5_95%_on_Web_1?=" || msg.Get("To") != "ops@example.com, oncall@example.com" {
+		t.Errorf("headers = %v", msg)
+	}
+	if _, body, _ := strings.Cut(m.data, "\n\n"); body != "Disk 95% full\non Web 1" {
+		t.Errorf("body = %q", body)
+	}
+	if want := "ops@example.com, oncall@example.com via 127.0.0.1:" + strconv.Itoa(srv.port()); e.Target() != want {
+		t.Errorf("Target = %q, want %q", e.Target(), want)
+	}
+}
+
+func TestEmailStartTLSRequired(t *testing.T) {
+	srv := startSMTPServer(t, &smtpServer{})
+	e := &Email{Host: "127.0.0.1", Port: srv.port(), From: "dashboard@example.com", To: []string{"ops@example.com"}}
+	err := sendEmail(e)
+	if !IsPermanent(err) || !strings.Contains(err.Error(), "does not offer STARTTLS") {
+		t.Errorf("err = %v, want a permanent STARTTLS error", err)
+	}
+	if len(srv.received()) != 0 {
+		t.Error("mail sent without TLS")
+	}
+
+	// Unless plain text is configured
+	e.Security = SMTPNone
+	if err := sendEmail(e); err != nil {
+		t.Fatal(err)
+	}
+	if mails := srv.received(); len(mails) != 1 || mails[0].tls {
+		t.Errorf("received %+v", mails)
+	}
+}
+
+func TestEmailCertificateVerified(t *testing.T) {
+	srv := startSMTPServer(t, &smtpServer{tlsConfig: selfSignedTLS(t)})
+	e := &Email{Host: "127.0.0.1", Port: srv.port(), From: "dashboard@example.com", To: []string{"ops@example.com"}}
+	if err := sendEmail(e); err == nil {
+		t.Error("self-signed certificate accepted")
+	}
+}
+
+func TestEmailErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		srv       *smtpServer
+		email     Email
+		permanent bool
+	}{
+		{"wrong password",
+			&smtpServer{user: "alerts", password: "s3cret"},
+			Email{Username: "alerts", Password: "wrong"}, true},
+		{"no authentication offered",
+			&smtpServer{},
+			Email{Username: "alerts", Password: "s3cret"}, true},
+		{"recipient rejected",
+			&smtpServer{rcptReply: "550 5.1.1 no such user"},
+			Email{}, true},
+		{"recipient deferred",
+			&smtpServer{rcptReply: "451 4.3.0 try again later"},
+			Email{}, false},
+		{"unknown security",
+			&smtpServer{},
+			Email{Security: "ssl"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := startSMTPServer(t, tt.srv)
+			e := tt.email
+			e.Host, e.Port, e.From, e.To = "127.0.0.1", srv.port(), "dashboard@example.com", []string{"ops@example.com"}
+			if e.Security == "" {
+				e.Security = SMTPNone
+			}
+			err := sendEmail(&e)
+			if err == nil || IsPermanent(err) != tt.permanent {
+				t.Errorf("err = %v, permanent %v, want permanent %v", err, IsPermanent(err), tt.permanent)
+			}
+			if len(srv.received()) != 0 {
+				t.Error("mail was accepted")
+			}
+		})
+	}
+
+	if err := sendEmail(&Email{Host: "127.0.0.1", To: []string{"ops@example.com"}}); !IsPermanent(err) {
+		t.Errorf("without from: err = %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"server-dashboard/internal/models"
+)
+
+// Headers on signed webhook requests. The signature is the hex HMAC-SHA256
+// of "<timestamp>.<body>" keyed with the channel secret, so receivers can
+// reject altered and replayed requests.
+const (
+	SignatureHeader = "X-Dashboard-Signature" // "sha256=<hex>"
+	TimestampHeader = "X-Dashboard-Timestamp" // Unix seconds
+)
+
+// Sign returns the signature header value for a request body
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature produced by Sign in constant time
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// WebhookPayload is the JSON body of generic webhook notifications
+type WebhookPayload struct {
+	Status  string       `json:"status"` // firing, resolved or test
+	Title   string       `json:"title"`
+	Message string       `json:"message"`
+	URL     string       `json:"url,omitempty"`
+	Alert   models.Alert `json:"alert"`
+	SentAt  time.Time    `json:"sent_at"`
+}
+
+// Webhook posts a JSON WebhookPayload, signed when a secret is set
+type Webhook struct {
+	ChannelName string
+	URL         string
+	Secret      string
+	Headers     map[string]string
+	Client      *http.Client
+}
+
+func (w *Webhook) Name() string   { return w.ChannelName }
+func (w *Webhook) Type() string   { return "webhook" }
+func (w *Webhook) Target() string { return redactURL(w.URL) }
+
+func (w *Webhook) Send(ctx context.Context, m Message) error {
+	body, err := json.Marshal(WebhookPayload{
+		Status:  m.Status,
+		Title:   m.Title,
+		Message: m.Body,
+		URL:     m.URL,
+		Alert:   m.Alert,
+		SentAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return Permanent(err)
+	}
+	headers := map[string]string{}
+	for k, v := range w.Headers {
+		headers[k] = v
+	}
+	if w.Secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		headers[TimestampHeader] = ts
+		headers[SignatureHeader] = Sign(w.Secret, ts, body)
+	}
+	return postJSON(ctx, w.Client, w.URL, body, headers)
+}
+
+// Slack posts to a Slack or Mattermost incoming webhook. Both accept the
+// same payload; channel and username override the webhook's defaults
+// where the server allows it.
+type Slack struct {
+	ChannelName string
+	Kind        string // "slack" or "mattermost"
+	URL         string
+	Channel     string
+	Username    string
+	Client      *http.Client
+}
+
+func (s *Slack) Name() string   { return s.ChannelName }
+func (s *Slack) Type() string   { return s.Kind }
+func (s *Slack) Target() string { return redactURL(s.URL) }
+
+func (s *Slack) Send(ctx context.Context, m Message) error {
+	payload := map[string]string{"text": "*" + m.Title + "*\n" + m.Body}
+	if s.Channel != "" {
+		payload["channel"] = s.Channel
+	}
+	if s.Username != "" {
+		payload["username"] = s.Username
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Permanent(err)
+	}
+	return postJSON(ctx, s.Client, s.URL, body, nil)
+}
+
+// postJSON posts a body and fails on any status but 2xx. Client errors
+// other than timeouts and rate limiting are permanent.
+func postJSON(ctx context.Context, client *http.Client, target string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "server-dashboard")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	err = fmt.Errorf("HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return Permanent(err)
+	}
+	return err
+}
+
+// redactURL keeps the scheme and host of a URL; webhook paths often embed
+// a secret token
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "(invalid URL)"
+	}
+	return u.Scheme + "://" + u.Host + "/..."
+}
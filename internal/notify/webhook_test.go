@@ -0,0 +1,171 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// captured is the last request a test server received
+type captured struct {
+	header http.Header
+	body   []byte
+}
+
+func captureServer(t *testing.T, status int) (*httptest.Server, *captured) {
+	t.Helper()
+	got := &captured{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.header = r.Header.Clone()
+		got.body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+		io.WriteString(w, "  detail from the server\n")
+	}))
+	t.Cleanup(srv.Close)
+	return srv, got
+}
+
+func testMessage() Message {
+	return Message{Status: StatusFiring, Alert: testAlert(), URL: "http://dashboard/server/web1", Title: "Disk full", Body: "Disk 95% full\non Web 1"}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"status":"firing"}`)
+	want := "sha256=cc94fd497f49f3955fe70059f98188b67df57f1dc73dfc83497495f23a538b2c"
+	if got := Sign("topsecret", "1700000000", body); got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+	if !Verify("topsecret", "1700000000", body, want) {
+		t.Error("Verify rejected a valid signature")
+	}
+	for name, ok := range map[string]bool{
+		"wrong secret":    Verify("other", "1700000000", body, want),
+		"other timestamp": Verify("topsecret", "1700000001", body, want),
+		"altered body":    Verify("topsecret", "1700000000", []byte(`{"status":"resolved"}`), want),
+		"no signature":    Verify("topsecret", "1700000000", body, ""),
+	} {
+		if ok {
+			t.Errorf("Verify accepted a request with %s", name)
+		}
+	}
+}
+
+func TestWebhookSend(t *testing.T) {
+	srv, got := captureServer(t, http.StatusOK)
+	w := &Webhook{ChannelName: "hook", URL: srv.URL + "/hooks/T0KEN", Secret: "topsecret", Headers: map[string]string{"X-Team": "ops"}}
+	if err := w.Send(context.Background(), testMessage()); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := got.header.Get(TimestampHeader)
+	if !Verify("topsecret", ts, got.body, got.header.Get(SignatureHeader)) {
+		t.Errorf("signature %q does not match the body", got.header.Get(SignatureHeader))
+	}
+	if sec, err := strconv.ParseInt(ts, 10, 64); err != nil || time.Since(time.Unix(sec, 0)) > time.Minute {
+		t.Errorf("timestamp = %q", ts)
+	}
+	if got.header.Get("Content-Type") != "application/json" || got.header.Get("X-Team") != "ops" {
+		t.Errorf("headers = %v", got.header)
+	}
+
+	var p WebhookPayload
+	if err := json.Unmarshal(got.body, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != StatusFiring || p.Title != "Disk full" || p.Message != "Disk 95% full\non Web 1" || p.URL != "http://dashboard/server/web1" {
+		t.Errorf("payload = %+v", p)
+	}
+	if p.Alert.ID != testAlert().ID || !p.Alert.ActiveSince.Equal(testAlert().ActiveSince) || p.SentAt.IsZero() {
+		t.Errorf("payload alert = %+v, sent at %v", p.Alert, p.SentAt)
+	}
+	if target := w.Target(); target != srv.URL+"/..." || strings.Contains(target, "T0KEN") {
+		t.Errorf("Target = %q leaks the path", target)
+	}
+
+	// Without a secret requests are not signed
+	w.Secret = ""
+	if err := w.Send(context.Background(), testMessage()); err != nil {
+		t.Fatal(err)
+	}
+	if got.header.Get(SignatureHeader) != "" || got.header.Get(TimestampHeader) != "" {
+		t.Errorf("unsigned webhook sent signature headers: %v", got.header)
+	}
+}
+
+func TestSlackSend(t *testing.T) {
+	for _, kind := range []string{"slack", "mattermost"} {
+		t.Run(kind, func(t *testing.T) {
+			srv, got := captureServer(t, http.StatusOK)
+			s := &Slack{ChannelName: "chat", Kind: kind, URL: srv.URL, Channel: "#alerts", Username: "dashboard"}
+			if s.Type() != kind {
+				t.Errorf("Type = %q", s.Type())
+			}
+			if err := s.Send(context.Background(), testMessage()); err != nil {
+				t.Fatal(err)
+			}
+			var p map[string]string
+			if err := json.Unmarshal(got.body, &p); err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]string{"text": "*Disk full*\nDisk 95% full\non Web 1", "channel": "#alerts", "username": "dashboard"}
+			if len(p) != len(want) || p["text"] != want["text"] || p["channel"] != want["channel"] || p["username"] != want["username"] {
+				t.Errorf("payload = %v, want %v", p, want)
+			}
+
+			// The webhook's own channel and username apply when unset
+			s.Channel, s.Username = "", ""
+			if err := s.Send(context.Background(), testMessage()); err != nil {
+				t.Fatal(err)
+			}
+			p = nil
+			json.Unmarshal(got.body, &p)
+			if _, ok := p["channel"]; ok || len(p) != 1 {
+				t.Errorf("payload = %v, want only text", p)
+			}
+		})
+	}
+}
+
+func TestPostJSONStatus(t *testing.T) {
+	tests := []struct {
+		status    int
+		ok        bool
+		permanent bool
+	}{
+		{http.StatusOK, true, false},
+		{http.StatusNoContent, true, false},
+		{http.StatusBadRequest, false, true},
+		{http.StatusUnauthorized, false, true},
+		{http.StatusNotFound, false, true},
+		{http.StatusRequestTimeout, false, false},
+		{http.StatusTooManyRequests, false, false},
+		{http.StatusInternalServerError, false, false},
+		{http.StatusBadGateway, false, false},
+	}
+	for _, tt := range tests {
+		srv, _ := captureServer(t, tt.status)
+		err := (&Webhook{URL: srv.URL}).Send(context.Background(), testMessage())
+		if (err == nil) != tt.ok || IsPermanent(err) != tt.permanent {
+			t.Errorf("HTTP %d: err = %v, permanent %v", tt.status, err, IsPermanent(err))
+		}
+		if err != nil && err.Error() != "HTTP "+strconv.Itoa(tt.status)+": detail from the server" {
+			t.Errorf("HTTP %d: error = %q", tt.status, err)
+		}
+	}
+
+	// Unreachable servers are retried; malformed URLs are not
+	srv, _ := captureServer(t, http.StatusOK)
+	srv.Close()
+	if err := (&Webhook{URL: srv.URL}).Send(context.Background(), testMessage()); err == nil || IsPermanent(err) {
+		t.Errorf("closed server: err = %v", err)
+	}
+	if err := (&Webhook{URL: "http://[::1"}).Send(context.Background(), testMessage()); !IsPermanent(err) {
+		t.Errorf("malformed URL: err = %v", err)
+	}
+}
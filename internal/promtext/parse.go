@@ -0,0 +1,235 @@
+// Package promtext reads the Prometheus text exposition format (version
+// 0.0.4), as served by node_exporter and most other exporters.
+package promtext
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Sample is one line of an exposition: a metric name, its labels and value
+type Sample struct {
+	Name      string
+	Labels    map[string]string
+	Value     float64
+	Timestamp int64 // Milliseconds since the epoch; 0 when the line has none
+}
+
+// Label returns the value of a label, or "" when it is not set
+func (s Sample) Label(name string) string {
+	return s.Labels[name]
+}
+
+// ParseError reports a malformed line
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// maxLineLength bounds a single exposition line
+const maxLineLength = 1 << 20
+
+// Parse reads every sample from r. HELP and TYPE comments are validated but
+// not returned; the metric name alone identifies a sample.
+func Parse(r io.Reader) ([]Sample, error) {
+	var samples []Sample
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineLength)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			if err := parseComment(line); err != nil {
+				return nil, &ParseError{Line: lineNo, Msg: err.Error()}
+			}
+			continue
+		}
+		s, err := parseSample(line)
+		if err != nil {
+			return nil, &ParseError{Line: lineNo, Msg: err.Error()}
+		}
+		samples = append(samples, s)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// metricTypes are the values allowed in a TYPE comment
+var metricTypes = map[string]bool{
+	"counter": true, "gauge": true, "histogram": true, "summary": true, "untyped": true,
+}
+
+// parseComment checks HELP and TYPE lines; any other comment is ignored
+func parseComment(line string) error {
+	fields := strings.Fields(strings.TrimPrefix(line, "#"))
+	if len(fields) == 0 || (fields[0] != "HELP" && fields[0] != "TYPE") {
+		return nil
+	}
+	if len(fields) < 2 || !validMetricName(fields[1]) {
+		return fmt.Errorf("%s comment without a valid metric name", fields[0])
+	}
+	if fields[0] == "TYPE" && (len(fields) != 3 || !metricTypes[fields[2]]) {
+		return fmt.Errorf("invalid TYPE for %s", fields[1])
+	}
+	return nil
+}
+
+// parseSample parses `name{label="value",...} value [timestamp]`
+func parseSample(line string) (Sample, error) {
+	s := Sample{}
+	i := 0
+	for i < len(line) && isNameChar(line[i], i == 0) {
+		i++
+	}
+	s.Name = line[:i]
+	if s.Name == "" {
+		return s, fmt.Errorf("invalid metric name in %q", line)
+	}
+
+	rest := line[i:]
+	if strings.HasPrefix(rest, "{") {
+		labels, n, err := parseLabels(rest)
+		if err != nil {
+			return s, fmt.Errorf("%s: %v", s.Name, err)
+		}
+		s.Labels = labels
+		rest = rest[n:]
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return s, fmt.Errorf("%s: expected a value and optional timestamp", s.Name)
+	}
+	v, err := parseValue(fields[0])
+	if err != nil {
+		return s, fmt.Errorf("%s: invalid value %q", s.Name, fields[0])
+	}
+	s.Value = v
+	if len(fields) == 2 {
+		ts, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return s, fmt.Errorf("%s: invalid timestamp %q", s.Name, fields[1])
+		}
+		s.Timestamp = ts
+	}
+	return s, nil
+}
+
+// parseLabels parses a brace-enclosed label set and returns the number of
+// bytes consumed, closing brace included
+func parseLabels(text string) (map[string]string, int, error) {
+	labels := make(map[string]string)
+	i := 1 // skip "{"
+	for {
+		for i < len(text) && (text[i] == ' ' || text[i] == '\t') {
+			i++
+		}
+		if i >= len(text) {
+			return nil, 0, fmt.Errorf("unterminated label set")
+		}
+		if text[i] == '}' {
+			return labels, i + 1, nil
+		}
+
+		start := i
+		for i < len(text) && isLabelChar(text[i], i == start) {
+			i++
+		}
+		name := text[start:i]
+		if name == "" {
+			return nil, 0, fmt.Errorf("invalid label name at %q", text[start:])
+		}
+		for i < len(text) && text[i] == ' ' {
+			i++
+		}
+		if i+1 >= len(text) || text[i] != '=' || text[i+1] != '"' {
+			return nil, 0, fmt.Errorf("label %s: expected =\"value\"", name)
+		}
+		i += 2
+
+		var value strings.Builder
+		closed := false
+		for i < len(text) {
+			c := text[i]
+			i++
+			if c == '"' {
+				closed = true
+				break
+			}
+			if c != '\\' {
+				value.WriteByte(c)
+				continue
+			}
+			if i >= len(text) {
+				break
+			}
+			switch text[i] {
+			case 'n':
+				value.WriteByte('\n')
+			case '\\', '"':
+				value.WriteByte(text[i])
+			default:
+				return nil, 0, fmt.Errorf("label %s: invalid escape \\%c", name, text[i])
+			}
+			i++
+		}
+		if !closed {
+			return nil, 0, fmt.Errorf("label %s: unterminated value", name)
+		}
+		if _, dup := labels[name]; dup {
+			return nil, 0, fmt.Errorf("duplicate label %s", name)
+		}
+		labels[name] = value.String()
+
+		for i < len(text) && text[i] == ' ' {
+			i++
+		}
+		if i < len(text) && text[i] == ',' {
+			i++
+		}
+	}
+}
+
+// parseValue accepts Go floats plus the exposition spellings of NaN and ±Inf
+func parseValue(s string) (float64, error) {
+	switch s {
+	case "NaN":
+		return math.NaN(), nil
+	case "+Inf":
+		return math.Inf(1), nil
+	case "-Inf":
+		return math.Inf(-1), nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func isNameChar(c byte, first bool) bool {
+	return c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
+
+func isLabelChar(c byte, first bool) bool {
+	return c != ':' && isNameChar(c, first)
+}
+
+func validMetricName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if !isNameChar(name[i], i == 0) {
+			return false
+		}
+	}
+	return name != ""
+}
@@ -0,0 +1,140 @@
+package promtext
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	input := strings.Join([]string{
+		"# HELP node_load1 1m load average.",
+		"# TYPE node_load1 gauge",
+		"node_load1 0.52",
+		"",
+		"# A free-form comment is ignored",
+		`node_filesystem_size_bytes{device="/dev/sda1",fstype="ext4",mountpoint="/"} 5.36870912e+10`,
+		`node_uname_info{release="6.1.0-13-amd64", version="#1 SMP \"PREEMPT\" C:\\build\nline two",} 1`,
+		"node_empty_label{a=\"\", b=\"x\"}\t-3",
+		`node_no_labels{} 1700000000 1700000000123`,
+		"node_scrape_error NaN",
+		`node_hwmon_max{chip="a"} +Inf`,
+		`node_hwmon_min{chip="a"} -Inf`,
+		"go_memstats_alloc_bytes_total 1.5E6\r",
+		"  node_padded:rule_total   42  ",
+	}, "\n")
+	samples, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Sample{
+		{Name: "node_load1", Value: 0.52},
+		{Name: "node_filesystem_size_bytes", Labels: map[string]string{"device": "/dev/sda1", "fstype": "ext4", "mountpoint": "/"}, Value: 5.36870912e10},
+		{Name: "node_uname_info", Labels: map[string]string{"release": "6.1.0-13-amd64", "version": "#1 SMP \"PREEMPT\" C:\\build\nline two"}, Value: 1},
+		{Name: "node_empty_label", Labels: map[string]string{"a": "", "b": "x"}, Value: -3},
+		{Name: "node_no_labels", Labels: map[string]string{}, Value: 1700000000, Timestamp: 1700000000123},
+		{Name: "node_scrape_error", Value: math.NaN()},
+		{Name: "node_hwmon_max", Labels: map[string]string{"chip": "a"}, Value: math.Inf(1)},
+		{Name: "node_hwmon_min", Labels: map[string]string{"chip": "a"}, Value: math.Inf(-1)},
+		{Name: "go_memstats_alloc_bytes_total", Value: 1.5e6},
+		{Name: "node_padded:rule_total", Value: 42},
+	}
+	if len(samples) != len(want) {
+		t.Fatalf("got %d samples, want %d: %+v", len(samples), len(want), samples)
+	}
+	for i, got := range samples {
+		w := want[i]
+		sameValue := got.Value == w.Value || math.IsNaN(got.Value) && math.IsNaN(w.Value)
+		if got.Name != w.Name || !reflect.DeepEqual(got.Labels, w.Labels) || !sameValue || got.Timestamp != w.Timestamp {
+			t.Errorf("sample %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if got := samples[1].Label("mountpoint"); got != "/" {
+		t.Errorf(`Label("mountpoint") = %q`, got)
+	}
+	if got := samples[0].Label("missing"); got != "" {
+		t.Errorf(`Label("missing") = %q`, got)
+	}
+}
+
+// Parse reports every line; deciding which of duplicate series counts is
+// left to the caller
+func TestParseDuplicateSeries(t *testing.T) {
+	samples, err := Parse(strings.NewReader("x{a=\"1\"} 1\nx{a=\"1\"} 2\nx{a=\"2\"} 3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []float64
+	for _, s := range samples {
+		values = append(values, s.Value)
+	}
+	if !reflect.DeepEqual(values, []float64{1, 2, 3}) {
+		t.Errorf("values = %v, want [1 2 3]", values)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		line  int
+	}{
+		{"ok 1\n1x 2", 2},
+		{"x{a=\"b} 1", 1},
+		{"x{a=b} 1", 1},
+		{`x{a="b\t"} 1`, 1},
+		{`x{a="1",a="2"} 1`, 1},
+		{"x{1a=\"b\"} 1", 1},
+		{"x{a=\"b\"", 1},
+		{"x", 1},
+		{"x 1 2 3", 1},
+		{"x abc", 1},
+		{"x 1 1.5", 1},
+		{"ok 1\n\n# TYPE x foo", 3},
+		{"# TYPE x", 1},
+		{"# HELP 1x help", 1},
+	}
+	for _, tt := range tests {
+		_, err := Parse(strings.NewReader(tt.input))
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("%q: err = %v, want a ParseError", tt.input, err)
+			continue
+		}
+		if pe.Line != tt.line {
+			t.Errorf("%q: error on line %d, want %d: %v", tt.input, pe.Line, tt.line, err)
+		}
+	}
+
+	long := "x{a=\"" + strings.Repeat("a", maxLineLength) + "\"} 1"
+	if _, err := Parse(strings.NewReader(long)); err == nil {
+		t.Error("line over the length limit accepted")
+	}
+}
+
+func TestWriteThenParse(t *testing.T) {
+	var b bytes.Buffer
+	w := NewWriter(&b)
+	w.Family("dashboard_device_up", "gauge", "Whether a device answers.\nSecond \\ line")
+	w.Sample("dashboard_device_up", []Label{{"name", `Core "A" \ B` + "\nrack 2"}, {"kind", "switch"}}, 1)
+	w.Sample("dashboard_device_up", nil, math.NaN())
+	w.Sample("dashboard_device_up", []Label{{"kind", "vm"}}, math.Inf(-1))
+	w.Flush()
+
+	samples, err := Parse(&b)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, b.String())
+	}
+	if len(samples) != 3 {
+		t.Fatalf("got %d samples", len(samples))
+	}
+	if got := samples[0].Label("name"); got != `Core "A" \ B`+"\nrack 2" || samples[0].Label("kind") != "switch" || samples[0].Value != 1 {
+		t.Errorf("sample 0 = %+v", samples[0])
+	}
+	if !math.IsNaN(samples[1].Value) || !math.IsInf(samples[2].Value, -1) {
+		t.Errorf("values = %v, %v", samples[1].Value, samples[2].Value)
+	}
+}
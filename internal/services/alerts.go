@@ -0,0 +1,526 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"server-dashboard/internal/alerting"
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/models"
+)
+
+// KindSynthetic identifies synthetic checks as alert targets
+const KindSynthetic = "synthetic"
+
+// diskThresholdRule is the built-in rule for monitoring.disk_threshold_percent.
+// A configured rule with the same name replaces it.
+const diskThresholdRule = "disk_threshold"
+
+// maxResolvedAlerts bounds the resolved alerts kept for display
+const maxResolvedAlerts = 500
+
+// alerts evaluates the configured alert rules. It is nil when alerting is
+// disabled.
+var alerts *alertManager
+
+// deviceAlertMetrics are the metrics device rules can compare. Host metrics
+// have no value while a device's metrics are stale or were never collected,
+// which leaves its alerts as they are rather than resolving them.
+var deviceAlertMetrics = map[string]func(d exportedDevice) (alerting.Value, bool){
+	"status": func(d exportedDevice) (alerting.Value, bool) {
+		return alerting.Text(d.status), d.status != ""
+	},
+	"up": func(d exportedDevice) (alerting.Value, bool) {
+		return alerting.Number(boolValue(d.ping == models.StatusOnline)), !d.checkedAt.IsZero()
+	},
+	"flapping": func(d exportedDevice) (alerting.Value, bool) {
+		return alerting.Number(boolValue(d.state.Flapping)), !d.checkedAt.IsZero()
+	},
+	"rtt_ms": func(d exportedDevice) (alerting.Value, bool) {
+		return alerting.Number(d.rtt), d.rtt > 0
+	},
+	"packet_loss_percent": func(d exportedDevice) (alerting.Value, bool) {
+		return alerting.Number(d.loss), !d.checkedAt.IsZero()
+	},
+	"metrics_stale": func(d exportedDevice) (alerting.Value, bool) {
+		return alerting.Number(boolValue(d.stale)), d.stale || !d.collectedAt.IsZero()
+	},
+	"disk_percent":   hostAlertMetric(func(d exportedDevice) (float64, bool) { return d.metrics.DiskPercent, true }),
+	"disk_used_gb":   hostAlertMetric(func(d exportedDevice) (float64, bool) { return d.metrics.DiskUsage, true }),
+	"memory_used_mb": hostAlertMetric(func(d exportedDevice) (float64, bool) { return d.metrics.MemoryUsed, true }),
+	"memory_percent": hostAlertMetric(func(d exportedDevice) (float64, bool) {
+		return d.memoryPercent, d.metrics.MemoryTotal > 0
+	}),
+	"inode_percent": hostAlertMetric(func(d exportedDevice) (float64, bool) {
+		return d.metrics.InodePercent, d.metrics.InodeTotal > 0
+	}),
+	"load1":  hostAlertMetric(func(d exportedDevice) (float64, bool) { return loadValue(d.metrics.LoadAverage, 0) }),
+	"load5":  hostAlertMetric(func(d exportedDevice) (float64, bool) { return loadValue(d.metrics.LoadAverage, 1) }),
+	"load15": hostAlertMetric(func(d exportedDevice) (float64, bool) { return loadValue(d.metrics.LoadAverage, 2) }),
+	"cpu_percent": hostAlertMetric(func(d exportedDevice) (float64, bool) {
+		return cpuValue(d, func(u *models.CPUUsage) float64 { return u.UsedPercent })
+	}),
+	"cpu_iowait_percent": hostAlertMetric(func(d exportedDevice) (float64, bool) {
+		return cpuValue(d, func(u *models.CPUUsage) float64 { return u.IOWaitPercent })
+	}),
+	"cpu_steal_percent": hostAlertMetric(func(d exportedDevice) (float64, bool) {
+		return cpuValue(d, func(u *models.CPUUsage) float64 { return u.StealPercent })
+	}),
+	// Pressure stall averages over the last minute, "some" tasks stalled
+	"cpu_pressure_percent":    hostAlertMetric(func(d exportedDevice) (float64, bool) { return pressureValue(d, "cpu", "some") }),
+	"memory_pressure_percent": hostAlertMetric(func(d exportedDevice) (float64, bool) { return pressureValue(d, "memory", "some") }),
+	"io_pressure_percent":     hostAlertMetric(func(d exportedDevice) (float64, bool) { return pressureValue(d, "io", "some") }),
+	"processes": hostAlertMetric(func(d exportedDevice) (float64, bool) {
+		return float64(d.metrics.Processes), d.metrics.Processes > 0
+	}),
+	"failed_services": hostAlertMetric(func(d exportedDevice) (float64, bool) {
+		return float64(d.metrics.FailedServices), true
+	}),
+	"services_down": hostAlertMetric(func(d exportedDevice) (float64, bool) {
+		return float64(servicesDown(d.metrics.Services)), d.metrics.Services != nil
+	}),
+	"full_partitions": hostAlertMetric(func(d exportedDevice) (float64, bool) {
+		return float64(len(d.metrics.FullPartitions)), true
+	}),
+	"readonly_filesystems": hostAlertMetric(func(d exportedDevice) (float64, bool) {
+		n := 0
+		for _, fs := range d.metrics.Filesystems {
+			if fs.ReadOnly {
+				n++
+			}
+		}
+		return float64(n), d.metrics.Filesystems != nil
+	}),
+	// Of the busiest interface with a known speed, over the last sample interval
+	"interface_utilization_percent": hostAlertMetric(func(d exportedDevice) (float64, bool) {
+		busiest, ok := 0.0, false
+		for _, iface := range d.metrics.Interfaces {
+			if iface.Rates != nil && iface.SpeedMbps > 0 {
+				busiest, ok = math.Max(busiest, iface.Rates.UtilizationPercent), true
+			}
+		}
+		return busiest, ok
+	}),
+	"interfaces_down": hostAlertMetric(func(d exportedDevice) (float64, bool) {
+		n := 0
+		for _, iface := range d.metrics.Interfaces {
+			if iface.OperState == "down" || iface.OperState == "lowerlayerdown" {
+				n++
+			}
+		}
+		return float64(n), d.metrics.Interfaces != nil
+	}),
+}
+
+// syntheticAlertMetrics are the metrics synthetic.* rules can compare
+var syntheticAlertMetrics = map[string]func(r models.SyntheticCheckResult) (alerting.Value, bool){
+	"synthetic.status": func(r models.SyntheticCheckResult) (alerting.Value, bool) {
+		return alerting.Text(r.Status), !r.LastRun.IsZero()
+	},
+	"synthetic.up": func(r models.SyntheticCheckResult) (alerting.Value, bool) {
+		return alerting.Number(boolValue(r.Status == "ok")), !r.LastRun.IsZero()
+	},
+	"synthetic.flapping": func(r models.SyntheticCheckResult) (alerting.Value, bool) {
+		return alerting.Number(boolValue(r.Flapping)), !r.LastRun.IsZero()
+	},
+	"synthetic.latency_ms": func(r models.SyntheticCheckResult) (alerting.Value, bool) {
+		return alerting.Number(float64(r.LatencyMs)), r.Status == "ok"
+	},
+}
+
+func hostAlertMetric(value func(d exportedDevice) (float64, bool)) func(d exportedDevice) (alerting.Value, bool) {
+	return func(d exportedDevice) (alerting.Value, bool) {
+		if d.collectedAt.IsZero() || d.stale {
+			return alerting.Value{}, false
+		}
+		v, ok := value(d)
+		return alerting.Number(v), ok
+	}
+}
+
+// alertRule is a rule that loaded successfully
+type alertRule struct {
+	models.AlertRule
+	summary string
+	expr    alerting.Expr
+}
+
+// alertTarget is a device or synthetic check a rule is evaluated against
+type alertTarget struct {
+	kind, id, name string
+	tags           []string
+	sampledAt      time.Time // When the current value was measured
+}
+
+// alertState is a pending or firing alert with what is needed to decide
+// when it fires
+type alertState struct {
+	alert      models.Alert
+	lastSample time.Time
+	runs       int  // Consecutive samples matching the rule
+	notified   bool // A firing notification was sent
+}
+
+type alertManager struct {
+	rules     []alertRule
+	allRules  []models.AlertRule // Every configured rule, including ones that failed to load
+	retention time.Duration
+
+	mu       sync.RWMutex
+	active   map[string]*alertState // Pending and firing alerts by ID
+	resolved []models.Alert         // Oldest first
+}
+
+// initAlerts loads the alert rules and starts evaluating them
+func initAlerts(cfg *config.Config) {
+	if !cfg.Alerting.Enabled {
+		return
+	}
+	retention := time.Duration(cfg.Alerting.ResolvedRetentionHours) * time.Hour
+	if retention <= 0 {
+		retention = 24 * time.Hour
+	}
+	m := newAlertManager(alertRuleConfigs(cfg), retention)
+	for _, r := range m.allRules {
+		if r.Error != "" {
+			log.Printf("Alert rule %q disabled: %s", r.Name, r.Error)
+		}
+	}
+	alerts = m
+
+	interval := time.Duration(cfg.Alerting.EvaluationIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			m.evaluate(now)
+		}
+	}()
+	log.Printf("Alerting enabled with %d rules, evaluated every %s", len(m.rules), interval)
+}
+
+// alertRuleConfigs returns the configured rules plus the built-in rule for
+// disk_threshold_percent
+func alertRuleConfigs(cfg *config.Config) []config.AlertRuleConfig {
+	rules := cfg.Alerting.Rules
+	threshold := cfg.Monitoring.DiskThresholdPercent
+	if threshold <= 0 {
+		return rules
+	}
+	for _, r := range rules {
+		if r.Name == diskThresholdRule {
+			return rules
+		}
+	}
+	return append([]config.AlertRuleConfig{{
+		Name:     diskThresholdRule,
+		Expr:     fmt.Sprintf("disk_percent > %d", threshold),
+		Severity: models.SeverityWarning,
+		Summary:  fmt.Sprintf("Root partition is more than %d%% full", threshold),
+	}}, rules...)
+}
+
+func newAlertManager(configs []config.AlertRuleConfig, retention time.Duration) *alertManager {
+	m := &alertManager{retention: retention, active: make(map[string]*alertState)}
+	names := make(map[string]bool)
+	for _, c := range configs {
+		rule, err := loadAlertRule(c)
+		if err == nil && names[c.Name] {
+			err = fmt.Errorf("duplicate rule name")
+		}
+		names[c.Name] = true
+		if err != nil {
+			rule.Error = err.Error()
+		} else {
+			m.rules = append(m.rules, rule)
+		}
+		m.allRules = append(m.allRules, rule.AlertRule)
+	}
+	return m
+}
+
+// loadAlertRule parses and validates one configured rule
+func loadAlertRule(c config.AlertRuleConfig) (alertRule, error) {
+	rule := alertRule{
+		AlertRule: models.AlertRule{
+			Name: c.Name, Expr: c.Expr, Severity: strings.ToLower(c.Severity),
+			Devices: c.Devices, Tags: c.Tags, Types: c.Types,
+		},
+		summary: c.Summary,
+	}
+	if rule.Severity == "" {
+		rule.Severity = models.SeverityWarning
+	}
+	if c.Name == "" {
+		return rule, fmt.Errorf("missing name")
+	}
+	switch rule.Severity {
+	case models.SeverityInfo, models.SeverityWarning, models.SeverityCritical:
+	default:
+		return rule, fmt.Errorf("unknown severity %q", c.Severity)
+	}
+
+	expr, err := alerting.ParseExpr(c.Expr)
+	if err != nil {
+		return rule, err
+	}
+	rule.expr = expr
+	rule.Expr = expr.String()
+
+	_, synthetic := syntheticAlertMetrics[expr.Metric]
+	if _, ok := deviceAlertMetrics[expr.Metric]; !ok && !synthetic {
+		return rule, fmt.Errorf("unknown metric %q", expr.Metric)
+	}
+	for _, t := range c.Types {
+		if synthetic {
+			return rule, fmt.Errorf("types do not apply to synthetic checks")
+		}
+		if t != KindServer && t != KindVM && t != KindSwitch {
+			return rule, fmt.Errorf("unknown type %q", t)
+		}
+	}
+	return rule, nil
+}
+
+// selects reports whether the rule's selectors match a target
+func (r alertRule) selects(t alertTarget) bool {
+	if len(r.Devices) > 0 && !containsString(r.Devices, t.id) {
+		return false
+	}
+	if len(r.Types) > 0 && !containsString(r.Types, t.kind) {
+		return false
+	}
+	if len(r.Tags) == 0 {
+		return true
+	}
+	for _, tag := range t.tags {
+		if containsString(r.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// describe summarizes why the alert matched
+func (r alertRule) describe(v alerting.Value) string {
+	if r.summary != "" {
+		return r.summary
+	}
+	return fmt.Sprintf("%s is %s (%s %s)", r.expr.Metric, v, r.expr.Op, r.expr.Value)
+}
+
+// ready reports whether a pending alert has held long enough to fire
+func (r alertRule) ready(st *alertState, now time.Time) bool {
+	if r.expr.Runs > 0 {
+		return st.runs >= r.expr.Runs
+	}
+	return now.Sub(st.alert.ActiveSince) >= r.expr.For
+}
+
+// evaluate checks every rule against the latest device and synthetic
+// check results
+func (m *alertManager) evaluate(now time.Time) {
+	devices := exportedDevices(inventory.Snapshot())
+	checks := GetSyntheticResults()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, rule := range m.rules {
+		if value, ok := syntheticAlertMetrics[rule.expr.Metric]; ok {
+			for _, c := range checks {
+				t := alertTarget{kind: KindSynthetic, id: c.ID, name: c.Name, tags: c.Tags, sampledAt: c.LastRun}
+				if rule.selects(t) {
+					v, ok := value(c)
+					m.observe(rule, t, v, ok, now, seen)
+				}
+			}
+			continue
+		}
+		value := deviceAlertMetrics[rule.expr.Metric]
+		for _, d := range devices {
+			t := alertTarget{kind: d.kind, id: d.id, name: d.name, tags: d.tags, sampledAt: d.checkedAt}
+			if rule.selects(t) {
+				v, ok := value(d)
+				m.observe(rule, t, v, ok, now, seen)
+			}
+		}
+	}
+
+	// Alerts whose device or check is gone no longer apply
+	for id, st := range m.active {
+		if !seen[id] {
+			m.resolve(id, st, now)
+		}
+	}
+
+	cutoff := now.Add(-m.retention)
+	keep := 0
+	for i, a := range m.resolved {
+		if a.ResolvedAt.After(cutoff) {
+			keep = len(m.resolved) - i
+			break
+		}
+	}
+	if keep > maxResolvedAlerts {
+		keep = maxResolvedAlerts
+	}
+	m.resolved = append([]models.Alert(nil), m.resolved[len(m.resolved)-keep:]...)
+}
+
+// observe advances one rule's alert for one target. Callers hold m.mu.
+func (m *alertManager) observe(rule alertRule, t alertTarget, v alerting.Value, ok bool, now time.Time, seen map[string]bool) {
+	id := rule.Name + "/" + t.kind + "/" + t.id
+	st := m.active[id]
+	if !ok {
+		// No current value: keep the alert until there is evidence either way
+		if st != nil {
+			seen[id] = true
+		}
+		return
+	}
+	if !rule.expr.Match(v) {
+		if st != nil {
+			st.alert.Value = v.String()
+			st.alert.Summary = rule.describe(v)
+			st.alert.LastEval = now
+			m.resolve(id, st, now)
+		}
+		return
+	}
+
+	seen[id] = true
+	if st == nil {
+		st = &alertState{alert: models.Alert{
+			ID:          id,
+			Rule:        rule.Name,
+			Expr:        rule.Expr,
+			Severity:    rule.Severity,
+			State:       models.AlertPending,
+			TargetKind:  t.kind,
+			TargetID:    t.id,
+			ActiveSince: now,
+		}}
+		m.active[id] = st
+	}
+	if t.sampledAt.After(st.lastSample) {
+		st.runs++
+		st.lastSample = t.sampledAt
+	}
+
+	a := &st.alert
+	a.TargetName = t.name
+	a.Tags = t.tags
+	a.Value = v.String()
+	a.Summary = rule.describe(v)
+	a.LastEval = now
+	a.Suppressed = alertSuppression(*a, now)
+	if a.State == models.AlertPending && rule.ready(st, now) {
+		a.State = models.AlertFiring
+		a.FiredAt = now
+		log.Printf("Alert %s firing for %s %s: %s", rule.Name, t.kind, t.name, a.Summary)
+		if a.Suppressed != "" {
+			log.Printf("Notifications for alert %s held back (%s)", id, a.Suppressed)
+		}
+	}
+	// Alerts firing through a maintenance window or silence are announced
+	// once it ends, if they are still firing
+	if a.State == models.AlertFiring && !st.notified && a.Suppressed == "" {
+		st.notified = true
+		notifyAlert(*a)
+	}
+}
+
+// resolve ends an alert. Pending alerts are dropped; firing ones are kept
+// as resolved for the retention period. Callers hold m.mu.
+func (m *alertManager) resolve(id string, st *alertState, now time.Time) {
+	delete(m.active, id)
+	if st.alert.State != models.AlertFiring {
+		return
+	}
+	a := st.alert
+	a.State = models.AlertResolved
+	a.ResolvedAt = now
+	a.Suppressed = alertSuppression(a, now)
+	m.resolved = append(m.resolved, a)
+	log.Printf("Alert %s resolved for %s %s", a.Rule, a.TargetKind, a.TargetName)
+	// Only alerts that were announced are announced as resolved
+	if st.notified && a.Suppressed == "" {
+		notifyAlert(a)
+	}
+}
+
+// AlertingEnabled reports whether alert rules are being evaluated
+func AlertingEnabled() bool {
+	return alerts != nil
+}
+
+// GetAlerts returns firing and pending alerts, most severe first, followed
+// by recently resolved alerts, newest first
+func GetAlerts() []models.Alert {
+	if alerts == nil {
+		return nil
+	}
+	alerts.mu.RLock()
+	defer alerts.mu.RUnlock()
+
+	out := make([]models.Alert, 0, len(alerts.active)+len(alerts.resolved))
+	for _, st := range alerts.active {
+		out = append(out, st.alert)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.State != b.State {
+			return a.State == models.AlertFiring
+		}
+		if ra, rb := severityRank(a.Severity), severityRank(b.Severity); ra != rb {
+			return ra > rb
+		}
+		if !a.ActiveSince.Equal(b.ActiveSince) {
+			return a.ActiveSince.Before(b.ActiveSince)
+		}
+		return a.ID < b.ID
+	})
+	for i := len(alerts.resolved) - 1; i >= 0; i-- {
+		out = append(out, alerts.resolved[i])
+	}
+	return out
+}
+
+// GetAlertRules returns every configured rule, with the reason for any that
+// could not be loaded
+func GetAlertRules() []models.AlertRule {
+	if alerts == nil {
+		return nil
+	}
+	return append([]models.AlertRule(nil), alerts.allRules...)
+}
+
+// severityRank orders severities from least to most severe
+func severityRank(severity string) int {
+	switch severity {
+	case models.SeverityCritical:
+		return 2
+	case models.SeverityWarning:
+		return 1
+	}
+	return 0
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"server-dashboard/internal/alerting"
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/models"
+)
+
+// testAlertManager loads rules that must all be valid
+func testAlertManager(t *testing.T, configs ...config.AlertRuleConfig) *alertManager {
+	t.Helper()
+	m := newAlertManager(configs, time.Hour)
+	for _, r := range m.allRules {
+		if r.Error != "" {
+			t.Fatalf("rule %s: %s", r.Name, r.Error)
+		}
+	}
+	return m
+}
+
+// stateOf returns the state of the alert with the given ID: pending,
+// firing, resolved, or empty when there is none
+func (m *alertManager) stateOf(id string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if st := m.active[id]; st != nil {
+		return st.alert.State
+	}
+	for _, a := range m.resolved {
+		if a.ID == id {
+			return a.State
+		}
+	}
+	return ""
+}
+
+// alertObservation is one evaluation of a rule against the test server.
+// Times are offsets from the start of the test.
+type alertObservation struct {
+	at      time.Duration // The evaluation's clock
+	sampled time.Duration // When the value was measured
+	value   float64
+	missing bool // The target has no current value
+	want    string
+}
+
+func TestAlertObserve(t *testing.T) {
+	tests := []struct {
+		name  string
+		expr  string
+		steps []alertObservation
+	}{
+		{"fires at once without a duration", "disk_percent > 90", []alertObservation{
+			{at: 0, value: 95, want: models.AlertFiring},
+		}},
+		{"fires after for", "disk_percent > 90 for 1m", []alertObservation{
+			{at: 0, value: 95, want: models.AlertPending},
+			{at: 30 * time.Second, sampled: 30 * time.Second, value: 96, want: models.AlertPending},
+			{at: 59 * time.Second, sampled: 59 * time.Second, value: 97, want: models.AlertPending},
+			{at: time.Minute, sampled: time.Minute, value: 91, want: models.AlertFiring},
+		}},
+		{"pending alert dropped when the value recovers", "disk_percent > 90 for 1m", []alertObservation{
+			{at: 0, value: 95, want: models.AlertPending},
+			{at: 30 * time.Second, sampled: 30 * time.Second, value: 90, want: ""},
+			{at: time.Minute, sampled: time.Minute, value: 95, want: models.AlertPending},
+		}},
+		{"runs count only new samples", "disk_percent > 90 for 3 runs", []alertObservation{
+			{at: 0, value: 95, want: models.AlertPending},
+			{at: 15 * time.Second, value: 95, want: models.AlertPending},
+			{at: 30 * time.Second, value: 95, want: models.AlertPending},
+			{at: 45 * time.Second, sampled: 40 * time.Second, value: 95, want: models.AlertPending},
+			{at: time.Minute, sampled: 20 * time.Second, value: 95, want: models.AlertPending},
+			{at: 75 * time.Second, sampled: 70 * time.Second, value: 95, want: models.AlertFiring},
+		}},
+		{"missing value keeps the alert", "disk_percent > 90 for 1m", []alertObservation{
+			{at: 0, value: 95, want: models.AlertPending},
+			{at: 30 * time.Second, missing: true, want: models.AlertPending},
+			{at: time.Minute, sampled: time.Minute, value: 95, want: models.AlertFiring},
+			{at: 90 * time.Second, missing: true, want: models.AlertFiring},
+		}},
+		{"firing alert resolves", "disk_percent > 90", []alertObservation{
+			{at: 0, value: 95, want: models.AlertFiring},
+			{at: 30 * time.Second, sampled: 30 * time.Second, value: 80, want: models.AlertResolved},
+			{at: time.Minute, sampled: time.Minute, value: 95, want: models.AlertFiring},
+		}},
+	}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testAlertManager(t, config.AlertRuleConfig{Name: "disk", Expr: tt.expr})
+			target := alertTarget{kind: KindServer, id: "web1", name: "Web 1"}
+			for i, s := range tt.steps {
+				target.sampledAt = start.Add(s.sampled)
+				m.observe(m.rules[0], target, alerting.Number(s.value), !s.missing, start.Add(s.at), map[string]bool{})
+				if got := m.stateOf("disk/server/web1"); got != s.want {
+					t.Fatalf("step %d at %v: state %q, want %q", i, s.at, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAlertTimestamps(t *testing.T) {
+	m := testAlertManager(t, config.AlertRuleConfig{Name: "disk", Expr: "disk_percent > 90 for 1m", Summary: "Disk full"})
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	target := alertTarget{kind: KindServer, id: "web1", name: "Web 1", tags: []string{"prod"}, sampledAt: start}
+	for _, s := range []struct {
+		at    time.Duration
+		value float64
+	}{{0, 95}, {time.Minute, 97}, {2 * time.Minute, 50}} {
+		m.observe(m.rules[0], target, alerting.Number(s.value), true, start.Add(s.at), map[string]bool{})
+	}
+
+	if len(m.active) != 0 || len(m.resolved) != 1 {
+		t.Fatalf("%d active, %d resolved alerts", len(m.active), len(m.resolved))
+	}
+	a := m.resolved[0]
+	if !a.ActiveSince.Equal(start) || !a.FiredAt.Equal(start.Add(time.Minute)) || !a.ResolvedAt.Equal(start.Add(2*time.Minute)) {
+		t.Errorf("active since %v, fired at %v, resolved at %v", a.ActiveSince, a.FiredAt, a.ResolvedAt)
+	}
+	if a.Value != "50" || a.Summary != "Disk full" || a.TargetName != "Web 1" || a.Severity != models.SeverityWarning {
+		t.Errorf("resolved alert = %+v", a)
+	}
+}
+
+func TestDiskThresholdRule(t *testing.T) {
+	monitoring := config.Config{}
+	monitoring.Monitoring.DiskThresholdPercent = 90
+	override := monitoring
+	override.Alerting.Rules = []config.AlertRuleConfig{
+		{Name: "load", Expr: "load1 > 8"},
+		{Name: diskThresholdRule, Expr: "disk_percent > 80 for 2 runs", Severity: "critical"},
+	}
+	tests := []struct {
+		name     string
+		cfg      config.Config
+		rules    int
+		expr     string
+		severity string
+		at85     string // State after two samples at 85%
+	}{
+		{"built in", monitoring, 1, "disk_percent > 90", models.SeverityWarning, ""},
+		{"overridden", override, 2, "disk_percent > 80 for 2 runs", models.SeverityCritical, models.AlertFiring},
+		{"disabled", config.Config{}, 0, "", "", ""},
+	}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testAlertManager(t, alertRuleConfigs(&tt.cfg)...)
+			if len(m.rules) != tt.rules {
+				t.Fatalf("%d rules: %+v", len(m.rules), m.allRules)
+			}
+			var disk *alertRule
+			for i := range m.rules {
+				if m.rules[i].Name == diskThresholdRule {
+					disk = &m.rules[i]
+				}
+			}
+			if tt.expr == "" {
+				if disk != nil {
+					t.Errorf("disk_threshold loaded without a threshold: %+v", disk.AlertRule)
+				}
+				return
+			}
+			if disk == nil || disk.Expr != tt.expr || disk.Severity != tt.severity {
+				t.Fatalf("disk_threshold = %+v", disk)
+			}
+
+			target := alertTarget{kind: KindServer, id: "web1"}
+			for i := 0; i < 2; i++ {
+				target.sampledAt = start.Add(time.Duration(i) * time.Minute)
+				m.observe(*disk, target, alerting.Number(85), true, target.sampledAt, map[string]bool{})
+			}
+			if got := m.stateOf(diskThresholdRule + "/server/web1"); got != tt.at85 {
+				t.Errorf("state at 85%% = %q, want %q", got, tt.at85)
+			}
+		})
+	}
+}
+
+// evaluate resolves alerts of devices that left the inventory and drops
+// resolved alerts after the retention period
+func TestAlertEvaluate(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	srv := models.Server{ID: "web1", Name: "Web 1", PingStatus: models.StatusOnline, Status: models.StatusOnline,
+		LastChecked: start, MetricsCollectedAt: start, DiskPercent: 95}
+	withInventory(t, []models.Server{srv}, nil, nil)
+	m := testAlertManager(t, config.AlertRuleConfig{Name: "disk", Expr: "disk_percent > 90"})
+
+	m.evaluate(start)
+	if got := m.stateOf("disk/server/web1"); got != models.AlertFiring {
+		t.Fatalf("state %q, want firing", got)
+	}
+	inventory.Load(nil, nil, nil)
+	m.evaluate(start.Add(time.Minute))
+	if got := m.stateOf("disk/server/web1"); got != models.AlertResolved {
+		t.Fatalf("state of a removed device's alert %q, want resolved", got)
+	}
+	m.evaluate(start.Add(time.Minute + m.retention))
+	if got := m.stateOf("disk/server/web1"); got != "" {
+		t.Errorf("state %q after the retention period, want none", got)
+	}
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"server-dashboard/internal/models"
+)
+
+// Collector gathers host metrics for a device. Implementations register
+// themselves by name and are selected per device with the collector setting.
+type Collector interface {
+	// Name is the value used to select the collector in config
+	Name() string
+	// Collect returns one metrics sample. Problems with individual metrics
+	// are reported as MetricErrors; an error means no usable sample.
+	Collect(target CollectTarget) (*models.HostMetrics, []MetricError, error)
+}
+
+// CollectTarget identifies the device being collected
+type CollectTarget struct {
+	Kind    string // KindServer, KindVM or KindSwitch
+	ID      string
+	Name    string
+	Address string
+	Port    int
+}
+
+// fullPartitionPercent is the usage above which a mount counts as full
+// unless a filesystems threshold matches it: monitoring.disk_threshold_percent,
+// default 90%
+func fullPartitionPercent() float64 {
+	if Config != nil && Config.Monitoring.DiskThresholdPercent > 0 {
+		return float64(Config.Monitoring.DiskThresholdPercent)
+	}
+	return models.FullPartitionPercent
+}
+
+// Built-in collector names
+const (
+	CollectorMock = "mock"
+	CollectorSSH  = "ssh"
+)
+
+var (
+	collectorsMu sync.RWMutex
+	collectors   = make(map[string]Collector)
+)
+
+func init() {
+	RegisterCollector(mockCollector{})
+	RegisterCollector(sshCollector{})
+}
+
+// RegisterCollector makes a collector available by name, replacing any
+// collector previously registered under the same name
+func RegisterCollector(c Collector) {
+	collectorsMu.Lock()
+	defer collectorsMu.Unlock()
+	collectors[c.Name()] = c
+}
+
+// GetCollector returns the collector registered under name
+func GetCollector(name string) (Collector, bool) {
+	collectorsMu.RLock()
+	defer collectorsMu.RUnlock()
+	c, ok := collectors[name]
+	return c, ok
+}
+
+// CollectorNames lists the registered collectors
+func CollectorNames() []string {
+	collectorsMu.RLock()
+	defer collectorsMu.RUnlock()
+	names := make([]string, 0, len(collectors))
+	for name := range collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// collectorNameFor returns the collector configured for a device. Devices
+// with an agent_token default to the agent, devices with a node_exporter
+// block to scraping it and switches with an snmp block to SNMP. Without
+// explicit configuration, devices use mock data in mock mode or when SSH is
+// disabled, and SSH otherwise.
+func collectorNameFor(kind, id string) string {
+	name := ""
+	switch kind {
+	case KindServer:
+		for _, c := range Config.Servers {
+			if c.ID == id {
+				name = c.Collector
+				if name == "" && c.AgentToken != "" {
+					name = CollectorAgent
+				} else if name == "" && c.NodeExporter != nil {
+					name = CollectorNodeExporter
+				}
+			}
+		}
+	case KindVM:
+		for _, c := range Config.VirtualMachines {
+			if c.ID == id {
+				name = c.Collector
+				if name == "" && c.AgentToken != "" {
+					name = CollectorAgent
+				} else if name == "" && c.NodeExporter != nil {
+					name = CollectorNodeExporter
+				}
+			}
+		}
+	case KindSwitch:
+		for _, c := range Config.Switches {
+			if c.ID == id {
+				name = c.Collector
+				if name == "" && c.AgentToken != "" {
+					name = CollectorAgent
+				} else if name == "" && c.SNMP != nil {
+					name = CollectorSNMP
+				}
+			}
+		}
+	}
+	if name == "" {
+		name = Config.Monitoring.Collector
+	}
+	if name == "" {
+		if Config.Monitoring.UseMockData || !Config.SSH.Enabled {
+			return CollectorMock
+		}
+		return CollectorSSH
+	}
+	return name
+}
+
+// collectDevice runs the device's collector and returns its name alongside
+// the result, with the device's filesystem config applied
+func collectDevice(target CollectTarget) (string, *models.HostMetrics, []MetricError, error) {
+	name := collectorNameFor(target.Kind, target.ID)
+	c, ok := GetCollector(name)
+	if !ok {
+		return name, nil, nil, fmt.Errorf("unknown collector %q", name)
+	}
+	metrics, fieldErrs, err := c.Collect(target)
+	if err != nil {
+		log.Printf("Metric collection (%s) failed for %s: %v", name, target.Name, err)
+		return name, nil, nil, err
+	}
+	for _, fe := range fieldErrs {
+		log.Printf("Metric %s failed on %s (%s)", fe.Error(), target.Name, target.Address)
+	}
+	selectFilesystems(target.Kind, target.ID, metrics)
+	selectServices(target.Kind, target.ID, metrics)
+	return name, metrics, fieldErrs, nil
+}
+
+// collectionFailureStatus is the device status after a failed collection.
+// Devices keep their last real sample, marked stale; devices that never had
+// one are unknown. Nothing is ever filled in with made-up values.
+func collectionFailureStatus(err error, lastCollected time.Time) string {
+	if status, ok := hostKeyStatus(err); ok {
+		return status
+	}
+	if lastCollected.IsZero() {
+		return models.StatusUnknown
+	}
+	return models.StatusStale
+}
+
+// errNoSSHClient is returned when a device selects SSH collection but has
+// no usable SSH credentials
+var errNoSSHClient = errors.New("SSH is not enabled or no usable credentials are configured for this device")
+
+// sshCollector runs the embedded collector script over SSH
+type sshCollector struct{}
+
+func (sshCollector) Name() string { return CollectorSSH }
+
+func (sshCollector) Collect(target CollectTarget) (*models.HostMetrics, []MetricError, error) {
+	client := sshClientFor(target.Kind, target.ID)
+	if client == nil || !Config.SSH.Enabled {
+		return nil, nil, errNoSSHClient
+	}
+	metrics, fieldErrs, err := client.collectHostMetrics(target.Address, target.Port)
+	if err != nil {
+		recordSSHError(target.Address)
+	}
+	return metrics, fieldErrs, err
+}
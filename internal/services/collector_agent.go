@@ -0,0 +1,179 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"server-dashboard/internal/models"
+)
+
+// CollectorAgent serves metrics pushed by dashboard-agent
+const CollectorAgent = "agent"
+
+func init() {
+	RegisterCollector(agentCollector{})
+}
+
+// agentPush is the latest report received from a device's agent
+type agentPush struct {
+	report     models.AgentReport
+	receivedAt time.Time
+	remoteAddr string
+}
+
+var (
+	agentPushesMu sync.RWMutex
+	agentPushes   = make(map[string]agentPush) // keyed by kind + "/" + device ID
+)
+
+// AuthenticateAgent returns the kind of the device an agent reports for
+// when the token matches that device's agent_token
+func AuthenticateAgent(deviceID, token string) (string, bool) {
+	if deviceID == "" || token == "" {
+		return "", false
+	}
+	match := func(kind, id, want string) (string, bool) {
+		if id != deviceID || want == "" {
+			return "", false
+		}
+		// Compare digests so the comparison time does not depend on length
+		a, b := sha256.Sum256([]byte(token)), sha256.Sum256([]byte(want))
+		return kind, subtle.ConstantTimeCompare(a[:], b[:]) == 1
+	}
+	for _, c := range Config.Servers {
+		if kind, ok := match(KindServer, c.ID, c.AgentToken); ok {
+			return kind, true
+		}
+	}
+	for _, c := range Config.VirtualMachines {
+		if kind, ok := match(KindVM, c.ID, c.AgentToken); ok {
+			return kind, true
+		}
+	}
+	for _, c := range Config.Switches {
+		if kind, ok := match(KindSwitch, c.ID, c.AgentToken); ok {
+			return kind, true
+		}
+	}
+	return "", false
+}
+
+// RecordAgentReport stores a report pushed by a device's agent. It is picked
+// up by the device's next check.
+func RecordAgentReport(kind, id string, report models.AgentReport, remoteAddr string) {
+	agentPushesMu.Lock()
+	defer agentPushesMu.Unlock()
+	agentPushes[kind+"/"+id] = agentPush{report: report, receivedAt: time.Now(), remoteAddr: remoteAddr}
+}
+
+// lastAgentPush returns the latest report for a device
+func lastAgentPush(kind, id string) (agentPush, bool) {
+	agentPushesMu.RLock()
+	defer agentPushesMu.RUnlock()
+	p, ok := agentPushes[kind+"/"+id]
+	return p, ok
+}
+
+// agentStaleAfter is how long a pushed report stays current
+func agentStaleAfter() time.Duration {
+	if Config.Ingest.StaleAfterSeconds > 0 {
+		return time.Duration(Config.Ingest.StaleAfterSeconds) * time.Second
+	}
+	if Config.MonitoringInterval > 0 {
+		return 3 * time.Duration(Config.MonitoringInterval) * time.Second
+	}
+	return 90 * time.Second
+}
+
+// agentProbe reports whether a device's agent has pushed recently. A fresh
+// push proves the host is up even when the dashboard cannot reach it.
+func agentProbe(kind, id string) (models.ProbeResult, bool) {
+	if collectorNameFor(kind, id) != CollectorAgent {
+		return models.ProbeResult{}, false
+	}
+	result := models.ProbeResult{Type: models.ProbeAgent, Target: "push"}
+	p, ok := lastAgentPush(kind, id)
+	switch {
+	case !ok:
+		result.Error = "no report received yet"
+	case time.Since(p.receivedAt) > agentStaleAfter():
+		result.Error = fmt.Sprintf("last report %s ago", time.Since(p.receivedAt).Round(time.Second))
+	default:
+		result.Success = true
+		result.Target = p.remoteAddr
+	}
+	return result, true
+}
+
+// withAgentProbe adds the agent's push status to a device's reachability
+func withAgentProbe(kind, id string, r models.Reachability) models.Reachability {
+	probe, ok := agentProbe(kind, id)
+	if !ok {
+		return r
+	}
+	return models.NewReachability(append(r.Probes, probe))
+}
+
+// agentCollector returns the device's latest pushed report, failing once
+// pushes have stopped so the device's metrics are marked stale
+type agentCollector struct{}
+
+func (agentCollector) Name() string { return CollectorAgent }
+
+func (agentCollector) Collect(target CollectTarget) (*models.HostMetrics, []MetricError, error) {
+	p, ok := lastAgentPush(target.Kind, target.ID)
+	if !ok {
+		return nil, nil, fmt.Errorf("no report received from dashboard-agent")
+	}
+	if age := time.Since(p.receivedAt); age > agentStaleAfter() {
+		return nil, nil, fmt.Errorf("no report from dashboard-agent since %s", p.receivedAt.Format("2006-01-02 15:04:05"))
+	}
+	m := p.report.Metrics
+	m.FullPartitions = filterFullPartitions(m.FullPartitions)
+	// Interface rates run between the agent's samples, not between checks,
+	// which see the same report until the agent pushes again
+	m.SampledAt = p.report.CollectedAt
+	if m.SampledAt.IsZero() {
+		m.SampledAt = p.receivedAt
+	}
+	var errs []MetricError
+	for _, e := range p.report.MetricErrors {
+		errs = append(errs, parseMetricError(e))
+	}
+	return &m, errs, nil
+}
+
+// filterFullPartitions drops reported partitions that are under the
+// dashboard's threshold. It only matters for agents that predate the mount
+// list: they list partitions above the default 90%, so a lower
+// disk_threshold_percent and per-mount thresholds do not apply to them.
+func filterFullPartitions(reported []string) []string {
+	full := []string{}
+	for _, p := range reported {
+		i := strings.LastIndexByte(p, ' ')
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(p[i+1:], "%"), 64)
+		if err != nil || pct > fullPartitionPercent() {
+			full = append(full, p)
+		}
+	}
+	return full
+}
+
+// parseMetricError turns "section.field: problem", as rendered by
+// MetricError, back into its parts
+func parseMetricError(s string) MetricError {
+	name, problem, ok := strings.Cut(s, ": ")
+	if !ok {
+		return MetricError{Section: "agent", Field: "report", Problem: s}
+	}
+	section, field, ok := strings.Cut(name, ".")
+	if !ok {
+		return MetricError{Section: "agent", Field: name, Problem: problem}
+	}
+	return MetricError{Section: section, Field: field, Problem: problem}
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/models"
+)
+
+// pushedAgo stores a report for server id as if it arrived age ago
+func pushedAgo(t *testing.T, id string, age time.Duration) {
+	t.Helper()
+	RecordAgentReport(KindServer, id, models.AgentReport{DeviceID: id, Metrics: models.HostMetrics{KernelVersion: "6.1.0"}}, "192.0.2.7:40000")
+	agentPushesMu.Lock()
+	p := agentPushes[KindServer+"/"+id]
+	p.receivedAt = time.Now().Add(-age)
+	agentPushes[KindServer+"/"+id] = p
+	agentPushesMu.Unlock()
+	t.Cleanup(func() {
+		agentPushesMu.Lock()
+		delete(agentPushes, KindServer+"/"+id)
+		agentPushesMu.Unlock()
+	})
+}
+
+func TestAgentReportsGoStale(t *testing.T) {
+	tests := []struct {
+		name   string
+		ingest config.IngestConfig
+		age    time.Duration
+		stale  bool
+	}{
+		{"fresh", config.IngestConfig{}, 10 * time.Second, false},
+		{"three intervals", config.IngestConfig{}, 91 * time.Second, true},
+		{"stale_after_seconds", config.IngestConfig{StaleAfterSeconds: 300}, 200 * time.Second, false},
+		{"past stale_after_seconds", config.IngestConfig{StaleAfterSeconds: 300}, 301 * time.Second, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, &config.Config{
+				MonitoringInterval: 30,
+				Ingest:             tt.ingest,
+				Servers:            []config.ServerConfig{{ID: "web1", AgentToken: "token"}},
+			})
+			pushedAgo(t, "web1", tt.age)
+
+			m, _, err := agentCollector{}.Collect(CollectTarget{Kind: KindServer, ID: "web1"})
+			if stale := err != nil; stale != tt.stale || !tt.stale && m.KernelVersion != "6.1.0" {
+				t.Errorf("metrics %+v, err %v", m, err)
+			}
+			probe, ok := agentProbe(KindServer, "web1")
+			if !ok || probe.Success == tt.stale {
+				t.Errorf("probe %+v", probe)
+			}
+			if tt.stale && !strings.HasPrefix(probe.Error, "last report") {
+				t.Errorf("probe error %q", probe.Error)
+			}
+		})
+	}
+}
+
+func TestAgentWithoutReports(t *testing.T) {
+	withConfig(t, &config.Config{Servers: []config.ServerConfig{{ID: "web1", AgentToken: "token"}, {ID: "db1"}}})
+	if _, _, err := (agentCollector{}).Collect(CollectTarget{Kind: KindServer, ID: "web1"}); err == nil {
+		t.Error("collected without a report")
+	}
+	if probe, ok := agentProbe(KindServer, "web1"); !ok || probe.Success || probe.Error != "no report received yet" {
+		t.Errorf("probe %+v", probe)
+	}
+	// Devices polled over SSH have no agent probe
+	if probe, ok := agentProbe(KindServer, "db1"); ok {
+		t.Errorf("probe for an SSH device: %+v", probe)
+	}
+}
@@ -0,0 +1,351 @@
+package services
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"time"
+
+	"server-dashboard/internal/models"
+)
+
+// mockCollector generates plausible random metrics for development and
+// demos. It is only used when selected; it is never a fallback for a
+// collector that failed.
+type mockCollector struct{}
+
+func (mockCollector) Name() string { return CollectorMock }
+
+func (mockCollector) Collect(target CollectTarget) (*models.HostMetrics, []MetricError, error) {
+	switch target.Kind {
+	case KindVM:
+		return mockVMMetrics(target.ID), nil, nil
+	case KindSwitch:
+		return mockSwitchMetrics(target.ID), nil, nil
+	default:
+		return mockServerMetrics(target.ID), nil, nil
+	}
+}
+
+// mockServerMetrics generates mock metrics for a server
+func mockServerMetrics(id string) *models.HostMetrics {
+	m := &models.HostMetrics{}
+	m.Uptime = GenerateUptime()
+	m.Processes = rand.Intn(200) + 50 // 50-250 processes
+	m.DiskTotal = 1000.0
+	m.DiskUsage = generateDiskUsage(m.DiskTotal, 30, 70) // 30-70% usage
+	m.DiskPercent = (m.DiskUsage / m.DiskTotal) * 100
+
+	// Memory metrics
+	m.MemoryTotal = float64(rand.Intn(48000) + 16000)       // 16-64 GB in MB
+	m.MemoryUsed = generateDiskUsage(m.MemoryTotal, 40, 80) // 40-80% usage
+
+	// Load average - generate realistic values
+	load1 := float64(rand.Intn(400)+50) / 100.0  // 0.5-4.5
+	load5 := float64(rand.Intn(350)+60) / 100.0  // 0.6-4.1
+	load15 := float64(rand.Intn(300)+70) / 100.0 // 0.7-3.7
+	m.LoadAverage = fmt.Sprintf("%.2f %.2f %.2f", load1, load5, load15)
+
+	// Failed services - usually 0, occasionally 1-2
+	failed := 0
+	if rand.Float64() < 0.2 { // 20% chance
+		failed = rand.Intn(2) + 1
+	}
+	m.Units = mockUnits(m, failed, "ssh.service", "cron.service", "chrony.service", "nginx.service", "postgresql.service", "docker.service")
+
+	// Inode usage
+	m.InodeTotal = int64(rand.Intn(5000000) + 1000000)                               // 1M-6M inodes
+	m.InodeUsed = int64(float64(m.InodeTotal) * (float64(rand.Intn(40)+20) / 100.0)) // 20-60%
+	m.InodePercent = (float64(m.InodeUsed) / float64(m.InodeTotal)) * 100
+
+	// Separate boot, var and home filesystems
+	m.Filesystems = mockFilesystems(m, "/dev/sda2",
+		mockMount{"/boot", "ext4", "/dev/sda1", 1},
+		mockMount{"/var", "xfs", "/dev/sdb1", 200},
+		mockMount{"/home", "xfs", "/dev/sdb2", 500})
+
+	// CPU - a busy host, mostly in user space
+	mockCPU(m, id, 8*(int(mockSeed(id)%4)+1), 30, 8, 3, 0)
+
+	// Bonded uplinks plus a management port
+	m.Interfaces = mockInterfaces(m, id,
+		mockLink{"eno1", 10000, 15},
+		mockLink{"eno2", 10000, 15},
+		mockLink{"eno3", 1000, 2},
+		mockLink{"eno4", 0, 0})
+
+	// Kernel version - common versions
+	kernels := []string{"5.15.0-91-generic", "6.1.0-17-amd64", "5.10.0-27-arm64", "6.5.0-14-generic"}
+	m.KernelVersion = kernels[rand.Intn(len(kernels))]
+	return m
+}
+
+// mockVMMetrics generates mock metrics for a VM
+func mockVMMetrics(id string) *models.HostMetrics {
+	m := &models.HostMetrics{}
+	m.Uptime = GenerateUptime()
+	m.Processes = rand.Intn(150) + 30 // 30-180 processes
+	m.DiskTotal = 500.0
+	m.DiskUsage = generateDiskUsage(m.DiskTotal, 20, 60) // 20-60% usage
+	m.DiskPercent = (m.DiskUsage / m.DiskTotal) * 100
+
+	// Memory metrics
+	m.MemoryTotal = float64(rand.Intn(24000) + 4000)        // 4-28 GB in MB
+	m.MemoryUsed = generateDiskUsage(m.MemoryTotal, 35, 75) // 35-75% usage
+
+	// Load average - generate realistic values
+	load1 := float64(rand.Intn(250)+30) / 100.0  // 0.3-2.8
+	load5 := float64(rand.Intn(220)+40) / 100.0  // 0.4-2.6
+	load15 := float64(rand.Intn(200)+50) / 100.0 // 0.5-2.5
+	m.LoadAverage = fmt.Sprintf("%.2f %.2f %.2f", load1, load5, load15)
+
+	// Failed services - usually 0, occasionally 1
+	failed := 0
+	if rand.Float64() < 0.15 { // 15% chance
+		failed = 1
+	}
+	m.Units = mockUnits(m, failed, "ssh.service", "cron.service", "nginx.service", "redis-server.service")
+
+	// Inode usage
+	m.InodeTotal = int64(rand.Intn(3000000) + 500000)                                // 500K-3.5M inodes
+	m.InodeUsed = int64(float64(m.InodeTotal) * (float64(rand.Intn(35)+15) / 100.0)) // 15-50%
+	m.InodePercent = (float64(m.InodeUsed) / float64(m.InodeTotal)) * 100
+
+	// Cloud image layout with an EFI partition and a data disk
+	m.Filesystems = mockFilesystems(m, "/dev/vda1",
+		mockMount{"/boot/efi", "vfat", "/dev/vda15", 0.1},
+		mockMount{"/data", "ext4", "/dev/vdb", 250})
+
+	// CPU - VMs lose some time to other guests on their host
+	mockCPU(m, id, 2<<(mockSeed(id)%3), 25, 6, 2, 4)
+
+	// A virtio NIC, which reports no link speed
+	m.Interfaces = mockInterfaces(m, id,
+		mockLink{"ens3", -1, 20})
+
+	// Kernel version - common versions
+	kernels := []string{"5.15.0-91-generic", "6.1.0-17-amd64", "5.10.0-27-arm64", "6.5.0-14-generic"}
+	m.KernelVersion = kernels[rand.Intn(len(kernels))]
+	return m
+}
+
+// mockSwitchMetrics generates mock metrics for a switch, including OpenFlow state
+func mockSwitchMetrics(id string) *models.HostMetrics {
+	m := &models.HostMetrics{}
+	m.Uptime = GenerateUptime()
+	m.Processes = rand.Intn(80) + 20                     // 20-100 processes (lighter than servers)
+	m.DiskTotal = 50.0                                   // Small Debian typically 8-64GB
+	m.DiskUsage = generateDiskUsage(m.DiskTotal, 15, 40) // 15-40% usage
+	m.DiskPercent = (m.DiskUsage / m.DiskTotal) * 100
+
+	// Memory metrics - switches have less RAM
+	m.MemoryTotal = float64(rand.Intn(3000) + 1000)         // 1-4 GB in MB
+	m.MemoryUsed = generateDiskUsage(m.MemoryTotal, 30, 60) // 30-60% usage
+
+	// Load average - generate realistic low values for switches
+	load1 := float64(rand.Intn(100)+10) / 100.0 // 0.1-1.1
+	load5 := float64(rand.Intn(90)+15) / 100.0  // 0.15-1.05
+	load15 := float64(rand.Intn(80)+20) / 100.0 // 0.2-1.0
+	m.LoadAverage = fmt.Sprintf("%.2f %.2f %.2f", load1, load5, load15)
+
+	// Failed services - usually 0
+	failed := 0
+	if rand.Float64() < 0.05 { // 5% chance
+		failed = 1
+	}
+	m.Units = mockUnits(m, failed, "ssh.service", "openvswitch-switch.service", "lldpd.service")
+
+	// Inode usage - small for switches
+	m.InodeTotal = int64(rand.Intn(500000) + 200000)                                 // 200K-700K inodes
+	m.InodeUsed = int64(float64(m.InodeTotal) * (float64(rand.Intn(25)+10) / 100.0)) // 10-35%
+	m.InodePercent = (float64(m.InodeUsed) / float64(m.InodeTotal)) * 100
+
+	// Flash storage with a small boot partition
+	m.Filesystems = mockFilesystems(m, "/dev/mmcblk0p2",
+		mockMount{"/boot", "vfat", "/dev/mmcblk0p1", 0.25})
+
+	// CPU - forwarding happens in hardware, so the CPU is mostly idle
+	mockCPU(m, id, 4, 5, 10, 0.5, 0)
+
+	// Switches handle lots of traffic; the last port has nothing plugged in
+	m.Interfaces = mockInterfaces(m, id,
+		mockLink{"swp1", 10000, 40},
+		mockLink{"swp2", 10000, 25},
+		mockLink{"swp3", 1000, 30},
+		mockLink{"swp4", 1000, 10},
+		mockLink{"swp5", 0, 0})
+
+	// Kernel version - Debian versions
+	kernels := []string{"6.1.0-17-amd64", "5.10.0-27-amd64", "6.5.0-14-amd64"}
+	m.KernelVersion = kernels[rand.Intn(len(kernels))]
+
+	// OpenFlow specific metrics. The controller address has no host part,
+	// so the switch keeps its configured controller IP.
+	m.OpenFlow = &models.OpenFlowMetrics{
+		Controller: "mock",
+		FlowCount:  rand.Intn(500) + 50, // 50-550 flow rules
+		PortCount:  rand.Intn(16) + 8,   // 8-24 ports
+	}
+	return m
+}
+
+// mockMount describes an extra mock filesystem; sizes are in GB
+type mockMount struct {
+	mountpoint, fstype, device string
+	sizeGB                     float64
+}
+
+// mockFilesystems lists the sample's root filesystem plus extra mounts with
+// random usage, now and then close to full
+func mockFilesystems(m *models.HostMetrics, rootDevice string, extra ...mockMount) []models.Filesystem {
+	root := mockFilesystem(mockMount{"/", "ext4", rootDevice, m.DiskTotal}, m.DiskUsage)
+	root.InodesTotal = uint64(m.InodeTotal)
+	root.InodesUsed = uint64(m.InodeUsed)
+	root.InodePercent = m.InodePercent
+	out := []models.Filesystem{root}
+	for _, mount := range extra {
+		percent := float64(rand.Intn(50) + 20) // 20-70% usage
+		if rand.Float64() < 0.1 {              // 10% chance
+			percent = float64(rand.Intn(8) + 91) // 91-98% usage
+		}
+		out = append(out, mockFilesystem(mount, mount.sizeGB*percent/100))
+	}
+	return out
+}
+
+func mockFilesystem(mount mockMount, usedGB float64) models.Filesystem {
+	const gb = 1024 * 1024 * 1024
+	fs := models.Filesystem{
+		Mountpoint: mount.mountpoint, FSType: mount.fstype, Device: mount.device,
+		SizeBytes: uint64(mount.sizeGB * gb), UsedBytes: uint64(usedGB * gb),
+	}
+	fs.AvailBytes = fs.SizeBytes - fs.UsedBytes
+	fs.UsedPercent = models.FilesystemUsage(fs.UsedBytes, fs.AvailBytes)
+	if mount.fstype != "vfat" { // FAT has no inode table
+		fs.InodesTotal = uint64(mount.sizeGB * 65536)
+		fs.InodesUsed = uint64(float64(fs.InodesTotal) * fs.UsedPercent / 200)
+		fs.InodePercent = models.InodeUsage(fs.InodesUsed, fs.InodesTotal)
+	}
+	return fs
+}
+
+// mockStart anchors the mock interface counters, which grow from it
+var mockStart = time.Now()
+
+// mockLink describes a mock interface: its speed in Mbit/s, 0 for a link
+// that is down and -1 for one without a speed, and its average load in
+// percent of the speed (of 1 Gbit/s without a speed)
+type mockLink struct {
+	name        string
+	speedMbps   int64
+	loadPercent float64
+}
+
+// mockInterfaces lists interfaces whose counters grow steadily from a
+// device and interface specific starting point, so that consecutive samples
+// give rates that rise and fall. It also sets the sample's network totals.
+func mockInterfaces(m *models.HostMetrics, id string, links ...mockLink) []models.NetworkInterface {
+	out := []models.NetworkInterface{}
+	for _, link := range links {
+		seed := mockSeed(id + "/" + link.name)
+		iface := models.NetworkInterface{Name: link.name, OperState: "up", SpeedMbps: link.speedMbps}
+		if link.speedMbps == 0 {
+			iface.OperState = "down"
+		}
+		if link.speedMbps < 0 {
+			iface.SpeedMbps = 0
+		}
+		speed := math.Max(float64(link.speedMbps), 1000)
+		avg := speed * 1e6 / 8 * link.loadPercent / 100 * (0.5 + float64(seed%100)/100) // bytes per second
+		phase := float64(seed%628) / 100
+		iface.RxBytes = uint64(mockCounter(seed, avg, phase))
+		iface.TxBytes = uint64(mockCounter(seed, avg/2, phase+2))
+		iface.RxPackets, iface.TxPackets = iface.RxBytes/900, iface.TxBytes/700
+		iface.RxErrors, iface.TxErrors = iface.RxPackets/5000000, iface.TxPackets/20000000
+		iface.RxDropped, iface.TxDropped = iface.RxPackets/400000, iface.TxPackets/2000000
+		out = append(out, iface)
+
+		m.NetworkRxMB += float64(iface.RxBytes) / (1024 * 1024)
+		m.NetworkTxMB += float64(iface.TxBytes) / (1024 * 1024)
+	}
+	return out
+}
+
+// mockCPU sets CPU times that grow like mockInterfaces' counters, with
+// average user, system, iowait and steal shares in percent, plus pressure
+// stall averages in line with how busy the host is
+func mockCPU(m *models.HostMetrics, id string, cores int, user, system, iowait, steal float64) {
+	seed := mockSeed(id + "/cpu")
+	phase := float64(seed%628) / 100
+	n := float64(cores)
+	t := &models.CPUTimes{
+		User:    mockCounter(seed, n*user/100, phase),
+		Nice:    mockCounter(seed, n*user/1000, phase+1),
+		System:  mockCounter(seed, n*system/100, phase),
+		IOWait:  mockCounter(seed, n*iowait/100, phase+2),
+		SoftIRQ: mockCounter(seed, n*system/20/100, phase),
+		Steal:   mockCounter(seed, n*steal/100, phase+3),
+	}
+	// Idle is what is left; the shares above stay below 100% at their peak
+	uptime := float64(seed%864000) + time.Since(mockStart).Seconds()
+	t.Idle = n*uptime - t.User - t.Nice - t.System - t.IOWait - t.SoftIRQ - t.Steal
+	m.CPUCores = cores
+	m.CPUTimes = t
+
+	stall := func(avg float64) *models.PressureStall {
+		return &models.PressureStall{
+			Avg10:  avg * (0.5 + rand.Float64()),
+			Avg60:  avg * (0.75 + rand.Float64()/2),
+			Avg300: avg,
+		}
+	}
+	m.Pressure = &models.Pressure{
+		CPUSome:    stall((user + system + steal) / 10),
+		CPUFull:    stall(0),
+		MemorySome: stall(rand.Float64()),
+		MemoryFull: stall(rand.Float64() / 4),
+		IOSome:     stall(iowait),
+		IOFull:     stall(iowait / 2),
+	}
+}
+
+// mockUnits lists running services plus periodic jobs that are inactive
+// between runs, the given number of which failed within the last six hours
+func mockUnits(m *models.HostMetrics, failed int, running ...string) []models.SystemdUnit {
+	var units []models.SystemdUnit
+	for _, name := range running {
+		units = append(units, models.SystemdUnit{
+			Name: name, LoadState: "loaded", ActiveState: "active", SubState: "running", UnitFileState: "enabled",
+		})
+	}
+	jobs := []string{"backup.service", "certbot.service", "logrotate.service", "fstrim.service"}
+	for i, j := range rand.Perm(len(jobs)) {
+		u := models.SystemdUnit{Name: jobs[j], LoadState: "loaded", ActiveState: "inactive", SubState: "dead", UnitFileState: "static"}
+		if i < failed {
+			u.ActiveState, u.SubState, u.Result = "failed", "failed", "exit-code"
+			u.FailedAt = time.Now().Add(-time.Duration(rand.Intn(6*3600)) * time.Second)
+		}
+		units = append(units, u)
+	}
+	m.FailedServices = failed
+	return models.WithUnitFileStates(units, nil)
+}
+
+// mockSeed derives a stable number from a device and metric name, so each
+// mock device gets its own but repeatable traffic and load
+func mockSeed(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
+// mockCounter returns a counter that has grown at avg per second for up to
+// ten days before the dashboard started and since. The rate swings by 80%
+// either way over a ten minute cycle, so it never drops to zero.
+func mockCounter(seed uint32, avg, phase float64) float64 {
+	const period = 600.0 // seconds
+	w := 2 * math.Pi / period
+	t := float64(seed%864000) + time.Since(mockStart).Seconds()
+	return avg * (t + 0.8/w*(math.Cos(phase)-math.Cos(w*t+phase)))
+}
@@ -0,0 +1,373 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/models"
+	"server-dashboard/internal/promtext"
+)
+
+// CollectorNodeExporter scrapes a Prometheus node_exporter instead of
+// logging in to the host
+const CollectorNodeExporter = "node_exporter"
+
+func init() {
+	RegisterCollector(nodeExporterCollector{})
+}
+
+// maxScrapeBytes bounds a scrape; node_exporter output is usually well
+// under a megabyte
+const maxScrapeBytes = 16 << 20
+
+// nodeExporterConfigFor returns the node_exporter settings for a device.
+// Devices that select the collector without a node_exporter block scrape
+// the default port.
+func nodeExporterConfigFor(kind, id string) config.NodeExporterConfig {
+	var ne *config.NodeExporterConfig
+	switch kind {
+	case KindServer:
+		for _, c := range Config.Servers {
+			if c.ID == id {
+				ne = c.NodeExporter
+			}
+		}
+	case KindVM:
+		for _, c := range Config.VirtualMachines {
+			if c.ID == id {
+				ne = c.NodeExporter
+			}
+		}
+	}
+	if ne == nil {
+		return config.NodeExporterConfig{}
+	}
+	return *ne
+}
+
+// nodeExporterCollector maps node_exporter's filesystem, memory, load,
+// network and systemd metrics onto a HostMetrics sample
+type nodeExporterCollector struct{}
+
+func (nodeExporterCollector) Name() string { return CollectorNodeExporter }
+
+func (nodeExporterCollector) Collect(target CollectTarget) (*models.HostMetrics, []MetricError, error) {
+	ne := nodeExporterConfigFor(target.Kind, target.ID)
+	url := ne.URL
+	if url == "" {
+		url = "http://" + net.JoinHostPort(target.Address, "9100") + "/metrics"
+	}
+	timeout := time.Duration(ne.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	samples, err := scrapeNodeExporter(url, timeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	m, errs := nodeExporterMetrics(samples, time.Now())
+	return m, errs, nil
+}
+
+// scrapeNodeExporter fetches and parses one exposition
+func scrapeNodeExporter(url string, timeout time.Duration) ([]promtext.Sample, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/plain;version=0.0.4;q=1,*/*;q=0.1")
+	req.Header.Set("User-Agent", "server-dashboard")
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scrape of %s returned %s", url, resp.Status)
+	}
+	samples, err := promtext.Parse(io.LimitReader(resp.Body, maxScrapeBytes))
+	if err != nil {
+		return nil, fmt.Errorf("invalid exposition from %s: %w", url, err)
+	}
+	if len(samples) == 0 {
+		return nil, errors.New("scrape of " + url + " returned no samples")
+	}
+	return samples, nil
+}
+
+// nodeSamples indexes an exposition by metric name
+type nodeSamples map[string][]promtext.Sample
+
+// value returns the first sample of a metric without labels to choose by
+func (s nodeSamples) value(name string) (float64, bool) {
+	if len(s[name]) == 0 {
+		return 0, false
+	}
+	return s[name][0].Value, true
+}
+
+// byLabel returns a metric's values keyed by one label
+func (s nodeSamples) byLabel(name, label string) map[string]float64 {
+	out := make(map[string]float64)
+	for _, sample := range s[name] {
+		out[sample.Label(label)] = sample.Value
+	}
+	return out
+}
+
+// seriesKey identifies a sample's series by its name and labels
+func seriesKey(sample promtext.Sample) string {
+	names := make([]string, 0, len(sample.Labels))
+	for name := range sample.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	key := sample.Name
+	for _, name := range names {
+		key += "\xff" + name + "\xfe" + sample.Labels[name]
+	}
+	return key
+}
+
+// nodeExporterMetrics converts node_exporter samples into a HostMetrics
+// sample. Collectors that node_exporter enables by default are reported as
+// metric errors when missing; optional ones (systemd, processes) are not.
+func nodeExporterMetrics(samples []promtext.Sample, now time.Time) (*models.HostMetrics, []MetricError) {
+	// NaN and ±Inf mean nothing for the metrics read here and would not
+	// survive JSON encoding, so they count as missing. Of duplicate series
+	// the first sample counts, as in Prometheus.
+	s := make(nodeSamples)
+	seen := make(map[string]bool)
+	for _, sample := range samples {
+		if math.IsNaN(sample.Value) || math.IsInf(sample.Value, 0) {
+			continue
+		}
+		if key := seriesKey(sample); !seen[key] {
+			seen[key] = true
+			s[sample.Name] = append(s[sample.Name], sample)
+		}
+	}
+	m := &models.HostMetrics{Uptime: "N/A", FullPartitions: []string{}}
+	var errs []MetricError
+	fail := func(section, field, problem string) {
+		errs = append(errs, MetricError{Section: section, Field: field, Problem: problem})
+	}
+
+	// System
+	if uname := s["node_uname_info"]; len(uname) > 0 {
+		m.KernelVersion = uname[0].Label("release")
+	} else {
+		fail("node_uname", "info", "missing")
+	}
+	if boot, ok := s.value("node_boot_time_seconds"); ok {
+		current, ok := s.value("node_time_seconds")
+		if !ok {
+			current = float64(now.Unix())
+		}
+		if current > boot {
+			m.Uptime = models.FormatUptime(int64(current - boot))
+		}
+	} else {
+		fail("node_boot_time", "seconds", "missing")
+	}
+	if pids, ok := s.value("node_processes_pids"); ok {
+		m.Processes = int(pids)
+	}
+	load1, ok1 := s.value("node_load1")
+	load5, ok5 := s.value("node_load5")
+	load15, ok15 := s.value("node_load15")
+	if ok1 && ok5 && ok15 {
+		m.LoadAverage = fmt.Sprintf("%.2f %.2f %.2f", load1, load5, load15)
+	} else {
+		fail("node_load", "load1/5/15", "missing")
+	}
+
+	// CPU times summed over all cores. node_exporter only exports pressure
+	// stall totals, not the averages the dashboard shows, so PSI is left out.
+	if cpus := s["node_cpu_seconds_total"]; len(cpus) > 0 {
+		t := &models.CPUTimes{}
+		cores := make(map[string]bool)
+		for _, sample := range cpus {
+			cores[sample.Label("cpu")] = true
+			switch sample.Label("mode") {
+			case "user":
+				t.User += sample.Value
+			case "nice":
+				t.Nice += sample.Value
+			case "system":
+				t.System += sample.Value
+			case "idle":
+				t.Idle += sample.Value
+			case "iowait":
+				t.IOWait += sample.Value
+			case "irq":
+				t.IRQ += sample.Value
+			case "softirq":
+				t.SoftIRQ += sample.Value
+			case "steal":
+				t.Steal += sample.Value
+			}
+		}
+		m.CPUTimes = t
+		m.CPUCores = len(cores)
+	} else {
+		fail("node_cpu", "seconds_total", "missing")
+	}
+
+	// Memory (bytes, shown in MB). MemAvailable needs Linux 3.14; older
+	// kernels get the traditional free + buffers + cached estimate.
+	if total, ok := s.value("node_memory_MemTotal_bytes"); ok {
+		available, ok := s.value("node_memory_MemAvailable_bytes")
+		if !ok {
+			free, _ := s.value("node_memory_MemFree_bytes")
+			buffers, _ := s.value("node_memory_Buffers_bytes")
+			cached, _ := s.value("node_memory_Cached_bytes")
+			available = free + buffers + cached
+		}
+		m.MemoryTotal = total / (1024 * 1024)
+		if available <= total {
+			m.MemoryUsed = (total - available) / (1024 * 1024)
+		}
+	} else {
+		fail("node_memory", "MemTotal_bytes", "missing")
+	}
+
+	nodeFilesystems(s, m, fail)
+
+	nodeInterfaces(s, m, fail)
+
+	// systemd units; the systemd collector is off by default. node_exporter
+	// only reports loaded units and their active state, so sub state, unit
+	// file state and failure details are left out.
+	for _, unit := range s["node_systemd_unit_state"] {
+		if unit.Value != 1 {
+			continue
+		}
+		u := models.SystemdUnit{Name: unit.Label("name"), LoadState: "loaded", ActiveState: unit.Label("state")}
+		if u.Failed() {
+			m.FailedServices++
+		}
+		if models.ReportedSystemdUnit(u) {
+			m.Units = append(m.Units, u)
+		}
+	}
+	if m.Units != nil {
+		sort.Slice(m.Units, func(i, j int) bool { return m.Units[i].Name < m.Units[j].Name })
+	}
+
+	return m, errs
+}
+
+// nodeInterfaces fills the interface list and the network totals (bytes,
+// shown in MB) from node_network_*, loopback excluded
+func nodeInterfaces(s nodeSamples, m *models.HostMetrics, fail func(section, field, problem string)) {
+	rxBytes := s.byLabel("node_network_receive_bytes_total", "device")
+	txBytes := s.byLabel("node_network_transmit_bytes_total", "device")
+	if len(rxBytes) == 0 || len(txBytes) == 0 {
+		fail("node_network", "receive/transmit_bytes_total", "missing")
+		return
+	}
+	rxPackets := s.byLabel("node_network_receive_packets_total", "device")
+	txPackets := s.byLabel("node_network_transmit_packets_total", "device")
+	rxErrors := s.byLabel("node_network_receive_errs_total", "device")
+	txErrors := s.byLabel("node_network_transmit_errs_total", "device")
+	rxDropped := s.byLabel("node_network_receive_drop_total", "device")
+	txDropped := s.byLabel("node_network_transmit_drop_total", "device")
+	speed := s.byLabel("node_network_speed_bytes", "device")
+	operstate := make(map[string]string)
+	for _, info := range s["node_network_info"] {
+		operstate[info.Label("device")] = info.Label("operstate")
+	}
+
+	m.Interfaces = []models.NetworkInterface{}
+	for device, rx := range rxBytes {
+		if device == "lo" {
+			continue
+		}
+		iface := models.NetworkInterface{
+			Name: device, OperState: operstate[device],
+			RxBytes: uint64(rx), TxBytes: uint64(txBytes[device]),
+			RxPackets: uint64(rxPackets[device]), TxPackets: uint64(txPackets[device]),
+			RxErrors: uint64(rxErrors[device]), TxErrors: uint64(txErrors[device]),
+			RxDropped: uint64(rxDropped[device]), TxDropped: uint64(txDropped[device]),
+		}
+		if iface.OperState == "" {
+			iface.OperState = "unknown"
+		}
+		// Negative (-1) for links without a speed, such as virtual ones
+		if v := speed[device]; v > 0 {
+			iface.SpeedMbps = int64(v * 8 / 1e6)
+		}
+		m.Interfaces = append(m.Interfaces, iface)
+		m.NetworkRxMB += rx / (1024 * 1024)
+		m.NetworkTxMB += txBytes[device] / (1024 * 1024)
+	}
+	sort.Slice(m.Interfaces, func(i, j int) bool { return m.Interfaces[i].Name < m.Interfaces[j].Name })
+}
+
+// nodeFilesystems fills the root filesystem, inode and mount list fields
+// from node_filesystem_*
+func nodeFilesystems(s nodeSamples, m *models.HostMetrics, fail func(section, field, problem string)) {
+	sizes := s["node_filesystem_size_bytes"]
+	if len(sizes) == 0 {
+		fail("node_filesystem", "size_bytes", "missing")
+		return
+	}
+	avail := s.byLabel("node_filesystem_avail_bytes", "mountpoint")
+	free := s.byLabel("node_filesystem_free_bytes", "mountpoint")
+	files := s.byLabel("node_filesystem_files", "mountpoint")
+	filesFree := s.byLabel("node_filesystem_files_free", "mountpoint")
+	readonly := s.byLabel("node_filesystem_readonly", "mountpoint")
+	deviceError := s.byLabel("node_filesystem_device_error", "mountpoint")
+
+	var mounts []models.Filesystem
+	for _, fs := range sizes {
+		mountpoint := fs.Label("mountpoint")
+		if fs.Value <= 0 || deviceError[mountpoint] == 1 {
+			continue
+		}
+
+		// Used as df reports it: reserved blocks count as unavailable
+		mount := models.Filesystem{
+			Mountpoint: mountpoint, FSType: fs.Label("fstype"), Device: fs.Label("device"),
+			SizeBytes: uint64(fs.Value), UsedBytes: uint64(fs.Value - free[mountpoint]), AvailBytes: uint64(avail[mountpoint]),
+			ReadOnly: readonly[mountpoint] == 1,
+		}
+		mount.UsedPercent = models.FilesystemUsage(mount.UsedBytes, mount.AvailBytes)
+		if total := files[mountpoint]; total > 0 && filesFree[mountpoint] <= total {
+			mount.InodesTotal = uint64(total)
+			mount.InodesUsed = uint64(total - filesFree[mountpoint])
+			mount.InodePercent = models.InodeUsage(mount.InodesUsed, mount.InodesTotal)
+		}
+		mounts = append(mounts, mount)
+	}
+	m.Filesystems = uniqueMounts(mounts)
+
+	haveRoot := false
+	for _, mount := range m.Filesystems {
+		if mount.Mountpoint != "/" {
+			continue
+		}
+		haveRoot = true
+		m.DiskTotal = float64(mount.SizeBytes) / (1024 * 1024 * 1024)
+		m.DiskUsage = float64(mount.UsedBytes) / (1024 * 1024 * 1024)
+		m.DiskPercent = mount.UsedPercent
+		if mount.InodesTotal > 0 {
+			m.InodeTotal = int64(mount.InodesTotal)
+			m.InodeUsed = int64(mount.InodesUsed)
+			m.InodePercent = mount.InodePercent
+		}
+	}
+	if !haveRoot {
+		fail("node_filesystem", `mountpoint="/"`, "missing")
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/models"
+	"server-dashboard/internal/promtext"
+)
+
+// exporterServer serves an exposition at /metrics
+func exporterServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", promtext.ContentType)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// collectNodeExporter runs the collector for a server scraped at url
+func collectNodeExporter(t *testing.T, url string) (*models.HostMetrics, []MetricError, error) {
+	t.Helper()
+	withConfig(t, &config.Config{Servers: []config.ServerConfig{{ID: "web1", NodeExporter: &config.NodeExporterConfig{URL: url}}}})
+	return nodeExporterCollector{}.Collect(CollectTarget{Kind: KindServer, ID: "web1", Address: "127.0.0.1"})
+}
+
+func TestNodeExporterCollector(t *testing.T) {
+	fixture, err := os.ReadFile("../../test/node_exporter/metrics.prom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, errs, err := collectNodeExporter(t, exporterServer(t, string(fixture)).URL+"/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 0 {
+		t.Errorf("metric errors: %v", errs)
+	}
+	if m.KernelVersion != "6.1.0-13-amd64" || m.LoadAverage != "0.52 0.41 0.30" || m.Uptime == "N/A" {
+		t.Errorf("system: kernel %q, load %q, uptime %q", m.KernelVersion, m.LoadAverage, m.Uptime)
+	}
+	if m.MemoryTotal != 8192 || m.MemoryUsed != 3072 {
+		t.Errorf("memory: %v of %v MB used", m.MemoryUsed, m.MemoryTotal)
+	}
+	if m.DiskTotal != 50 || m.DiskUsage != 20 || m.InodeTotal != 3276800 || len(m.Filesystems) != 3 {
+		t.Errorf("disks: %v of %v GB used, %d inodes, %d filesystems", m.DiskUsage, m.DiskTotal, m.InodeTotal, len(m.Filesystems))
+	}
+	if m.NetworkRxMB != 5000 || m.FailedServices != 1 {
+		t.Errorf("rx %v MB, %d failed services", m.NetworkRxMB, m.FailedServices)
+	}
+}
+
+func TestNodeExporterScrapeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		path string
+		want string
+	}{
+		{"not found", "node_load1 1\n", "/nope", "404"},
+		{"invalid exposition", "node_load1 1\nnode_load5{ 1\n", "/metrics", "line 2"},
+		{"no samples", "# HELP node_load1 Load.\n", "/metrics", "no samples"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := exporterServer(t, tt.body)
+			_, _, err := collectNodeExporter(t, srv.URL+tt.path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeExporterMetrics(t *testing.T) {
+	exposition := strings.Join([]string{
+		`node_uname_info{release="6.1.0",version="#1 SMP \"custom\"\nbuild"} 1`,
+		"node_boot_time_seconds 1000",
+		"node_time_seconds 91000",
+		"node_load1 0.5",
+		"node_load5 NaN",
+		"node_load15 0.1",
+		// Duplicate series: the first sample counts
+		`node_cpu_seconds_total{cpu="0",mode="user"} 10`,
+		`node_cpu_seconds_total{cpu="0",mode="user"} 99`,
+		`node_cpu_seconds_total{cpu="0",mode="idle"} 90`,
+		`node_cpu_seconds_total{cpu="1",mode="idle"} 100`,
+		"node_memory_MemTotal_bytes 4294967296",
+		"node_memory_MemAvailable_bytes +Inf",
+		"node_memory_MemFree_bytes 1073741824",
+		"node_memory_Buffers_bytes 0",
+		"node_memory_Cached_bytes 1073741824",
+		// A bind mount listed before the device's shortest mountpoint, two
+		// tmpfs mounts and a mountpoint with escapes
+		`node_filesystem_size_bytes{device="/dev/sda1",fstype="ext4",mountpoint="/srv/www"} 1000`,
+		`node_filesystem_size_bytes{device="/dev/sda1",fstype="ext4",mountpoint="/"} 1000`,
+		`node_filesystem_size_bytes{device="tmpfs",fstype="tmpfs",mountpoint="/run"} 100`,
+		`node_filesystem_size_bytes{device="tmpfs",fstype="tmpfs",mountpoint="/tmp"} 100`,
+		`node_filesystem_size_bytes{device="/dev/sdb1",fstype="xfs",mountpoint="/mnt/\"old\" \\ disk"} 2000`,
+		`node_filesystem_size_bytes{device="/dev/sdc1",fstype="xfs",mountpoint="/mnt/broken"} NaN`,
+		`node_filesystem_free_bytes{mountpoint="/"} 200`,
+		`node_filesystem_avail_bytes{mountpoint="/"} 200`,
+		`node_filesystem_free_bytes{mountpoint="/mnt/\"old\" \\ disk"} 1000`,
+		`node_filesystem_avail_bytes{mountpoint="/mnt/\"old\" \\ disk"} 1000`,
+		`node_network_receive_bytes_total{device="eth0"} 1048576`,
+		`node_network_receive_bytes_total{device="eth0"} 9999999999`,
+		`node_network_transmit_bytes_total{device="eth0"} 2097152`,
+		`node_network_speed_bytes{device="eth0"} +Inf`,
+		`node_network_receive_bytes_total{device="lo"} 1048576`,
+		`node_network_transmit_bytes_total{device="lo"} 1048576`,
+	}, "\n")
+	samples, err := promtext.Parse(strings.NewReader(exposition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, errs := nodeExporterMetrics(samples, time.Unix(91000, 0))
+
+	if m.KernelVersion != "6.1.0" || m.Uptime != "1 days 1 hours" {
+		t.Errorf("kernel %q, uptime %q", m.KernelVersion, m.Uptime)
+	}
+	if len(errs) != 1 || errs[0].Section != "node_load" {
+		t.Errorf("metric errors = %v, want only node_load for the NaN load5", errs)
+	}
+	if m.CPUCores != 2 || m.CPUTimes == nil || m.CPUTimes.User != 10 || m.CPUTimes.Idle != 190 {
+		t.Errorf("cpu: %d cores, times %+v", m.CPUCores, m.CPUTimes)
+	}
+	// MemAvailable is not finite, so free + buffers + cached is used
+	if m.MemoryTotal != 4096 || m.MemoryUsed != 2048 {
+		t.Errorf("memory: %v of %v MB used", m.MemoryUsed, m.MemoryTotal)
+	}
+	if m.NetworkRxMB != 1 || m.NetworkTxMB != 2 || len(m.Interfaces) != 1 || m.Interfaces[0].SpeedMbps != 0 {
+		t.Errorf("network: rx %v MB, tx %v MB, interfaces %+v", m.NetworkRxMB, m.NetworkTxMB, m.Interfaces)
+	}
+
+	var mounts []string
+	for _, fs := range m.Filesystems {
+		mounts = append(mounts, fs.Mountpoint)
+	}
+	if got := strings.Join(mounts, ","); got != `/,/mnt/"old" \ disk,/run,/tmp` {
+		t.Errorf("mounts = %s", got)
+	}
+	if m.DiskPercent != 80 || m.Filesystems[0].UsedBytes != 800 {
+		t.Errorf("root: %v%% used, %+v", m.DiskPercent, m.Filesystems[0])
+	}
+}
+
+// A device mounted only at bind mounts keeps its shortest mountpoint, and
+// the root filesystem is reported missing
+func TestNodeExporterBindMountsWithoutRoot(t *testing.T) {
+	samples, err := promtext.Parse(strings.NewReader(strings.Join([]string{
+		`node_filesystem_size_bytes{device="/dev/sda1",fstype="ext4",mountpoint="/srv/www/cache"} 1000`,
+		`node_filesystem_size_bytes{device="/dev/sda1",fstype="ext4",mountpoint="/srv"} 1000`,
+	}, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, errs := nodeExporterMetrics(samples, time.Now())
+	if len(m.Filesystems) != 1 || m.Filesystems[0].Mountpoint != "/srv" {
+		t.Errorf("filesystems = %+v", m.Filesystems)
+	}
+	missingRoot := false
+	for _, e := range errs {
+		missingRoot = missingRoot || e.Section == "node_filesystem" && e.Field == `mountpoint="/"`
+	}
+	if !missingRoot {
+		t.Errorf("metric errors = %v, want the root filesystem missing", errs)
+	}
+}
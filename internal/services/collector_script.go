@@ -0,0 +1,420 @@
+package services
+
+import (
+	_ "embed"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"server-dashboard/internal/models"
+)
+
+// collectorScript gathers every host metric in one SSH exec. See the header
+// of scripts/collect.sh for the output format.
+//
+//go:embed scripts/collect.sh
+var collectorScript string
+
+// collectorVersion is the output format version this parser understands
+const collectorVersion = 2
+
+// MetricError reports a single metric that could not be collected or parsed
+type MetricError struct {
+	Section string
+	Field   string
+	Problem string
+}
+
+func (e MetricError) Error() string {
+	return fmt.Sprintf("%s.%s: %s", e.Section, e.Field, e.Problem)
+}
+
+// collectorOutput is the parsed key=value output of the collector script.
+// Keys may repeat within a section, so each key maps to all of its values.
+type collectorOutput struct {
+	version  int
+	sections map[string]map[string][]string
+}
+
+// parseCollectorOutput splits collector output into sections. It only fails
+// when the output is not recognisable as collector output at all.
+func parseCollectorOutput(output string) (*collectorOutput, error) {
+	out := &collectorOutput{sections: make(map[string]map[string][]string)}
+	section := ""
+	sawVersion := false
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if out.sections[section] == nil {
+				out.sections[section] = make(map[string][]string)
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue // stray output such as shell warnings
+		}
+		key = strings.TrimSpace(key)
+		if section == "" && key == "collector_version" {
+			v, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid collector version %q", value)
+			}
+			out.version = v
+			sawVersion = true
+			continue
+		}
+		if section == "" {
+			continue
+		}
+		out.sections[section][key] = append(out.sections[section][key], value)
+	}
+
+	if !sawVersion {
+		return nil, fmt.Errorf("collector output has no version header")
+	}
+	if out.version != collectorVersion {
+		return nil, fmt.Errorf("unsupported collector version %d (want %d)", out.version, collectorVersion)
+	}
+	return out, nil
+}
+
+// has reports whether the script emitted the given section
+func (o *collectorOutput) has(section string) bool {
+	_, ok := o.sections[section]
+	return ok
+}
+
+// fieldReader reads typed values from collector output and records a
+// MetricError for every field that is missing or malformed
+type fieldReader struct {
+	out  *collectorOutput
+	errs []MetricError
+}
+
+func (r *fieldReader) fail(section, field, problem string) {
+	r.errs = append(r.errs, MetricError{Section: section, Field: field, Problem: problem})
+}
+
+func (r *fieldReader) raw(section, field string) (string, bool) {
+	values := r.out.sections[section][field]
+	if len(values) == 0 || strings.TrimSpace(values[0]) == "" {
+		r.fail(section, field, "missing")
+		return "", false
+	}
+	return strings.TrimSpace(values[0]), true
+}
+
+func (r *fieldReader) str(section, field string) string {
+	v, _ := r.raw(section, field)
+	return v
+}
+
+func (r *fieldReader) int64(section, field string) int64 {
+	v, ok := r.raw(section, field)
+	if !ok {
+		return 0
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		r.fail(section, field, fmt.Sprintf("not an integer: %q", v))
+		return 0
+	}
+	return n
+}
+
+func (r *fieldReader) percent(section, field string) float64 {
+	v, ok := r.raw(section, field)
+	if !ok {
+		return 0
+	}
+	f, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+	if err != nil {
+		r.fail(section, field, fmt.Sprintf("not a percentage: %q", v))
+		return 0
+	}
+	return f
+}
+
+// hostMetrics converts parsed collector output into a HostMetrics sample
+func (o *collectorOutput) hostMetrics() (*models.HostMetrics, []MetricError) {
+	r := &fieldReader{out: o}
+	m := &models.HostMetrics{}
+
+	// System
+	m.KernelVersion = r.str("system", "kernel")
+	if values := o.sections["system"]["uptime"]; len(values) > 0 && values[0] != "" {
+		m.Uptime = values[0]
+	} else if secs := r.int64("system", "uptime_seconds"); secs > 0 {
+		m.Uptime = models.FormatUptime(secs)
+	}
+	if m.Uptime == "" {
+		m.Uptime = "N/A"
+	}
+	m.Processes = int(r.int64("system", "processes"))
+	if load := r.str("system", "load"); load != "" {
+		if len(strings.Fields(load)) != 3 {
+			r.fail("system", "load", fmt.Sprintf("expected three values: %q", load))
+		} else {
+			m.LoadAverage = load
+		}
+	}
+
+	// CPU times (reported in clock ticks, stored in seconds); usage is
+	// computed by the dashboard between two samples
+	m.CPUCores = int(r.int64("cpu", "cores"))
+	if times := r.str("cpu", "times"); times != "" {
+		ticks := 100.0 // USER_HZ on every mainstream architecture
+		if values := o.sections["cpu"]["clock_ticks"]; len(values) > 0 {
+			if n, err := strconv.ParseFloat(strings.TrimSpace(values[0]), 64); err == nil && n > 0 {
+				ticks = n
+			}
+		}
+		if t, err := parseCPUTimes(times, ticks); err != nil {
+			r.fail("cpu", "times", err.Error())
+		} else {
+			m.CPUTimes = t
+		}
+	}
+
+	// Pressure stall information, only on kernels with PSI
+	for _, resource := range models.PressureResources {
+		for _, kind := range models.PressureKinds {
+			values := o.sections["pressure"][resource+"_"+kind]
+			if len(values) == 0 {
+				continue
+			}
+			stall, err := parsePressure(values[0])
+			if err != nil {
+				r.fail("pressure", resource+"_"+kind, err.Error())
+				continue
+			}
+			if m.Pressure == nil {
+				m.Pressure = &models.Pressure{}
+			}
+			m.Pressure.SetStall(resource, kind, stall)
+		}
+	}
+
+	// Memory (reported in kB, shown in MB)
+	totalKB := r.int64("memory", "total_kb")
+	availableKB := r.int64("memory", "available_kb")
+	m.MemoryTotal = float64(totalKB) / 1024
+	if totalKB > 0 && availableKB <= totalKB {
+		m.MemoryUsed = float64(totalKB-availableKB) / 1024
+	}
+
+	// Root filesystem (reported in kB, shown in GB)
+	m.DiskTotal = float64(r.int64("disk", "total_kb")) / (1024 * 1024)
+	m.DiskUsage = float64(r.int64("disk", "used_kb")) / (1024 * 1024)
+	m.DiskPercent = r.percent("disk", "percent")
+
+	// Inodes
+	m.InodeTotal = r.int64("inodes", "total")
+	m.InodeUsed = r.int64("inodes", "used")
+	m.InodePercent = r.percent("inodes", "percent")
+
+	// Every mount with blocks; thresholds are applied by selectFilesystems
+	m.Filesystems = []models.Filesystem{}
+	for _, line := range o.sections["filesystems"]["mount"] {
+		fs, err := parseMount(line)
+		if err != nil {
+			r.fail("filesystems", "mount", err.Error())
+			continue
+		}
+		m.Filesystems = append(m.Filesystems, fs)
+	}
+	if len(o.sections["filesystems"]["mount"]) == 0 {
+		r.fail("filesystems", "mount", "missing")
+	}
+
+	// Network totals (reported in bytes, shown in MB)
+	m.NetworkRxMB = float64(r.int64("network", "rx_bytes")) / (1024 * 1024)
+	m.NetworkTxMB = float64(r.int64("network", "tx_bytes")) / (1024 * 1024)
+
+	// Per-interface counters
+	m.Interfaces = []models.NetworkInterface{}
+	for _, line := range o.sections["interfaces"]["iface"] {
+		iface, err := parseInterface(line)
+		if err != nil {
+			r.fail("interfaces", "iface", err.Error())
+			continue
+		}
+		m.Interfaces = append(m.Interfaces, iface)
+	}
+
+	// systemd units, when the host runs systemd; selectServices picks out
+	// the failed and watched ones
+	if r.str("services", "available") == "1" {
+		m.FailedServices = int(r.int64("services", "failed"))
+		m.Units = systemdUnits(r)
+	}
+
+	// Open vSwitch, only present on switches with OVS installed
+	if o.has("openflow") {
+		of := &models.OpenFlowMetrics{}
+		if values := o.sections["openflow"]["controller"]; len(values) > 0 {
+			of.Controller = strings.TrimSpace(values[0])
+		}
+		of.FlowCount = int(r.int64("openflow", "flows"))
+		of.PortCount = int(r.int64("openflow", "ports"))
+		of.Version = r.str("openflow", "version")
+		m.OpenFlow = of
+	}
+
+	return m, r.errs
+}
+
+// systemdUnits reads the units of the services section with their unit file
+// state and, for failed ones, why and when they failed
+func systemdUnits(r *fieldReader) []models.SystemdUnit {
+	section := r.out.sections["services"]
+	units := []models.SystemdUnit{}
+	for _, line := range section["unit"] {
+		u, ok := models.ParseSystemdUnit(line)
+		if !ok {
+			r.fail("services", "unit", fmt.Sprintf("malformed entry: %q", line))
+			continue
+		}
+		units = append(units, u)
+	}
+	states := make(map[string]string, len(section["unit_file"]))
+	for _, line := range section["unit_file"] {
+		if name, state, ok := models.ParseUnitFile(line); ok {
+			states[name] = state
+		}
+	}
+	units = models.WithUnitFileStates(units, states)
+
+	if len(section["failure"]) == 0 {
+		return units
+	}
+	bootTime := r.int64("services", "boot_time")
+	for _, line := range section["failure"] {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			r.fail("services", "failure", fmt.Sprintf("malformed entry: %q", line))
+			continue
+		}
+		since, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			r.fail("services", "failure", fmt.Sprintf("not an integer: %q in %q", fields[2], line))
+			continue
+		}
+		for i := range units {
+			if units[i].Name == fields[0] {
+				units[i].Result = fields[1]
+				units[i].FailedAt = models.SystemdTimestamp(bootTime, since)
+			}
+		}
+	}
+	return units
+}
+
+// parseMount parses one mount line of the filesystems section:
+// "<mountpoint> <fstype> <device> <size_kb> <used_kb> <avail_kb> <inodes> <inodes_used> <ro|rw>".
+// Mountpoints and devices carry the octal escapes of /proc/mounts.
+func parseMount(line string) (models.Filesystem, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 9 || (fields[8] != "ro" && fields[8] != "rw") {
+		return models.Filesystem{}, fmt.Errorf("malformed entry: %q", line)
+	}
+	var n [5]uint64
+	for i := range n {
+		v, err := strconv.ParseUint(fields[3+i], 10, 64)
+		if err != nil {
+			return models.Filesystem{}, fmt.Errorf("not an integer: %q in %q", fields[3+i], line)
+		}
+		n[i] = v
+	}
+	known := func(s string) string {
+		if s == "-" {
+			return ""
+		}
+		return s
+	}
+	fs := models.Filesystem{
+		Mountpoint: models.UnescapeMount(fields[0]), FSType: known(fields[1]), Device: models.UnescapeMount(known(fields[2])),
+		SizeBytes: n[0] * 1024, UsedBytes: n[1] * 1024, AvailBytes: n[2] * 1024,
+		InodesTotal: n[3], InodesUsed: n[4], ReadOnly: fields[8] == "ro",
+	}
+	fs.UsedPercent = models.FilesystemUsage(fs.UsedBytes, fs.AvailBytes)
+	fs.InodePercent = models.InodeUsage(fs.InodesUsed, fs.InodesTotal)
+	return fs, nil
+}
+
+// parseInterface parses one line of the interfaces section: "<name>
+// <operstate> <speed_mbps> <rx_bytes> <rx_packets> <rx_errors> <rx_dropped>
+// <tx_bytes> <tx_packets> <tx_errors> <tx_dropped>"
+func parseInterface(line string) (models.NetworkInterface, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 11 {
+		return models.NetworkInterface{}, fmt.Errorf("malformed entry: %q", line)
+	}
+	var n [9]uint64
+	for i := range n {
+		v, err := strconv.ParseUint(fields[2+i], 10, 64)
+		if err != nil {
+			return models.NetworkInterface{}, fmt.Errorf("not an integer: %q in %q", fields[2+i], line)
+		}
+		n[i] = v
+	}
+	return models.NetworkInterface{
+		Name: fields[0], OperState: fields[1], SpeedMbps: int64(n[0]),
+		RxBytes: n[1], RxPackets: n[2], RxErrors: n[3], RxDropped: n[4],
+		TxBytes: n[5], TxPackets: n[6], TxErrors: n[7], TxDropped: n[8],
+	}, nil
+}
+
+// parseCPUTimes parses the times line of the cpu section, "<user> <nice>
+// <system> <idle> <iowait> <irq> <softirq> <steal>" in clock ticks
+func parseCPUTimes(line string, ticksPerSecond float64) (*models.CPUTimes, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 8 {
+		return nil, fmt.Errorf("expected eight values: %q", line)
+	}
+	var n [8]float64
+	for i := range n {
+		v, err := strconv.ParseUint(fields[i], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("not an integer: %q in %q", fields[i], line)
+		}
+		n[i] = float64(v) / ticksPerSecond
+	}
+	return &models.CPUTimes{
+		User: n[0], Nice: n[1], System: n[2], Idle: n[3],
+		IOWait: n[4], IRQ: n[5], SoftIRQ: n[6], Steal: n[7],
+	}, nil
+}
+
+// parsePressure parses a line of the pressure section, "<avg10> <avg60>
+// <avg300>" in percent
+func parsePressure(line string) (models.PressureStall, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return models.PressureStall{}, fmt.Errorf("expected three values: %q", line)
+	}
+	var n [3]float64
+	for i := range n {
+		v, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return models.PressureStall{}, fmt.Errorf("not a number: %q in %q", fields[i], line)
+		}
+		n[i] = v
+	}
+	return models.PressureStall{Avg10: n[0], Avg60: n[1], Avg300: n[2]}, nil
+}
+
+// metricErrorStrings renders metric errors for display on the device model
+func metricErrorStrings(errs []MetricError) []string {
+	out := make([]string, 0, len(errs))
+	for _, e := range errs {
+		out = append(out, e.Error())
+	}
+	return out
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"server-dashboard/internal/models"
+)
+
+// collectorSample is collector script output from a host with systemd
+const collectorSample = `collector_version=2
+[system]
+kernel=6.1.0-18-amd64
+uptime_seconds=93784
+uptime=up 1 day, 2 hours, 3 minutes
+processes=214
+load=0.52 0.41 0.30
+[cpu]
+times=1000 20 300 8000 50 0 10 0
+cores=4
+clock_ticks=100
+[pressure]
+[memory]
+total_kb=8048576
+available_kb=6000000
+[disk]
+total_kb=51475068
+used_kb=20590027
+percent=42%
+[inodes]
+total=3276800
+used=327680
+percent=10%
+[filesystems]
+mount=/ ext4 /dev/sda2 51475068 20590027 28237304 3276800 327680 rw
+mount=/boot/efi vfat /dev/sda1 523248 6220 517028 0 0 rw
+mount=/srv/media\040library xfs /dev/sdb1 1000 600 200 50 5 ro
+[network]
+rx_bytes=1048576
+tx_bytes=2097152
+[interfaces]
+iface=eth0 up 1000 1048576 900 0 1 2097152 800 0 0
+[services]
+available=1
+failed=1
+boot_time=1700000000
+unit=nginx.service loaded active running
+unit=backup.service loaded failed failed
+unit_file=nginx.service enabled
+unit_file=backup.service disabled
+failure=backup.service exit-code 60000000
+`
+
+// collectorSampleWith returns the sample with line old replaced by new,
+// or removed when new is empty
+func collectorSampleWith(t *testing.T, old, new string) string {
+	t.Helper()
+	if !strings.Contains(collectorSample, old+"\n") {
+		t.Fatalf("sample has no line %q", old)
+	}
+	if new != "" {
+		new += "\n"
+	}
+	return strings.Replace(collectorSample, old+"\n", new, 1)
+}
+
+func TestCollectorOutput(t *testing.T) {
+	out, err := parseCollectorOutput(collectorSample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, errs := out.hostMetrics()
+	if len(errs) != 0 {
+		t.Fatalf("errors: %v", errs)
+	}
+	if m.KernelVersion != "6.1.0-18-amd64" || m.Uptime != "up 1 day, 2 hours, 3 minutes" || m.Processes != 214 || m.LoadAverage != "0.52 0.41 0.30" {
+		t.Errorf("system: %+v", m)
+	}
+	if m.CPUCores != 4 || m.CPUTimes == nil || m.CPUTimes.User != 10 || m.CPUTimes.Idle != 80 || m.Pressure != nil {
+		t.Errorf("cpu: %d cores, times %+v, pressure %+v", m.CPUCores, m.CPUTimes, m.Pressure)
+	}
+	if m.DiskPercent != 42 || m.InodeTotal != 3276800 || m.InodePercent != 10 || m.MemoryUsed != (8048576-6000000)/1024.0 {
+		t.Errorf("disk %v%%, inodes %d %v%%, memory used %v", m.DiskPercent, m.InodeTotal, m.InodePercent, m.MemoryUsed)
+	}
+	if len(m.Filesystems) != 3 || m.Filesystems[1].SizeBytes != 523248*1024 || m.Filesystems[2].Mountpoint != "/srv/media library" {
+		t.Errorf("filesystems: %+v", m.Filesystems)
+	}
+	if m.NetworkRxMB != 1 || m.NetworkTxMB != 2 || len(m.Interfaces) != 1 || m.Interfaces[0].RxDropped != 1 {
+		t.Errorf("network: rx %v, tx %v, interfaces %+v", m.NetworkRxMB, m.NetworkTxMB, m.Interfaces)
+	}
+	if m.FailedServices != 1 || len(m.Units) != 2 {
+		t.Fatalf("services: %d failed, units %+v", m.FailedServices, m.Units)
+	}
+	var backup models.SystemdUnit
+	for _, u := range m.Units {
+		if u.Name == "backup.service" {
+			backup = u
+		}
+	}
+	if backup.Result != "exit-code" || backup.UnitFileState != "disabled" || backup.FailedAt.Unix() != 1700000060 {
+		t.Errorf("failed unit: %+v", backup)
+	}
+	if m.OpenFlow != nil {
+		t.Errorf("openflow without an openflow section: %+v", m.OpenFlow)
+	}
+}
+
+func TestCollectorOutputErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []MetricError // Errors of a sample still read
+		err    string        // Or the error for output not read at all
+	}{
+		{
+			name:   "missing field",
+			output: collectorSampleWith(t, "total_kb=8048576", ""),
+			want:   []MetricError{{Section: "memory", Field: "total_kb", Problem: "missing"}},
+		},
+		{
+			name:   "empty field",
+			output: collectorSampleWith(t, "kernel=6.1.0-18-amd64", "kernel="),
+			want:   []MetricError{{Section: "system", Field: "kernel", Problem: "missing"}},
+		},
+		{
+			name:   "malformed number",
+			output: collectorSampleWith(t, "processes=214", "processes=many"),
+			want:   []MetricError{{Section: "system", Field: "processes", Problem: `not an integer: "many"`}},
+		},
+		{
+			name:   "malformed percentage",
+			output: collectorSampleWith(t, "percent=42%", "percent=n/a"),
+			want:   []MetricError{{Section: "disk", Field: "percent", Problem: `not a percentage: "n/a"`}},
+		},
+		{
+			name:   "malformed mount",
+			output: collectorSampleWith(t, "mount=/boot/efi vfat /dev/sda1 523248 6220 517028 0 0 rw", "mount=/boot/efi vfat /dev/sda1 523248 6220 -1 0 0 rw"),
+			want: []MetricError{{Section: "filesystems", Field: "mount",
+				Problem: `not an integer: "-1" in "/boot/efi vfat /dev/sda1 523248 6220 -1 0 0 rw"`}},
+		},
+		{
+			name:   "malformed failure",
+			output: collectorSampleWith(t, "failure=backup.service exit-code 60000000", "failure=backup.service exit-code"),
+			want:   []MetricError{{Section: "services", Field: "failure", Problem: `malformed entry: "backup.service exit-code"`}},
+		},
+		{
+			name:   "unknown collector version",
+			output: collectorSampleWith(t, "collector_version=2", "collector_version=3"),
+			err:    "unsupported collector version 3 (want 2)",
+		},
+		{
+			name:   "malformed collector version",
+			output: collectorSampleWith(t, "collector_version=2", "collector_version=two"),
+			err:    `invalid collector version "two"`,
+		},
+		{
+			name:   "no version header",
+			output: "sh: 1: syntax error\n",
+			err:    "collector output has no version header",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := parseCollectorOutput(tt.output)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, errs := out.hostMetrics(); !reflect.DeepEqual(errs, tt.want) {
+				t.Errorf("errors = %v, want %v", errs, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMount(t *testing.T) {
+	tests := []struct {
+		line string
+		want models.Filesystem
+		err  bool
+	}{
+		{
+			line: `/srv/media\040library xfs /dev/sdb1 1000 600 200 50 5 ro`,
+			want: models.Filesystem{Mountpoint: "/srv/media library", FSType: "xfs", Device: "/dev/sdb1",
+				SizeBytes: 1024000, UsedBytes: 614400, AvailBytes: 204800, UsedPercent: 75,
+				InodesTotal: 50, InodesUsed: 5, InodePercent: 10, ReadOnly: true},
+		},
+		{
+			line: `/mnt/tab\011and\134backslash - - 100 0 100 0 0 rw`,
+			want: models.Filesystem{Mountpoint: "/mnt/tab\tand\\backslash", SizeBytes: 102400, AvailBytes: 102400},
+		},
+		// A space left unescaped splits the mountpoint
+		{line: "/srv/media library xfs /dev/sdb1 1000 600 200 50 5 ro", err: true},
+		{line: "/ ext4 /dev/sda2 1000 600 200 50 5 mounted", err: true},
+	}
+	for _, tt := range tests {
+		got, err := parseMount(tt.line)
+		if (err != nil) != tt.err {
+			t.Errorf("%q: err = %v", tt.line, err)
+			continue
+		}
+		if !tt.err && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
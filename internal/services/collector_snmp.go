@@ -0,0 +1,445 @@
+package services
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/models"
+	"server-dashboard/internal/snmp"
+)
+
+// CollectorSNMP polls standard MIBs for devices without shell access
+const CollectorSNMP = "snmp"
+
+func init() {
+	RegisterCollector(snmpCollector{})
+}
+
+// Objects read by the SNMP collector
+var (
+	oidSysDescr          = snmp.MustParseOID("1.3.6.1.2.1.1.1.0")
+	oidSysUpTime         = snmp.MustParseOID("1.3.6.1.2.1.1.3.0")
+	oidHrSystemUptime    = snmp.MustParseOID("1.3.6.1.2.1.25.1.1.0")
+	oidHrSystemProcesses = snmp.MustParseOID("1.3.6.1.2.1.25.1.6.0")
+
+	// IF-MIB ifTable and ifXTable columns
+	oidIfDescr              = snmp.MustParseOID("1.3.6.1.2.1.2.2.1.2")
+	oidIfType               = snmp.MustParseOID("1.3.6.1.2.1.2.2.1.3")
+	oidIfSpeed              = snmp.MustParseOID("1.3.6.1.2.1.2.2.1.5")
+	oidIfOperStatus         = snmp.MustParseOID("1.3.6.1.2.1.2.2.1.8")
+	oidIfInOctets           = snmp.MustParseOID("1.3.6.1.2.1.2.2.1.10")
+	oidIfInUcastPkts        = snmp.MustParseOID("1.3.6.1.2.1.2.2.1.11")
+	oidIfInNUcastPkts       = snmp.MustParseOID("1.3.6.1.2.1.2.2.1.12")
+	oidIfInDiscards         = snmp.MustParseOID("1.3.6.1.2.1.2.2.1.13")
+	oidIfInErrors           = snmp.MustParseOID("1.3.6.1.2.1.2.2.1.14")
+	oidIfOutOctets          = snmp.MustParseOID("1.3.6.1.2.1.2.2.1.16")
+	oidIfOutUcastPkts       = snmp.MustParseOID("1.3.6.1.2.1.2.2.1.17")
+	oidIfOutNUcastPkts      = snmp.MustParseOID("1.3.6.1.2.1.2.2.1.18")
+	oidIfOutDiscards        = snmp.MustParseOID("1.3.6.1.2.1.2.2.1.19")
+	oidIfOutErrors          = snmp.MustParseOID("1.3.6.1.2.1.2.2.1.20")
+	oidIfName               = snmp.MustParseOID("1.3.6.1.2.1.31.1.1.1.1")
+	oidIfHCInOctets         = snmp.MustParseOID("1.3.6.1.2.1.31.1.1.1.6")
+	oidIfHCInUcastPkts      = snmp.MustParseOID("1.3.6.1.2.1.31.1.1.1.7")
+	oidIfHCInMulticastPkts  = snmp.MustParseOID("1.3.6.1.2.1.31.1.1.1.8")
+	oidIfHCInBroadcastPkts  = snmp.MustParseOID("1.3.6.1.2.1.31.1.1.1.9")
+	oidIfHCOutOctets        = snmp.MustParseOID("1.3.6.1.2.1.31.1.1.1.10")
+	oidIfHCOutUcastPkts     = snmp.MustParseOID("1.3.6.1.2.1.31.1.1.1.11")
+	oidIfHCOutMulticastPkts = snmp.MustParseOID("1.3.6.1.2.1.31.1.1.1.12")
+	oidIfHCOutBroadcastPkts = snmp.MustParseOID("1.3.6.1.2.1.31.1.1.1.13")
+	oidIfHighSpeed          = snmp.MustParseOID("1.3.6.1.2.1.31.1.1.1.15")
+
+	// HOST-RESOURCES-MIB hrStorageTable columns and storage types
+	oidHrStorageType  = snmp.MustParseOID("1.3.6.1.2.1.25.2.3.1.2")
+	oidHrStorageDescr = snmp.MustParseOID("1.3.6.1.2.1.25.2.3.1.3")
+	oidHrStorageUnits = snmp.MustParseOID("1.3.6.1.2.1.25.2.3.1.4")
+	oidHrStorageSize  = snmp.MustParseOID("1.3.6.1.2.1.25.2.3.1.5")
+	oidHrStorageUsed  = snmp.MustParseOID("1.3.6.1.2.1.25.2.3.1.6")
+	oidHrStorageRAM   = snmp.MustParseOID("1.3.6.1.2.1.25.2.1.2")
+	oidHrStorageFixed = snmp.MustParseOID("1.3.6.1.2.1.25.2.1.4")
+	oidHrStorageOther = snmp.MustParseOID("1.3.6.1.2.1.25.2.1.1")
+
+	// HOST-RESOURCES-MIB hrProcessorLoad, one row per logical CPU
+	oidHrProcessorLoad = snmp.MustParseOID("1.3.6.1.2.1.25.3.3.1.2")
+
+	// UCD-SNMP-MIB laLoad.1-3, exported by net-snmp based agents
+	oidLaLoad = snmp.MustParseOID("1.3.6.1.4.1.2021.10.1.3")
+
+	// UCD-SNMP-MIB systemStats raw CPU counters in clock ticks. ssCpuRawSystem
+	// includes interrupts on Linux, so ssCpuRawKernel is preferred.
+	oidSsCpuRawUser      = snmp.MustParseOID("1.3.6.1.4.1.2021.11.50.0")
+	oidSsCpuRawNice      = snmp.MustParseOID("1.3.6.1.4.1.2021.11.51.0")
+	oidSsCpuRawSystem    = snmp.MustParseOID("1.3.6.1.4.1.2021.11.52.0")
+	oidSsCpuRawIdle      = snmp.MustParseOID("1.3.6.1.4.1.2021.11.53.0")
+	oidSsCpuRawWait      = snmp.MustParseOID("1.3.6.1.4.1.2021.11.54.0")
+	oidSsCpuRawKernel    = snmp.MustParseOID("1.3.6.1.4.1.2021.11.55.0")
+	oidSsCpuRawInterrupt = snmp.MustParseOID("1.3.6.1.4.1.2021.11.56.0")
+	oidSsCpuRawSoftIRQ   = snmp.MustParseOID("1.3.6.1.4.1.2021.11.61.0")
+	oidSsCpuRawSteal     = snmp.MustParseOID("1.3.6.1.4.1.2021.11.64.0")
+)
+
+// ifType values counted as switch ports: ethernetCsmacd, fastEther,
+// fastEtherFX and gigabitEthernet
+var physicalIfTypes = map[int64]bool{6: true, 62: true, 69: true, 117: true}
+
+// ifTypeSoftwareLoopback is excluded from traffic totals
+const ifTypeSoftwareLoopback = 24
+
+// ifOperStatus values, named as in /sys/class/net operstate
+var ifOperStates = map[int64]string{
+	1: "up", 2: "down", 3: "testing", 4: "unknown", 5: "dormant", 6: "notpresent", 7: "lowerlayerdown",
+}
+
+// snmpConfigFor returns the SNMP settings configured for a device
+func snmpConfigFor(kind, id string) *config.SNMPConfig {
+	if kind != KindSwitch {
+		return nil
+	}
+	for _, c := range Config.Switches {
+		if c.ID == id {
+			return c.SNMP
+		}
+	}
+	return nil
+}
+
+// SNMP clients are kept per device so v3 engine discovery and key
+// localization happen once rather than on every poll
+var (
+	snmpClientsMu sync.Mutex
+	snmpClients   = make(map[string]*snmp.Client)
+)
+
+// snmpClientFor returns the cached client for a device
+func snmpClientFor(target CollectTarget, sc *config.SNMPConfig) (*snmp.Client, error) {
+	key := target.Kind + "/" + target.ID
+	snmpClientsMu.Lock()
+	defer snmpClientsMu.Unlock()
+	if c, ok := snmpClients[key]; ok {
+		return c, nil
+	}
+
+	port := sc.Port
+	if port == 0 {
+		port = 161
+	}
+	timeout := time.Duration(sc.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	community := sc.Community
+	if community == "" {
+		community = "public"
+	}
+	auth, err := snmp.ParseAuthProtocol(sc.AuthProtocol)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := snmp.ParsePrivProtocol(sc.PrivProtocol)
+	if err != nil {
+		return nil, err
+	}
+	c, err := snmp.Dial(snmp.Config{
+		Address:      net.JoinHostPort(target.Address, strconv.Itoa(port)),
+		Version:      sc.Version,
+		Community:    community,
+		Timeout:      timeout,
+		Retries:      sc.Retries,
+		Username:     sc.Username,
+		AuthProtocol: auth,
+		AuthPassword: sc.AuthPassword,
+		PrivProtocol: priv,
+		PrivPassword: sc.PrivPassword,
+		ContextName:  sc.ContextName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	snmpClients[key] = c
+	return c, nil
+}
+
+// snmpCollector reads uptime, interface counters, ports, memory and disk
+// from IF-MIB and HOST-RESOURCES-MIB
+type snmpCollector struct{}
+
+func (snmpCollector) Name() string { return CollectorSNMP }
+
+func (snmpCollector) Collect(target CollectTarget) (*models.HostMetrics, []MetricError, error) {
+	sc := snmpConfigFor(target.Kind, target.ID)
+	if sc == nil {
+		return nil, nil, errors.New("no snmp configuration for this device")
+	}
+	client, err := snmpClientFor(target, sc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return collectSNMP(client)
+}
+
+// collectSNMP polls one agent. It only fails when the agent cannot be
+// queried at all; missing MIBs are reported as metric errors.
+func collectSNMP(client *snmp.Client) (*models.HostMetrics, []MetricError, error) {
+	scalars, err := client.Get(oidSysDescr, oidSysUpTime, oidHrSystemUptime, oidHrSystemProcesses)
+	if err != nil {
+		return nil, nil, err
+	}
+	m := &models.HostMetrics{Uptime: "N/A", FullPartitions: []string{}}
+	var errs []MetricError
+
+	if descr, ok := scalars[0].Text(); ok {
+		m.KernelVersion = strings.TrimSpace(strings.SplitN(descr, "\n", 2)[0])
+	}
+	// hrSystemUptime is the host's uptime; sysUpTime only the agent's
+	if ticks, ok := scalars[2].Uint(); ok {
+		m.Uptime = models.FormatUptime(int64(ticks / 100))
+	} else if ticks, ok := scalars[1].Uint(); ok {
+		m.Uptime = models.FormatUptime(int64(ticks / 100))
+	} else {
+		errs = append(errs, MetricError{Section: "SNMPv2-MIB", Field: "sysUpTime", Problem: "missing"})
+	}
+	if n, ok := scalars[3].Uint(); ok {
+		m.Processes = int(n)
+	}
+
+	if err := snmpInterfaces(client, m); err != nil {
+		errs = append(errs, MetricError{Section: "IF-MIB", Field: "ifTable", Problem: err.Error()})
+	}
+	errs = append(errs, snmpStorage(client, m)...)
+
+	// Load average is optional; most switches do not export UCD-SNMP-MIB
+	if load, err := client.Get(oidLaLoad.Append(1), oidLaLoad.Append(2), oidLaLoad.Append(3)); err == nil {
+		var values []string
+		for _, vb := range load {
+			if s, ok := vb.Text(); ok {
+				values = append(values, strings.TrimSpace(s))
+			}
+		}
+		if len(values) == 3 {
+			m.LoadAverage = strings.Join(values, " ")
+		}
+	}
+	snmpCPU(client, m)
+	return m, errs, nil
+}
+
+// snmpCPU counts logical CPUs and reads CPU times where the agent exports
+// them. Both are optional: switches rarely have HOST-RESOURCES-MIB or
+// UCD-SNMP-MIB. The raw counters are 32-bit, so the interval in which they
+// wrap gets no usage.
+func snmpCPU(client *snmp.Client, m *models.HostMetrics) {
+	if rows, err := snmpColumn(client, oidHrProcessorLoad); err == nil {
+		m.CPUCores = len(rows)
+	}
+	raw, err := client.Get(oidSsCpuRawUser, oidSsCpuRawNice, oidSsCpuRawIdle, oidSsCpuRawWait,
+		oidSsCpuRawKernel, oidSsCpuRawInterrupt, oidSsCpuRawSoftIRQ, oidSsCpuRawSteal, oidSsCpuRawSystem)
+	if err != nil {
+		return
+	}
+	var n [9]float64
+	for i, vb := range raw {
+		v, ok := vb.Uint()
+		if !ok && i < 3 { // user, nice and idle are always there when the table is
+			return
+		}
+		n[i] = float64(v) / 100 // USER_HZ
+	}
+	t := &models.CPUTimes{
+		User: n[0], Nice: n[1], Idle: n[2], IOWait: n[3],
+		System: n[4], IRQ: n[5], SoftIRQ: n[6], Steal: n[7],
+	}
+	if _, ok := raw[4].Uint(); !ok {
+		t.System, t.IRQ, t.SoftIRQ = n[8], 0, 0
+	}
+	m.CPUTimes = t
+}
+
+// snmpColumn walks one table column into a map keyed by row index
+func snmpColumn(client *snmp.Client, column snmp.OID) (map[string]snmp.VarBind, error) {
+	rows := make(map[string]snmp.VarBind)
+	err := client.Walk(column, func(vb snmp.VarBind) error {
+		rows[vb.OID[len(column):].String()] = vb
+		return nil
+	})
+	return rows, err
+}
+
+// snmpInterfaces lists the non-loopback interfaces, totals their traffic
+// and counts physical ports. 64-bit ifXTable counters are used when
+// present; an interface with only ifTable counters for either direction
+// wraps at 2^32. Error and discard counters only exist as 32-bit counters.
+func snmpInterfaces(client *snmp.Client, m *models.HostMetrics) error {
+	types, err := snmpColumn(client, oidIfType)
+	if err != nil {
+		return err
+	}
+	if len(types) == 0 {
+		return errors.New("no interfaces")
+	}
+	columns := make(map[string]map[string]snmp.VarBind)
+	for name, oid := range map[string]snmp.OID{
+		"descr": oidIfDescr, "name": oidIfName, "speed": oidIfSpeed, "highspeed": oidIfHighSpeed, "oper": oidIfOperStatus,
+		"in": oidIfInOctets, "out": oidIfOutOctets, "hcin": oidIfHCInOctets, "hcout": oidIfHCOutOctets,
+		"inucast": oidIfInUcastPkts, "innucast": oidIfInNUcastPkts, "outucast": oidIfOutUcastPkts, "outnucast": oidIfOutNUcastPkts,
+		"hcinucast": oidIfHCInUcastPkts, "hcinmcast": oidIfHCInMulticastPkts, "hcinbcast": oidIfHCInBroadcastPkts,
+		"hcoutucast": oidIfHCOutUcastPkts, "hcoutmcast": oidIfHCOutMulticastPkts, "hcoutbcast": oidIfHCOutBroadcastPkts,
+		"indiscards": oidIfInDiscards, "inerrors": oidIfInErrors, "outdiscards": oidIfOutDiscards, "outerrors": oidIfOutErrors,
+	} {
+		if columns[name], err = snmpColumn(client, oid); err != nil {
+			return err
+		}
+	}
+	value := func(index string, names ...string) uint64 {
+		var total uint64
+		for _, name := range names {
+			n, _ := columns[name][index].Uint()
+			total += n
+		}
+		return total
+	}
+
+	counter := func(hc, legacy, index string) (uint64, bool) {
+		if n, ok := columns[hc][index].Uint(); ok {
+			return n, true
+		}
+		n, _ := columns[legacy][index].Uint()
+		return n, false
+	}
+
+	var rx, tx uint64
+	ports, others := 0, 0
+	m.Interfaces = []models.NetworkInterface{}
+	for index, vb := range types {
+		ifType, _ := vb.Int()
+		if ifType == ifTypeSoftwareLoopback {
+			continue
+		}
+		if physicalIfTypes[ifType] {
+			ports++
+		} else {
+			others++
+		}
+
+		iface := models.NetworkInterface{
+			Name:      snmpInterfaceName(columns["name"][index], columns["descr"][index], index),
+			OperState: "unknown",
+			RxErrors:  value(index, "inerrors"), TxErrors: value(index, "outerrors"),
+			RxDropped: value(index, "indiscards"), TxDropped: value(index, "outdiscards"),
+		}
+		if status, ok := columns["oper"][index].Int(); ok && ifOperStates[status] != "" {
+			iface.OperState = ifOperStates[status]
+		}
+		// ifHighSpeed is in Mbit/s; ifSpeed in bit/s saturates at 4.29 Gbit/s
+		if speed, ok := columns["highspeed"][index].Uint(); ok && speed > 0 {
+			iface.SpeedMbps = int64(speed)
+		} else if speed, ok := columns["speed"][index].Uint(); ok {
+			iface.SpeedMbps = int64(speed / 1000000)
+		}
+		var hcIn, hcOut bool
+		iface.RxBytes, hcIn = counter("hcin", "in", index)
+		iface.TxBytes, hcOut = counter("hcout", "out", index)
+		if hcIn {
+			iface.RxPackets = value(index, "hcinucast", "hcinmcast", "hcinbcast")
+		} else {
+			iface.RxPackets = value(index, "inucast", "innucast")
+		}
+		if hcOut {
+			iface.TxPackets = value(index, "hcoutucast", "hcoutmcast", "hcoutbcast")
+		} else {
+			iface.TxPackets = value(index, "outucast", "outnucast")
+		}
+		iface.Counter32 = !hcIn || !hcOut
+		m.Interfaces = append(m.Interfaces, iface)
+		rx += iface.RxBytes
+		tx += iface.TxBytes
+	}
+	if ports == 0 {
+		ports = others
+	}
+	sort.Slice(m.Interfaces, func(i, j int) bool { return m.Interfaces[i].Name < m.Interfaces[j].Name })
+	m.PortCount = ports
+	m.NetworkRxMB = float64(rx) / (1024 * 1024)
+	m.NetworkTxMB = float64(tx) / (1024 * 1024)
+	return nil
+}
+
+// snmpInterfaceName names an interface by ifName, falling back to ifDescr
+// and then to its ifIndex
+func snmpInterfaceName(name, descr snmp.VarBind, index string) string {
+	for _, vb := range []snmp.VarBind{name, descr} {
+		if s, ok := vb.Text(); ok && strings.TrimSpace(s) != "" {
+			return strings.TrimSpace(s)
+		}
+	}
+	return "if" + index
+}
+
+// snmpStorage reads memory and fixed disks from hrStorageTable
+func snmpStorage(client *snmp.Client, m *models.HostMetrics) []MetricError {
+	fail := func(field, problem string) []MetricError {
+		return []MetricError{{Section: "HOST-RESOURCES-MIB", Field: field, Problem: problem}}
+	}
+	columns := make(map[string]map[string]snmp.VarBind)
+	for name, oid := range map[string]snmp.OID{"type": oidHrStorageType, "descr": oidHrStorageDescr, "units": oidHrStorageUnits, "size": oidHrStorageSize, "used": oidHrStorageUsed} {
+		rows, err := snmpColumn(client, oid)
+		if err != nil {
+			return fail("hrStorageTable", err.Error())
+		}
+		columns[name] = rows
+	}
+	if len(columns["type"]) == 0 {
+		return fail("hrStorageTable", "missing")
+	}
+
+	// Net-SNMP reports page cache and buffers as used RAM and lists them
+	// separately, so they are subtracted like /proc/meminfo's MemAvailable
+	m.Filesystems = []models.Filesystem{}
+	var ramTotal, ramUsed, reclaimable float64
+	haveRAM, haveRoot := false, false
+	for index, vb := range columns["type"] {
+		storageType, _ := vb.OIDValue()
+		units, _ := columns["units"][index].Uint()
+		size, _ := columns["size"][index].Uint()
+		used, _ := columns["used"][index].Uint()
+		descr, _ := columns["descr"][index].Text()
+		total := float64(size) * float64(units)
+		usedBytes := float64(used) * float64(units)
+
+		switch {
+		case storageType.Compare(oidHrStorageRAM) == 0:
+			ramTotal += total
+			ramUsed += usedBytes
+			haveRAM = true
+		case storageType.Compare(oidHrStorageOther) == 0 && (descr == "Cached memory" || descr == "Memory buffers"):
+			reclaimable += usedBytes
+		case storageType.Compare(oidHrStorageFixed) == 0 && size > 0:
+			// The MIB has no type, device or inodes, nor reserved blocks
+			pct := usedBytes / total * 100
+			if descr == "/" || !haveRoot {
+				m.DiskTotal = total / (1024 * 1024 * 1024)
+				m.DiskUsage = usedBytes / (1024 * 1024 * 1024)
+				m.DiskPercent = pct
+				haveRoot = descr == "/"
+			}
+			fs := models.Filesystem{Mountpoint: descr, SizeBytes: uint64(total), UsedBytes: uint64(usedBytes), UsedPercent: pct}
+			if usedBytes < total {
+				fs.AvailBytes = uint64(total - usedBytes)
+			}
+			m.Filesystems = append(m.Filesystems, fs)
+		}
+	}
+	if !haveRAM {
+		return fail("hrStorageRam", "missing")
+	}
+	if reclaimable < ramUsed {
+		ramUsed -= reclaimable
+	}
+	m.MemoryTotal = ramTotal / (1024 * 1024)
+	m.MemoryUsed = ramUsed / (1024 * 1024)
+	return nil
+}
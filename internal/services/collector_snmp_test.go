@@ -0,0 +1,218 @@
+package services
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/snmp"
+	"server-dashboard/internal/snmp/snmptest"
+)
+
+// withConfig replaces the configuration for one test
+func withConfig(t *testing.T, cfg *config.Config) {
+	t.Helper()
+	old := Config
+	Config = cfg
+	t.Cleanup(func() { Config = old })
+}
+
+// snmpTestValues is a net-snmp style Linux host: a loopback and four
+// ethernet interfaces, RAM with buffers and cache, and two disks
+func snmpTestValues() []snmp.VarBind {
+	values := []snmp.VarBind{
+		{OID: oidSysDescr, Type: snmp.OctetString, Value: []byte("Linux sw1 6.1.0\nsecond line")},
+		{OID: oidSysUpTime, Type: snmp.TimeTicks, Value: uint64(100)},
+		{OID: oidHrSystemUptime, Type: snmp.TimeTicks, Value: uint64(90000 * 100)},
+		{OID: oidHrSystemProcesses, Type: snmp.Gauge32, Value: uint64(77)},
+		{OID: oidLaLoad.Append(1), Type: snmp.OctetString, Value: []byte("0.10")},
+		{OID: oidLaLoad.Append(2), Type: snmp.OctetString, Value: []byte("0.20")},
+		{OID: oidLaLoad.Append(3), Type: snmp.OctetString, Value: []byte("0.30")},
+	}
+	for i := uint32(1); i <= 5; i++ {
+		ifType := int64(6)
+		if i == 1 {
+			ifType = ifTypeSoftwareLoopback
+		}
+		values = append(values,
+			snmp.VarBind{OID: oidIfType.Append(i), Type: snmp.Integer, Value: ifType},
+			snmp.VarBind{OID: oidIfOperStatus.Append(i), Type: snmp.Integer, Value: int64(1)},
+			snmp.VarBind{OID: oidIfInOctets.Append(i), Type: snmp.Counter32, Value: uint64(1024 * 1024)},
+			snmp.VarBind{OID: oidIfOutOctets.Append(i), Type: snmp.Counter32, Value: uint64(1024 * 1024)},
+			snmp.VarBind{OID: oidIfHCInOctets.Append(i), Type: snmp.Counter64, Value: uint64(10 * 1024 * 1024)},
+		)
+	}
+	storage := []struct {
+		storageType       snmp.OID
+		descr             string
+		units, size, used int64
+	}{
+		{oidHrStorageRAM, "Physical memory", 1024, 4 * 1024 * 1024, 3 * 1024 * 1024},
+		{oidHrStorageOther, "Memory buffers", 1024, 4 * 1024 * 1024, 512 * 1024},
+		{oidHrStorageOther, "Cached memory", 1024, 1024 * 1024, 1024 * 1024},
+		{oidHrStorageFixed, "/boot", 4096, 1000, 950},
+		{oidHrStorageFixed, "/", 4096, 1024 * 1024, 256 * 1024},
+	}
+	for i, s := range storage {
+		index := uint32(i + 1)
+		values = append(values,
+			snmp.VarBind{OID: oidHrStorageType.Append(index), Type: snmp.ObjectIdentifier, Value: s.storageType},
+			snmp.VarBind{OID: oidHrStorageDescr.Append(index), Type: snmp.OctetString, Value: []byte(s.descr)},
+			snmp.VarBind{OID: oidHrStorageUnits.Append(index), Type: snmp.Integer, Value: s.units},
+			snmp.VarBind{OID: oidHrStorageSize.Append(index), Type: snmp.Integer, Value: s.size},
+			snmp.VarBind{OID: oidHrStorageUsed.Append(index), Type: snmp.Integer, Value: s.used},
+		)
+	}
+	return values
+}
+
+func startSNMPAgent(t *testing.T, cfg snmp.AgentConfig, values []snmp.VarBind) *snmptest.Agent {
+	t.Helper()
+	a, err := snmptest.NewAgent("127.0.0.1:0", cfg, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { a.Close() })
+	return a
+}
+
+func dialSNMP(t *testing.T, a *snmptest.Agent, cfg snmp.Config) *snmp.Client {
+	t.Helper()
+	cfg.Address = a.Addr()
+	cfg.Timeout = 200 * time.Millisecond
+	c, err := snmp.Dial(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestCollectSNMP(t *testing.T) {
+	tests := []struct {
+		name  string
+		agent snmp.AgentConfig
+		cfg   snmp.Config
+	}{
+		{"v2c",
+			snmp.AgentConfig{Community: "monitor"},
+			snmp.Config{Version: "2c", Community: "monitor"}},
+		{"v3 authNoPriv",
+			snmp.AgentConfig{Username: "mon", AuthProtocol: snmp.AuthSHA, AuthPassword: "authpass1"},
+			snmp.Config{Version: "3", Username: "mon", AuthProtocol: snmp.AuthSHA, AuthPassword: "authpass1"}},
+		{"v3 authPriv",
+			snmp.AgentConfig{Username: "mon", AuthProtocol: snmp.AuthSHA256, AuthPassword: "authpass1", PrivProtocol: snmp.PrivAES, PrivPassword: "privpass1"},
+			snmp.Config{Version: "3", Username: "mon", AuthProtocol: snmp.AuthSHA256, AuthPassword: "authpass1", PrivProtocol: snmp.PrivAES, PrivPassword: "privpass1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := dialSNMP(t, startSNMPAgent(t, tt.agent, snmpTestValues()), tt.cfg)
+			m, errs, err := collectSNMP(client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(errs) != 0 {
+				t.Errorf("metric errors: %v", errs)
+			}
+			if m.KernelVersion != "Linux sw1 6.1.0" || m.Uptime != "1 days 1 hours" || m.Processes != 77 || m.LoadAverage != "0.10 0.20 0.30" {
+				t.Errorf("system: kernel %q, uptime %q, processes %d, load %q", m.KernelVersion, m.Uptime, m.Processes, m.LoadAverage)
+			}
+			// The loopback is neither a port nor counted in the totals; HC
+			// counters win over the 32-bit ones
+			if m.PortCount != 4 || len(m.Interfaces) != 4 || m.NetworkRxMB != 40 || m.NetworkTxMB != 4 {
+				t.Errorf("interfaces: %d ports, %d interfaces, rx %v MB, tx %v MB", m.PortCount, len(m.Interfaces), m.NetworkRxMB, m.NetworkTxMB)
+			}
+			// Buffers and cache do not count as used memory
+			if m.MemoryTotal != 4096 || m.MemoryUsed != 1536 {
+				t.Errorf("memory: %v of %v MB used", m.MemoryUsed, m.MemoryTotal)
+			}
+			if m.DiskPercent != 25 || len(m.Filesystems) != 2 {
+				t.Errorf("disks: root %v%% used, %d filesystems", m.DiskPercent, len(m.Filesystems))
+			}
+		})
+	}
+}
+
+// Agents with only MIB-II, as on most switches, still yield a sample
+func TestCollectSNMPWithoutHostResources(t *testing.T) {
+	a := startSNMPAgent(t, snmp.AgentConfig{Community: "monitor"}, snmpTestValues()[:2])
+	m, errs, err := collectSNMP(dialSNMP(t, a, snmp.Config{Version: "2c", Community: "monitor"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Uptime == "N/A" {
+		t.Errorf("uptime = %q", m.Uptime)
+	}
+	var sections []string
+	for _, e := range errs {
+		sections = append(sections, e.Section+" "+e.Field)
+	}
+	if got := strings.Join(sections, ", "); got != "IF-MIB ifTable, HOST-RESOURCES-MIB hrStorageTable" {
+		t.Errorf("metric errors = %q", got)
+	}
+}
+
+func TestCollectSNMPWrongCredentials(t *testing.T) {
+	a := startSNMPAgent(t, snmp.AgentConfig{
+		Community: "monitor",
+		Username:  "mon", AuthProtocol: snmp.AuthSHA256, AuthPassword: "authpass1", PrivProtocol: snmp.PrivAES, PrivPassword: "privpass1",
+	}, snmpTestValues())
+
+	tests := []struct {
+		name string
+		cfg  snmp.Config
+		want string
+	}{
+		{"wrong community",
+			snmp.Config{Version: "2c", Community: "public"}, snmp.ErrTimeout.Error()},
+		{"wrong auth password",
+			snmp.Config{Version: "3", Username: "mon", AuthProtocol: snmp.AuthSHA256, AuthPassword: "wrongpass", PrivProtocol: snmp.PrivAES, PrivPassword: "privpass1"},
+			"wrong auth password"},
+		{"wrong priv password",
+			snmp.Config{Version: "3", Username: "mon", AuthProtocol: snmp.AuthSHA256, AuthPassword: "authpass1", PrivProtocol: snmp.PrivAES, PrivPassword: "wrongpass"},
+			"decryption failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _, err := collectSNMP(dialSNMP(t, a, tt.cfg))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("collectSNMP = %v, want an error containing %q", err, tt.want)
+			}
+			if m != nil {
+				t.Errorf("got a sample: %+v", m)
+			}
+		})
+	}
+}
+
+// The collector reads the device's snmp settings, protocol names included
+func TestSNMPCollectorUsesDeviceConfig(t *testing.T) {
+	a := startSNMPAgent(t, snmp.AgentConfig{
+		Username: "mon", AuthProtocol: snmp.AuthSHA256, AuthPassword: "authpass1", PrivProtocol: snmp.PrivAES, PrivPassword: "privpass1",
+	}, snmpTestValues())
+	host, portText, _ := net.SplitHostPort(a.Addr())
+	port, _ := strconv.Atoi(portText)
+	withConfig(t, &config.Config{Switches: []config.SwitchConfig{{ID: "snmp-test", SNMP: &config.SNMPConfig{
+		Version: "3", Port: port, TimeoutSeconds: 1,
+		Username: "mon", AuthProtocol: "sha-256", AuthPassword: "authpass1", PrivProtocol: "aes", PrivPassword: "privpass1",
+	}}}})
+	t.Cleanup(func() {
+		snmpClientsMu.Lock()
+		if c := snmpClients[KindSwitch+"/snmp-test"]; c != nil {
+			c.Close()
+			delete(snmpClients, KindSwitch+"/snmp-test")
+		}
+		snmpClientsMu.Unlock()
+	})
+
+	m, _, err := snmpCollector{}.Collect(CollectTarget{Kind: KindSwitch, ID: "snmp-test", Address: host})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.PortCount != 4 {
+		t.Errorf("PortCount = %d, want 4", m.PortCount)
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import "server-dashboard/internal/models"
+
+// Devices depend on their parents: VMs on their host server, and servers
+// and VMs on their uplink switch. A device that is down while a parent is
+// down cannot be told apart from one that failed itself, so it shows as
+// unreachable and its alerts are held back until the parent recovers.
+
+// downParent describes the first of a device's parents that is down, e.g.
+// "host server Web Server 1", or returns "" when none is. A parent is down
+// when its own probes fail, whether it shows as offline or unreachable.
+func downParent(hostServerID, uplinkSwitchID string) string {
+	snap := inventory.Snapshot()
+	if srv, ok := snapshotServer(snap, hostServerID); ok && srv.PingStatus == models.StatusOffline {
+		return "host server " + srv.Name
+	}
+	if sw, ok := snapshotSwitch(snap, uplinkSwitchID); ok && sw.PingStatus == models.StatusOffline {
+		return "uplink switch " + sw.Name
+	}
+	return ""
+}
+
+// downStatus is the status of a device that is down: unreachable while a
+// parent is down, offline otherwise
+func downStatus(parentDown string) string {
+	if parentDown != "" {
+		return models.StatusUnreachable
+	}
+	return models.StatusOffline
+}
+
+// labelDownServer sets the status of a server that is down from its
+// uplink switch as the inventory has it now. It runs inside inventory
+// updates, so that a label always reflects the latest parent state
+// whichever of a check and a relabel is stored last.
+func labelDownServer(s *models.Server) {
+	if s.PingStatus != models.StatusOffline {
+		return
+	}
+	s.ParentDown = downParent("", s.UplinkSwitchID)
+	s.Status = downStatus(s.ParentDown)
+}
+
+// labelDownVM sets the status of a VM that is down from its host server
+// and uplink switch as the inventory has them now
+func labelDownVM(vm *models.VM) {
+	if vm.PingStatus != models.StatusOffline {
+		return
+	}
+	vm.ParentDown = downParent(vm.HostServerID, vm.UplinkSwitchID)
+	vm.Status = downStatus(vm.ParentDown)
+}
+
+// relabelDependents updates the devices that depend on a server or switch
+// after it went down or came back. Dependents that are down themselves
+// switch between offline and unreachable right away instead of at their
+// next check. A check of a dependent that is stored later labels it the
+// same way, so the relabel is never lost.
+func relabelDependents(kind, id string) {
+	snap := inventory.Snapshot()
+	if kind == KindSwitch {
+		for _, srv := range snap.Servers {
+			if srv.UplinkSwitchID == id && srv.PingStatus == models.StatusOffline &&
+				srv.ParentDown != downParent("", srv.UplinkSwitchID) {
+				inventory.UpdateServer(srv.ID, labelDownServer)
+			}
+		}
+	}
+	for _, vm := range snap.VMs {
+		dependent := (kind == KindServer && vm.HostServerID == id) || (kind == KindSwitch && vm.UplinkSwitchID == id)
+		if dependent && vm.PingStatus == models.StatusOffline &&
+			vm.ParentDown != downParent(vm.HostServerID, vm.UplinkSwitchID) {
+			inventory.UpdateVM(vm.ID, labelDownVM)
+		}
+	}
+}
+
+// unreachableVia returns the parent that is down for an unreachable device,
+// or "" if the device is not unreachable
+func unreachableVia(kind, id string) string {
+	snap := inventory.Snapshot()
+	switch kind {
+	case KindServer:
+		if srv, ok := snapshotServer(snap, id); ok && srv.Status == models.StatusUnreachable {
+			return srv.ParentDown
+		}
+	case KindVM:
+		if vm, ok := snapshotVM(snap, id); ok && vm.Status == models.StatusUnreachable {
+			return vm.ParentDown
+		}
+	}
+	return ""
+}
+
+// DependencyParents returns the devices a device depends on: a VM's host
+// server and uplink switch, or a server's uplink switch. Each node's
+// children are in turn the devices it depends on.
+func DependencyParents(kind, id string) []models.DependencyNode {
+	return dependencyParents(inventory.Snapshot(), kind, id)
+}
+
+func dependencyParents(snap *InventorySnapshot, kind, id string) []models.DependencyNode {
+	var hostID, uplinkID string
+	switch kind {
+	case KindServer:
+		if srv, ok := snapshotServer(snap, id); ok {
+			uplinkID = srv.UplinkSwitchID
+		}
+	case KindVM:
+		if vm, ok := snapshotVM(snap, id); ok {
+			hostID, uplinkID = vm.HostServerID, vm.UplinkSwitchID
+		}
+	}
+
+	var out []models.DependencyNode
+	if srv, ok := snapshotServer(snap, hostID); ok {
+		n := dependencyNode(KindServer, srv.ID, srv.Name, srv.Status, models.DependencyHost)
+		n.Children = dependencyParents(snap, KindServer, srv.ID)
+		out = append(out, n)
+	}
+	if sw, ok := snapshotSwitch(snap, uplinkID); ok {
+		out = append(out, dependencyNode(KindSwitch, sw.ID, sw.Name, sw.Status, models.DependencyUplink))
+	}
+	return out
+}
+
+// DependencyChildren returns the devices that depend on a server or switch.
+// Each node's children are in turn the devices depending on it.
+func DependencyChildren(kind, id string) []models.DependencyNode {
+	return dependencyChildren(inventory.Snapshot(), kind, id)
+}
+
+func dependencyChildren(snap *InventorySnapshot, kind, id string) []models.DependencyNode {
+	var out []models.DependencyNode
+	if kind == KindSwitch {
+		for _, srv := range snap.Servers {
+			if srv.UplinkSwitchID == id {
+				n := dependencyNode(KindServer, srv.ID, srv.Name, srv.Status, models.DependencyUplink)
+				n.Children = dependencyChildren(snap, KindServer, srv.ID)
+				out = append(out, n)
+			}
+		}
+	}
+	for _, vm := range snap.VMs {
+		switch {
+		case kind == KindServer && vm.HostServerID == id:
+			out = append(out, dependencyNode(KindVM, vm.ID, vm.Name, vm.Status, models.DependencyHost))
+		case kind == KindSwitch && vm.UplinkSwitchID == id:
+			out = append(out, dependencyNode(KindVM, vm.ID, vm.Name, vm.Status, models.DependencyUplink))
+		}
+	}
+	return out
+}
+
+func dependencyNode(kind, id, name, status, relation string) models.DependencyNode {
+	return models.DependencyNode{
+		Kind: kind, ID: id, Name: name, Status: status,
+		Relation: relation, Link: TargetPath(kind, id),
+	}
+}
+
+func snapshotServer(snap *InventorySnapshot, id string) (models.Server, bool) {
+	for _, srv := range snap.Servers {
+		if id != "" && srv.ID == id {
+			return srv, true
+		}
+	}
+	return models.Server{}, false
+}
+
+func snapshotVM(snap *InventorySnapshot, id string) (models.VM, bool) {
+	for _, vm := range snap.VMs {
+		if id != "" && vm.ID == id {
+			return vm, true
+		}
+	}
+	return models.VM{}, false
+}
+
+func snapshotSwitch(snap *InventorySnapshot, id string) (models.Switch, bool) {
+	for _, sw := range snap.Switches {
+		if id != "" && sw.ID == id {
+			return sw, true
+		}
+	}
+	return models.Switch{}, false
+}
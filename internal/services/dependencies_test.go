@@ -0,0 +1,37 @@
+package services
+
+import (
+	"testing"
+
+	"server-dashboard/internal/models"
+)
+
+func TestRelabelSurvivesStaleCheckResult(t *testing.T) {
+	sw := models.Switch{ID: "sw1", Name: "Core", PingStatus: models.StatusOffline, Status: models.StatusOffline}
+	srv := models.Server{ID: "srv1", Name: "Web", UplinkSwitchID: "sw1", PingStatus: models.StatusOffline, Status: models.StatusOffline}
+	vm := models.VM{ID: "vm1", HostServerID: "srv1", PingStatus: models.StatusOffline, Status: models.StatusOffline}
+	withInventory(t, []models.Server{srv}, []models.VM{vm}, []models.Switch{sw})
+
+	// A check of the server started while the switch was still up and
+	// finishes after the switch went down and its dependents were relabelled
+	stale, _ := inventory.Server("srv1")
+	relabelDependents(KindSwitch, "sw1")
+	if got, _ := inventory.Server("srv1"); got.Status != models.StatusUnreachable || got.ParentDown != "uplink switch Core" {
+		t.Fatalf("relabel: status %q, parent %q", got.Status, got.ParentDown)
+	}
+	got, _ := inventory.UpdateServer("srv1", func(s *models.Server) {
+		*s = stale
+		labelDownServer(s)
+	})
+	if got.Status != models.StatusUnreachable || got.ParentDown != "uplink switch Core" {
+		t.Fatalf("after stale check: status %q, parent %q", got.Status, got.ParentDown)
+	}
+	if via := unreachableVia(KindServer, "srv1"); via != "uplink switch Core" {
+		t.Fatalf("unreachableVia = %q", via)
+	}
+
+	relabelDependents(KindServer, "srv1")
+	if got, _ := inventory.VM("vm1"); got.Status != models.StatusUnreachable || got.ParentDown != "host server Web" {
+		t.Fatalf("vm: status %q, parent %q", got.Status, got.ParentDown)
+	}
+}
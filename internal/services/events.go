@@ -0,0 +1,219 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/eventlog"
+	"server-dashboard/internal/models"
+)
+
+// events is the state-transition log. It is nil when it could not be opened.
+var events *eventlog.Log
+
+// initEvents opens the log under the data directory and starts recording
+// device transitions from inventory changes. Synthetic checks record their
+// own transitions.
+func initEvents(cfg *config.Config) {
+	l, err := eventlog.Open(eventlog.Options{
+		Dir:       filepath.Join(cfg.DataDir(), "events"),
+		Retention: time.Duration(cfg.Events.RetentionDays) * 24 * time.Hour,
+	})
+	if err != nil {
+		log.Printf("Event log disabled: %v", err)
+		return
+	}
+	events = l
+
+	changes, _ := inventory.Subscribe(1024)
+	go func() {
+		for change := range changes {
+			if change.Old == nil {
+				continue
+			}
+			old, ok1 := exportDevice(change.Old)
+			cur, ok2 := exportDevice(change.New)
+			if ok1 && ok2 {
+				for _, e := range deviceEvents(old, cur) {
+					recordEvent(e)
+				}
+			}
+		}
+	}()
+}
+
+// recordEvent appends an event to the log and writes it to the application
+// log
+func recordEvent(e models.Event) {
+	if events == nil {
+		return
+	}
+	what := e.Type
+	if e.Subject != "" {
+		what += " " + e.Subject
+	}
+	log.Printf("Event: %s %s %s %s -> %s", e.TargetKind, e.TargetName, what, e.Old, e.New)
+	if _, err := events.Append(e); err != nil {
+		log.Printf("Failed to record event: %v", err)
+	}
+}
+
+// deviceEvents compares two states of a device
+func deviceEvents(old, cur exportedDevice) []models.Event {
+	var out []models.Event
+	event := func(typ, subject, from, to, reason string) {
+		out = append(out, models.Event{
+			Type:       typ,
+			TargetKind: cur.kind,
+			TargetID:   cur.id,
+			TargetName: cur.name,
+			Subject:    subject,
+			Old:        from,
+			New:        to,
+			Reason:     reason,
+			Error:      cur.collectionError,
+		})
+	}
+
+	if old.ping != cur.ping {
+		event(models.EventPing, "", old.ping, cur.ping, probeReason(cur))
+	}
+	if old.state.Flapping != cur.state.Flapping {
+		event(models.EventFlapping, "", models.FlapState(old.state.Flapping), models.FlapState(cur.state.Flapping), flapReason(cur.state))
+	}
+	if old.status != cur.status {
+		reason := probeReason(cur)
+		if cur.ping == models.StatusOnline && cur.collectionError != "" {
+			reason = "collection failed"
+		}
+		if cur.parentDown != "" {
+			reason = cur.parentDown + " is down"
+		}
+		event(models.EventStatus, "", old.status, cur.status, reason)
+	}
+	// Only compare counts from two successful collections; offline devices
+	// and failed collections keep the last count
+	if !old.collectedAt.IsZero() && cur.collectedAt.After(old.collectedAt) &&
+		old.metrics.FailedServices != cur.metrics.FailedServices {
+		event(models.EventFailedServices, "", strconv.Itoa(old.metrics.FailedServices), strconv.Itoa(cur.metrics.FailedServices), failedUnitsReason(cur))
+	}
+
+	was := make(map[int]models.StreamStatus, len(old.streams))
+	for _, s := range old.streams {
+		was[s.Port] = s
+	}
+	for _, s := range cur.streams {
+		subject := "port " + strconv.Itoa(s.Port)
+		prev, ok := was[s.Port]
+		if prev.Flapping != s.Flapping {
+			event(models.EventFlapping, subject, models.FlapState(prev.Flapping), models.FlapState(s.Flapping), flapReason(s.StateTracker))
+		}
+		if ok && prev.Active == s.Active {
+			continue
+		}
+		from := "unknown"
+		if ok {
+			from = streamState(prev.Active)
+		}
+		event(models.EventStream, subject, from, streamState(s.Active), "")
+	}
+	return out
+}
+
+func streamState(active bool) string {
+	if active {
+		return "up"
+	}
+	return "down"
+}
+
+// failedUnitsReason names the failed units, when the collector lists them
+func failedUnitsReason(d exportedDevice) string {
+	if len(d.metrics.FailedUnits) == 0 {
+		return ""
+	}
+	names := make([]string, len(d.metrics.FailedUnits))
+	for i, u := range d.metrics.FailedUnits {
+		names[i] = u.Name
+	}
+	return "failed: " + strings.Join(names, ", ")
+}
+
+// flapReason counts the state changes behind a flapping change
+func flapReason(t models.StateTracker) string {
+	return fmt.Sprintf("%d state changes in the last %.0f minutes", len(t.StateChanges), stateHysteresis().FlapWindow.Minutes())
+}
+
+// probeReason summarizes the reachability probes: the first one that
+// succeeded, or why each failed
+func probeReason(d exportedDevice) string {
+	var failures []string
+	for _, p := range d.probes {
+		if p.Success {
+			return fmt.Sprintf("%s %s answered in %.1f ms", p.Type, p.Target, p.RTTMillis)
+		}
+		msg := p.Type + " " + p.Target
+		if p.Error != "" {
+			msg += ": " + p.Error
+		}
+		failures = append(failures, msg)
+	}
+	return strings.Join(failures, "; ")
+}
+
+// recordSyntheticEvent logs a synthetic check changing between ok and
+// fail, or starting or stopping flapping
+func recordSyntheticEvent(old models.SyntheticCheckResult, hadOld bool, cur models.SyntheticCheckResult) {
+	from := "unknown"
+	if hadOld {
+		from = old.Status
+	}
+	if old.Flapping != cur.Flapping {
+		recordEvent(models.Event{
+			Type:       models.EventFlapping,
+			TargetKind: KindSynthetic,
+			TargetID:   cur.ID,
+			TargetName: cur.Name,
+			Old:        models.FlapState(old.Flapping),
+			New:        models.FlapState(cur.Flapping),
+			Reason:     flapReason(cur.StateTracker),
+		})
+	}
+	if from == cur.Status {
+		return
+	}
+	recordEvent(models.Event{
+		Type:       models.EventSynthetic,
+		TargetKind: KindSynthetic,
+		TargetID:   cur.ID,
+		TargetName: cur.Name,
+		Old:        from,
+		New:        cur.Status,
+		Reason:     cur.Message,
+	})
+}
+
+// EventsEnabled reports whether transitions are being recorded
+func EventsEnabled() bool {
+	return events != nil
+}
+
+// QueryEvents returns a page of events, newest first
+func QueryEvents(q eventlog.Query) (eventlog.Page, error) {
+	if events == nil {
+		return eventlog.Page{Events: []models.Event{}}, nil
+	}
+	return events.Query(q)
+}
+
+// CloseEvents closes the event log on shutdown
+func CloseEvents() {
+	if events != nil {
+		events.Close()
+	}
+}
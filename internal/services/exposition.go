@@ -0,0 +1,526 @@
+package services
+
+import (
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"server-dashboard/internal/models"
+	"server-dashboard/internal/promtext"
+)
+
+// SSH collection failures per host, exported as dashboard_ssh_errors_total
+var (
+	sshErrorsMu sync.Mutex
+	sshErrors   = make(map[string]uint64)
+)
+
+// recordSSHError counts a failed SSH collection against a host
+func recordSSHError(host string) {
+	sshErrorsMu.Lock()
+	sshErrors[host]++
+	sshErrorsMu.Unlock()
+}
+
+// exportedDevice is the part of a server, VM or switch that is exported
+// here, recorded in the metrics history and watched for events
+type exportedDevice struct {
+	kind, id, name  string
+	tags            []string
+	status, ping    string
+	rtt, loss       float64
+	probes          []models.ProbeResult
+	checkedAt       time.Time
+	collectedAt     time.Time
+	stale           bool
+	state           models.StateTracker
+	parentDown      string
+	collectionError string
+	metrics         models.HostMetrics
+	memoryPercent   float64
+	cpu             *models.CPUUsage      // Nil until two samples could be compared
+	streams         []models.StreamStatus // VMs only
+}
+
+func exportedDevices(snap *InventorySnapshot) []exportedDevice {
+	var out []exportedDevice
+	for _, s := range snap.Servers {
+		out = append(out, exportServer(s))
+	}
+	for _, vm := range snap.VMs {
+		out = append(out, exportVM(vm))
+	}
+	for _, sw := range snap.Switches {
+		out = append(out, exportSwitch(sw))
+	}
+	return out
+}
+
+// exportDevice returns the exported view of an inventory value
+func exportDevice(device interface{}) (exportedDevice, bool) {
+	switch d := device.(type) {
+	case models.Server:
+		return exportServer(d), true
+	case models.VM:
+		return exportVM(d), true
+	case models.Switch:
+		return exportSwitch(d), true
+	}
+	return exportedDevice{}, false
+}
+
+func exportServer(s models.Server) exportedDevice {
+	return exportedDevice{
+		kind: KindServer, id: s.ID, name: s.Name, tags: s.Tags,
+		status: s.Status, ping: s.PingStatus, rtt: s.RTTMillis, loss: s.PacketLoss,
+		checkedAt: s.LastChecked, collectedAt: s.MetricsCollectedAt, stale: s.MetricsStale, memoryPercent: s.MemoryPercent, cpu: s.CPU,
+		probes: s.Probes, collectionError: s.CollectionError, state: s.StateTracker, parentDown: s.ParentDown,
+		metrics: models.HostMetrics{
+			Processes: s.Processes, DiskUsage: s.DiskUsage, DiskTotal: s.DiskTotal, DiskPercent: s.DiskPercent,
+			FullPartitions: s.FullPartitions, Filesystems: s.Filesystems, MemoryUsed: s.MemoryUsed, MemoryTotal: s.MemoryTotal,
+			LoadAverage: s.LoadAverage, FailedServices: s.FailedServices,
+			FailedUnits: s.FailedUnits, Services: s.WatchedServices,
+			CPUCores: s.CPUCores, Pressure: s.Pressure,
+			InodeUsed: s.InodeUsed, InodeTotal: s.InodeTotal, InodePercent: s.InodePercent,
+			NetworkRxMB: s.NetworkRxMB, NetworkTxMB: s.NetworkTxMB, Interfaces: s.Interfaces,
+		},
+	}
+}
+
+func exportVM(vm models.VM) exportedDevice {
+	return exportedDevice{
+		kind: KindVM, id: vm.ID, name: vm.Name, tags: vm.Tags,
+		status: vm.Status, ping: vm.PingStatus, rtt: vm.RTTMillis, loss: vm.PacketLoss,
+		checkedAt: vm.LastChecked, collectedAt: vm.MetricsCollectedAt, stale: vm.MetricsStale, memoryPercent: vm.MemoryPercent, cpu: vm.CPU,
+		probes: vm.Probes, collectionError: vm.CollectionError, state: vm.StateTracker, parentDown: vm.ParentDown, streams: vm.Streams,
+		metrics: models.HostMetrics{
+			Processes: vm.Processes, DiskUsage: vm.DiskUsage, DiskTotal: vm.DiskTotal, DiskPercent: vm.DiskPercent,
+			FullPartitions: vm.FullPartitions, Filesystems: vm.Filesystems, MemoryUsed: vm.MemoryUsed, MemoryTotal: vm.MemoryTotal,
+			LoadAverage: vm.LoadAverage, FailedServices: vm.FailedServices,
+			FailedUnits: vm.FailedUnits, Services: vm.WatchedServices,
+			CPUCores: vm.CPUCores, Pressure: vm.Pressure,
+			InodeUsed: vm.InodeUsed, InodeTotal: vm.InodeTotal, InodePercent: vm.InodePercent,
+			NetworkRxMB: vm.NetworkRxMB, NetworkTxMB: vm.NetworkTxMB, Interfaces: vm.Interfaces,
+		},
+	}
+}
+
+func exportSwitch(sw models.Switch) exportedDevice {
+	return exportedDevice{
+		kind: KindSwitch, id: sw.ID, name: sw.Name, tags: sw.Tags,
+		status: sw.Status, ping: sw.PingStatus, rtt: sw.RTTMillis, loss: sw.PacketLoss,
+		checkedAt: sw.LastChecked, collectedAt: sw.MetricsCollectedAt, stale: sw.MetricsStale, memoryPercent: sw.MemoryPercent, cpu: sw.CPU,
+		probes: sw.Probes, collectionError: sw.CollectionError, state: sw.StateTracker,
+		metrics: models.HostMetrics{
+			Processes: sw.Processes, DiskUsage: sw.DiskUsage, DiskTotal: sw.DiskTotal, DiskPercent: sw.DiskPercent,
+			FullPartitions: sw.FullPartitions, Filesystems: sw.Filesystems, MemoryUsed: sw.MemoryUsed, MemoryTotal: sw.MemoryTotal,
+			LoadAverage: sw.LoadAverage, FailedServices: sw.FailedServices,
+			FailedUnits: sw.FailedUnits, Services: sw.WatchedServices,
+			CPUCores: sw.CPUCores, Pressure: sw.Pressure,
+			InodeUsed: sw.InodeUsed, InodeTotal: sw.InodeTotal, InodePercent: sw.InodePercent,
+			NetworkRxMB: sw.NetworkRxMB, NetworkTxMB: sw.NetworkTxMB, Interfaces: sw.Interfaces, PortCount: sw.PortCount,
+		},
+	}
+}
+
+// labels identifies a device in every series. Tags are joined with commas
+// so they can be matched with a regex such as tags=~".*,prod,.*".
+func (d exportedDevice) labels(extra ...promtext.Label) []promtext.Label {
+	tags := ""
+	if len(d.tags) > 0 {
+		tags = "," + strings.Join(d.tags, ",") + ","
+	}
+	return append([]promtext.Label{
+		{Name: "id", Value: d.id}, {Name: "name", Value: d.name},
+		{Name: "type", Value: d.kind}, {Name: "tags", Value: tags},
+	}, extra...)
+}
+
+// deviceGauge is one per-device metric. Metric values are only exported for
+// devices that have been collected at least once.
+type deviceGauge struct {
+	name, typ, help string
+	needsMetrics    bool
+	value           func(d exportedDevice) (float64, bool)
+}
+
+const mb = 1024 * 1024
+
+var deviceGauges = []deviceGauge{
+	{"dashboard_device_up", "gauge", "Whether the device is reachable, after hysteresis.", false,
+		func(d exportedDevice) (float64, bool) { return boolValue(d.ping == models.StatusOnline), true }},
+	{"dashboard_device_flapping", "gauge", "Whether the device changes between up and down too often.", false,
+		func(d exportedDevice) (float64, bool) { return boolValue(d.state.Flapping), true }},
+	{"dashboard_device_rtt_seconds", "gauge", "Round-trip time of the last ICMP probe.", false,
+		func(d exportedDevice) (float64, bool) { return d.rtt / 1000, d.rtt > 0 }},
+	{"dashboard_device_packet_loss_percent", "gauge", "ICMP packet loss in the last check.", false,
+		func(d exportedDevice) (float64, bool) { return d.loss, d.ping != "" }},
+	{"dashboard_device_metrics_stale", "gauge", "Whether the last metrics collection failed.", false,
+		func(d exportedDevice) (float64, bool) { return boolValue(d.stale), true }},
+	{"dashboard_device_metrics_collected_timestamp_seconds", "gauge", "When metrics were last collected successfully.", true,
+		func(d exportedDevice) (float64, bool) { return float64(d.collectedAt.Unix()), true }},
+	{"dashboard_device_disk_used_percent", "gauge", "Root filesystem usage.", true,
+		func(d exportedDevice) (float64, bool) { return d.metrics.DiskPercent, true }},
+	{"dashboard_device_disk_used_bytes", "gauge", "Root filesystem bytes used.", true,
+		func(d exportedDevice) (float64, bool) { return d.metrics.DiskUsage * 1024 * mb, true }},
+	{"dashboard_device_disk_size_bytes", "gauge", "Root filesystem size in bytes.", true,
+		func(d exportedDevice) (float64, bool) { return d.metrics.DiskTotal * 1024 * mb, true }},
+	{"dashboard_device_full_partitions", "gauge", "Number of filesystems over their full threshold.", true,
+		func(d exportedDevice) (float64, bool) { return float64(len(d.metrics.FullPartitions)), true }},
+	{"dashboard_device_inodes_used_percent", "gauge", "Root filesystem inode usage.", true,
+		func(d exportedDevice) (float64, bool) { return d.metrics.InodePercent, d.metrics.InodeTotal > 0 }},
+	{"dashboard_device_inodes_used", "gauge", "Root filesystem inodes used.", true,
+		func(d exportedDevice) (float64, bool) { return float64(d.metrics.InodeUsed), d.metrics.InodeTotal > 0 }},
+	{"dashboard_device_inodes_total", "gauge", "Root filesystem inodes.", true,
+		func(d exportedDevice) (float64, bool) { return float64(d.metrics.InodeTotal), d.metrics.InodeTotal > 0 }},
+	{"dashboard_device_memory_used_bytes", "gauge", "Memory in use, excluding reclaimable cache.", true,
+		func(d exportedDevice) (float64, bool) { return d.metrics.MemoryUsed * mb, true }},
+	{"dashboard_device_memory_total_bytes", "gauge", "Total memory.", true,
+		func(d exportedDevice) (float64, bool) { return d.metrics.MemoryTotal * mb, true }},
+	{"dashboard_device_memory_used_percent", "gauge", "Memory usage.", true,
+		func(d exportedDevice) (float64, bool) { return d.memoryPercent, d.metrics.MemoryTotal > 0 }},
+	{"dashboard_device_load1", "gauge", "1 minute load average.", true,
+		func(d exportedDevice) (float64, bool) { return loadValue(d.metrics.LoadAverage, 0) }},
+	{"dashboard_device_load5", "gauge", "5 minute load average.", true,
+		func(d exportedDevice) (float64, bool) { return loadValue(d.metrics.LoadAverage, 1) }},
+	{"dashboard_device_load15", "gauge", "15 minute load average.", true,
+		func(d exportedDevice) (float64, bool) { return loadValue(d.metrics.LoadAverage, 2) }},
+	{"dashboard_device_cpu_cores", "gauge", "Number of logical CPUs.", true,
+		func(d exportedDevice) (float64, bool) { return float64(d.metrics.CPUCores), d.metrics.CPUCores > 0 }},
+	{"dashboard_device_cpu_used_percent", "gauge", "CPU time not idle or waiting for I/O between the last two samples.", true,
+		func(d exportedDevice) (float64, bool) {
+			return cpuValue(d, func(u *models.CPUUsage) float64 { return u.UsedPercent })
+		}},
+	{"dashboard_device_cpu_user_percent", "gauge", "CPU time in user space, including nice, between the last two samples.", true,
+		func(d exportedDevice) (float64, bool) {
+			return cpuValue(d, func(u *models.CPUUsage) float64 { return u.UserPercent })
+		}},
+	{"dashboard_device_cpu_system_percent", "gauge", "CPU time in the kernel, including interrupts, between the last two samples.", true,
+		func(d exportedDevice) (float64, bool) {
+			return cpuValue(d, func(u *models.CPUUsage) float64 { return u.SystemPercent })
+		}},
+	{"dashboard_device_cpu_iowait_percent", "gauge", "CPU time idle while waiting for I/O between the last two samples.", true,
+		func(d exportedDevice) (float64, bool) {
+			return cpuValue(d, func(u *models.CPUUsage) float64 { return u.IOWaitPercent })
+		}},
+	{"dashboard_device_cpu_steal_percent", "gauge", "CPU time taken by the hypervisor for other guests between the last two samples.", true,
+		func(d exportedDevice) (float64, bool) {
+			return cpuValue(d, func(u *models.CPUUsage) float64 { return u.StealPercent })
+		}},
+	{"dashboard_device_processes", "gauge", "Number of processes.", true,
+		func(d exportedDevice) (float64, bool) { return float64(d.metrics.Processes), d.metrics.Processes > 0 }},
+	{"dashboard_device_failed_services", "gauge", "Number of failed systemd units.", true,
+		func(d exportedDevice) (float64, bool) { return float64(d.metrics.FailedServices), true }},
+	{"dashboard_device_services_down", "gauge", "Number of watched systemd units that are not active and enabled.", true,
+		func(d exportedDevice) (float64, bool) {
+			return float64(servicesDown(d.metrics.Services)), d.metrics.Services != nil
+		}},
+	{"dashboard_device_network_receive_bytes_total", "counter", "Bytes received on all interfaces except loopback.", true,
+		func(d exportedDevice) (float64, bool) { return d.metrics.NetworkRxMB * mb, true }},
+	{"dashboard_device_network_transmit_bytes_total", "counter", "Bytes transmitted on all interfaces except loopback.", true,
+		func(d exportedDevice) (float64, bool) { return d.metrics.NetworkTxMB * mb, true }},
+	{"dashboard_device_ports", "gauge", "Physical switch ports.", true,
+		func(d exportedDevice) (float64, bool) { return float64(d.metrics.PortCount), d.metrics.PortCount > 0 }},
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// loadValue returns one field of a "1.00 0.50 0.25" load average
+func loadValue(load string, i int) (float64, bool) {
+	load1, load5, load15, ok := models.ParseLoadAverage(load)
+	return []float64{load1, load5, load15}[i], ok
+}
+
+// cpuValue returns one of a device's CPU usage shares, if it has them
+func cpuValue(d exportedDevice, share func(u *models.CPUUsage) float64) (float64, bool) {
+	if d.cpu == nil {
+		return 0, false
+	}
+	return share(d.cpu), true
+}
+
+// pressureValue returns a device's pressure stall average over the last
+// minute, if its kernel reports it
+func pressureValue(d exportedDevice, resource, kind string) (float64, bool) {
+	stall := d.metrics.Pressure.Stall(resource, kind)
+	if stall == nil {
+		return 0, false
+	}
+	return stall.Avg60, true
+}
+
+// WriteExposition writes fleet, synthetic and dashboard metrics in the
+// Prometheus text format
+func WriteExposition(out io.Writer) error {
+	w := promtext.NewWriter(out)
+	devices := exportedDevices(inventory.Snapshot())
+
+	w.Family("dashboard_device_status", "gauge", "Current device status; the status label holds the value.")
+	for _, d := range devices {
+		w.Sample("dashboard_device_status", d.labels(promtext.Label{Name: "status", Value: d.status}), 1)
+	}
+	for _, g := range deviceGauges {
+		w.Family(g.name, g.typ, g.help)
+		for _, d := range devices {
+			if g.needsMetrics && d.collectedAt.IsZero() {
+				continue
+			}
+			if v, ok := g.value(d); ok {
+				w.Sample(g.name, d.labels(), v)
+			}
+		}
+	}
+
+	writeFilesystemExposition(w, devices)
+	writeInterfaceExposition(w, devices)
+	writePressureExposition(w, devices)
+	writeServiceExposition(w, devices)
+	writeSyntheticExposition(w)
+	writeInternalExposition(w, devices)
+	return w.Flush()
+}
+
+// filesystemGauges are the per-mount metrics, labelled with the mount on
+// top of the device labels
+var filesystemGauges = []struct {
+	name, help string
+	value      func(fs models.Filesystem) (float64, bool)
+}{
+	{"dashboard_filesystem_size_bytes", "Filesystem size in bytes.",
+		func(fs models.Filesystem) (float64, bool) { return float64(fs.SizeBytes), true }},
+	{"dashboard_filesystem_used_bytes", "Filesystem bytes used.",
+		func(fs models.Filesystem) (float64, bool) { return float64(fs.UsedBytes), true }},
+	{"dashboard_filesystem_avail_bytes", "Filesystem bytes available to unprivileged users.",
+		func(fs models.Filesystem) (float64, bool) { return float64(fs.AvailBytes), true }},
+	{"dashboard_filesystem_used_percent", "Filesystem usage, with reserved blocks counted as unavailable.",
+		func(fs models.Filesystem) (float64, bool) { return fs.UsedPercent, true }},
+	{"dashboard_filesystem_inodes_total", "Filesystem inodes.",
+		func(fs models.Filesystem) (float64, bool) { return float64(fs.InodesTotal), fs.InodesTotal > 0 }},
+	{"dashboard_filesystem_inodes_used", "Filesystem inodes used.",
+		func(fs models.Filesystem) (float64, bool) { return float64(fs.InodesUsed), fs.InodesTotal > 0 }},
+	{"dashboard_filesystem_readonly", "Whether the filesystem is mounted read-only.",
+		func(fs models.Filesystem) (float64, bool) { return boolValue(fs.ReadOnly), true }},
+	{"dashboard_filesystem_full_threshold_percent", "Usage above which the filesystem counts as full.",
+		func(fs models.Filesystem) (float64, bool) { return fs.ThresholdPercent, true }},
+	{"dashboard_filesystem_full", "Whether the filesystem is over its full threshold.",
+		func(fs models.Filesystem) (float64, bool) { return boolValue(fs.Full), true }},
+}
+
+func writeFilesystemExposition(w *promtext.Writer, devices []exportedDevice) {
+	for _, g := range filesystemGauges {
+		w.Family(g.name, "gauge", g.help)
+		for _, d := range devices {
+			if d.collectedAt.IsZero() {
+				continue
+			}
+			for _, fs := range d.metrics.Filesystems {
+				if v, ok := g.value(fs); ok {
+					w.Sample(g.name, d.labels(
+						promtext.Label{Name: "mountpoint", Value: fs.Mountpoint},
+						promtext.Label{Name: "fstype", Value: fs.FSType},
+						promtext.Label{Name: "device", Value: fs.Device},
+					), v)
+				}
+			}
+		}
+	}
+}
+
+// interfaceMetrics are the per-interface metrics, labelled with the
+// interface name. Rates are only exported once two samples of the
+// interface could be compared.
+var interfaceMetrics = []struct {
+	name, typ, help string
+	value           func(i models.NetworkInterface) (float64, bool)
+}{
+	{"dashboard_interface_up", "gauge", "Whether the interface is operationally up.",
+		func(i models.NetworkInterface) (float64, bool) { return boolValue(i.Up()), true }},
+	{"dashboard_interface_speed_bytes", "gauge", "Link speed in bytes per second.",
+		func(i models.NetworkInterface) (float64, bool) {
+			return float64(i.SpeedMbps) * 1e6 / 8, i.SpeedMbps > 0
+		}},
+	{"dashboard_interface_receive_bytes_total", "counter", "Bytes received.",
+		func(i models.NetworkInterface) (float64, bool) { return float64(i.RxBytes), true }},
+	{"dashboard_interface_transmit_bytes_total", "counter", "Bytes transmitted.",
+		func(i models.NetworkInterface) (float64, bool) { return float64(i.TxBytes), true }},
+	{"dashboard_interface_receive_packets_total", "counter", "Packets received.",
+		func(i models.NetworkInterface) (float64, bool) { return float64(i.RxPackets), true }},
+	{"dashboard_interface_transmit_packets_total", "counter", "Packets transmitted.",
+		func(i models.NetworkInterface) (float64, bool) { return float64(i.TxPackets), true }},
+	{"dashboard_interface_receive_errors_total", "counter", "Receive errors.",
+		func(i models.NetworkInterface) (float64, bool) { return float64(i.RxErrors), true }},
+	{"dashboard_interface_transmit_errors_total", "counter", "Transmit errors.",
+		func(i models.NetworkInterface) (float64, bool) { return float64(i.TxErrors), true }},
+	{"dashboard_interface_receive_drops_total", "counter", "Received packets dropped.",
+		func(i models.NetworkInterface) (float64, bool) { return float64(i.RxDropped), true }},
+	{"dashboard_interface_transmit_drops_total", "counter", "Packets dropped on transmit.",
+		func(i models.NetworkInterface) (float64, bool) { return float64(i.TxDropped), true }},
+	{"dashboard_interface_receive_bytes_per_second", "gauge", "Receive throughput between the last two samples.",
+		func(i models.NetworkInterface) (float64, bool) {
+			return interfaceRate(i, func(r *models.InterfaceRates) float64 { return r.RxBytesPerSec })
+		}},
+	{"dashboard_interface_transmit_bytes_per_second", "gauge", "Transmit throughput between the last two samples.",
+		func(i models.NetworkInterface) (float64, bool) {
+			return interfaceRate(i, func(r *models.InterfaceRates) float64 { return r.TxBytesPerSec })
+		}},
+	{"dashboard_interface_utilization_percent", "gauge", "Throughput of the busier direction as a share of link speed.",
+		func(i models.NetworkInterface) (float64, bool) {
+			return interfaceRate(i, func(r *models.InterfaceRates) float64 { return r.UtilizationPercent })
+		}},
+}
+
+// interfaceRate returns one of an interface's rates, if it has them
+func interfaceRate(i models.NetworkInterface, rate func(r *models.InterfaceRates) float64) (float64, bool) {
+	if i.Rates == nil {
+		return 0, false
+	}
+	return rate(i.Rates), true
+}
+
+func writeInterfaceExposition(w *promtext.Writer, devices []exportedDevice) {
+	for _, g := range interfaceMetrics {
+		w.Family(g.name, g.typ, g.help)
+		for _, d := range devices {
+			if d.collectedAt.IsZero() {
+				continue
+			}
+			for _, iface := range d.metrics.Interfaces {
+				if v, ok := g.value(iface); ok {
+					w.Sample(g.name, d.labels(promtext.Label{Name: "interface", Value: iface.Name}), v)
+				}
+			}
+		}
+	}
+}
+
+// writePressureExposition writes the pressure stall averages, labelled
+// with the resource, the kind of stall and the averaging window
+func writePressureExposition(w *promtext.Writer, devices []exportedDevice) {
+	const name = "dashboard_device_pressure_percent"
+	w.Family(name, "gauge", "Share of time tasks were stalled on a resource (Linux PSI).")
+	for _, d := range devices {
+		if d.collectedAt.IsZero() || d.metrics.Pressure == nil {
+			continue
+		}
+		for _, resource := range models.PressureResources {
+			for _, kind := range models.PressureKinds {
+				stall := d.metrics.Pressure.Stall(resource, kind)
+				if stall == nil {
+					continue
+				}
+				for _, window := range []struct {
+					label string
+					value float64
+				}{{"10s", stall.Avg10}, {"60s", stall.Avg60}, {"300s", stall.Avg300}} {
+					w.Sample(name, d.labels(
+						promtext.Label{Name: "resource", Value: resource},
+						promtext.Label{Name: "stall", Value: kind},
+						promtext.Label{Name: "window", Value: window.label},
+					), window.value)
+				}
+			}
+		}
+	}
+}
+
+// writeServiceExposition writes the health of watched units and when
+// failed units failed, labelled with the unit
+func writeServiceExposition(w *promtext.Writer, devices []exportedDevice) {
+	const healthy = "dashboard_service_healthy"
+	w.Family(healthy, "gauge", "Whether a watched systemd unit is active and enabled (1) or not (0).")
+	for _, d := range devices {
+		if d.collectedAt.IsZero() {
+			continue
+		}
+		for _, svc := range d.metrics.Services {
+			w.Sample(healthy, d.labels(promtext.Label{Name: "unit", Value: svc.Name}), boolValue(svc.Healthy))
+		}
+	}
+
+	const failedAt = "dashboard_unit_failed_timestamp_seconds"
+	w.Family(failedAt, "gauge", "When a failed systemd unit entered the failed state, as a Unix timestamp.")
+	for _, d := range devices {
+		if d.collectedAt.IsZero() {
+			continue
+		}
+		for _, u := range d.metrics.FailedUnits {
+			if !u.FailedAt.IsZero() {
+				w.Sample(failedAt, d.labels(promtext.Label{Name: "unit", Value: u.Name}), float64(u.FailedAt.Unix()))
+			}
+		}
+	}
+}
+
+func writeSyntheticExposition(w *promtext.Writer) {
+	results := GetSyntheticResults()
+	sort.Slice(results, func(i, j int) bool { return results[i].ID < results[j].ID })
+	labels := func(r models.SyntheticCheckResult) []promtext.Label {
+		tags := ""
+		if len(r.Tags) > 0 {
+			tags = "," + strings.Join(r.Tags, ",") + ","
+		}
+		return []promtext.Label{
+			{Name: "id", Value: r.ID}, {Name: "name", Value: r.Name},
+			{Name: "type", Value: r.Type}, {Name: "target", Value: r.Target}, {Name: "tags", Value: tags},
+		}
+	}
+
+	w.Family("dashboard_synthetic_up", "gauge", "Whether a synthetic check is passing, after hysteresis.")
+	for _, r := range results {
+		w.Sample("dashboard_synthetic_up", labels(r), boolValue(r.Status == "ok"))
+	}
+	w.Family("dashboard_synthetic_flapping", "gauge", "Whether a synthetic check changes between passing and failing too often.")
+	for _, r := range results {
+		w.Sample("dashboard_synthetic_flapping", labels(r), boolValue(r.Flapping))
+	}
+	w.Family("dashboard_synthetic_latency_seconds", "gauge", "Latency of the last run of a synthetic check.")
+	for _, r := range results {
+		w.Sample("dashboard_synthetic_latency_seconds", labels(r), float64(r.LatencyMs)/1000)
+	}
+	w.Family("dashboard_synthetic_last_run_timestamp_seconds", "gauge", "When a synthetic check last ran.")
+	for _, r := range results {
+		if !r.LastRun.IsZero() {
+			w.Sample("dashboard_synthetic_last_run_timestamp_seconds", labels(r), float64(r.LastRun.Unix()))
+		}
+	}
+}
+
+func writeInternalExposition(w *promtext.Writer, devices []exportedDevice) {
+	if scheduler != nil {
+		stats := scheduler.Stats()
+		w.Family("dashboard_monitor_check_duration_seconds", "summary", "Time taken by device checks: probes plus metrics collection.")
+		w.Sample("dashboard_monitor_check_duration_seconds_sum", nil, stats.TotalSeconds)
+		w.Sample("dashboard_monitor_check_duration_seconds_count", nil, float64(stats.Count))
+		w.Family("dashboard_device_check_duration_seconds", "gauge", "Time taken by the last check of a device.")
+		for _, d := range devices {
+			if last, ok := stats.Last[d.kind+"/"+d.id]; ok {
+				w.Sample("dashboard_device_check_duration_seconds", d.labels(), last.Seconds())
+			}
+		}
+		w.Family("dashboard_monitor_interval_seconds", "gauge", "Configured base interval between device checks.")
+		w.Sample("dashboard_monitor_interval_seconds", nil, scheduler.Interval().Seconds())
+	}
+
+	sshErrorsMu.Lock()
+	hosts := make([]string, 0, len(sshErrors))
+	for host := range sshErrors {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	w.Family("dashboard_ssh_errors_total", "counter", "Failed SSH metric collections per host.")
+	for _, host := range hosts {
+		w.Sample("dashboard_ssh_errors_total", []promtext.Label{{Name: "host", Value: host}}, float64(sshErrors[host]))
+	}
+	sshErrorsMu.Unlock()
+
+	w.Family("go_goroutines", "gauge", "Number of goroutines that currently exist.")
+	w.Sample("go_goroutines", nil, float64(runtime.NumGoroutine()))
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"server-dashboard/internal/models"
+	"server-dashboard/internal/promtext"
+)
+
+// checkFamilies checks that every sample follows a HELP and TYPE pair for
+// its metric and that no metric is declared twice
+func checkFamilies(t *testing.T, exposition string) {
+	t.Helper()
+	declared := make(map[string]bool)
+	family, typ, help := "", "", ""
+	scanner := bufio.NewScanner(strings.NewReader(exposition))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "# HELP ") {
+			fields := strings.SplitN(line, " ", 4)
+			if len(fields) != 4 || fields[3] == "" {
+				t.Errorf("HELP without text: %q", line)
+				continue
+			}
+			help = fields[2]
+			continue
+		}
+		if strings.HasPrefix(line, "# TYPE ") {
+			fields := strings.Fields(line)
+			if len(fields) != 4 || fields[2] != help {
+				t.Errorf("TYPE not preceded by its HELP: %q", line)
+				continue
+			}
+			family, typ = fields[2], fields[3]
+			if declared[family] {
+				t.Errorf("%s declared twice", family)
+			}
+			declared[family] = true
+			continue
+		}
+		name := line[:strings.IndexAny(line, "{ ")]
+		if typ == "summary" {
+			name = strings.TrimSuffix(strings.TrimSuffix(name, "_sum"), "_count")
+		}
+		if name != family {
+			t.Errorf("%s sample in the %s family", name, family)
+		}
+		if typ == "counter" && !strings.HasSuffix(name, "_total") {
+			t.Errorf("counter %s does not end in _total", name)
+		}
+	}
+}
+
+func TestWriteExposition(t *testing.T) {
+	collected := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	withInventory(t, []models.Server{{
+		ID: "web1", Name: "Web \"front\" \\ end\nrack 2", Tags: []string{"prod", "eu"},
+		Status: models.StatusOnline, PingStatus: models.StatusOnline, RTTMillis: 1.5,
+		MetricsCollectedAt: collected, DiskPercent: 42, LoadAverage: "0.50 0.25 0.10",
+		MemoryUsed: 512, MemoryTotal: 1024, MemoryPercent: 50,
+	}}, []models.VM{{
+		ID: "vm1", Name: "Build", Status: models.StatusOffline, PingStatus: models.StatusOffline,
+	}}, nil)
+	recordSSHError(`10.0.0.9:22`)
+	t.Cleanup(func() {
+		sshErrorsMu.Lock()
+		delete(sshErrors, `10.0.0.9:22`)
+		sshErrorsMu.Unlock()
+	})
+
+	var buf bytes.Buffer
+	if err := WriteExposition(&buf); err != nil {
+		t.Fatal(err)
+	}
+	checkFamilies(t, buf.String())
+	samples, err := promtext.Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	find := func(name, id string) (promtext.Sample, bool) {
+		for _, s := range samples {
+			if s.Name == name && s.Label("id") == id {
+				return s, true
+			}
+		}
+		return promtext.Sample{}, false
+	}
+	up, ok := find("dashboard_device_up", "web1")
+	if !ok || up.Value != 1 || up.Label("type") != KindServer || up.Label("tags") != ",prod,eu," {
+		t.Errorf("web1 up: %+v", up)
+	}
+	// Label values come back exactly after escaping
+	if want := "Web \"front\" \\ end\nrack 2"; up.Label("name") != want {
+		t.Errorf("name label %q, want %q", up.Label("name"), want)
+	}
+	if s, ok := find("dashboard_device_memory_used_bytes", "web1"); !ok || s.Value != 512*mb {
+		t.Errorf("memory: %+v", s)
+	}
+	if s, ok := find("dashboard_device_load5", "web1"); !ok || s.Value != 0.25 {
+		t.Errorf("load5: %+v", s)
+	}
+	if s, ok := find("dashboard_device_rtt_seconds", "web1"); !ok || s.Value != 0.0015 {
+		t.Errorf("rtt: %+v", s)
+	}
+
+	// Metric values are left out until a device has been collected
+	if s, ok := find("dashboard_device_up", "vm1"); !ok || s.Value != 0 || s.Label("tags") != "" {
+		t.Errorf("vm1 up: %+v", s)
+	}
+	if s, ok := find("dashboard_device_disk_used_percent", "vm1"); ok {
+		t.Errorf("uncollected vm1 has disk usage: %+v", s)
+	}
+
+	var sshErrorsSeen bool
+	for _, s := range samples {
+		if s.Name == "dashboard_ssh_errors_total" && s.Label("host") == "10.0.0.9:22" {
+			sshErrorsSeen = s.Value == 1
+		}
+	}
+	if !sshErrorsSeen {
+		t.Error("no SSH error count for 10.0.0.9:22")
+	}
+}
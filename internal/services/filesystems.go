@@ -0,0 +1,198 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/models"
+)
+
+// defaultExcludedFSTypes are in-memory, image and container filesystems
+// left out unless the filesystems config lists its own exclude_fstypes
+var defaultExcludedFSTypes = []string{
+	"tmpfs", "devtmpfs", "ramfs", "squashfs", "overlay", "iso9660", "efivarfs", "fuse.lxcfs",
+}
+
+// filesystemsFor returns the filesystem config for a device: monitoring
+// defaults, with the device's include, exclude and exclude_fstypes lists
+// replacing them where set and its thresholds taking precedence
+func filesystemsFor(kind, id string) config.FilesystemConfig {
+	var fc config.FilesystemConfig
+	if Config == nil {
+		return fc
+	}
+	fc = Config.Monitoring.Filesystems
+	var override *config.FilesystemConfig
+	switch kind {
+	case KindServer:
+		for _, c := range Config.Servers {
+			if c.ID == id {
+				override = c.Filesystems
+			}
+		}
+	case KindVM:
+		for _, c := range Config.VirtualMachines {
+			if c.ID == id {
+				override = c.Filesystems
+			}
+		}
+	case KindSwitch:
+		for _, c := range Config.Switches {
+			if c.ID == id {
+				override = c.Filesystems
+			}
+		}
+	}
+	if override == nil {
+		return fc
+	}
+	if len(override.Include) > 0 {
+		fc.Include = override.Include
+	}
+	if len(override.Exclude) > 0 {
+		fc.Exclude = override.Exclude
+	}
+	if len(override.ExcludeFSTypes) > 0 {
+		fc.ExcludeFSTypes = override.ExcludeFSTypes
+	}
+	if len(override.Thresholds) > 0 {
+		thresholds := make(map[string]int, len(fc.Thresholds)+len(override.Thresholds))
+		for pattern, pct := range fc.Thresholds {
+			thresholds[pattern] = pct
+		}
+		for pattern, pct := range override.Thresholds {
+			thresholds[pattern] = pct
+		}
+		fc.Thresholds = thresholds
+	}
+	return fc
+}
+
+// selectFilesystems applies a device's filesystem config to a collected
+// sample. Mounts that are not monitored are dropped, as are further mounts
+// of a block device already listed (bind mounts), and the rest get their
+// full threshold. FullPartitions is rebuilt from the mounts over their
+// threshold. Samples without a mount list, such as from older agents, keep
+// the FullPartitions their collector reported.
+func selectFilesystems(kind, id string, m *models.HostMetrics) {
+	if m.Filesystems == nil {
+		return
+	}
+	fc := filesystemsFor(kind, id)
+	excludedTypes := fc.ExcludeFSTypes
+	if len(excludedTypes) == 0 {
+		excludedTypes = defaultExcludedFSTypes
+	}
+
+	// A new slice, as the sample may share its mounts with a stored report
+	var monitored []models.Filesystem
+	for _, fs := range m.Filesystems {
+		if filesystemMonitored(fc, excludedTypes, fs) {
+			monitored = append(monitored, fs)
+		}
+	}
+
+	selected := uniqueMounts(monitored)
+	for i := range selected {
+		fs := &selected[i]
+		fs.ThresholdPercent = filesystemThreshold(fc, fs.Mountpoint)
+		fs.Full = fs.UsedPercent > fs.ThresholdPercent
+	}
+
+	m.Filesystems = selected
+	m.FullPartitions = []string{}
+	for _, fs := range selected {
+		if fs.Full {
+			m.FullPartitions = append(m.FullPartitions, fmt.Sprintf("%s %.0f%%", fs.Mountpoint, fs.UsedPercent))
+		}
+	}
+}
+
+// uniqueMounts sorts mounts by mountpoint and drops further mounts of a
+// block device already listed (bind mounts). Sorting first means that of
+// several mounts of one device the shortest mountpoint, such as "/", is the
+// one kept. Pseudo filesystems share device names like "tmpfs" and are all
+// kept. mounts is sorted in place.
+func uniqueMounts(mounts []models.Filesystem) []models.Filesystem {
+	sort.SliceStable(mounts, func(i, j int) bool { return mounts[i].Mountpoint < mounts[j].Mountpoint })
+	unique := []models.Filesystem{}
+	seenDevices := make(map[string]bool)
+	for _, fs := range mounts {
+		if strings.HasPrefix(fs.Device, "/") {
+			if seenDevices[fs.Device] {
+				continue
+			}
+			seenDevices[fs.Device] = true
+		}
+		unique = append(unique, fs)
+	}
+	return unique
+}
+
+// filesystemMonitored reports whether a mount passes the include and
+// exclude patterns. The root filesystem is kept whatever its type, as
+// containers run on overlay roots.
+func filesystemMonitored(fc config.FilesystemConfig, excludedTypes []string, fs models.Filesystem) bool {
+	if len(fc.Include) > 0 && !matchesAnyMount(fc.Include, fs.Mountpoint) {
+		return false
+	}
+	if matchesAnyMount(fc.Exclude, fs.Mountpoint) {
+		return false
+	}
+	if fs.Mountpoint != "/" {
+		for _, t := range excludedTypes {
+			if fs.FSType == t {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// filesystemThreshold returns a mount's full threshold: that of the longest
+// matching thresholds pattern, or monitoring.disk_threshold_percent
+func filesystemThreshold(fc config.FilesystemConfig, mountpoint string) float64 {
+	best, threshold := "", fullPartitionPercent()
+	for pattern, pct := range fc.Thresholds {
+		if !matchesMount(pattern, mountpoint) {
+			continue
+		}
+		if len(pattern) > len(best) || len(pattern) == len(best) && pattern < best {
+			best, threshold = pattern, float64(pct)
+		}
+	}
+	return threshold
+}
+
+func matchesAnyMount(patterns []string, mountpoint string) bool {
+	for _, pattern := range patterns {
+		if matchesMount(pattern, mountpoint) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesMount matches a shell glob against a mountpoint. A pattern ending
+// in "/**" matches the directory and every mount below it. Malformed
+// patterns never match.
+func matchesMount(pattern, mountpoint string) bool {
+	if dir := strings.TrimSuffix(pattern, "/**"); dir != pattern {
+		if dir == "" {
+			return true
+		}
+		for p := mountpoint; ; p = path.Dir(p) {
+			if matchesMount(dir, p) {
+				return true
+			}
+			if p == "/" || p == "." {
+				return false
+			}
+		}
+	}
+	ok, err := path.Match(pattern, mountpoint)
+	return err == nil && ok
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"path/filepath"
+	"time"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/models"
+	"server-dashboard/internal/tsdb"
+)
+
+// history records every collected sample for charts and the query API. It
+// is nil when history is disabled or could not be opened.
+var history *tsdb.DB
+
+// Errors returned by history queries
+var (
+	ErrHistoryDisabled = errors.New("metrics history is disabled")
+	ErrUnknownDevice   = errors.New("unknown device")
+)
+
+// HistoryMetrics lists the metrics recorded for every device, in the order
+// detail pages chart them
+var HistoryMetrics = []string{
+	"disk_percent", "memory_percent", "cpu_percent", "load1", "load5", "load15", "inode_percent",
+	"cpu_iowait_percent", "cpu_steal_percent", "network_rx_bytes_per_sec", "network_tx_bytes_per_sec", "processes", "failed_services",
+	"disk_used_gb", "memory_used_mb", "up", "rtt_ms", "packet_loss_percent",
+}
+
+// initHistory opens the store under the data directory and starts recording
+// inventory changes into it
+func initHistory(cfg *config.Config) {
+	if !cfg.History.Enabled {
+		return
+	}
+	db, err := tsdb.Open(tsdb.Options{
+		Dir:             filepath.Join(cfg.DataDir(), "history"),
+		RawRetention:    time.Duration(cfg.History.RawRetentionHours) * time.Hour,
+		MinuteRetention: time.Duration(cfg.History.MinuteRetentionDays) * 24 * time.Hour,
+		HourRetention:   time.Duration(cfg.History.HourRetentionDays) * 24 * time.Hour,
+	})
+	if err != nil {
+		log.Printf("Metrics history disabled: %v", err)
+		return
+	}
+	history = db
+
+	changes, _ := inventory.Subscribe(256)
+	go func() {
+		for change := range changes {
+			recordHistory(db, change)
+		}
+	}()
+	log.Printf("Metrics history enabled in %s", filepath.Join(cfg.DataDir(), "history"))
+}
+
+// CloseHistory flushes partially filled downsampling buckets on shutdown
+func CloseHistory() {
+	if history != nil {
+		if err := history.Close(); err != nil {
+			log.Printf("Failed to close metrics history: %v", err)
+		}
+	}
+}
+
+// historySeries names a device's series in the store
+func historySeries(kind, id string) string {
+	return kind + "/" + id
+}
+
+// recordHistory stores the samples behind one inventory update. Probe
+// results are recorded on every check; host metrics only when a new sample
+// was collected, so stale and offline devices leave gaps instead of zeros.
+func recordHistory(db *tsdb.DB, change InventoryChange) {
+	if change.Old == nil {
+		return
+	}
+	cur, ok := exportDevice(change.New)
+	if !ok {
+		return
+	}
+	prev, _ := exportDevice(change.Old)
+	if cur.checkedAt.Equal(prev.checkedAt) {
+		return
+	}
+
+	values := map[string]float64{"up": boolValue(cur.ping == models.StatusOnline)}
+	if cur.rtt > 0 {
+		values["rtt_ms"] = cur.rtt
+	}
+	if cur.ping == models.StatusOnline || cur.loss > 0 {
+		values["packet_loss_percent"] = cur.loss
+	}
+
+	if !cur.collectedAt.IsZero() && !cur.collectedAt.Equal(prev.collectedAt) {
+		m := cur.metrics
+		values["disk_percent"] = m.DiskPercent
+		values["disk_used_gb"] = m.DiskUsage
+		values["memory_used_mb"] = m.MemoryUsed
+		if m.MemoryTotal > 0 {
+			values["memory_percent"] = cur.memoryPercent
+		}
+		if m.InodeTotal > 0 {
+			values["inode_percent"] = m.InodePercent
+		}
+		for i, name := range []string{"load1", "load5", "load15"} {
+			if v, ok := loadValue(m.LoadAverage, i); ok {
+				values[name] = v
+			}
+		}
+		if cur.cpu != nil {
+			values["cpu_percent"] = cur.cpu.UsedPercent
+			values["cpu_iowait_percent"] = cur.cpu.IOWaitPercent
+			values["cpu_steal_percent"] = cur.cpu.StealPercent
+		}
+		if m.Processes > 0 {
+			values["processes"] = float64(m.Processes)
+		}
+		// Collectors that cannot list units and report no count, such as
+		// SNMP or hosts without systemd, leave the count unknown, not zero
+		if m.FailedUnits != nil || m.FailedServices > 0 {
+			values["failed_services"] = float64(m.FailedServices)
+		}
+
+		// Counters are cumulative since boot; a drop means a reboot or
+		// counter reset, so no rate is recorded for that interval
+		if !prev.collectedAt.IsZero() {
+			secs := cur.collectedAt.Sub(prev.collectedAt).Seconds()
+			if rx := m.NetworkRxMB - prev.metrics.NetworkRxMB; secs > 0 && rx >= 0 {
+				values["network_rx_bytes_per_sec"] = rx * mb / secs
+			}
+			if tx := m.NetworkTxMB - prev.metrics.NetworkTxMB; secs > 0 && tx >= 0 {
+				values["network_tx_bytes_per_sec"] = tx * mb / secs
+			}
+		}
+	}
+
+	if err := db.Append(historySeries(cur.kind, cur.id), cur.checkedAt, values); err != nil {
+		log.Printf("Failed to record history for %s: %v", cur.name, err)
+	}
+}
+
+// QueryHistory returns one metric of a device over a time range. The
+// device is looked up by ID across servers, VMs and switches.
+func QueryHistory(id, metric string, from, to time.Time, step time.Duration) (tsdb.Result, error) {
+	if history == nil {
+		return tsdb.Result{}, ErrHistoryDisabled
+	}
+	kind, ok := deviceKind(id)
+	if !ok {
+		return tsdb.Result{}, ErrUnknownDevice
+	}
+	return history.Query(historySeries(kind, id), metric, from, to, step)
+}
+
+// HistoryMetricNames lists the metrics recorded for a device recently
+func HistoryMetricNames(id string) ([]string, error) {
+	if history == nil {
+		return nil, ErrHistoryDisabled
+	}
+	kind, ok := deviceKind(id)
+	if !ok {
+		return nil, ErrUnknownDevice
+	}
+	return history.Metrics(historySeries(kind, id))
+}
+
+// deviceKind finds which kind of device an ID belongs to
+func deviceKind(id string) (string, bool) {
+	if _, ok := inventory.Server(id); ok {
+		return KindServer, true
+	}
+	if _, ok := inventory.VM(id); ok {
+		return KindVM, true
+	}
+	if _, ok := inventory.Switch(id); ok {
+		return KindSwitch, true
+	}
+	return "", false
+}
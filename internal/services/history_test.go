@@ -0,0 +1,56 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"server-dashboard/internal/models"
+	"server-dashboard/internal/tsdb"
+)
+
+func TestRecordHistoryFailedServices(t *testing.T) {
+	db, err := tsdb.Open(tsdb.Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// sample returns a server that collected a sample n minutes after start
+	start := time.Now().Add(-time.Hour)
+	sample := func(id string, n, failed int, units []models.SystemdUnit) models.Server {
+		s := models.Server{ID: id, PingStatus: models.StatusOnline, FailedServices: failed, FailedUnits: units}
+		s.LastChecked = start.Add(time.Duration(n) * time.Minute)
+		s.MetricsCollectedAt = s.LastChecked
+		return s
+	}
+	recorded := func(series string) bool {
+		names, err := db.Metrics(series)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			if name == "failed_services" {
+				return true
+			}
+		}
+		return false
+	}
+
+	tests := []struct {
+		name   string
+		failed int
+		units  []models.SystemdUnit
+		want   bool
+	}{
+		{"units listed, none failed", 0, []models.SystemdUnit{}, true},
+		{"count without units", 2, nil, true},
+		{"services unavailable", 0, nil, false},
+	}
+	for _, tt := range tests {
+		old, cur := sample(tt.name, 0, 0, nil), sample(tt.name, 1, tt.failed, tt.units)
+		recordHistory(db, InventoryChange{Kind: KindServer, ID: tt.name, Old: old, New: cur})
+		if got := recorded(historySeries(KindServer, tt.name)); got != tt.want {
+			t.Errorf("%s: failed_services recorded %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,506 @@
+package services
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"server-dashboard/internal/models"
+)
+
+// Host key verification modes
+const (
+	HostKeyModeStrict   = "strict"   // only keys in known_hosts are accepted
+	HostKeyModeTOFU     = "tofu"     // first key seen for a host is used and queued for review
+	HostKeyModeInsecure = "insecure" // no verification (not recommended)
+)
+
+// Review states for host keys awaiting an admin decision
+const (
+	HostKeyPending  = "pending"  // new host, key not yet in known_hosts
+	HostKeyChanged  = "changed"  // host presented a key that differs from known_hosts
+	HostKeyRejected = "rejected" // admin rejected the key; connections are refused
+)
+
+// HostKeyRecord is a host key waiting for, or refused by, an admin
+type HostKeyRecord struct {
+	Host                string    `json:"host"` // known_hosts form, e.g. "10.0.0.1" or "[10.0.0.1]:2222"
+	KeyType             string    `json:"key_type"`
+	Fingerprint         string    `json:"fingerprint"`
+	Key                 string    `json:"key"` // authorized_keys encoding
+	PreviousFingerprint string    `json:"previous_fingerprint,omitempty"`
+	Status              string    `json:"status"`
+	FirstSeen           time.Time `json:"first_seen"`
+	LastSeen            time.Time `json:"last_seen"`
+}
+
+// HostKeyError is returned when a host key cannot be trusted
+type HostKeyError struct {
+	Host        string
+	Fingerprint string
+	Status      string // one of the HostKey* review states
+}
+
+func (e *HostKeyError) Error() string {
+	switch e.Status {
+	case HostKeyChanged:
+		return fmt.Sprintf("host key for %s has changed (now %s); approve it on the host keys page", e.Host, e.Fingerprint)
+	case HostKeyRejected:
+		return fmt.Sprintf("host key %s for %s was rejected", e.Fingerprint, e.Host)
+	default:
+		return fmt.Sprintf("host key %s for %s is not trusted yet; approve it on the host keys page", e.Fingerprint, e.Host)
+	}
+}
+
+// DeviceStatus maps the error to the device status shown in the UI
+func (e *HostKeyError) DeviceStatus() string {
+	if e.Status == HostKeyPending {
+		return models.StatusHostKeyPending
+	}
+	return models.StatusHostKeyMismatch
+}
+
+// hostKeyStatus returns the device status for an SSH error caused by host
+// key verification, and false for any other error
+func hostKeyStatus(err error) (string, bool) {
+	var hkErr *HostKeyError
+	if errors.As(err, &hkErr) {
+		return hkErr.DeviceStatus(), true
+	}
+	return "", false
+}
+
+// hostKeyCallback returns the callback used by new SSH clients
+func hostKeyCallback() ssh.HostKeyCallback {
+	if hostKeys == nil {
+		return func(string, net.Addr, ssh.PublicKey) error {
+			return errors.New("host key verification is not initialized")
+		}
+	}
+	return hostKeys.Callback()
+}
+
+// HostKeyStore verifies SSH host keys against a known_hosts file and keeps
+// unknown or changed keys in a review queue persisted next to it.
+type HostKeyStore struct {
+	mu             sync.Mutex
+	mode           string
+	knownHostsPath string
+	reviewPath     string
+	known          ssh.HostKeyCallback
+	records        map[string]*HostKeyRecord // keyed by host + " " + fingerprint
+}
+
+// hostKeys is the store used by every SSHClient
+var hostKeys *HostKeyStore
+
+// NewHostKeyStore opens (creating if needed) the known_hosts file and review queue
+func NewHostKeyStore(mode, knownHostsPath, reviewPath string) (*HostKeyStore, error) {
+	switch mode {
+	case "":
+		mode = HostKeyModeStrict
+	case HostKeyModeStrict, HostKeyModeTOFU, HostKeyModeInsecure:
+	default:
+		return nil, fmt.Errorf("unknown host_key_mode %q", mode)
+	}
+
+	s := &HostKeyStore{
+		mode:           mode,
+		knownHostsPath: expandHome(knownHostsPath),
+		reviewPath:     reviewPath,
+		records:        make(map[string]*HostKeyRecord),
+	}
+	if mode == HostKeyModeInsecure {
+		return s, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.knownHostsPath), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create known_hosts directory: %w", err)
+	}
+	f, err := os.OpenFile(s.knownHostsPath, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open known_hosts: %w", err)
+	}
+	f.Close()
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	if err := s.loadReview(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Mode returns the configured verification mode
+func (s *HostKeyStore) Mode() string {
+	return s.mode
+}
+
+// KnownHostsPath returns the known_hosts file the store verifies against
+func (s *HostKeyStore) KnownHostsPath() string {
+	return s.knownHostsPath
+}
+
+// Callback returns the host key callback used for SSH client configs
+func (s *HostKeyStore) Callback() ssh.HostKeyCallback {
+	if s.mode == HostKeyModeInsecure {
+		return ssh.InsecureIgnoreHostKey()
+	}
+	return s.verify
+}
+
+func (s *HostKeyStore) verify(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.known(hostname, remote, key)
+	if err == nil {
+		return nil
+	}
+	var keyErr *knownhosts.KeyError
+	if !errors.As(err, &keyErr) {
+		return err
+	}
+
+	host := knownhosts.Normalize(hostname)
+	fingerprint := ssh.FingerprintSHA256(key)
+	now := time.Now()
+
+	if rec, ok := s.records[host+" "+fingerprint]; ok {
+		rec.LastSeen = now
+		switch {
+		case rec.Status == HostKeyRejected:
+			return &HostKeyError{Host: host, Fingerprint: fingerprint, Status: HostKeyRejected}
+		case rec.Status == HostKeyPending && s.mode == HostKeyModeTOFU:
+			return nil // trusted on first use, still awaiting review
+		default:
+			return &HostKeyError{Host: host, Fingerprint: fingerprint, Status: rec.Status}
+		}
+	}
+
+	rec := &HostKeyRecord{
+		Host:        host,
+		KeyType:     key.Type(),
+		Fingerprint: fingerprint,
+		Key:         strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))),
+		Status:      HostKeyPending,
+		FirstSeen:   now,
+		LastSeen:    now,
+	}
+	if len(keyErr.Want) > 0 {
+		rec.Status = HostKeyChanged
+		rec.PreviousFingerprint = ssh.FingerprintSHA256(keyErr.Want[0].Key)
+	} else if s.pendingForHost(host) != nil {
+		// A different key is already pending for this host: treat as a change
+		rec.Status = HostKeyChanged
+		rec.PreviousFingerprint = s.pendingForHost(host).Fingerprint
+	}
+	s.records[host+" "+fingerprint] = rec
+	if err := s.saveReview(); err != nil {
+		log.Printf("Failed to save host key review queue: %v", err)
+	}
+	log.Printf("SSH host key %s for %s queued for review (%s)", fingerprint, host, rec.Status)
+
+	if rec.Status == HostKeyPending && s.mode == HostKeyModeTOFU {
+		return nil
+	}
+	return &HostKeyError{Host: host, Fingerprint: fingerprint, Status: rec.Status}
+}
+
+// pendingForHost returns the pending record for host, if any. Callers hold s.mu.
+func (s *HostKeyStore) pendingForHost(host string) *HostKeyRecord {
+	for _, rec := range s.records {
+		if rec.Host == host && rec.Status == HostKeyPending {
+			return rec
+		}
+	}
+	return nil
+}
+
+// Records returns the review queue sorted by host
+func (s *HostKeyStore) Records() []HostKeyRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]HostKeyRecord, 0, len(s.records))
+	for _, rec := range s.records {
+		out = append(out, *rec)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Host != out[j].Host {
+			return out[i].Host < out[j].Host
+		}
+		return out[i].FirstSeen.Before(out[j].FirstSeen)
+	})
+	return out
+}
+
+// Accept trusts the key: it replaces any existing known_hosts entries for
+// the host and removes the key from the review queue. It fails, changing
+// nothing, when a wildcard or marker line in known_hosts applies to the host.
+func (s *HostKeyStore) Accept(host, fingerprint string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.records[host+" "+fingerprint]
+	if !ok {
+		return fmt.Errorf("no host key %s for %s awaiting review", fingerprint, host)
+	}
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(rec.Key))
+	if err != nil {
+		return fmt.Errorf("stored key is invalid: %w", err)
+	}
+	if err := s.replaceKnownHost(host, knownhosts.Line([]string{host}, key)); err != nil {
+		return err
+	}
+	if err := s.reload(); err != nil {
+		return err
+	}
+
+	// Drop the pending keys for this host: the accepted one is now authoritative
+	for k, r := range s.records {
+		if r.Host == host && r.Status != HostKeyRejected {
+			delete(s.records, k)
+		}
+	}
+	delete(s.records, host+" "+fingerprint)
+	return s.saveReview()
+}
+
+// Reject refuses the key; connections presenting it keep failing
+func (s *HostKeyStore) Reject(host, fingerprint string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.records[host+" "+fingerprint]
+	if !ok {
+		return fmt.Errorf("no host key %s for %s awaiting review", fingerprint, host)
+	}
+	rec.Status = HostKeyRejected
+	return s.saveReview()
+}
+
+// replaceKnownHost rewrites known_hosts without entries for host, then
+// appends line. Hashed entries are matched as ssh does, and a host is
+// dropped from a plain entry that lists other hosts too. Wildcard patterns
+// and @cert-authority or @revoked lines that apply to host cannot be
+// rewritten for one host, so they fail instead of leaving an old key
+// trusted. Callers hold s.mu.
+func (s *HostKeyStore) replaceKnownHost(host, line string) error {
+	data, err := os.ReadFile(s.knownHostsPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read known_hosts: %w", err)
+	}
+
+	var kept []string
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			kept = append(kept, text)
+			continue
+		}
+		fields := strings.Fields(trimmed)
+		if strings.HasPrefix(fields[0], "@") {
+			if len(fields) > 1 && knownHostsLineMatches(fields[1], host) {
+				return fmt.Errorf("%s line %d (%s) applies to %s; edit it by hand", s.knownHostsPath, n, fields[0], host)
+			}
+			kept = append(kept, text)
+			continue
+		}
+
+		if !knownHostsLineMatches(fields[0], host) {
+			kept = append(kept, text)
+			continue
+		}
+		var others []string
+		positive := false
+		for _, pattern := range strings.Split(fields[0], ",") {
+			if !knownHostsPatternMatches(pattern, host) {
+				others = append(others, pattern)
+				positive = positive || !strings.HasPrefix(pattern, "!")
+				continue
+			}
+			if isWildcardPattern(pattern) {
+				return fmt.Errorf("%s line %d matches %s through pattern %q; edit it by hand", s.knownHostsPath, n, host, pattern)
+			}
+		}
+		if positive {
+			kept = append(kept, strings.Join(others, ",")+trimmed[len(fields[0]):])
+		}
+	}
+	kept = append(kept, line)
+
+	tmp := s.knownHostsPath + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(kept, "\n")+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	return os.Rename(tmp, s.knownHostsPath)
+}
+
+// knownHostsLineMatches reports whether a comma-separated pattern list
+// applies to host: some pattern matches and no negated one does
+func knownHostsLineMatches(patterns, host string) bool {
+	matched := false
+	for _, pattern := range strings.Split(patterns, ",") {
+		if strings.HasPrefix(pattern, "!") {
+			if knownHostsPatternMatches(pattern[1:], host) {
+				return false
+			}
+			continue
+		}
+		matched = matched || knownHostsPatternMatches(pattern, host)
+	}
+	return matched
+}
+
+// knownHostsPatternMatches reports whether one known_hosts pattern, plain,
+// hashed (|1|salt|hash) or with wildcards, names host. host is in
+// knownhosts.Normalize form. Negated patterns never match.
+func knownHostsPatternMatches(pattern, host string) bool {
+	if strings.HasPrefix(pattern, "!") {
+		return false
+	}
+	if strings.HasPrefix(pattern, "|1|") {
+		parts := strings.Split(pattern[3:], "|")
+		if len(parts) != 2 {
+			return false
+		}
+		salt, err1 := base64.StdEncoding.DecodeString(parts[0])
+		hash, err2 := base64.StdEncoding.DecodeString(parts[1])
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		mac := hmac.New(sha1.New, salt)
+		mac.Write([]byte(host))
+		return hmac.Equal(mac.Sum(nil), hash)
+	}
+	if !isWildcardPattern(pattern) {
+		return knownhosts.Normalize(pattern) == host
+	}
+	patternHost, patternPort := splitKnownHost(pattern)
+	hostName, port := splitKnownHost(host)
+	return patternPort == port && wildcardMatch(patternHost, hostName)
+}
+
+// isWildcardPattern reports whether a known_hosts pattern can name more
+// than one host
+func isWildcardPattern(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?!")
+}
+
+// splitKnownHost splits "[host]:port" or "host" into host and port
+func splitKnownHost(entry string) (string, string) {
+	if strings.HasPrefix(entry, "[") {
+		if i := strings.LastIndex(entry, "]:"); i > 0 {
+			return entry[1:i], entry[i+2:]
+		}
+		return strings.TrimSuffix(entry[1:], "]"), "22"
+	}
+	return entry, "22"
+}
+
+// wildcardMatch matches s against a pattern where * is any run of
+// characters and ? any one character
+func wildcardMatch(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := len(s); i >= 0; i-- {
+				if wildcardMatch(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+		}
+		pattern, s = pattern[1:], s[1:]
+	}
+	return len(s) == 0
+}
+
+// reload re-reads known_hosts. Callers hold s.mu (or have exclusive access).
+func (s *HostKeyStore) reload() error {
+	cb, err := knownhosts.New(s.knownHostsPath)
+	if err != nil {
+		return fmt.Errorf("failed to load known_hosts: %w", err)
+	}
+	s.known = cb
+	return nil
+}
+
+func (s *HostKeyStore) loadReview() error {
+	data, err := os.ReadFile(s.reviewPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read host key review queue: %w", err)
+	}
+	var records []*HostKeyRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("failed to parse host key review queue: %w", err)
+	}
+	for _, rec := range records {
+		s.records[rec.Host+" "+rec.Fingerprint] = rec
+	}
+	return nil
+}
+
+// saveReview persists the review queue. Callers hold s.mu.
+func (s *HostKeyStore) saveReview() error {
+	records := make([]*HostKeyRecord, 0, len(s.records))
+	for _, rec := range s.records {
+		records = append(records, rec)
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.reviewPath), 0700); err != nil {
+		return err
+	}
+	tmp := s.reviewPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.reviewPath)
+}
+
+// GetHostKeyStore returns the host key store, or nil before initialization
+func GetHostKeyStore() *HostKeyStore {
+	return hostKeys
+}
+
+// expandHome expands environment variables and a leading ~/ in a path
+func expandHome(path string) string {
+	expanded := os.ExpandEnv(path)
+	if strings.HasPrefix(expanded, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			expanded = filepath.Join(home, expanded[2:])
+		}
+	}
+	return expanded
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func testHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// testHostKeyStore opens a strict store over a known_hosts file with the
+// given lines
+func testHostKeyStore(t *testing.T, lines ...string) *HostKeyStore {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "known_hosts")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewHostKeyStore(HostKeyModeStrict, path, filepath.Join(dir, "review.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// checkHostKey runs the store's callback for a connection to 10.0.0.5:22
+func checkHostKey(s *HostKeyStore, key ssh.PublicKey) error {
+	return s.Callback()("10.0.0.5:22", &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 22}, key)
+}
+
+func TestAcceptChangedKeyReplacesEntries(t *testing.T) {
+	oldKey, newKey, otherKey := testHostKey(t), testHostKey(t), testHostKey(t)
+	hashed := knownhosts.Line([]string{knownhosts.HashHostname("10.0.0.5")}, oldKey)
+	otherHashed := knownhosts.Line([]string{knownhosts.HashHostname("10.0.0.6")}, otherKey)
+	s := testHostKeyStore(t,
+		"# managed by hand",
+		hashed,
+		knownhosts.Line([]string{"db1", "10.0.0.5"}, oldKey),
+		knownhosts.Line([]string{"[10.0.0.5]:2222"}, otherKey),
+		otherHashed,
+		knownhosts.Line([]string{"!10.0.0.5", "10.0.0.*"}, otherKey),
+	)
+
+	var hkErr *HostKeyError
+	if err := checkHostKey(s, newKey); !errors.As(err, &hkErr) || hkErr.Status != HostKeyChanged {
+		t.Fatalf("new key: err = %v, want a changed key", err)
+	}
+	if err := s.Accept("10.0.0.5", ssh.FingerprintSHA256(newKey)); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Records()) != 0 {
+		t.Errorf("review queue = %+v", s.Records())
+	}
+
+	if err := checkHostKey(s, newKey); err != nil {
+		t.Errorf("accepted key: %v", err)
+	}
+	if err := checkHostKey(s, oldKey); err == nil {
+		t.Error("the replaced key, listed hashed, is still trusted")
+	}
+	data, err := os.ReadFile(s.KnownHostsPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"# managed by hand",
+		knownhosts.Line([]string{"db1"}, oldKey),
+		knownhosts.Line([]string{"[10.0.0.5]:2222"}, otherKey),
+		otherHashed,
+		knownhosts.Line([]string{"!10.0.0.5", "10.0.0.*"}, otherKey),
+		knownhosts.Line([]string{"10.0.0.5"}, newKey),
+	}, "\n") + "\n"
+	if string(data) != want {
+		t.Errorf("known_hosts =\n%s\nwant\n%s", data, want)
+	}
+}
+
+// Lines that apply to more hosts than the one being accepted cannot be
+// rewritten for it alone
+func TestAcceptRefusesPatternAndMarkerEntries(t *testing.T) {
+	oldKey := testHostKey(t)
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"wildcard", knownhosts.Line([]string{"10.0.0.*"}, oldKey)},
+		{"single character wildcard", knownhosts.Line([]string{"db1", "10.0.0.?"}, oldKey)},
+		{"cert authority", "@cert-authority 10.0.0.* " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(oldKey)))},
+		{"revoked", "@revoked 10.0.0.5 " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(oldKey)))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testHostKeyStore(t, tt.line)
+			newKey := testHostKey(t)
+			checkHostKey(s, newKey)
+			if len(s.Records()) != 1 {
+				t.Fatalf("review queue = %+v", s.Records())
+			}
+
+			if err := s.Accept("10.0.0.5", ssh.FingerprintSHA256(newKey)); err == nil || !strings.Contains(err.Error(), "by hand") {
+				t.Errorf("err = %v, want a refusal", err)
+			}
+			if data, _ := os.ReadFile(s.KnownHostsPath()); string(data) != tt.line+"\n" {
+				t.Errorf("known_hosts changed to %q", data)
+			}
+			if len(s.Records()) != 1 {
+				t.Errorf("key left the review queue")
+			}
+		})
+	}
+}
+
+func TestKnownHostsPatternMatches(t *testing.T) {
+	tests := []struct {
+		pattern, host string
+		want          bool
+	}{
+		{"10.0.0.5", "10.0.0.5", true},
+		{"[10.0.0.5]:22", "10.0.0.5", true},
+		{"10.0.0.5", "[10.0.0.5]:2222", false},
+		{"[10.0.0.5]:2222", "[10.0.0.5]:2222", true},
+		{knownhosts.HashHostname("[10.0.0.5]:2222"), "[10.0.0.5]:2222", true},
+		{knownhosts.HashHostname("10.0.0.5"), "10.0.0.50", false},
+		{"|1|bad|base64", "10.0.0.5", false},
+		{"10.0.*", "10.0.0.5", true},
+		{"10.0.0.?", "10.0.0.50", false},
+		{"[10.0.0.*]:2222", "[10.0.0.5]:2222", true},
+		{"10.0.0.*", "[10.0.0.5]:2222", false},
+		{"!10.0.0.5", "10.0.0.5", false},
+	}
+	for _, tt := range tests {
+		if got := knownHostsPatternMatches(tt.pattern, tt.host); got != tt.want {
+			t.Errorf("%q matches %q = %v, want %v", tt.pattern, tt.host, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"time"
+
+	"server-dashboard/internal/models"
+)
+
+// Hysteresis defaults, see config.HysteresisConfig
+const (
+	defaultFailuresBeforeOffline = 3
+	defaultSuccessesBeforeOnline = 2
+	defaultFlapWindow            = 30 * time.Minute
+)
+
+// stateHysteresis returns the configured hysteresis with defaults applied
+func stateHysteresis() models.Hysteresis {
+	h := models.Hysteresis{
+		FailuresBeforeDown: defaultFailuresBeforeOffline,
+		SuccessesBeforeUp:  defaultSuccessesBeforeOnline,
+		FlapWindow:         defaultFlapWindow,
+	}
+	if Config == nil {
+		return h
+	}
+	hc := Config.Monitoring.Hysteresis
+	if hc.FailuresBeforeOffline > 0 {
+		h.FailuresBeforeDown = hc.FailuresBeforeOffline
+	}
+	if hc.SuccessesBeforeOnline > 0 {
+		h.SuccessesBeforeUp = hc.SuccessesBeforeOnline
+	}
+	if hc.FlapWindowMinutes > 0 {
+		h.FlapWindow = time.Duration(hc.FlapWindowMinutes) * time.Minute
+	}
+	h.FlapThreshold = hc.FlapThreshold
+	return h
+}
+
+// debounceStatus feeds a device's probe result, "online" or "offline",
+// through its tracker and returns the debounced status
+func debounceStatus(t *models.StateTracker, status string) string {
+	if t.ObserveState(status == models.StatusOnline, time.Now(), stateHysteresis()) {
+		return models.StatusOnline
+	}
+	return models.StatusOffline
+}
+
+// debounceStream returns a stream port's debounced status, carrying its
+// tracker over from the previous check
+func debounceStream(prev []models.StreamStatus, port int, active bool) models.StreamStatus {
+	s := models.StreamStatus{Port: port}
+	for _, p := range prev {
+		if p.Port == port {
+			s.StateTracker = p.StateTracker
+			break
+		}
+	}
+	s.Active = s.ObserveState(active, time.Now(), stateHysteresis())
+	return s
+}
+
+// debounceSynthetic applies hysteresis to a synthetic check result, "ok"
+// or "fail", carrying the tracker over from the previous result
+func debounceSynthetic(prev models.SyntheticCheckResult, res *models.SyntheticCheckResult) {
+	res.StateTracker = prev.StateTracker
+	if res.ObserveState(res.Status == "ok", res.LastRun, stateHysteresis()) {
+		res.Status = "ok"
+	} else {
+		res.Status = "fail"
+	}
+}
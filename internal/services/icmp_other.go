@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package services
+
+import (
+	"errors"
+	"time"
+)
+
+// icmpEcho is not implemented on this platform; TCP and HTTP probes still work
+func icmpEcho(address string, count int, timeout time.Duration) ([]time.Duration, error) {
+	return nil, errors.New("ICMP ping is not supported on this platform")
+}
@@ -0,0 +1,118 @@
+//go:build linux || darwin
+
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// icmpEcho sends count echo requests to address over an unprivileged ping
+// socket (SOCK_DGRAM, IPPROTO_ICMP) and returns the round trip of each reply.
+// On Linux the socket requires the process group to be within
+// net.ipv4.ping_group_range.
+func icmpEcho(address string, count int, timeout time.Duration) ([]time.Duration, error) {
+	ips, err := net.LookupIP(address)
+	if err != nil || len(ips) == 0 {
+		return nil, fmt.Errorf("resolve %s: %v", address, err)
+	}
+	ip := ips[0]
+
+	family, proto, echoType, replyType := syscall.AF_INET, syscall.IPPROTO_ICMP, byte(8), byte(0)
+	if ip.To4() == nil {
+		family, proto, echoType, replyType = syscall.AF_INET6, syscall.IPPROTO_ICMPV6, 128, 129
+	} else {
+		ip = ip.To4()
+	}
+
+	fd, err := syscall.Socket(family, syscall.SOCK_DGRAM, proto)
+	if err != nil {
+		return nil, fmt.Errorf("ping socket unavailable: %w", err)
+	}
+	f := os.NewFile(uintptr(fd), "icmp")
+	conn, err := net.FilePacketConn(f)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("ping socket unavailable: %w", err)
+	}
+	defer conn.Close()
+
+	// The kernel rewrites the identifier, so replies are matched on the
+	// sequence number and a per-call token in the payload
+	token := make([]byte, 8)
+	binary.BigEndian.PutUint64(token, uint64(time.Now().UnixNano()))
+	dst := &net.UDPAddr{IP: ip}
+	sent := make(map[uint16]time.Time, count)
+	for seq := 1; seq <= count; seq++ {
+		msg := icmpEchoMessage(echoType, uint16(seq), token)
+		sent[uint16(seq)] = time.Now()
+		if _, err := conn.WriteTo(msg, dst); err != nil {
+			return nil, fmt.Errorf("send echo: %w", err)
+		}
+	}
+
+	return echoReplies(conn, family == syscall.AF_INET, replyType, token, sent, timeout)
+}
+
+// echoReplies reads replies to the echo requests in sent until all arrived
+// or the timeout passed, and returns the round trip of each. A read error
+// ends the wait like the timeout does; it is returned only when no reply
+// arrived, so replies already received still count.
+func echoReplies(conn net.PacketConn, ipv4 bool, replyType byte, token []byte, sent map[uint16]time.Time, timeout time.Duration) ([]time.Duration, error) {
+	count := len(sent)
+	var rtts []time.Duration
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	buf := make([]byte, 1500)
+	for len(rtts) < count {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			var netErr net.Error
+			if (errors.As(err, &netErr) && netErr.Timeout()) || len(rtts) > 0 {
+				break
+			}
+			return nil, err
+		}
+		reply := buf[:n]
+		// Darwin includes the IPv4 header in what is read back
+		if ipv4 && len(reply) >= 20 && reply[0]>>4 == 4 {
+			reply = reply[int(reply[0]&0x0f)*4:]
+		}
+		if len(reply) < 8+len(token) || reply[0] != replyType || !bytes.Equal(reply[8:8+len(token)], token) {
+			continue
+		}
+		seq := binary.BigEndian.Uint16(reply[6:8])
+		if at, ok := sent[seq]; ok {
+			rtts = append(rtts, time.Since(at))
+			delete(sent, seq)
+		}
+	}
+	return rtts, nil
+}
+
+// icmpEchoMessage builds an echo request with a checksum. The kernel fills
+// in its own identifier (and the checksum for ICMPv6).
+func icmpEchoMessage(typ byte, seq uint16, payload []byte) []byte {
+	msg := make([]byte, 8+len(payload))
+	msg[0] = typ
+	binary.BigEndian.PutUint16(msg[6:8], seq)
+	copy(msg[8:], payload)
+
+	var sum uint32
+	for i := 0; i+1 < len(msg); i += 2 {
+		sum += uint32(msg[i])<<8 | uint32(msg[i+1])
+	}
+	if len(msg)%2 == 1 {
+		sum += uint32(msg[len(msg)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	binary.BigEndian.PutUint16(msg[2:4], ^uint16(sum))
+	return msg
+}
@@ -0,0 +1,74 @@
+//go:build linux || darwin
+
+package services
+
+import (
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+// fakePacketConn returns queued packets and then err, or a timeout
+type fakePacketConn struct {
+	net.PacketConn
+	packets [][]byte
+	err     error
+}
+
+func (c *fakePacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	if len(c.packets) == 0 {
+		if c.err != nil {
+			return 0, nil, c.err
+		}
+		return 0, nil, os.ErrDeadlineExceeded
+	}
+	n := copy(b, c.packets[0])
+	c.packets = c.packets[1:]
+	return n, &net.UDPAddr{}, nil
+}
+
+func (c *fakePacketConn) SetReadDeadline(time.Time) error { return nil }
+
+func TestEchoReplies(t *testing.T) {
+	token := []byte("12345678")
+	reply := func(seq uint16) []byte { return icmpEchoMessage(0, seq, token) }
+	// As Darwin reads it back, behind a 20-byte IPv4 header
+	withHeader := func(seq uint16) []byte {
+		header := make([]byte, 20)
+		header[0] = 0x45
+		return append(header, reply(seq)...)
+	}
+	otherToken := icmpEchoMessage(0, 2, []byte("87654321"))
+	request := icmpEchoMessage(8, 2, token)
+	broken := errors.New("network is down")
+
+	tests := []struct {
+		name    string
+		packets [][]byte
+		err     error
+		want    int
+		wantErr bool
+	}{
+		{"all replies", [][]byte{reply(2), reply(1), reply(3)}, nil, 3, false},
+		{"IPv4 header", [][]byte{withHeader(1), withHeader(2), withHeader(3)}, nil, 3, false},
+		{"foreign packets skipped", [][]byte{otherToken, request, reply(9), reply(1)[:10], reply(1), reply(1)}, nil, 1, false},
+		{"timeout", nil, nil, 0, false},
+		{"error after replies", [][]byte{reply(1), reply(3)}, broken, 2, false},
+		{"error before any reply", nil, broken, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := map[uint16]time.Time{}
+			for seq := uint16(1); seq <= 3; seq++ {
+				sent[seq] = time.Now()
+			}
+			conn := &fakePacketConn{packets: tt.packets, err: tt.err}
+			rtts, err := echoReplies(conn, true, 0, token, sent, time.Second)
+			if len(rtts) != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("%d replies, err %v; want %d", len(rtts), err, tt.want)
+			}
+		})
+	}
+}
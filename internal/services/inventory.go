@@ -0,0 +1,256 @@
+package services
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"server-dashboard/internal/models"
+)
+
+// Device kinds used in inventory change notifications
+const (
+	KindServer = "server"
+	KindVM     = "vm"
+	KindSwitch = "switch"
+)
+
+// InventorySnapshot is an immutable view of every monitored device at a
+// given version. Readers must treat the slices as read-only; writers never
+// modify a published snapshot, they publish a new one instead.
+type InventorySnapshot struct {
+	Version  uint64
+	Servers  []models.Server
+	VMs      []models.VM
+	Switches []models.Switch
+}
+
+// InventoryChange is published to subscribers after a device is updated.
+// Old is nil when the device was added by a full reload.
+type InventoryChange struct {
+	Version uint64
+	Kind    string
+	ID      string
+	Old     interface{}
+	New     interface{}
+}
+
+// InventoryStore holds the device inventory behind copy-on-write snapshots.
+// Reads are lock-free; updates are serialised and replace one device at a time.
+type InventoryStore struct {
+	mu      sync.Mutex   // serialises writers
+	current atomic.Value // *InventorySnapshot
+
+	subMu       sync.Mutex
+	subscribers map[int]chan InventoryChange
+	nextSubID   int
+}
+
+// NewInventoryStore creates an empty inventory store.
+func NewInventoryStore() *InventoryStore {
+	inv := &InventoryStore{subscribers: make(map[int]chan InventoryChange)}
+	inv.current.Store(&InventorySnapshot{})
+	return inv
+}
+
+// Snapshot returns the current inventory snapshot.
+func (inv *InventoryStore) Snapshot() *InventorySnapshot {
+	return inv.current.Load().(*InventorySnapshot)
+}
+
+// Load replaces the whole inventory, e.g. after reading the configuration.
+func (inv *InventoryStore) Load(servers []models.Server, vms []models.VM, switches []models.Switch) {
+	inv.mu.Lock()
+	next := &InventorySnapshot{
+		Version:  inv.Snapshot().Version + 1,
+		Servers:  make([]models.Server, len(servers)),
+		VMs:      make([]models.VM, len(vms)),
+		Switches: make([]models.Switch, len(switches)),
+	}
+	for i := range servers {
+		next.Servers[i] = servers[i].Clone()
+	}
+	for i := range vms {
+		next.VMs[i] = vms[i].Clone()
+	}
+	for i := range switches {
+		next.Switches[i] = switches[i].Clone()
+	}
+	inv.current.Store(next)
+	inv.mu.Unlock()
+
+	for _, srv := range next.Servers {
+		inv.publish(InventoryChange{Version: next.Version, Kind: KindServer, ID: srv.ID, New: srv})
+	}
+	for _, vm := range next.VMs {
+		inv.publish(InventoryChange{Version: next.Version, Kind: KindVM, ID: vm.ID, New: vm})
+	}
+	for _, sw := range next.Switches {
+		inv.publish(InventoryChange{Version: next.Version, Kind: KindSwitch, ID: sw.ID, New: sw})
+	}
+}
+
+// Server returns a private copy of the server with the given ID.
+func (inv *InventoryStore) Server(id string) (models.Server, bool) {
+	for _, srv := range inv.Snapshot().Servers {
+		if srv.ID == id {
+			return srv.Clone(), true
+		}
+	}
+	return models.Server{}, false
+}
+
+// VM returns a private copy of the VM with the given ID.
+func (inv *InventoryStore) VM(id string) (models.VM, bool) {
+	for _, vm := range inv.Snapshot().VMs {
+		if vm.ID == id {
+			return vm.Clone(), true
+		}
+	}
+	return models.VM{}, false
+}
+
+// Switch returns a private copy of the switch with the given ID.
+func (inv *InventoryStore) Switch(id string) (models.Switch, bool) {
+	for _, sw := range inv.Snapshot().Switches {
+		if sw.ID == id {
+			return sw.Clone(), true
+		}
+	}
+	return models.Switch{}, false
+}
+
+// UpdateServer applies fn to a copy of the server and publishes the result
+// atomically. It returns the updated server and false if the ID is unknown.
+func (inv *InventoryStore) UpdateServer(id string, fn func(*models.Server)) (models.Server, bool) {
+	inv.mu.Lock()
+	cur := inv.Snapshot()
+	idx := -1
+	for i := range cur.Servers {
+		if cur.Servers[i].ID == id {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		inv.mu.Unlock()
+		return models.Server{}, false
+	}
+
+	old := cur.Servers[idx]
+	updated := old.Clone()
+	fn(&updated)
+	updated.ID = old.ID
+
+	next := *cur
+	next.Version++
+	next.Servers = append([]models.Server(nil), cur.Servers...)
+	next.Servers[idx] = updated
+	inv.current.Store(&next)
+	inv.mu.Unlock()
+
+	inv.publish(InventoryChange{Version: next.Version, Kind: KindServer, ID: id, Old: old, New: updated})
+	return updated.Clone(), true
+}
+
+// UpdateVM applies fn to a copy of the VM and publishes the result atomically.
+func (inv *InventoryStore) UpdateVM(id string, fn func(*models.VM)) (models.VM, bool) {
+	inv.mu.Lock()
+	cur := inv.Snapshot()
+	idx := -1
+	for i := range cur.VMs {
+		if cur.VMs[i].ID == id {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		inv.mu.Unlock()
+		return models.VM{}, false
+	}
+
+	old := cur.VMs[idx]
+	updated := old.Clone()
+	fn(&updated)
+	updated.ID = old.ID
+
+	next := *cur
+	next.Version++
+	next.VMs = append([]models.VM(nil), cur.VMs...)
+	next.VMs[idx] = updated
+	inv.current.Store(&next)
+	inv.mu.Unlock()
+
+	inv.publish(InventoryChange{Version: next.Version, Kind: KindVM, ID: id, Old: old, New: updated})
+	return updated.Clone(), true
+}
+
+// UpdateSwitch applies fn to a copy of the switch and publishes the result atomically.
+func (inv *InventoryStore) UpdateSwitch(id string, fn func(*models.Switch)) (models.Switch, bool) {
+	inv.mu.Lock()
+	cur := inv.Snapshot()
+	idx := -1
+	for i := range cur.Switches {
+		if cur.Switches[i].ID == id {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		inv.mu.Unlock()
+		return models.Switch{}, false
+	}
+
+	old := cur.Switches[idx]
+	updated := old.Clone()
+	fn(&updated)
+	updated.ID = old.ID
+
+	next := *cur
+	next.Version++
+	next.Switches = append([]models.Switch(nil), cur.Switches...)
+	next.Switches[idx] = updated
+	inv.current.Store(&next)
+	inv.mu.Unlock()
+
+	inv.publish(InventoryChange{Version: next.Version, Kind: KindSwitch, ID: id, Old: old, New: updated})
+	return updated.Clone(), true
+}
+
+// Subscribe registers for change notifications. Delivery is non-blocking:
+// if the subscriber falls more than buffer changes behind, changes are dropped.
+// The returned function unsubscribes and closes the channel.
+func (inv *InventoryStore) Subscribe(buffer int) (<-chan InventoryChange, func()) {
+	if buffer <= 0 {
+		buffer = 64
+	}
+	ch := make(chan InventoryChange, buffer)
+
+	inv.subMu.Lock()
+	id := inv.nextSubID
+	inv.nextSubID++
+	inv.subscribers[id] = ch
+	inv.subMu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			inv.subMu.Lock()
+			delete(inv.subscribers, id)
+			inv.subMu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func (inv *InventoryStore) publish(change InventoryChange) {
+	inv.subMu.Lock()
+	defer inv.subMu.Unlock()
+	for _, ch := range inv.subscribers {
+		select {
+		case ch <- change:
+		default:
+			log.Printf("Inventory subscriber is full, dropping change for %s %s", change.Kind, change.ID)
+		}
+	}
+}
@@ -0,0 +1,490 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/cron"
+	"server-dashboard/internal/models"
+)
+
+// maxWindowDuration bounds recurring windows so finding the occurrence in
+// effect stays cheap
+const maxWindowDuration = 31 * 24 * time.Hour
+
+// maintenanceHistory is how long ended one-off windows and expired
+// silences stay listed before they are dropped
+const maintenanceHistory = 7 * 24 * time.Hour
+
+// ErrMaintenanceNotFound is returned for unknown window or silence IDs
+var ErrMaintenanceNotFound = errors.New("no such maintenance window or silence")
+
+// maintenance holds the maintenance windows and silences. It is nil until
+// InitializeCache runs.
+var maintenance *maintenanceStore
+
+// maintenanceWindow is a window with its schedule parsed
+type maintenanceWindow struct {
+	models.MaintenanceWindow
+	schedule cron.Schedule
+	loc      *time.Location
+}
+
+// maintenanceStore keeps windows and silences in a JSON file under the
+// data directory
+type maintenanceStore struct {
+	path string
+
+	mu       sync.RWMutex
+	windows  []*maintenanceWindow
+	silences []models.Silence
+}
+
+type maintenanceFile struct {
+	Windows  []models.MaintenanceWindow `json:"windows"`
+	Silences []models.Silence           `json:"silences"`
+}
+
+// initMaintenance loads the saved windows and silences
+func initMaintenance(cfg *config.Config) {
+	s := &maintenanceStore{path: filepath.Join(cfg.DataDir(), "maintenance.json")}
+	if err := s.load(); err != nil {
+		log.Printf("Maintenance windows not loaded: %v", err)
+	}
+	maintenance = s
+}
+
+func (s *maintenanceStore) load() error {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var f maintenanceFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+	for _, w := range f.Windows {
+		mw, err := newMaintenanceWindow(w)
+		if err != nil {
+			log.Printf("Skipping maintenance window %q: %v", w.Name, err)
+			continue
+		}
+		s.windows = append(s.windows, mw)
+	}
+	s.silences = f.Silences
+	return nil
+}
+
+// save persists the store. Callers hold s.mu.
+func (s *maintenanceStore) save() error {
+	f := maintenanceFile{Windows: []models.MaintenanceWindow{}, Silences: s.silences}
+	for _, w := range s.windows {
+		f.Windows = append(f.Windows, w.MaintenanceWindow)
+	}
+	if f.Silences == nil {
+		f.Silences = []models.Silence{}
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// prune drops windows and silences that ended long ago. Callers hold s.mu
+// for writing.
+func (s *maintenanceStore) prune(now time.Time) {
+	cutoff := now.Add(-maintenanceHistory)
+	windows := s.windows[:0]
+	for _, w := range s.windows {
+		if w.Recurring() || w.End.After(cutoff) {
+			windows = append(windows, w)
+		}
+	}
+	s.windows = windows
+	silences := s.silences[:0]
+	for _, sl := range s.silences {
+		if sl.ExpiresAt.After(cutoff) {
+			silences = append(silences, sl)
+		}
+	}
+	s.silences = silences
+}
+
+// newMaintenanceWindow validates a window and parses its schedule
+func newMaintenanceWindow(w models.MaintenanceWindow) (*maintenanceWindow, error) {
+	w.Name = strings.TrimSpace(w.Name)
+	if w.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if scopeEmpty(w.MaintenanceScope) {
+		return nil, fmt.Errorf("select at least one device, tag or synthetic check")
+	}
+	mw := &maintenanceWindow{loc: time.Local}
+	if !w.Recurring() {
+		if w.Start.IsZero() || w.End.IsZero() {
+			return nil, fmt.Errorf("one-off windows need a start and an end")
+		}
+		if !w.End.After(w.Start) {
+			return nil, fmt.Errorf("end must be after start")
+		}
+		w.DurationMinutes, w.Timezone = 0, ""
+		mw.MaintenanceWindow = w
+		return mw, nil
+	}
+
+	sched, err := cron.Parse(w.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	d := time.Duration(w.DurationMinutes) * time.Minute
+	if d <= 0 || d > maxWindowDuration {
+		return nil, fmt.Errorf("duration must be between 1 minute and %d days", int(maxWindowDuration.Hours()/24))
+	}
+	if w.Timezone != "" {
+		loc, err := time.LoadLocation(w.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("unknown timezone %q", w.Timezone)
+		}
+		mw.loc = loc
+	}
+	w.Start, w.End = time.Time{}, time.Time{}
+	mw.MaintenanceWindow = w
+	mw.schedule = sched
+	return mw, nil
+}
+
+// occurrence returns the start and end of the occurrence in effect at now
+func (w *maintenanceWindow) occurrence(now time.Time) (time.Time, time.Time, bool) {
+	if !w.Recurring() {
+		return w.Start, w.End, !now.Before(w.Start) && now.Before(w.End)
+	}
+	d := time.Duration(w.DurationMinutes) * time.Minute
+	start, ok := w.schedule.Last(now.In(w.loc), d)
+	if !ok || !now.Before(start.Add(d)) {
+		return time.Time{}, time.Time{}, false
+	}
+	return start, start.Add(d), true
+}
+
+// nextStart returns when the window next begins after now
+func (w *maintenanceWindow) nextStart(now time.Time) (time.Time, bool) {
+	if !w.Recurring() {
+		return w.Start, w.Start.After(now)
+	}
+	return w.schedule.Next(now.In(w.loc), 366*24*time.Hour)
+}
+
+// status fills in the computed fields for display
+func (w *maintenanceWindow) status(now time.Time) models.MaintenanceWindow {
+	out := w.MaintenanceWindow
+	out.Active, out.ActiveEnd, out.NextStart = false, time.Time{}, time.Time{}
+	if _, end, ok := w.occurrence(now); ok {
+		out.Active, out.ActiveEnd = true, end
+	}
+	if next, ok := w.nextStart(now); ok {
+		out.NextStart = next
+	}
+	return out
+}
+
+func scopeEmpty(s models.MaintenanceScope) bool {
+	return len(s.Devices) == 0 && len(s.Tags) == 0 && len(s.Synthetics) == 0
+}
+
+// scopeMatches reports whether a device or synthetic check is in scope
+func scopeMatches(s models.MaintenanceScope, kind, id string, tags []string) bool {
+	if kind == KindSynthetic {
+		if containsString(s.Synthetics, id) {
+			return true
+		}
+	} else if containsString(s.Devices, id) {
+		return true
+	}
+	for _, t := range tags {
+		if containsString(s.Tags, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// silenceMatches reports whether a silence covers an alert
+func silenceMatches(sl models.Silence, a models.Alert) bool {
+	if len(sl.Rules) > 0 && !containsString(sl.Rules, a.Rule) {
+		return false
+	}
+	return scopeEmpty(sl.MaintenanceScope) || scopeMatches(sl.MaintenanceScope, a.TargetKind, a.TargetID, a.Tags)
+}
+
+// windowFor returns the active window covering a target, if any
+func (s *maintenanceStore) windowFor(kind, id string, tags []string, now time.Time) (*maintenanceWindow, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, w := range s.windows {
+		if !scopeMatches(w.MaintenanceScope, kind, id, tags) {
+			continue
+		}
+		if _, _, ok := w.occurrence(now); ok {
+			return w, true
+		}
+	}
+	return nil, false
+}
+
+// MaintenanceWindowFor returns the name of the active maintenance window
+// covering a device or synthetic check, or "" when there is none
+func MaintenanceWindowFor(kind, id string, tags []string) string {
+	if maintenance == nil {
+		return ""
+	}
+	if w, ok := maintenance.windowFor(kind, id, tags, time.Now()); ok {
+		return w.Name
+	}
+	return ""
+}
+
+// alertSuppression explains why notifications for an alert are held back,
+// or returns "" when they are not
+func alertSuppression(a models.Alert, now time.Time) string {
+	if parent := unreachableVia(a.TargetKind, a.TargetID); parent != "" {
+		return "parent down: " + parent
+	}
+	if maintenance == nil {
+		return ""
+	}
+	if w, ok := maintenance.windowFor(a.TargetKind, a.TargetID, a.Tags, now); ok {
+		return "maintenance: " + w.Name
+	}
+	maintenance.mu.RLock()
+	defer maintenance.mu.RUnlock()
+	for _, sl := range maintenance.silences {
+		if now.Before(sl.ExpiresAt) && !now.Before(sl.CreatedAt) && silenceMatches(sl, a) {
+			return "silenced: " + sl.Comment
+		}
+	}
+	return ""
+}
+
+// GetMaintenanceWindows returns every window, active ones first, then by
+// next start
+func GetMaintenanceWindows() []models.MaintenanceWindow {
+	if maintenance == nil {
+		return nil
+	}
+	now := time.Now()
+	maintenance.mu.RLock()
+	out := make([]models.MaintenanceWindow, 0, len(maintenance.windows))
+	for _, w := range maintenance.windows {
+		out = append(out, w.status(now))
+	}
+	maintenance.mu.RUnlock()
+
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Active != b.Active {
+			return a.Active
+		}
+		if a.NextStart.IsZero() != b.NextStart.IsZero() {
+			return !a.NextStart.IsZero()
+		}
+		if !a.NextStart.Equal(b.NextStart) {
+			return a.NextStart.Before(b.NextStart)
+		}
+		return a.Name < b.Name
+	})
+	return out
+}
+
+// GetSilences returns every silence, active ones first, newest first
+func GetSilences() []models.Silence {
+	if maintenance == nil {
+		return nil
+	}
+	now := time.Now()
+	maintenance.mu.RLock()
+	out := append([]models.Silence(nil), maintenance.silences...)
+	maintenance.mu.RUnlock()
+
+	for i := range out {
+		out[i].Active = now.Before(out[i].ExpiresAt)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Active != out[j].Active {
+			return out[i].Active
+		}
+		return out[i].CreatedAt.After(out[j].CreatedAt)
+	})
+	return out
+}
+
+// MaintenanceTargets lists the devices and synthetic checks in an active
+// maintenance window
+func MaintenanceTargets() []models.MaintenanceTarget {
+	if maintenance == nil {
+		return nil
+	}
+	now := time.Now()
+	var out []models.MaintenanceTarget
+	for _, d := range exportedDevices(inventory.Snapshot()) {
+		if w, ok := maintenance.windowFor(d.kind, d.id, d.tags, now); ok {
+			out = append(out, models.MaintenanceTarget{Kind: d.kind, ID: d.id, Name: d.name, Window: w.Name})
+		}
+	}
+	for _, c := range GetSyntheticResults() {
+		if w, ok := maintenance.windowFor(KindSynthetic, c.ID, c.Tags, now); ok {
+			out = append(out, models.MaintenanceTarget{Kind: KindSynthetic, ID: c.ID, Name: c.Name, Window: w.Name})
+		}
+	}
+	return out
+}
+
+// AddMaintenanceWindow validates and saves a new window
+func AddMaintenanceWindow(w models.MaintenanceWindow, username string) (models.MaintenanceWindow, error) {
+	if maintenance == nil {
+		return models.MaintenanceWindow{}, fmt.Errorf("maintenance windows are not initialized")
+	}
+	w.ID = newMaintenanceID()
+	w.CreatedBy = username
+	w.CreatedAt = time.Now().UTC()
+	w.MaintenanceScope = cleanScope(w.MaintenanceScope)
+	mw, err := newMaintenanceWindow(w)
+	if err != nil {
+		return models.MaintenanceWindow{}, err
+	}
+
+	maintenance.mu.Lock()
+	defer maintenance.mu.Unlock()
+	maintenance.prune(time.Now())
+	maintenance.windows = append(maintenance.windows, mw)
+	if err := maintenance.save(); err != nil {
+		maintenance.windows = maintenance.windows[:len(maintenance.windows)-1]
+		return models.MaintenanceWindow{}, fmt.Errorf("failed to save maintenance windows: %w", err)
+	}
+	return mw.status(time.Now()), nil
+}
+
+// DeleteMaintenanceWindow removes a window
+func DeleteMaintenanceWindow(id string) (models.MaintenanceWindow, error) {
+	if maintenance == nil {
+		return models.MaintenanceWindow{}, ErrMaintenanceNotFound
+	}
+	maintenance.mu.Lock()
+	defer maintenance.mu.Unlock()
+	for i, w := range maintenance.windows {
+		if w.ID != id {
+			continue
+		}
+		windows := append(append([]*maintenanceWindow(nil), maintenance.windows[:i]...), maintenance.windows[i+1:]...)
+		old := maintenance.windows
+		maintenance.windows = windows
+		if err := maintenance.save(); err != nil {
+			maintenance.windows = old
+			return models.MaintenanceWindow{}, fmt.Errorf("failed to save maintenance windows: %w", err)
+		}
+		return w.MaintenanceWindow, nil
+	}
+	return models.MaintenanceWindow{}, ErrMaintenanceNotFound
+}
+
+// AddSilence validates and saves a new silence
+func AddSilence(sl models.Silence, username string) (models.Silence, error) {
+	if maintenance == nil {
+		return models.Silence{}, fmt.Errorf("silences are not initialized")
+	}
+	now := time.Now().UTC()
+	sl.ID = newMaintenanceID()
+	sl.CreatedBy = username
+	sl.CreatedAt = now
+	sl.Comment = strings.TrimSpace(sl.Comment)
+	sl.Rules = cleanList(sl.Rules)
+	sl.MaintenanceScope = cleanScope(sl.MaintenanceScope)
+	switch {
+	case sl.Comment == "":
+		return models.Silence{}, fmt.Errorf("a comment is required")
+	case !sl.ExpiresAt.After(now):
+		return models.Silence{}, fmt.Errorf("expiry must be in the future")
+	case len(sl.Rules) == 0 && scopeEmpty(sl.MaintenanceScope):
+		return models.Silence{}, fmt.Errorf("select at least one rule, device, tag or synthetic check")
+	}
+
+	maintenance.mu.Lock()
+	defer maintenance.mu.Unlock()
+	maintenance.prune(now)
+	maintenance.silences = append(maintenance.silences, sl)
+	if err := maintenance.save(); err != nil {
+		maintenance.silences = maintenance.silences[:len(maintenance.silences)-1]
+		return models.Silence{}, fmt.Errorf("failed to save silences: %w", err)
+	}
+	sl.Active = true
+	return sl, nil
+}
+
+// ExpireSilence ends a silence now. It stays listed as expired for a while.
+func ExpireSilence(id string) (models.Silence, error) {
+	if maintenance == nil {
+		return models.Silence{}, ErrMaintenanceNotFound
+	}
+	now := time.Now().UTC()
+	maintenance.mu.Lock()
+	defer maintenance.mu.Unlock()
+	for i := range maintenance.silences {
+		sl := &maintenance.silences[i]
+		if sl.ID != id {
+			continue
+		}
+		if sl.ExpiresAt.After(now) {
+			old := sl.ExpiresAt
+			sl.ExpiresAt = now
+			if err := maintenance.save(); err != nil {
+				sl.ExpiresAt = old
+				return models.Silence{}, fmt.Errorf("failed to save silences: %w", err)
+			}
+		}
+		return *sl, nil
+	}
+	return models.Silence{}, ErrMaintenanceNotFound
+}
+
+func newMaintenanceID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func cleanScope(s models.MaintenanceScope) models.MaintenanceScope {
+	return models.MaintenanceScope{Devices: cleanList(s.Devices), Tags: cleanList(s.Tags), Synthetics: cleanList(s.Synthetics)}
+}
+
+// cleanList trims entries and drops empty ones and duplicates
+func cleanList(list []string) []string {
+	var out []string
+	for _, v := range list {
+		v = strings.TrimSpace(v)
+		if v != "" && !containsString(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"server-dashboard/internal/models"
+)
+
+// testWindow builds a valid window covering device web1
+func testWindow(t *testing.T, w models.MaintenanceWindow) *maintenanceWindow {
+	t.Helper()
+	if w.Name == "" {
+		w.Name = "patching"
+	}
+	if w.Devices == nil && w.Tags == nil {
+		w.Devices = []string{"web1"}
+	}
+	mw, err := newMaintenanceWindow(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mw
+}
+
+func TestMaintenanceWindowOccurrence(t *testing.T) {
+	utc := func(mo time.Month, d, h, m int) time.Time { return time.Date(2026, mo, d, h, m, 0, 0, time.UTC) }
+	monthEnd := models.MaintenanceWindow{Schedule: "0 23 31 * *", DurationMinutes: 120, Timezone: "UTC"}
+	// Berlin changes to summer time on 2026-03-29 and back on 2026-10-25
+	nightly := models.MaintenanceWindow{Schedule: "30 2 * * *", DurationMinutes: 60, Timezone: "Europe/Berlin"}
+	evening := models.MaintenanceWindow{Schedule: "0 22 * * *", DurationMinutes: 240, Timezone: "Europe/Berlin"}
+	oneOff := models.MaintenanceWindow{Start: utc(5, 1, 10, 0), End: utc(5, 1, 12, 0)}
+
+	tests := []struct {
+		name   string
+		window models.MaintenanceWindow
+		now    time.Time
+		end    time.Time // Zero when not active
+	}{
+		{"before a month-end window", monthEnd, utc(1, 31, 22, 59), time.Time{}},
+		{"month-end window into the next month", monthEnd, utc(2, 1, 0, 30), utc(2, 1, 1, 0)},
+		{"end is exclusive", monthEnd, utc(2, 1, 1, 0), time.Time{}},
+		{"month without the day", monthEnd, utc(5, 1, 0, 30), time.Time{}},
+		{"local midnight across a month", evening, utc(4, 30, 22, 30), utc(5, 1, 0, 0)},
+		{"skipped by spring forward", nightly, utc(3, 29, 1, 15), time.Time{}},
+		{"day after spring forward", nightly, utc(3, 30, 0, 45), utc(3, 30, 1, 30)},
+		{"before fall back", nightly, utc(10, 25, 0, 45), utc(10, 25, 1, 30)},
+		{"repeated hour after fall back", nightly, utc(10, 25, 1, 45), time.Time{}},
+		{"one-off", oneOff, utc(5, 1, 11, 0), utc(5, 1, 12, 0)},
+		{"one-off ended", oneOff, utc(5, 1, 12, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := testWindow(t, tt.window)
+			start, end, ok := w.occurrence(tt.now)
+			if ok != !tt.end.IsZero() || ok && (!end.Equal(tt.end) || start.After(tt.now)) {
+				t.Fatalf("active %v from %v until %v, want until %v", ok, start, end, tt.end)
+			}
+			if status := w.status(tt.now); status.Active != ok || !status.ActiveEnd.Equal(tt.end) {
+				t.Errorf("status active %v until %v", status.Active, status.ActiveEnd)
+			}
+		})
+	}
+
+	w := testWindow(t, nightly)
+	if next := w.status(utc(3, 28, 12, 0)).NextStart; !next.Equal(utc(3, 30, 0, 30)) {
+		t.Errorf("next start after spring forward = %v", next.UTC())
+	}
+	if next := testWindow(t, oneOff).status(utc(5, 1, 11, 0)).NextStart; !next.IsZero() {
+		t.Errorf("started one-off window next starts %v", next)
+	}
+}
+
+func TestNewMaintenanceWindowErrors(t *testing.T) {
+	start := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	scope := models.MaintenanceScope{Devices: []string{"web1"}}
+	tests := []struct {
+		name   string
+		window models.MaintenanceWindow
+		want   string
+	}{
+		{"no name", models.MaintenanceWindow{Name: " ", MaintenanceScope: scope, Start: start, End: start.Add(time.Hour)}, "name is required"},
+		{"no scope", models.MaintenanceWindow{Name: "w", Start: start, End: start.Add(time.Hour)}, "at least one device"},
+		{"no end", models.MaintenanceWindow{Name: "w", MaintenanceScope: scope, Start: start}, "need a start and an end"},
+		{"end before start", models.MaintenanceWindow{Name: "w", MaintenanceScope: scope, Start: start, End: start}, "end must be after start"},
+		{"bad schedule", models.MaintenanceWindow{Name: "w", MaintenanceScope: scope, Schedule: "0 25 * * *", DurationMinutes: 60}, "cron hour"},
+		{"no duration", models.MaintenanceWindow{Name: "w", MaintenanceScope: scope, Schedule: "@daily"}, "duration must be"},
+		{"too long", models.MaintenanceWindow{Name: "w", MaintenanceScope: scope, Schedule: "@daily", DurationMinutes: 32 * 24 * 60}, "duration must be"},
+		{"bad timezone", models.MaintenanceWindow{Name: "w", MaintenanceScope: scope, Schedule: "@daily", DurationMinutes: 60, Timezone: "Mars/Olympus"}, "unknown timezone"},
+	}
+	for _, tt := range tests {
+		if _, err := newMaintenanceWindow(tt.window); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestMaintenanceWindowFor(t *testing.T) {
+	now := time.Date(2026, 5, 1, 11, 0, 0, 0, time.UTC)
+	active := func(name string, scope models.MaintenanceScope) *maintenanceWindow {
+		return testWindow(t, models.MaintenanceWindow{Name: name, MaintenanceScope: scope, Start: now.Add(-time.Hour), End: now.Add(time.Hour)})
+	}
+	s := &maintenanceStore{windows: []*maintenanceWindow{
+		testWindow(t, models.MaintenanceWindow{Name: "later", MaintenanceScope: models.MaintenanceScope{Devices: []string{"db1"}},
+			Start: now.Add(time.Hour), End: now.Add(2 * time.Hour)}),
+		active("web", models.MaintenanceScope{Devices: []string{"web1"}}),
+		active("staging", models.MaintenanceScope{Tags: []string{"staging"}}),
+		active("checks", models.MaintenanceScope{Synthetics: []string{"web1"}}),
+	}}
+
+	tests := []struct {
+		kind, id string
+		tags     []string
+		want     string
+	}{
+		{KindServer, "web1", nil, "web"},
+		{KindVM, "vm7", []string{"prod", "staging"}, "staging"},
+		{KindServer, "db1", nil, ""},
+		{KindSynthetic, "web1", nil, "checks"},
+		{KindSynthetic, "api", []string{"staging"}, "staging"},
+		{KindSwitch, "checks", nil, ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if w, ok := s.windowFor(tt.kind, tt.id, tt.tags, now); ok {
+			got = w.Name
+		}
+		if got != tt.want {
+			t.Errorf("%s %s %v: window %q, want %q", tt.kind, tt.id, tt.tags, got, tt.want)
+		}
+	}
+}
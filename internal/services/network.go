@@ -2,54 +2,48 @@ package services
 
 import (
 	"fmt"
+	"log"
 	"math/rand"
 	"net"
+	"path/filepath"
 	"sync"
 	"time"
 	"server-dashboard/internal/config"
 	"server-dashboard/internal/models"
 )
 
-// Global slice to store servers and VMs
+// Global inventory of servers, VMs and switches
 var (
-	ServersCache []models.Server
-	VMsCache     []models.VM
-	SwitchesCache []models.Switch
-	Config       *config.Config
+	inventory = NewInventoryStore()
+	Config    *config.Config
 	
 	// Monitoring control
-	monitoringInterval time.Duration
-	stopMonitoring     chan bool
-	isMonitoring       bool
-	monitoringMutex    sync.RWMutex
-	
-	// SSH client for real monitoring
-	sshClient *SSHClient
+	scheduler       *Scheduler
+	isMonitoring    bool
+	monitoringMutex sync.RWMutex
 )
 
 // InitializeCache loads servers and VMs from config
 func InitializeCache(cfg *config.Config) {
 	Config = cfg
 	
+	// Share one SSH connection per host across all monitoring commands
+	sshConnPool = newSSHPool(
+		cfg.SSH.MaxSessionsPerHost,
+		time.Duration(cfg.SSH.IdleTimeoutSeconds)*time.Second,
+		time.Duration(cfg.SSH.KeepaliveSeconds)*time.Second,
+	)
+
 	// Initialize SSH client for real monitoring if enabled
 	if !cfg.Monitoring.UseMockData && cfg.SSH.Enabled {
-		client, err := NewSSHClient(
-			cfg.SSH.Username,
-			cfg.SSH.PrivateKeyPath,
-			cfg.SSH.Password,
-			cfg.SSH.TimeoutSeconds,
-		)
-		if err != nil {
-			fmt.Printf("Warning: Failed to initialize SSH client: %v\n", err)
-			fmt.Println("Falling back to mock data monitoring")
-		} else {
-			sshClient = client
-			fmt.Println("SSH monitoring enabled for production metrics")
+		if err := initHostKeys(cfg); err != nil {
+			log.Fatalf("Error initializing SSH host key verification: %v", err)
 		}
+		initSSHClients(cfg)
 	}
 	
 	// Initialize servers
-	ServersCache = make([]models.Server, len(cfg.Servers))
+	servers := make([]models.Server, len(cfg.Servers))
 	for i, srvCfg := range cfg.Servers {
 		srv := models.NewServer(srvCfg.ID, srvCfg.Name, srvCfg.IPAddress, srvCfg.Hostname, srvCfg.Port)
 		// Set default values
@@ -63,12 +57,13 @@ func InitializeCache(cfg *config.Config) {
 		srv.DiskPartition = "/"
 		srv.FullPartitions = []string{}
 		srv.Tags = append([]string{}, srvCfg.Tags...)
+		srv.UplinkSwitchID = srvCfg.UplinkSwitchID
 		srv.LastChecked = time.Now()
-		ServersCache[i] = *srv
+		servers[i] = *srv
 	}
 	
 	// Initialize VMs
-	VMsCache = make([]models.VM, len(cfg.VirtualMachines))
+	vms := make([]models.VM, len(cfg.VirtualMachines))
 	for i, vmCfg := range cfg.VirtualMachines {
 		vm := models.NewVM(vmCfg.ID, vmCfg.Name, vmCfg.IPAddress, vmCfg.Hostname, vmCfg.Port, vmCfg.HostServerID)
 		// Set default values
@@ -83,20 +78,21 @@ func InitializeCache(cfg *config.Config) {
 		vm.FullPartitions = []string{}
 		vm.LastChecked = time.Now()
 		vm.StreamPorts = vmCfg.StreamPorts
+		vm.UplinkSwitchID = vmCfg.UplinkSwitchID
 		vm.Streams = []models.StreamStatus{}
 		// Check if streams are active on initialization
 		for _, port := range vm.StreamPorts {
-			streamStatus := models.StreamStatus{Port: port, Active: false}
+			active := false
 			if stream, err := CheckStreamOnPort(port); err == nil && stream != nil {
-				streamStatus.Active = stream.Active
+				active = stream.Active
 			}
-			vm.Streams = append(vm.Streams, streamStatus)
+			vm.Streams = append(vm.Streams, debounceStream(nil, port, active))
 		}
-		VMsCache[i] = *vm
+		vms[i] = *vm
 	}
 	
 	// Initialize Switches
-	SwitchesCache = make([]models.Switch, len(cfg.Switches))
+	switches := make([]models.Switch, len(cfg.Switches))
 	for i, swCfg := range cfg.Switches {
 		sw := models.NewSwitch(swCfg.ID, swCfg.Name, swCfg.IPAddress, swCfg.Hostname, swCfg.Port)
 		// Set default values
@@ -116,54 +112,70 @@ func InitializeCache(cfg *config.Config) {
 		sw.PortCount = 0
 		sw.Tags = append([]string{}, swCfg.Tags...)
 		sw.LastChecked = time.Now()
-		SwitchesCache[i] = *sw
+		switches[i] = *sw
 	}
+
+	inventory.Load(servers, vms, switches)
 	
-	// Set monitoring interval (default 5 seconds)
-	monitoringInterval = 5 * time.Second
-	stopMonitoring = make(chan bool, 1)
+	// Set up the scheduler from monitoring_interval (seconds, default 5)
+	jitterPercent := -1
+	if cfg.Monitoring.JitterPercent != nil {
+		jitterPercent = *cfg.Monitoring.JitterPercent
+	}
+	scheduler = NewScheduler(
+		time.Duration(cfg.MonitoringInterval)*time.Second,
+		cfg.Monitoring.Workers,
+		jitterPercent,
+		time.Duration(cfg.Monitoring.MaxBackoffSeconds)*time.Second,
+	)
 	isMonitoring = true
 
+	// Record collected samples for charts and the history API
+	initHistory(cfg)
+
+	// Record state transitions for the timeline and the events API
+	initEvents(cfg)
+
 	// Start synthetic checks
 	InitSynthetic(cfg)
-	
-	// Run initial monitoring check immediately (non-blocking)
-	go func() {
-		MonitorAllServers()
-		MonitorAllVMs()
-		MonitorAllSwitches()
-	}()
-	
-	// Start background monitoring in goroutine to avoid blocking initialization
-	go StartBackgroundMonitoring()
+
+	// Evaluate alert rules against the latest results and send notifications,
+	// except for targets in maintenance or silenced alerts
+	initMaintenance(cfg)
+	initNotifications(cfg)
+	initAlerts(cfg)
+
+	// Start background monitoring; the first pass runs immediately
+	StartBackgroundMonitoring()
 }
 
-// StartBackgroundMonitoring runs periodic health checks on all servers and VMs
-func StartBackgroundMonitoring() {
-	ticker := time.NewTicker(monitoringInterval)
-	defer ticker.Stop()
-	
-	for {
-		select {
-		case <-stopMonitoring:
-			return
-		case <-ticker.C:
-			// Run monitoring in separate goroutine to avoid blocking
-			go func() {
-				MonitorAllServers()
-				MonitorAllVMs()
-				MonitorAllSwitches()
-			}()
-		}
+// initHostKeys sets up host key verification against known_hosts
+func initHostKeys(cfg *config.Config) error {
+	knownHostsPath := cfg.SSH.KnownHostsFile
+	if knownHostsPath == "" {
+		knownHostsPath = filepath.Join(cfg.DataDir(), "known_hosts")
+	}
+	store, err := NewHostKeyStore(cfg.SSH.HostKeyMode, knownHostsPath, filepath.Join(cfg.DataDir(), "host_key_review.json"))
+	if err != nil {
+		return err
 	}
+	hostKeys = store
+	if store.Mode() == HostKeyModeInsecure {
+		log.Printf("WARNING: SSH host key verification is disabled (host_key_mode: insecure)")
+	} else {
+		log.Printf("SSH host key verification: %s mode, known_hosts %s", store.Mode(), knownHostsPath)
+	}
+	return nil
+}
+
+// StartBackgroundMonitoring starts periodic health checks on all devices
+func StartBackgroundMonitoring() {
+	scheduler.Start()
 }
 
 // StopBackgroundMonitoring stops the background monitoring routine
 func StopBackgroundMonitoring() {
-	select {
-	case stopMonitoring <- true:
-	default:
-	}
+	scheduler.Stop()
 }
 
 // StopMonitoring stops the monitoring service
@@ -189,8 +201,7 @@ func StartMonitoring() error {
 		return nil // Already running
 	}
 	
-	stopMonitoring = make(chan bool, 1) // Reset the channel
-	go StartBackgroundMonitoring()
+	StartBackgroundMonitoring()
 	isMonitoring = true
 	return nil
 }
@@ -211,110 +222,168 @@ func GetMonitoringStatus() bool {
 	return isMonitoring
 }
 
-// getSwitchSSHClient creates a switch-specific SSH client or returns the global client
-func getSwitchSSHClient(sw *models.Switch) *SSHClient {
-	// Find the switch config to get credentials
-	var swCfg *config.SwitchConfig
-	for _, cfg := range Config.Switches {
-		if cfg.ID == sw.ID {
-			swCfg = &cfg
-			break
-		}
-	}
-	
-	if swCfg == nil {
-		// Config not found, use global client
-		return sshClient
-	}
-	
-	// Check if switch has custom SSH credentials
-	hasCustomCreds := swCfg.SSHUsername != "" || swCfg.SSHPassword != "" || swCfg.SSHKeyPath != ""
-	
-	if !hasCustomCreds {
-		// No custom credentials, use global client
-		return sshClient
-	}
-	
-	// Use switch-specific credentials, fall back to global for missing values
-	username := swCfg.SSHUsername
-	if username == "" {
-		username = Config.SSH.Username
-	}
-	
-	password := swCfg.SSHPassword
-	if password == "" {
-		password = Config.SSH.Password
-	}
-	
-	keyPath := swCfg.SSHKeyPath
-	if keyPath == "" {
-		keyPath = Config.SSH.PrivateKeyPath
-	}
-	
-	timeout := Config.SSH.TimeoutSeconds
-	
-	// Create switch-specific SSH client
-	client, err := NewSSHClient(username, keyPath, password, timeout)
-	if err != nil {
-		fmt.Printf("Warning: Failed to create SSH client for switch %s: %v, falling back to global client\n", sw.Name, err)
-		return sshClient
-	}
-	
-	return client
-}
-
 // MonitorAllServers checks status of all servers
 func MonitorAllServers() {
-	for i := range ServersCache {
-		MonitorServer(&ServersCache[i])
+	for _, srv := range inventory.Snapshot().Servers {
+		refreshServer(srv.ID)
 	}
 }
 
 // MonitorAllVMs checks status of all VMs
 func MonitorAllVMs() {
-	for i := range VMsCache {
-		MonitorVM(&VMsCache[i])
+	for _, vm := range inventory.Snapshot().VMs {
+		refreshVM(vm.ID)
 	}
 }
 
 // MonitorAllSwitches checks status of all switches
 func MonitorAllSwitches() {
-	for i := range SwitchesCache {
-		MonitorSwitch(&SwitchesCache[i])
+	for _, sw := range inventory.Snapshot().Switches {
+		refreshSwitch(sw.ID)
 	}
 }
 
+// refreshes holds the device checks in progress, so that no device is
+// checked twice at once, e.g. by the scheduler and from the API
+var (
+	refreshMu sync.Mutex
+	refreshes = make(map[string]chan struct{})
+)
+
+// beginRefresh claims a device's check and returns the function that ends
+// it. While another check of the device runs it waits for that one to
+// finish instead and returns false; its result is the one to use.
+func beginRefresh(kind, id string) (func(), bool) {
+	key := kind + "/" + id
+	refreshMu.Lock()
+	if running, busy := refreshes[key]; busy {
+		refreshMu.Unlock()
+		<-running
+		return nil, false
+	}
+	done := make(chan struct{})
+	refreshes[key] = done
+	refreshMu.Unlock()
+	return func() {
+		refreshMu.Lock()
+		delete(refreshes, key)
+		refreshMu.Unlock()
+		close(done)
+	}, true
+}
+
+// refreshServer monitors a private copy of the server and stores the result.
+// Checks run outside the inventory lock so slow hosts never block readers.
+// Only the check itself changes a device while it runs, apart from
+// relabelDependents, so the status of a server that is down is derived
+// again from its parents as they are when the result is stored.
+func refreshServer(id string) (models.Server, bool) {
+	done, ok := beginRefresh(KindServer, id)
+	if !ok {
+		return inventory.Server(id)
+	}
+	defer done()
+	srv, ok := inventory.Server(id)
+	if !ok {
+		return models.Server{}, false
+	}
+	wasDown := srv.PingStatus == models.StatusOffline
+	MonitorServer(&srv)
+	updated, ok := inventory.UpdateServer(id, func(s *models.Server) {
+		*s = srv
+		labelDownServer(s)
+	})
+	if ok && wasDown != (updated.PingStatus == models.StatusOffline) {
+		relabelDependents(KindServer, id)
+	}
+	return updated, ok
+}
+
+// refreshVM monitors a private copy of the VM and stores the result.
+func refreshVM(id string) (models.VM, bool) {
+	done, ok := beginRefresh(KindVM, id)
+	if !ok {
+		return inventory.VM(id)
+	}
+	defer done()
+	vm, ok := inventory.VM(id)
+	if !ok {
+		return models.VM{}, false
+	}
+	MonitorVM(&vm)
+	return inventory.UpdateVM(id, func(v *models.VM) {
+		*v = vm
+		labelDownVM(v)
+	})
+}
+
+// refreshSwitch monitors a private copy of the switch and stores the result.
+func refreshSwitch(id string) (models.Switch, bool) {
+	done, ok := beginRefresh(KindSwitch, id)
+	if !ok {
+		return inventory.Switch(id)
+	}
+	defer done()
+	sw, ok := inventory.Switch(id)
+	if !ok {
+		return models.Switch{}, false
+	}
+	wasDown := sw.PingStatus == models.StatusOffline
+	MonitorSwitch(&sw)
+	updated, ok := inventory.UpdateSwitch(id, func(s *models.Switch) { *s = sw })
+	if ok && wasDown != (updated.PingStatus == models.StatusOffline) {
+		relabelDependents(KindSwitch, id)
+	}
+	return updated, ok
+}
+
 // MonitorServer performs health checks on a server
 func MonitorServer(srv *models.Server) {
 	// When using mock data, always show as online (no network checks needed)
 	if Config.Monitoring.UseMockData {
 		srv.PingStatus = "online"
 		srv.Status = "online"
-		useServerMockData(srv)
+		metrics := mockServerMetrics(srv.ID)
+		selectFilesystems(KindServer, srv.ID, metrics)
+		selectServices(KindServer, srv.ID, metrics)
+		srv.RecordCollection(CollectorMock, metrics, nil)
 		srv.LastChecked = time.Now()
 		return
 	}
 	
-	// Production mode: Check ping status using TCP connectivity
-	srv.PingStatus = CheckPingStatus(srv.IPAddress)
+	// Production mode: run the device's reachability probes. Hosts behind
+	// jump hosts are checked from the last jump host instead, and a recent
+	// agent push also counts as reachable.
+	client := sshClientFor(KindServer, srv.ID)
+	var reach models.Reachability
+	if client != nil && client.viaJumpHost() {
+		reach = client.probeReachability(srv.IPAddress, srv.Port)
+	} else {
+		reach = ProbeReachability(srv.IPAddress, reachabilityFor(KindServer, srv.ID), probeTimeout())
+	}
+	reach = withAgentProbe(KindServer, srv.ID, reach)
+	srv.ApplyReachability(reach)
+	srv.PingStatus = debounceStatus(&srv.StateTracker, reach.Status)
 	
+	srv.ParentDown = ""
 	if srv.PingStatus == "online" {
-		srv.Status = "online"
-		
-		// Use real SSH monitoring if available and configured
-		if Config.SSH.Enabled && sshClient != nil {
-			err := sshClient.GetRealServerMetrics(srv)
+		// A failed check that has not taken the server offline yet keeps
+		// the last status and metrics
+		if reach.Status == "online" {
+			srv.Status = "online"
+			
+			// Collect metrics with the device's collector. A failed collection
+			// leaves the last sample in place, marked stale.
+			name, metrics, fieldErrs, err := collectDevice(CollectTarget{Kind: KindServer, ID: srv.ID, Name: srv.Name, Address: srv.IPAddress, Port: srv.Port})
 			if err != nil {
-				// Fallback to mock data on error
-				fmt.Printf("SSH monitoring failed for %s: %v, using mock data\n", srv.Name, err)
-				useServerMockData(srv)
+				srv.RecordCollectionFailure(name, collectionFailureStatus(err, srv.MetricsCollectedAt), err)
+			} else {
+				srv.RecordCollection(name, metrics, metricErrorStrings(fieldErrs))
 			}
-		} else {
-			// Use mock data for development
-			useServerMockData(srv)
 		}
 	} else {
-		srv.Status = "offline"
+		srv.ParentDown = downParent("", srv.UplinkSwitchID)
+		srv.Status = downStatus(srv.ParentDown)
 		// Reset metrics for offline servers
 		srv.Uptime = "N/A"
 		srv.Processes = 0
@@ -326,61 +395,22 @@ func MonitorServer(srv *models.Server) {
 	srv.LastChecked = time.Now()
 }
 
-// useServerMockData generates mock metrics for a server
-func useServerMockData(srv *models.Server) {
-	srv.Uptime = GenerateUptime()
-	srv.Processes = rand.Intn(200) + 50 // 50-250 processes
-	srv.DiskTotal = 1000.0
-	srv.DiskUsage = generateDiskUsage(srv.DiskTotal, 30, 70) // 30-70% usage
-	srv.DiskPercent = (srv.DiskUsage / srv.DiskTotal) * 100
-	
-	// Memory metrics
-	srv.MemoryTotal = float64(rand.Intn(48000) + 16000) // 16-64 GB in MB
-	srv.MemoryUsed = generateDiskUsage(srv.MemoryTotal, 40, 80) // 40-80% usage
-	srv.MemoryPercent = (srv.MemoryUsed / srv.MemoryTotal) * 100
-	
-	// Load average - generate realistic values
-	load1 := float64(rand.Intn(400)+50) / 100.0  // 0.5-4.5
-	load5 := float64(rand.Intn(350)+60) / 100.0  // 0.6-4.1
-	load15 := float64(rand.Intn(300)+70) / 100.0 // 0.7-3.7
-	srv.LoadAverage = fmt.Sprintf("%.2f %.2f %.2f", load1, load5, load15)
-	
-	// Failed services - usually 0, occasionally 1-2
-	if rand.Float64() < 0.2 { // 20% chance
-		srv.FailedServices = rand.Intn(2) + 1
-	} else {
-		srv.FailedServices = 0
-	}
-	
-	// Inode usage
-	srv.InodeTotal = int64(rand.Intn(5000000) + 1000000) // 1M-6M inodes
-	srv.InodeUsed = int64(float64(srv.InodeTotal) * (float64(rand.Intn(40)+20) / 100.0)) // 20-60%
-	if srv.InodeTotal > 0 {
-		srv.InodePercent = (float64(srv.InodeUsed) / float64(srv.InodeTotal)) * 100
-	}
-	
-	// Network stats - cumulative MB since boot
-	srv.NetworkRxMB = float64(rand.Intn(500000) + 10000) // 10GB-500GB
-	srv.NetworkTxMB = float64(rand.Intn(200000) + 5000)  // 5GB-200GB
-	
-	// Kernel version - common versions
-	kernels := []string{"5.15.0-91-generic", "6.1.0-17-amd64", "5.10.0-27-arm64", "6.5.0-14-generic"}
-	srv.KernelVersion = kernels[rand.Intn(len(kernels))]
-}
-
 // MonitorVM performs health checks on a VM
 func MonitorVM(vm *models.VM) {
 	// When using mock data, always show as online (no network checks needed)
 	if Config.Monitoring.UseMockData {
 		vm.PingStatus = "online"
 		vm.Status = "running"
-		useVMMockData(vm)
+		metrics := mockVMMetrics(vm.ID)
+		selectFilesystems(KindVM, vm.ID, metrics)
+		selectServices(KindVM, vm.ID, metrics)
+		vm.RecordCollection(CollectorMock, metrics, nil)
 		
 		// Check stream status for configured ports
 		for j, port := range vm.StreamPorts {
 			if stream, err := CheckStreamOnPort(port); err == nil && stream != nil {
 				if j < len(vm.Streams) {
-					vm.Streams[j].Active = stream.Active
+					vm.Streams[j] = debounceStream(vm.Streams, port, stream.Active)
 				}
 			}
 		}
@@ -389,26 +419,39 @@ func MonitorVM(vm *models.VM) {
 		return
 	}
 	
-	// Production mode: Check ping status using TCP connectivity
-	vm.PingStatus = CheckPingStatus(vm.IPAddress)
+	// Production mode: run the device's reachability probes. Hosts behind
+	// jump hosts are checked from the last jump host instead, and a recent
+	// agent push also counts as reachable.
+	client := sshClientFor(KindVM, vm.ID)
+	var reach models.Reachability
+	if client != nil && client.viaJumpHost() {
+		reach = client.probeReachability(vm.IPAddress, vm.Port)
+	} else {
+		reach = ProbeReachability(vm.IPAddress, reachabilityFor(KindVM, vm.ID), probeTimeout())
+	}
+	reach = withAgentProbe(KindVM, vm.ID, reach)
+	vm.ApplyReachability(reach)
+	vm.PingStatus = debounceStatus(&vm.StateTracker, reach.Status)
 	
+	vm.ParentDown = ""
 	if vm.PingStatus == "online" {
-		vm.Status = "running"
-		
-		// Use real SSH monitoring if available and configured
-		if Config.SSH.Enabled && sshClient != nil {
-			err := sshClient.GetRealVMMetrics(vm)
+		// A failed check that has not taken the VM offline yet keeps
+		// the last status and metrics
+		if reach.Status == "online" {
+			vm.Status = "running"
+			
+			// Collect metrics with the device's collector. A failed collection
+			// leaves the last sample in place, marked stale.
+			name, metrics, fieldErrs, err := collectDevice(CollectTarget{Kind: KindVM, ID: vm.ID, Name: vm.Name, Address: vm.IPAddress, Port: vm.Port})
 			if err != nil {
-				// Fallback to mock data on error
-				fmt.Printf("SSH monitoring failed for %s: %v, using mock data\n", vm.Name, err)
-				useVMMockData(vm)
+				vm.RecordCollectionFailure(name, collectionFailureStatus(err, vm.MetricsCollectedAt), err)
+			} else {
+				vm.RecordCollection(name, metrics, metricErrorStrings(fieldErrs))
 			}
-		} else {
-			// Use mock data for development
-			useVMMockData(vm)
 		}
 	} else {
-		vm.Status = "offline"
+		vm.ParentDown = downParent(vm.HostServerID, vm.UplinkSwitchID)
+		vm.Status = downStatus(vm.ParentDown)
 		// Reset metrics for offline VMs
 		vm.Uptime = "N/A"
 		vm.Processes = 0
@@ -418,95 +461,61 @@ func MonitorVM(vm *models.VM) {
 	}
 	
 	// Check stream status for all configured ports
+	prevStreams := vm.Streams
 	vm.Streams = []models.StreamStatus{}
 	for _, port := range vm.StreamPorts {
-		streamStatus := models.StreamStatus{Port: port, Active: false}
+		active := false
 		if stream, err := CheckStreamOnPort(port); err == nil && stream != nil {
-			streamStatus.Active = stream.Active
+			active = stream.Active
 		}
-		vm.Streams = append(vm.Streams, streamStatus)
+		vm.Streams = append(vm.Streams, debounceStream(prevStreams, port, active))
 	}
 	
 	vm.LastChecked = time.Now()
 }
 
-// useVMMockData generates mock metrics for a VM
-func useVMMockData(vm *models.VM) {
-	vm.Uptime = GenerateUptime()
-	vm.Processes = rand.Intn(150) + 30 // 30-180 processes
-	vm.DiskTotal = 500.0
-	vm.DiskUsage = generateDiskUsage(vm.DiskTotal, 20, 60) // 20-60% usage
-	vm.DiskPercent = (vm.DiskUsage / vm.DiskTotal) * 100
-	
-	// Memory metrics
-	vm.MemoryTotal = float64(rand.Intn(24000) + 4000) // 4-28 GB in MB
-	vm.MemoryUsed = generateDiskUsage(vm.MemoryTotal, 35, 75) // 35-75% usage
-	vm.MemoryPercent = (vm.MemoryUsed / vm.MemoryTotal) * 100
-	
-	// Load average - generate realistic values
-	load1 := float64(rand.Intn(250)+30) / 100.0  // 0.3-2.8
-	load5 := float64(rand.Intn(220)+40) / 100.0  // 0.4-2.6
-	load15 := float64(rand.Intn(200)+50) / 100.0 // 0.5-2.5
-	vm.LoadAverage = fmt.Sprintf("%.2f %.2f %.2f", load1, load5, load15)
-	
-	// Failed services - usually 0, occasionally 1
-	if rand.Float64() < 0.15 { // 15% chance
-		vm.FailedServices = 1
-	} else {
-		vm.FailedServices = 0
-	}
-	
-	// Inode usage
-	vm.InodeTotal = int64(rand.Intn(3000000) + 500000) // 500K-3.5M inodes
-	vm.InodeUsed = int64(float64(vm.InodeTotal) * (float64(rand.Intn(35)+15) / 100.0)) // 15-50%
-	if vm.InodeTotal > 0 {
-		vm.InodePercent = (float64(vm.InodeUsed) / float64(vm.InodeTotal)) * 100
-	}
-	
-	// Network stats - cumulative MB since boot
-	vm.NetworkRxMB = float64(rand.Intn(200000) + 5000)  // 5GB-200GB
-	vm.NetworkTxMB = float64(rand.Intn(100000) + 2000)  // 2GB-100GB
-	
-	// Kernel version - common versions
-	kernels := []string{"5.15.0-91-generic", "6.1.0-17-amd64", "5.10.0-27-arm64", "6.5.0-14-generic"}
-	vm.KernelVersion = kernels[rand.Intn(len(kernels))]
-}
-
 // MonitorSwitch performs health checks on a switch
 func MonitorSwitch(sw *models.Switch) {
 	// When using mock data, always show as online (no network checks needed)
 	if Config.Monitoring.UseMockData {
 		sw.PingStatus = "online"
 		sw.Status = "online"
-		useSwitchMockData(sw)
+		metrics := mockSwitchMetrics(sw.ID)
+		selectFilesystems(KindSwitch, sw.ID, metrics)
+		selectServices(KindSwitch, sw.ID, metrics)
+		sw.RecordCollection(CollectorMock, metrics, nil)
 		sw.LastChecked = time.Now()
 		return
 	}
 	
-	// Production mode: Check ping status using TCP connectivity
-	sw.PingStatus = CheckPingStatus(sw.IPAddress)
+	// Production mode: run the device's reachability probes. Hosts behind
+	// jump hosts are checked from the last jump host instead, and a recent
+	// agent push also counts as reachable.
+	client := sshClientFor(KindSwitch, sw.ID)
+	var reach models.Reachability
+	if client != nil && client.viaJumpHost() {
+		reach = client.probeReachability(sw.IPAddress, sw.Port)
+	} else {
+		reach = ProbeReachability(sw.IPAddress, reachabilityFor(KindSwitch, sw.ID), probeTimeout())
+	}
+	reach = withAgentProbe(KindSwitch, sw.ID, reach)
+	sw.ApplyReachability(reach)
+	sw.PingStatus = debounceStatus(&sw.StateTracker, reach.Status)
 	
 	if sw.PingStatus == "online" {
-		sw.Status = "online"
-		
-		// Use real SSH monitoring if available and configured
-		if Config.SSH.Enabled {
-			// Get switch-specific SSH client or use global client
-			client := getSwitchSSHClient(sw)
-			if client != nil {
-				err := client.GetRealSwitchMetrics(sw)
-				if err != nil {
-					// Fallback to mock data on error
-					fmt.Printf("SSH monitoring failed for %s: %v, using mock data\n", sw.Name, err)
-					useSwitchMockData(sw)
-				}
+		// A failed check that has not taken the switch offline yet keeps
+		// the last status and metrics
+		if reach.Status == "online" {
+			sw.Status = "online"
+			
+			// Collect metrics with the device's collector. A failed collection
+			// leaves the last sample in place, marked stale.
+			name, metrics, fieldErrs, err := collectDevice(CollectTarget{Kind: KindSwitch, ID: sw.ID, Name: sw.Name, Address: sw.IPAddress, Port: sw.Port})
+			if err != nil {
+				sw.RecordCollectionFailure(name, collectionFailureStatus(err, sw.MetricsCollectedAt), err)
 			} else {
-				// Use mock data if no client available
-				useSwitchMockData(sw)
+				sw.RecordCollection(name, metrics, metricErrorStrings(fieldErrs))
 			}
-		} else {
-			// Use mock data for development
-			useSwitchMockData(sw)
 		}
 	} else {
 		sw.Status = "offline"
@@ -523,77 +532,20 @@ func MonitorSwitch(sw *models.Switch) {
 	sw.LastChecked = time.Now()
 }
 
-// useSwitchMockData generates mock metrics for a switch
-func useSwitchMockData(sw *models.Switch) {
-	sw.Uptime = GenerateUptime()
-	sw.Processes = rand.Intn(80) + 20 // 20-100 processes (lighter than servers)
-	sw.DiskTotal = 50.0 // Small Debian typically 8-64GB
-	sw.DiskUsage = generateDiskUsage(sw.DiskTotal, 15, 40) // 15-40% usage
-	sw.DiskPercent = (sw.DiskUsage / sw.DiskTotal) * 100
-	
-	// Memory metrics - switches have less RAM
-	sw.MemoryTotal = float64(rand.Intn(3000) + 1000) // 1-4 GB in MB
-	sw.MemoryUsed = generateDiskUsage(sw.MemoryTotal, 30, 60) // 30-60% usage
-	sw.MemoryPercent = (sw.MemoryUsed / sw.MemoryTotal) * 100
-	
-	// Load average - generate realistic low values for switches
-	load1 := float64(rand.Intn(100)+10) / 100.0  // 0.1-1.1
-	load5 := float64(rand.Intn(90)+15) / 100.0   // 0.15-1.05
-	load15 := float64(rand.Intn(80)+20) / 100.0  // 0.2-1.0
-	sw.LoadAverage = fmt.Sprintf("%.2f %.2f %.2f", load1, load5, load15)
-	
-	// Failed services - usually 0
-	if rand.Float64() < 0.05 { // 5% chance
-		sw.FailedServices = 1
-	} else {
-		sw.FailedServices = 0
-	}
-	
-	// Inode usage - small for switches
-	sw.InodeTotal = int64(rand.Intn(500000) + 200000) // 200K-700K inodes
-	sw.InodeUsed = int64(float64(sw.InodeTotal) * (float64(rand.Intn(25)+10) / 100.0)) // 10-35%
-	if sw.InodeTotal > 0 {
-		sw.InodePercent = (float64(sw.InodeUsed) / float64(sw.InodeTotal)) * 100
-	}
-	
-	// Network stats - switches handle lots of traffic
-	sw.NetworkRxMB = float64(rand.Intn(500000) + 50000)  // 50GB-500GB
-	sw.NetworkTxMB = float64(rand.Intn(500000) + 50000)  // 50GB-500GB
-	
-	// Kernel version - Debian versions
-	kernels := []string{"6.1.0-17-amd64", "5.10.0-27-amd64", "6.5.0-14-amd64"}
-	sw.KernelVersion = kernels[rand.Intn(len(kernels))]
-	
-	// OpenFlow specific metrics
-	sw.OpenFlowStatus = "active"
-	sw.FlowCount = rand.Intn(500) + 50 // 50-550 flow rules
-	sw.PortCount = rand.Intn(16) + 8   // 8-24 ports
-}
-
-// CheckPingStatus attempts TCP checks on multiple ports for connectivity
+// CheckPingStatus runs the default reachability probes against an address
+// that is not a configured device
 func CheckPingStatus(ipAddress string) string {
-	// Try TCP checks on common ports (HTTP, HTTPS, SSH, MySQL, PostgreSQL)
-	// Using sequential checks with short timeouts
-	ports := []int{80, 443, 22, 3306, 5432}
-	for _, port := range ports {
-		if IsReachableTCP(ipAddress, port) {
-			return "online"
-		}
+	rc := defaultReachability
+	if Config != nil && !Config.Monitoring.Reachability.IsZero() {
+		rc = Config.Monitoring.Reachability
 	}
-	
-	return "offline"
+	return ProbeReachability(ipAddress, rc, probeTimeout()).Status
 }
 
-// IsReachableTCP checks if a host is reachable on a specific TCP port
+// IsReachableTCP checks if a host accepts connections on a specific TCP port
+// within the configured probe timeout
 func IsReachableTCP(ipAddress string, port int) bool {
-	timeout := 1 * time.Second  // Shorter timeout per port
-	addr := fmt.Sprintf("%s:%d", ipAddress, port)
-	conn, err := net.DialTimeout("tcp", addr, timeout)
-	if err != nil {
-		return false
-	}
-	defer conn.Close()
-	return true
+	return probeTCP(ipAddress, port, probeTimeout()).Success
 }
 
 // GenerateUptime creates a realistic uptime string
@@ -633,39 +585,55 @@ func incrementIP(ip net.IP) {
 	}
 }
 
-// GetAllServers returns all configured servers
+// GetInventory returns the inventory store backing the device caches
+func GetInventory() *InventoryStore {
+	return inventory
+}
+
+// GetAllServers returns a snapshot of all configured servers
 func GetAllServers() ([]models.Server, error) {
-	return ServersCache, nil
+	return append([]models.Server(nil), inventory.Snapshot().Servers...), nil
 }
 
-// GetAllVMs returns all configured VMs
+// GetAllVMs returns a snapshot of all configured VMs
 func GetAllVMs() ([]models.VM, error) {
-	return VMsCache, nil
+	return append([]models.VM(nil), inventory.Snapshot().VMs...), nil
 }
 
-// GetAllSwitches returns all configured switches
+// GetAllSwitches returns a snapshot of all configured switches
 func GetAllSwitches() ([]models.Switch, error) {
-	return SwitchesCache, nil
+	return append([]models.Switch(nil), inventory.Snapshot().Switches...), nil
+}
+
+// GetServer returns a copy of a single server by ID
+func GetServer(id string) (models.Server, bool) {
+	return inventory.Server(id)
+}
+
+// GetVM returns a copy of a single VM by ID
+func GetVM(id string) (models.VM, bool) {
+	return inventory.VM(id)
+}
+
+// GetSwitch returns a copy of a single switch by ID
+func GetSwitch(id string) (models.Switch, bool) {
+	return inventory.Switch(id)
 }
 
 // CheckServerStatus checks the status of a specific server
 func CheckServerStatus(serverID string) (interface{}, error) {
-	for i := range ServersCache {
-		if ServersCache[i].ID == serverID {
-			MonitorServer(&ServersCache[i])
-			return ServersCache[i], nil
-		}
+	srv, ok := refreshServer(serverID)
+	if !ok {
+		return nil, fmt.Errorf("server not found: %s", serverID)
 	}
-	return nil, fmt.Errorf("server not found: %s", serverID)
+	return srv, nil
 }
 
 // CheckVMStatus checks the status of a specific VM
 func CheckVMStatus(vmID string) (string, error) {
-	for i := range VMsCache {
-		if VMsCache[i].ID == vmID {
-			MonitorVM(&VMsCache[i])
-			return VMsCache[i].Status, nil
-		}
+	vm, ok := refreshVM(vmID)
+	if !ok {
+		return "unknown", fmt.Errorf("vm not found: %s", vmID)
 	}
-	return "unknown", fmt.Errorf("vm not found: %s", vmID)
-}
\ No newline at end of file
+	return vm.Status, nil
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBeginRefreshSharesRunningCheck(t *testing.T) {
+	done, ok := beginRefresh(KindServer, "refresh-test")
+	if !ok {
+		t.Fatal("first refresh not claimed")
+	}
+	var wg sync.WaitGroup
+	waited := make(chan bool, 1)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, ok := beginRefresh(KindServer, "refresh-test")
+		waited <- ok
+	}()
+	select {
+	case <-waited:
+		t.Fatal("second refresh did not wait for the first")
+	case <-time.After(50 * time.Millisecond):
+	}
+	done()
+	wg.Wait()
+	if <-waited {
+		t.Fatal("second refresh ran its own check")
+	}
+	done, ok = beginRefresh(KindServer, "refresh-test")
+	if !ok {
+		t.Fatal("refresh not claimed after the first finished")
+	}
+	done()
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/models"
+	"server-dashboard/internal/notify"
+)
+
+// notifier sends alert notifications. It is nil when notifications are
+// disabled.
+var notifier *notify.Dispatcher
+
+// initNotifications sets up the configured channels. Channels with errors
+// are skipped and logged.
+func initNotifications(cfg *config.Config) {
+	if !cfg.Notifications.Enabled {
+		return
+	}
+	n := cfg.Notifications
+	d := notify.NewDispatcher(notify.Options{
+		MaxAttempts:  n.MaxAttempts,
+		RetryInitial: time.Duration(n.RetryInitialSeconds) * time.Second,
+		RetryMax:     time.Duration(n.RetryMaxSeconds) * time.Second,
+		Timeout:      time.Duration(n.TimeoutSeconds) * time.Second,
+	})
+	for _, c := range n.Channels {
+		ch, err := notificationChannel(c)
+		if err == nil {
+			err = d.Add(ch, notify.Route{Severities: c.Severities, Tags: c.Tags, SkipResolved: c.SkipResolved}, c.TitleTemplate, c.BodyTemplate)
+		}
+		if err != nil {
+			log.Printf("Notification channel %q disabled: %v", c.Name, err)
+		}
+	}
+	notifier = d
+	log.Printf("Notifications enabled with %d channels", len(d.Channels()))
+}
+
+// notificationChannel builds the channel a config entry describes
+func notificationChannel(c config.NotificationChannelConfig) (notify.Channel, error) {
+	if c.Name == "" {
+		return nil, fmt.Errorf("missing name")
+	}
+	client := &http.Client{}
+	switch strings.ToLower(c.Type) {
+	case "webhook":
+		if err := checkNotifyURL(c.URL); err != nil {
+			return nil, err
+		}
+		return &notify.Webhook{ChannelName: c.Name, URL: c.URL, Secret: c.Secret, Headers: c.Headers, Client: client}, nil
+	case "slack", "mattermost":
+		if err := checkNotifyURL(c.URL); err != nil {
+			return nil, err
+		}
+		return &notify.Slack{ChannelName: c.Name, Kind: strings.ToLower(c.Type), URL: c.URL, Channel: c.Channel, Username: c.Username, Client: client}, nil
+	case "email":
+		s := c.SMTP
+		if s.Host == "" || s.From == "" || len(s.To) == 0 {
+			return nil, fmt.Errorf("email needs smtp.host, smtp.from and smtp.to")
+		}
+		switch s.Security {
+		case "", notify.SMTPStartTLS, notify.SMTPTLS, notify.SMTPNone:
+		default:
+			return nil, fmt.Errorf("unknown smtp.security %q", s.Security)
+		}
+		password := s.Password
+		if s.PasswordEnv != "" {
+			password = os.Getenv(s.PasswordEnv)
+		}
+		return &notify.Email{
+			ChannelName:        c.Name,
+			Host:               s.Host,
+			Port:               s.Port,
+			Username:           s.Username,
+			Password:           password,
+			From:               s.From,
+			To:                 s.To,
+			Security:           s.Security,
+			InsecureSkipVerify: s.InsecureSkipVerify,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", c.Type)
+}
+
+func checkNotifyURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an http or https URL")
+	}
+	return nil
+}
+
+// notifyAlert sends a firing or resolved alert to the matching channels
+func notifyAlert(a models.Alert) {
+	if notifier == nil {
+		return
+	}
+	status := notify.StatusFiring
+	if a.State == models.AlertResolved {
+		status = notify.StatusResolved
+	}
+	notifier.Notify(status, dashboardLink(TargetPath(a.TargetKind, a.TargetID)), a)
+}
+
+// dashboardLink makes a path absolute with notifications.dashboard_url
+func dashboardLink(path string) string {
+	if Config == nil || Config.Notifications.DashboardURL == "" || path == "" {
+		return ""
+	}
+	return strings.TrimRight(Config.Notifications.DashboardURL, "/") + path
+}
+
+// TargetPath returns the detail page of a device or synthetic check
+func TargetPath(kind, id string) string {
+	id = url.PathEscape(id)
+	switch kind {
+	case KindServer:
+		return "/servers/" + id
+	case KindVM:
+		return "/vms/" + id
+	case KindSwitch:
+		return "/switches/" + id
+	case KindSynthetic:
+		return "/synthetics/" + id
+	}
+	return ""
+}
+
+// NotificationsEnabled reports whether alerts are sent anywhere
+func NotificationsEnabled() bool {
+	return notifier != nil
+}
+
+// NotificationChannels describes the configured channels
+func NotificationChannels() []notify.ChannelInfo {
+	if notifier == nil {
+		return nil
+	}
+	return notifier.Channels()
+}
+
+// NotificationDeliveries returns the delivery log, newest first
+func NotificationDeliveries() []notify.Delivery {
+	if notifier == nil {
+		return nil
+	}
+	return notifier.Deliveries()
+}
+
+// SendTestNotification queues a test message on one channel
+func SendTestNotification(channel string) error {
+	if notifier == nil {
+		return fmt.Errorf("notifications are disabled")
+	}
+	return notifier.Test(channel, dashboardLink("/alerts"))
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/models"
+)
+
+const defaultICMPCount = 3
+
+// defaultReachability is used when neither the device nor monitoring config
+// selects probes: ICMP plus the TCP ports that were checked historically
+var defaultReachability = config.ReachabilityConfig{
+	ICMP:     true,
+	TCPPorts: []int{22, 80, 443, 3306, 5432},
+}
+
+// reachabilityFor returns the probe selection for a device
+func reachabilityFor(kind, id string) config.ReachabilityConfig {
+	var override *config.ReachabilityConfig
+	switch kind {
+	case KindServer:
+		for _, c := range Config.Servers {
+			if c.ID == id {
+				override = c.Reachability
+			}
+		}
+	case KindVM:
+		for _, c := range Config.VirtualMachines {
+			if c.ID == id {
+				override = c.Reachability
+			}
+		}
+	case KindSwitch:
+		for _, c := range Config.Switches {
+			if c.ID == id {
+				override = c.Reachability
+			}
+		}
+	}
+	if override != nil && !override.IsZero() {
+		return *override
+	}
+	if !Config.Monitoring.Reachability.IsZero() {
+		return Config.Monitoring.Reachability
+	}
+	return defaultReachability
+}
+
+// probeTimeout is the per-probe timeout from monitoring.ping_timeout_seconds
+func probeTimeout() time.Duration {
+	if Config != nil && Config.Monitoring.PingTimeoutSeconds > 0 {
+		return time.Duration(Config.Monitoring.PingTimeoutSeconds) * time.Second
+	}
+	return 2 * time.Second
+}
+
+// ProbeReachability runs the selected probes against address in parallel
+func ProbeReachability(address string, rc config.ReachabilityConfig, timeout time.Duration) models.Reachability {
+	type probe func() models.ProbeResult
+	var probes []probe
+	if rc.ICMP {
+		count := rc.ICMPCount
+		if count <= 0 {
+			count = defaultICMPCount
+		}
+		probes = append(probes, func() models.ProbeResult { return probeICMP(address, count, timeout) })
+	}
+	for _, port := range rc.TCPPorts {
+		port := port
+		probes = append(probes, func() models.ProbeResult { return probeTCP(address, port, timeout) })
+	}
+	for _, url := range rc.HTTPURLs {
+		url := url
+		probes = append(probes, func() models.ProbeResult { return probeHTTP(url, timeout) })
+	}
+
+	results := make([]models.ProbeResult, len(probes))
+	var wg sync.WaitGroup
+	for i, p := range probes {
+		wg.Add(1)
+		go func(i int, p probe) {
+			defer wg.Done()
+			results[i] = p()
+		}(i, p)
+	}
+	wg.Wait()
+	return models.NewReachability(results)
+}
+
+// probeTCP connects to address:port
+func probeTCP(address string, port int, timeout time.Duration) models.ProbeResult {
+	addr := net.JoinHostPort(address, strconv.Itoa(port))
+	result := models.ProbeResult{Type: models.ProbeTCP, Target: addr}
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	conn.Close()
+	result.Success = true
+	result.RTTMillis = millis(time.Since(start))
+	return result
+}
+
+// probeHTTP requests a health URL; any status below 400 counts as healthy.
+// Certificates are not verified: the probe is about reachability, and
+// devices commonly serve self-signed certificates.
+func probeHTTP(url string, timeout time.Duration) models.ProbeResult {
+	result := models.ProbeResult{Type: models.ProbeHTTP, Target: url}
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	resp.Body.Close()
+	result.RTTMillis = millis(time.Since(start))
+	if resp.StatusCode >= 400 {
+		result.Error = fmt.Sprintf("unhealthy status %d", resp.StatusCode)
+		return result
+	}
+	result.Success = true
+	return result
+}
+
+// probeICMP sends count echo requests and reports average RTT and loss
+func probeICMP(address string, count int, timeout time.Duration) models.ProbeResult {
+	result := models.ProbeResult{Type: models.ProbeICMP, Target: address}
+	rtts, err := icmpEcho(address, count, timeout)
+	if err != nil {
+		// Nothing was measured, so there is no packet loss to report
+		result.Error = err.Error()
+		return result
+	}
+	if len(rtts) == 0 {
+		result.Error = "no echo replies"
+		result.PacketLoss = 100
+		return result
+	}
+	var total time.Duration
+	for _, rtt := range rtts {
+		total += rtt
+	}
+	result.Success = true
+	result.RTTMillis = millis(total / time.Duration(len(rtts)))
+	result.PacketLoss = 100 * float64(count-len(rtts)) / float64(count)
+	return result
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/models"
+)
+
+// closedPort returns a local port nothing listens on
+func closedPort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	return port
+}
+
+func TestProbeReachability(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	open := ln.Addr().(*net.TCPAddr).Port
+	closed := closedPort(t)
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer healthy.Close()
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unhealthy.Close()
+	tlsHealthy := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsHealthy.Close()
+
+	tests := []struct {
+		name   string
+		rc     config.ReachabilityConfig
+		status string
+		errors []string // Error of each probe, empty for success
+	}{
+		{"open port", config.ReachabilityConfig{TCPPorts: []int{closed, open}}, models.StatusOnline,
+			[]string{"connection refused", ""}},
+		{"closed port", config.ReachabilityConfig{TCPPorts: []int{closed}}, models.StatusOffline,
+			[]string{"connection refused"}},
+		{"healthy URL", config.ReachabilityConfig{HTTPURLs: []string{unhealthy.URL, healthy.URL}}, models.StatusOnline,
+			[]string{"unhealthy status 503", ""}},
+		{"self-signed certificate", config.ReachabilityConfig{HTTPURLs: []string{tlsHealthy.URL}}, models.StatusOnline,
+			[]string{""}},
+		{"unreachable URL", config.ReachabilityConfig{HTTPURLs: []string{"http://127.0.0.1:" + strconv.Itoa(closed)}}, models.StatusOffline,
+			[]string{"connection refused"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := ProbeReachability("127.0.0.1", tt.rc, time.Second)
+			if r.Status != tt.status || len(r.Probes) != len(tt.errors) {
+				t.Fatalf("status %q, probes %+v", r.Status, r.Probes)
+			}
+			for i, p := range r.Probes {
+				want := tt.errors[i]
+				if p.Success != (want == "") || !strings.Contains(p.Error, want) {
+					t.Errorf("probe %s: success %v, error %q, want %q", p.Target, p.Success, p.Error, want)
+				}
+				if p.Success && p.RTTMillis <= 0 {
+					t.Errorf("probe %s: no round trip", p.Target)
+				}
+			}
+		})
+	}
+}
+
+func TestIsReachableTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if !IsReachableTCP("127.0.0.1", ln.Addr().(*net.TCPAddr).Port) {
+		t.Error("listening port unreachable")
+	}
+	if IsReachableTCP("127.0.0.1", closedPort(t)) {
+		t.Error("closed port reachable")
+	}
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMonitoringInterval = 5 * time.Second
+	defaultMonitoringWorkers  = 8
+	defaultJitterPercent      = 10
+	defaultMaxBackoff         = 5 * time.Minute
+)
+
+// monitorJob identifies a single device to be checked by a worker
+type monitorJob struct {
+	kind string
+	id   string
+}
+
+func (j monitorJob) key() string {
+	return j.kind + "/" + j.id
+}
+
+// deviceSchedule tracks when a device is next due and whether it is being checked
+type deviceSchedule struct {
+	nextDue      time.Time
+	inFlight     bool
+	failures     int
+	lastDuration time.Duration // How long the last check took
+}
+
+// Scheduler runs device checks on a bounded worker pool. Each device is
+// scheduled independently: it is never checked by two workers at once, its
+// next run is spread by a random jitter, and unreachable devices back off
+// exponentially up to maxBackoff.
+type Scheduler struct {
+	interval   time.Duration
+	workers    int
+	jitter     float64
+	maxBackoff time.Duration
+
+	mu      sync.Mutex
+	devices map[string]*deviceSchedule
+	stop    chan struct{}
+	running bool
+
+	// Totals over every completed check, for the /metrics exposition
+	checks       uint64
+	checkSeconds float64
+}
+
+// CheckStats summarises completed device checks
+type CheckStats struct {
+	Count        uint64
+	TotalSeconds float64
+	Last         map[string]time.Duration // Duration of each device's last check, keyed by kind + "/" + ID
+}
+
+// NewScheduler creates a scheduler. Zero values fall back to defaults,
+// except that a jitterPercent of 0 disables jitter and a negative one
+// selects the default.
+func NewScheduler(interval time.Duration, workers, jitterPercent int, maxBackoff time.Duration) *Scheduler {
+	if interval <= 0 {
+		interval = defaultMonitoringInterval
+	}
+	if workers <= 0 {
+		workers = defaultMonitoringWorkers
+	}
+	if jitterPercent < 0 {
+		jitterPercent = defaultJitterPercent
+	}
+	if jitterPercent > 50 {
+		jitterPercent = 50
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+	if maxBackoff < interval {
+		maxBackoff = interval
+	}
+	return &Scheduler{
+		interval:   interval,
+		workers:    workers,
+		jitter:     float64(jitterPercent) / 100.0,
+		maxBackoff: maxBackoff,
+		devices:    make(map[string]*deviceSchedule),
+	}
+}
+
+// Interval returns the base monitoring interval
+func (s *Scheduler) Interval() time.Duration {
+	return s.interval
+}
+
+// Start launches the dispatcher and worker pool. It is a no-op if already running.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return
+	}
+	s.running = true
+	s.stop = make(chan struct{})
+
+	jobs := make(chan monitorJob, s.workers)
+	for i := 0; i < s.workers; i++ {
+		go s.worker(jobs, s.stop)
+	}
+	go s.dispatch(jobs, s.stop)
+}
+
+// Stop halts dispatching. Checks already in progress or queued are allowed
+// to finish.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		return
+	}
+	close(s.stop)
+	s.running = false
+}
+
+// dispatch wakes up regularly and queues every device that is due. Once
+// stopped it takes back jobs no worker picked up, so that the devices are
+// not left marked in flight when the scheduler is started again.
+func (s *Scheduler) dispatch(jobs chan monitorJob, stop <-chan struct{}) {
+	tick := time.Second
+	if s.interval < tick {
+		tick = s.interval
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	s.enqueueDue(jobs)
+	for {
+		select {
+		case <-stop:
+			for {
+				select {
+				case job := <-jobs:
+					s.release(job)
+				default:
+					return
+				}
+			}
+		case <-ticker.C:
+			s.enqueueDue(jobs)
+		}
+	}
+}
+
+// release clears the in-flight mark of a job that will not run
+func (s *Scheduler) release(job monitorJob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state := s.devices[job.key()]; state != nil {
+		state.inFlight = false
+	}
+}
+
+func (s *Scheduler) enqueueDue(jobs chan<- monitorJob) {
+	snap := inventory.Snapshot()
+	var due []monitorJob
+	for _, srv := range snap.Servers {
+		due = append(due, monitorJob{kind: KindServer, id: srv.ID})
+	}
+	for _, vm := range snap.VMs {
+		due = append(due, monitorJob{kind: KindVM, id: vm.ID})
+	}
+	for _, sw := range snap.Switches {
+		due = append(due, monitorJob{kind: KindSwitch, id: sw.ID})
+	}
+
+	now := time.Now()
+	for _, job := range due {
+		s.mu.Lock()
+		state, ok := s.devices[job.key()]
+		if !ok {
+			// Spread the first checks over the jitter window so that devices
+			// added together do not stay in step
+			state = &deviceSchedule{nextDue: now.Add(time.Duration(rand.Float64() * float64(s.interval) * s.jitter))}
+			s.devices[job.key()] = state
+		}
+		if state.inFlight || now.Before(state.nextDue) {
+			s.mu.Unlock()
+			continue
+		}
+		state.inFlight = true
+		s.mu.Unlock()
+
+		select {
+		case jobs <- job:
+		default:
+			// All workers are busy; pick the device up again on the next tick
+			s.mu.Lock()
+			state.inFlight = false
+			s.mu.Unlock()
+			return
+		}
+	}
+}
+
+// worker runs queued checks. When stopped it first runs the checks still
+// queued, which the dispatcher already marked in flight.
+func (s *Scheduler) worker(jobs <-chan monitorJob, stop <-chan struct{}) {
+	for {
+		select {
+		case job := <-jobs:
+			s.run(job)
+		case <-stop:
+			for {
+				select {
+				case job := <-jobs:
+					s.run(job)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// run checks a single device
+func (s *Scheduler) run(job monitorJob) {
+	start := time.Now()
+	reachable := true
+	switch job.kind {
+	case KindServer:
+		if srv, ok := refreshServer(job.id); ok {
+			reachable = srv.PingStatus == "online"
+		}
+	case KindVM:
+		if vm, ok := refreshVM(job.id); ok {
+			reachable = vm.PingStatus == "online"
+		}
+	case KindSwitch:
+		if sw, ok := refreshSwitch(job.id); ok {
+			reachable = sw.PingStatus == "online"
+		}
+	}
+
+	s.finish(job, start, reachable)
+}
+
+// finish records a completed check and computes when the device is next due
+func (s *Scheduler) finish(job monitorJob, start time.Time, reachable bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.devices[job.key()]
+	if state == nil {
+		return
+	}
+	state.inFlight = false
+	state.lastDuration = time.Since(start)
+	s.checks++
+	s.checkSeconds += state.lastDuration.Seconds()
+	if reachable {
+		state.failures = 0
+	} else {
+		state.failures++
+	}
+	state.nextDue = start.Add(s.nextDelay(state.failures))
+}
+
+// Stats returns check counts and durations
+func (s *Scheduler) Stats() CheckStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := CheckStats{Count: s.checks, TotalSeconds: s.checkSeconds, Last: make(map[string]time.Duration)}
+	for key, state := range s.devices {
+		if state.lastDuration > 0 {
+			stats.Last[key] = state.lastDuration
+		}
+	}
+	return stats
+}
+
+// nextDelay returns the interval with jitter applied, doubled for every
+// consecutive failure beyond the first and capped at maxBackoff
+func (s *Scheduler) nextDelay(failures int) time.Duration {
+	delay := s.interval
+	for i := 1; i < failures && delay < s.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > s.maxBackoff {
+		delay = s.maxBackoff
+	}
+	if s.jitter > 0 {
+		spread := float64(delay) * s.jitter
+		delay += time.Duration((rand.Float64()*2 - 1) * spread)
+	}
+	return delay
+}
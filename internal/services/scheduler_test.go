@@ -0,0 +1,152 @@
+package services
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"server-dashboard/internal/models"
+)
+
+// withInventory replaces the inventory for one test
+func withInventory(t *testing.T, servers []models.Server, vms []models.VM, switches []models.Switch) {
+	t.Helper()
+	old := inventory
+	inventory = NewInventoryStore()
+	inventory.Load(servers, vms, switches)
+	t.Cleanup(func() { inventory = old })
+}
+
+// queuedJob returns a scheduler with one job queued and marked in flight,
+// as enqueueDue leaves it, plus a stop channel that is already closed
+func queuedJob() (*Scheduler, monitorJob, chan monitorJob, chan struct{}) {
+	s := NewScheduler(time.Hour, 1, 0, 0)
+	job := monitorJob{kind: KindServer, id: "scheduler-test"}
+	s.devices[job.key()] = &deviceSchedule{inFlight: true}
+	jobs := make(chan monitorJob, 1)
+	jobs <- job
+	stop := make(chan struct{})
+	close(stop)
+	return s, job, jobs, stop
+}
+
+func TestWorkerRunsQueuedJobsWhenStopped(t *testing.T) {
+	s, job, jobs, stop := queuedJob()
+	s.worker(jobs, stop)
+	if len(jobs) != 0 {
+		t.Fatal("queued job left behind")
+	}
+	if state := s.devices[job.key()]; state.inFlight || state.nextDue.IsZero() {
+		t.Fatalf("job did not run: %+v", state)
+	}
+}
+
+func TestDispatcherReleasesQueuedJobsWhenStopped(t *testing.T) {
+	s, job, jobs, stop := queuedJob()
+	s.dispatch(jobs, stop)
+	if len(jobs) != 0 {
+		t.Fatal("queued job left behind")
+	}
+	if s.devices[job.key()].inFlight {
+		t.Fatal("device still marked in flight")
+	}
+}
+
+func TestNewSchedulerJitter(t *testing.T) {
+	tests := []struct {
+		percent int
+		want    float64
+	}{
+		{-1, 0.1},
+		{0, 0},
+		{25, 0.25},
+		{80, 0.5},
+	}
+	for _, tt := range tests {
+		if s := NewScheduler(time.Minute, 1, tt.percent, 0); s.jitter != tt.want {
+			t.Errorf("jitter_percent %d: jitter %v, want %v", tt.percent, s.jitter, tt.want)
+		}
+	}
+}
+
+func TestNextDelayJitterBounds(t *testing.T) {
+	s := NewScheduler(10*time.Second, 1, 20, time.Minute)
+	lo, hi := time.Duration(1<<62), time.Duration(0)
+	for i := 0; i < 1000; i++ {
+		d := s.nextDelay(0)
+		if d < lo {
+			lo = d
+		}
+		if d > hi {
+			hi = d
+		}
+	}
+	if lo < 8*time.Second || hi > 12*time.Second {
+		t.Errorf("delays from %v to %v, want within 8s to 12s", lo, hi)
+	}
+	if hi-lo < 2*time.Second {
+		t.Errorf("delays from %v to %v are hardly spread", lo, hi)
+	}
+
+	// Jitter also applies around the capped backoff
+	for i := 0; i < 100; i++ {
+		if d := s.nextDelay(10); d < 48*time.Second || d > 72*time.Second {
+			t.Fatalf("capped delay %v, want within 48s to 72s", d)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	s := NewScheduler(10*time.Second, 1, 0, time.Minute)
+	job := monitorJob{kind: KindServer, id: "backoff-test"}
+	s.devices[job.key()] = &deviceSchedule{}
+	start := time.Now()
+
+	// Each check starts when the last one made the device due
+	for i, want := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute} {
+		s.finish(job, start, false)
+		state := s.devices[job.key()]
+		if got := state.nextDue.Sub(start); got != want || state.failures != i+1 {
+			t.Fatalf("failure %d: next check after %v, want %v", state.failures, got, want)
+		}
+		start = state.nextDue
+	}
+
+	s.finish(job, start, true)
+	if state := s.devices[job.key()]; state.failures != 0 || state.nextDue.Sub(start) != 10*time.Second {
+		t.Errorf("after recovering: %d failures, next check after %v", state.failures, state.nextDue.Sub(start))
+	}
+}
+
+// New devices are first checked within the jitter window rather than all
+// at once
+func TestInitialChecksSpread(t *testing.T) {
+	var servers []models.Server
+	for i := 0; i < 50; i++ {
+		servers = append(servers, models.Server{ID: "spread" + strconv.Itoa(i)})
+	}
+	withInventory(t, servers, nil, nil)
+
+	for _, percent := range []int{0, 50} {
+		s := NewScheduler(time.Hour, 1, percent, 0)
+		jobs := make(chan monitorJob, len(servers))
+		before := time.Now()
+		s.enqueueDue(jobs)
+		after := time.Now()
+
+		window := time.Duration(float64(time.Hour) * s.jitter)
+		due := make(map[time.Time]bool)
+		for key, state := range s.devices {
+			if state.nextDue.Before(before) || state.nextDue.After(after.Add(window)) {
+				t.Errorf("jitter %d%%: %s first due %v after start", percent, key, state.nextDue.Sub(before))
+			}
+			due[state.nextDue] = true
+		}
+		if percent == 0 && len(jobs) != len(servers) {
+			t.Errorf("without jitter %d of %d devices queued at once", len(jobs), len(servers))
+		}
+		if percent > 0 && (len(jobs) > 0 || len(due) < len(servers)/2) {
+			t.Errorf("jitter %d%%: %d devices queued at once, %d distinct due times", percent, len(jobs), len(due))
+		}
+	}
+}
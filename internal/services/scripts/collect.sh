@@ -0,0 +1,152 @@
+#!/bin/sh
+# Server Dashboard metrics collector.
+#
+# Runs on the monitored host in a single SSH exec (piped to "sh -s") and
+# prints key=value pairs grouped into [sections]. The first line carries the
+# format version; bump it in both this script and collector_script.go when
+# keys change incompatibly. Commands that fail simply leave their keys out
+# so the parser can report exactly which metric is missing.
+
+echo "collector_version=2"
+
+echo "[system]"
+echo "kernel=$(uname -r 2>/dev/null)"
+if [ -r /proc/uptime ]; then
+	read up_secs _ < /proc/uptime
+	echo "uptime_seconds=${up_secs%.*}"
+fi
+up_pretty=$(uptime -p 2>/dev/null)
+[ -n "$up_pretty" ] && echo "uptime=$up_pretty"
+echo "processes=$(ls -d /proc/[0-9]* 2>/dev/null | wc -l)"
+if [ -r /proc/loadavg ]; then
+	read l1 l5 l15 _ < /proc/loadavg
+	echo "load=$l1 $l5 $l15"
+fi
+
+echo "[cpu]"
+# Cumulative time of all cores since boot from the first line of /proc/stat,
+# in clock ticks: times=<user> <nice> <system> <idle> <iowait> <irq> <softirq> <steal>
+awk '/^cpu / { print "times=" $2, $3, $4, $5, $6, $7, $8, $9 + 0 } /^cpu[0-9]/ { n++ } END { print "cores=" n + 0 }' /proc/stat 2>/dev/null
+echo "clock_ticks=$(getconf CLK_TCK 2>/dev/null)"
+
+echo "[pressure]"
+# Pressure stall averages in percent, on kernels with PSI (4.20+):
+# <resource>_<some|full>=<avg10> <avg60> <avg300>
+for resource in cpu memory io; do
+	awk -v r="$resource" '{
+		split($2, a, "="); split($3, b, "="); split($4, c, "=")
+		print r "_" $1 "=" a[2], b[2], c[2]
+	}' "/proc/pressure/$resource" 2>/dev/null
+done
+
+echo "[memory]"
+awk '/^MemTotal:/ { print "total_kb=" $2 } /^MemAvailable:/ { print "available_kb=" $2 }' /proc/meminfo 2>/dev/null
+
+echo "[disk]"
+df -Pk / 2>/dev/null | awk 'NR == 2 { print "total_kb=" $2; print "used_kb=" $3; print "percent=" $5 }'
+
+echo "[inodes]"
+df -Pi / 2>/dev/null | awk 'NR == 2 { print "total=" $2; print "used=" $3; print "percent=" $5 }'
+
+echo "[filesystems]"
+# One line per mount with blocks, joining df's block and inode usage with the
+# type, device and options from /proc/mounts (sizes in kB, "-" if unknown).
+# Mountpoints and devices keep the octal escapes of /proc/mounts, such as
+# \040 for a space, so that every field is a single word:
+# mount=<mountpoint> <fstype> <device> <size> <used> <avail> <inodes> <inodes_used> <ro|rw>
+{
+	# df prints mountpoints unescaped and last; escape them to match /proc/mounts
+	df -Pk 2>/dev/null | awk 'NR > 1 && NF >= 6 { print "k", $2, $3, $4, $0 }'
+	df -Pi 2>/dev/null | awk 'NR > 1 && NF >= 6 { print "i", $2, $3, 0, $0 }'
+	awk '{ print "m", $2, $3, $1, $4 }' /proc/mounts 2>/dev/null
+} | awk '
+function escape(s,    out, i, c) {
+	out = ""
+	for (i = 1; i <= length(s); i++) {
+		c = substr(s, i, 1)
+		if (c == " ") c = "\\040"
+		else if (c == "\t") c = "\\011"
+		else if (c == "\\") c = "\\134"
+		out = out c
+	}
+	return out
+}
+$1 == "k" || $1 == "i" {
+	# Drop the three values and the first five df columns before the mountpoint
+	mp = $0
+	for (i = 1; i <= 9; i++) sub(/^[^ \t]+[ \t]+/, "", mp)
+	mp = escape(mp)
+}
+$1 == "k" && $2 > 0 && !(mp in size) { size[mp] = $2; used[mp] = $3; avail[mp] = $4; order[++n] = mp }
+$1 == "i" { inodes[mp] = $2 + 0; iused[mp] = $3 + 0 }
+$1 == "m" { fstype[$2] = $3; device[$2] = $4; ro[$2] = ($5 ~ /^ro(,|$)/) ? "ro" : "rw" }
+END {
+	for (i = 1; i <= n; i++) {
+		mp = order[i]
+		if (!(mp in fstype)) { fstype[mp] = "-"; device[mp] = "-"; ro[mp] = "rw" }
+		print "mount=" mp, fstype[mp], device[mp], size[mp], used[mp], avail[mp], inodes[mp] + 0, iused[mp] + 0, ro[mp]
+	}
+}'
+
+echo "[network]"
+awk 'NR > 2 {
+	sub(/^ +/, "")
+	split($0, parts, ":")
+	if (parts[1] != "lo") {
+		split(parts[2], f, " ")
+		rx += f[1]
+		tx += f[9]
+	}
+} END { printf "rx_bytes=%.0f\ntx_bytes=%.0f\n", rx, tx }' /proc/net/dev 2>/dev/null
+
+echo "[interfaces]"
+# One line per interface except loopback, with counters from /proc/net/dev
+# and state and speed (Mbit/s, 0 if unknown) from /sys/class/net:
+# iface=<name> <operstate> <speed> <rx_bytes> <rx_packets> <rx_errors> <rx_dropped> <tx_bytes> <tx_packets> <tx_errors> <tx_dropped>
+awk 'NR > 2 {
+	sub(/^ +/, "")
+	split($0, parts, ":")
+	split(parts[2], f, " ")
+	if (parts[1] != "lo") print parts[1], f[1], f[2], f[3], f[4], f[9], f[10], f[11], f[12]
+}' /proc/net/dev 2>/dev/null | while read -r name counters; do
+	state=$(cat "/sys/class/net/$name/operstate" 2>/dev/null)
+	speed=$(cat "/sys/class/net/$name/speed" 2>/dev/null)
+	case $speed in
+	'' | *[!0-9]*) speed=0 ;;
+	esac
+	echo "iface=$name ${state:-unknown} $speed $counters"
+done
+
+echo "[services]"
+# systemctl may be installed without systemd running, e.g. in containers
+if command -v systemctl >/dev/null 2>&1 && [ -d /run/systemd/system ]; then
+	echo "available=1"
+	echo "failed=$(systemctl --failed --no-legend --plain --no-pager 2>/dev/null | grep -c .)"
+	awk '/^btime / { print "boot_time=" $2 }' /proc/stat 2>/dev/null
+	# Every service unit and failed units of any type:
+	# unit=<name> <load> <active> <sub>
+	systemctl list-units --all --no-legend --plain --no-pager 2>/dev/null | awk '
+	$1 == "●" { $1 = ""; $0 = $0 }
+	$1 ~ /\.service$/ || $3 == "failed" { print "unit=" $1, $2, $3, $4 }'
+	# Whether service units are enabled: unit_file=<name> <state>
+	systemctl list-unit-files --type=service --no-legend --no-pager 2>/dev/null | awk 'NF >= 2 { print "unit_file=" $1, $2 }'
+	# Why and when each failed unit failed, in microseconds since boot; a unit
+	# enters the inactive state when it fails:
+	# failure=<name> <result> <state_change_monotonic_usec>
+	for unit in $(systemctl --failed --no-legend --plain --no-pager 2>/dev/null | awk '{ print ($1 == "●") ? $2 : $1 }'); do
+		systemctl show -p Result,InactiveEnterTimestampMonotonic "$unit" 2>/dev/null | awk -F= -v unit="$unit" '
+		$1 == "Result" { result = $2 }
+		$1 == "InactiveEnterTimestampMonotonic" { since = $2 }
+		END { print "failure=" unit, (result == "" ? "unknown" : result), since + 0 }'
+	done
+else
+	echo "available=0"
+fi
+
+if command -v ovs-vsctl >/dev/null 2>&1; then
+	echo "[openflow]"
+	echo "controller=$(ovs-vsctl get-controller br0 2>/dev/null | head -1)"
+	echo "flows=$(ovs-ofctl dump-flows br0 2>/dev/null | grep -c 'cookie=')"
+	echo "ports=$(ovs-ofctl show br0 2>/dev/null | grep -c ' addr:')"
+	echo "version=$(ovs-ofctl -V 2>/dev/null | head -1 | awk '{ print $NF }')"
+fi
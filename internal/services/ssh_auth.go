@@ -0,0 +1,254 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"server-dashboard/internal/config"
+)
+
+// newSSHClientConfig builds the client config for one credential set. It
+// also returns a description of every auth method that loaded, for the
+// startup log. Problems with individual methods are logged and skipped; it
+// only fails when no method is usable at all.
+func newSSHClientConfig(creds config.SSHProfile, timeoutSeconds int) (*ssh.ClientConfig, []string, error) {
+	clientConfig := &ssh.ClientConfig{
+		User:            creds.Username,
+		HostKeyCallback: hostKeyCallback(),
+		Timeout:         time.Duration(timeoutSeconds) * time.Second,
+	}
+	var methods []string
+
+	// The SSH library tries each auth method type once, so every signer
+	// (certificate, key file, agent) has to go into a single publickey method
+	var signers []ssh.Signer
+	var keyErr error
+	if creds.PrivateKeyPath != "" {
+		keySigners, desc, err := loadKeySigners(creds)
+		if err != nil {
+			keyErr = err
+			log.Printf("SSH key for %s not loaded: %v", creds.Username, err)
+		} else {
+			signers = append(signers, keySigners...)
+			methods = append(methods, desc...)
+		}
+	}
+
+	var sshAgent *agentSigner
+	if creds.UseAgent {
+		sock := os.Getenv("SSH_AUTH_SOCK")
+		if sock == "" {
+			log.Printf("SSH agent for %s not used: SSH_AUTH_SOCK is not set", creds.Username)
+		} else {
+			sshAgent = sharedAgent(sock)
+			n, err := sshAgent.count()
+			if err != nil {
+				log.Printf("SSH agent at %s not reachable yet: %v", sock, err)
+				methods = append(methods, "agent (unreachable)")
+			} else {
+				methods = append(methods, fmt.Sprintf("agent (%d keys)", n))
+			}
+		}
+	}
+
+	if len(signers) > 0 || sshAgent != nil {
+		clientConfig.Auth = append(clientConfig.Auth, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+			all := append([]ssh.Signer{}, signers...)
+			if sshAgent != nil {
+				agentSigners, err := sshAgent.signers()
+				if err != nil {
+					log.Printf("SSH agent signing unavailable: %v", err)
+				}
+				all = append(all, agentSigners...)
+			}
+			return all, nil
+		}))
+	}
+
+	// Fallback to password authentication
+	if creds.Password != "" {
+		clientConfig.Auth = append(clientConfig.Auth, ssh.Password(creds.Password))
+		methods = append(methods, "password")
+	}
+
+	if len(methods) == 0 {
+		if keyErr != nil {
+			return nil, nil, fmt.Errorf("no usable authentication method: %w", keyErr)
+		}
+		return nil, nil, fmt.Errorf("no authentication method configured")
+	}
+	return clientConfig, methods, nil
+}
+
+// loadKeySigners reads the private key, decrypting it if needed, and pairs
+// it with its OpenSSH certificate when there is one. The certificate signer
+// comes first so servers that trust the CA accept it without the plain key.
+func loadKeySigners(creds config.SSHProfile) ([]ssh.Signer, []string, error) {
+	keyPath := expandHome(creds.PrivateKeyPath)
+	pemBytes, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signer, err := ssh.ParsePrivateKey(pemBytes)
+	encrypted := false
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		encrypted = true
+		passphrase, perr := keyPassphrase(creds)
+		if perr != nil {
+			return nil, nil, fmt.Errorf("%s is passphrase protected: %w", keyPath, perr)
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(pemBytes, passphrase)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", keyPath, err)
+	}
+
+	desc := fmt.Sprintf("publickey %s (%s)", keyPath, ssh.FingerprintSHA256(signer.PublicKey()))
+	if encrypted {
+		desc += " decrypted"
+	}
+	signers := []ssh.Signer{signer}
+	methods := []string{desc}
+
+	certPath := creds.CertificatePath
+	if certPath == "" {
+		// OpenSSH convention: id_ed25519 -> id_ed25519-cert.pub
+		if _, err := os.Stat(keyPath + "-cert.pub"); err == nil {
+			certPath = keyPath + "-cert.pub"
+		}
+	}
+	if certPath != "" {
+		certSigner, cert, err := loadCertSigner(expandHome(certPath), signer)
+		if err != nil {
+			log.Printf("SSH certificate for %s not loaded: %v", creds.Username, err)
+		} else {
+			signers = append([]ssh.Signer{certSigner}, signers...)
+			methods = append([]string{certificateDescription(cert)}, methods...)
+		}
+	}
+	return signers, methods, nil
+}
+
+// keyPassphrase returns the passphrase from the configured env var or file
+func keyPassphrase(creds config.SSHProfile) ([]byte, error) {
+	if creds.PassphraseEnv != "" {
+		if v, ok := os.LookupEnv(creds.PassphraseEnv); ok {
+			return []byte(v), nil
+		}
+		if creds.PassphraseFile == "" {
+			return nil, fmt.Errorf("environment variable %s is not set", creds.PassphraseEnv)
+		}
+	}
+	if creds.PassphraseFile != "" {
+		data, err := os.ReadFile(expandHome(creds.PassphraseFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read passphrase file: %w", err)
+		}
+		return []byte(strings.TrimRight(string(data), "\r\n")), nil
+	}
+	return nil, errors.New("no private_key_passphrase_env or private_key_passphrase_file configured")
+}
+
+// loadCertSigner pairs an OpenSSH user certificate with its private key
+func loadCertSigner(certPath string, signer ssh.Signer) (ssh.Signer, *ssh.Certificate, error) {
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", certPath, err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s is not a certificate", certPath)
+	}
+	certSigner, err := ssh.NewCertSigner(cert, signer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s does not match the private key: %w", certPath, err)
+	}
+	return certSigner, cert, nil
+}
+
+// certificateDescription summarizes a certificate for the startup log
+func certificateDescription(cert *ssh.Certificate) string {
+	desc := fmt.Sprintf("certificate %q", cert.KeyId)
+	if cert.ValidBefore != ssh.CertTimeInfinity {
+		expires := time.Unix(int64(cert.ValidBefore), 0)
+		desc += " valid until " + expires.Format(time.RFC3339)
+		if time.Now().After(expires) {
+			desc += " (EXPIRED)"
+		}
+	}
+	return desc
+}
+
+// agentSigner signs with keys held by an SSH agent. The agent connection is
+// opened lazily and redialed if the agent restarts.
+type agentSigner struct {
+	sock string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	client agent.ExtendedAgent
+}
+
+var (
+	agentsMu sync.Mutex
+	agents   = make(map[string]*agentSigner)
+)
+
+// sharedAgent returns the agent for sock, shared by every credential set
+func sharedAgent(sock string) *agentSigner {
+	agentsMu.Lock()
+	defer agentsMu.Unlock()
+	a, ok := agents[sock]
+	if !ok {
+		a = &agentSigner{sock: sock}
+		agents[sock] = a
+	}
+	return a
+}
+
+// signers returns the agent's current signers, reconnecting once on failure
+func (a *agentSigner) signers() ([]ssh.Signer, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if a.client == nil {
+			conn, err := net.Dial("unix", a.sock)
+			if err != nil {
+				return nil, err
+			}
+			a.conn = conn
+			a.client = agent.NewClient(conn)
+		}
+		signers, err := a.client.Signers()
+		if err == nil {
+			return signers, nil
+		}
+		a.conn.Close()
+		a.conn, a.client = nil, nil
+		if attempt == 1 {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// count returns how many keys the agent currently holds
+func (a *agentSigner) count() (int, error) {
+	signers, err := a.signers()
+	return len(signers), err
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"server-dashboard/internal/config"
+)
+
+// SSH clients are resolved once per device at startup. Devices whose
+// credentials resolve to the same set share one client, and with it the
+// same pooled connections.
+var (
+	sshClientsMu     sync.RWMutex
+	deviceSSHClients map[string]*SSHClient // keyed by kind + "/" + device ID
+)
+
+// deviceSSHSettings returns the profile name and overrides configured for a device
+func deviceSSHSettings(cfg *config.Config, kind, id string) (string, config.SSHProfile, bool) {
+	switch kind {
+	case KindServer:
+		for _, c := range cfg.Servers {
+			if c.ID == id {
+				return c.SSHProfile, config.SSHProfile{Username: c.SSHUsername, PrivateKeyPath: c.SSHKeyPath, Password: c.SSHPassword, JumpHosts: c.SSHJumpHosts}, true
+			}
+		}
+	case KindVM:
+		for _, c := range cfg.VirtualMachines {
+			if c.ID == id {
+				return c.SSHProfile, config.SSHProfile{Username: c.SSHUsername, PrivateKeyPath: c.SSHKeyPath, Password: c.SSHPassword, JumpHosts: c.SSHJumpHosts}, true
+			}
+		}
+	case KindSwitch:
+		for _, c := range cfg.Switches {
+			if c.ID == id {
+				return c.SSHProfile, config.SSHProfile{Username: c.SSHUsername, PrivateKeyPath: c.SSHKeyPath, Password: c.SSHPassword, JumpHosts: c.SSHJumpHosts}, true
+			}
+		}
+	}
+	return "", config.SSHProfile{}, false
+}
+
+// credentialSetSecret keys the HMAC that tells apart credential sets
+// differing only in their secrets. It is random per process, so pool keys
+// cannot be used to guess passwords offline.
+var credentialSetSecret = func() []byte {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}()
+
+// credentialSetName describes a resolved credential set by its non-secret
+// fields: user, key, certificate, agent use and jump route. It is safe to log.
+func credentialSetName(creds config.SSHProfile) string {
+	parts := []string{creds.Username}
+	if creds.PrivateKeyPath != "" {
+		parts = append(parts, "key="+creds.PrivateKeyPath)
+	}
+	if creds.CertificatePath != "" {
+		parts = append(parts, "cert="+creds.CertificatePath)
+	}
+	if creds.UseAgent {
+		parts = append(parts, "agent")
+	}
+	for _, jh := range creds.JumpHosts {
+		port := jh.Port
+		if port == 0 {
+			port = 22
+		}
+		// Jump hosts inherit the target's key unless they set their own
+		via := "via " + jh.Credentials(creds).Username + "@" + net.JoinHostPort(jh.Host, strconv.Itoa(port))
+		if jh.PrivateKeyPath != "" {
+			via += " key=" + jh.PrivateKeyPath
+		}
+		if jh.CertificatePath != "" {
+			via += " cert=" + jh.CertificatePath
+		}
+		parts = append(parts, via)
+	}
+	return strings.Join(parts, " ")
+}
+
+// credentialSetKey identifies a resolved credential set in the connection
+// pool. Sets with the same name but different passwords or passphrases get
+// different keys through an HMAC of the secrets; keys are never logged.
+func credentialSetKey(creds config.SSHProfile) string {
+	mac := hmac.New(sha256.New, credentialSetSecret)
+	secrets := []string{creds.Password, creds.PassphraseEnv, creds.PassphraseFile}
+	for _, jh := range creds.JumpHosts {
+		secrets = append(secrets, jh.Password, jh.PassphraseEnv, jh.PassphraseFile)
+	}
+	for _, s := range secrets {
+		fmt.Fprintf(mac, "%q\n", s)
+	}
+	return credentialSetName(creds) + " #" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// initSSHClients resolves and caches the SSH client for every configured device
+func initSSHClients(cfg *config.Config) {
+	bySet := make(map[string]*SSHClient)
+	clients := make(map[string]*SSHClient)
+
+	resolve := func(kind, id, name string) {
+		profile, override, _ := deviceSSHSettings(cfg, kind, id)
+		creds, err := cfg.SSH.ResolveCredentials(profile, override)
+		if err != nil {
+			log.Printf("SSH disabled for %s %s: %v", kind, name, err)
+			return
+		}
+		key := credentialSetKey(creds)
+		client, ok := bySet[key]
+		if !ok {
+			client, err = newSSHClientForCredentials(creds, cfg.SSH.TimeoutSeconds)
+			if err != nil {
+				log.Printf("SSH disabled for %s %s: %v", kind, name, err)
+				return
+			}
+			bySet[key] = client
+			client.logAuthMethods()
+		}
+		clients[kind+"/"+id] = client
+	}
+
+	for _, c := range cfg.Servers {
+		resolve(KindServer, c.ID, c.Name)
+	}
+	for _, c := range cfg.VirtualMachines {
+		resolve(KindVM, c.ID, c.Name)
+	}
+	for _, c := range cfg.Switches {
+		resolve(KindSwitch, c.ID, c.Name)
+	}
+
+	sshClientsMu.Lock()
+	deviceSSHClients = clients
+	sshClientsMu.Unlock()
+	log.Printf("SSH monitoring enabled: %d devices using %d credential sets", len(clients), len(bySet))
+}
+
+// sshClientFor returns the cached SSH client for a device, or nil when SSH
+// is not available for it
+func sshClientFor(kind, id string) *SSHClient {
+	sshClientsMu.RLock()
+	defer sshClientsMu.RUnlock()
+	return deviceSSHClients[kind+"/"+id]
+}
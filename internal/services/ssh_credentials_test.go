@@ -0,0 +1,57 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"server-dashboard/internal/config"
+)
+
+func TestCredentialSetKey(t *testing.T) {
+	base := config.SSHProfile{
+		Username:       "monitor",
+		PrivateKeyPath: "/etc/keys/id_ed25519",
+		Password:       "hunter2",
+		PassphraseEnv:  "KEY_PASSPHRASE",
+		JumpHosts:      []config.SSHJumpHost{{Host: "bastion", Password: "jump-secret"}},
+	}
+	const wantName = "monitor key=/etc/keys/id_ed25519 via monitor@bastion:22"
+	if got := credentialSetName(base); got != wantName {
+		t.Errorf("name = %q, want %q", got, wantName)
+	}
+
+	key := credentialSetKey(base)
+	for _, secret := range []string{"hunter2", "jump-secret"} {
+		if strings.Contains(key, secret) || strings.Contains(credentialSetName(base), secret) {
+			t.Errorf("key %q reveals %q", key, secret)
+		}
+	}
+	same := base
+	same.JumpHosts = append([]config.SSHJumpHost(nil), base.JumpHosts...)
+	if credentialSetKey(same) != key {
+		t.Error("equal credential sets have different keys")
+	}
+
+	password, jumpPassword, jumpKey, agent := base, base, base, base
+	password.Password = "hunter3"
+	jumpPassword.JumpHosts = []config.SSHJumpHost{{Host: "bastion", Password: "other"}}
+	jumpKey.JumpHosts = []config.SSHJumpHost{{Host: "bastion", Password: "jump-secret", PrivateKeyPath: "/etc/keys/bastion"}}
+	agent.UseAgent = true
+	if got, want := credentialSetName(jumpKey), "monitor key=/etc/keys/id_ed25519 via monitor@bastion:22 key=/etc/keys/bastion"; got != want {
+		t.Errorf("name = %q, want %q", got, want)
+	}
+	for name, creds := range map[string]config.SSHProfile{"password": password, "jump host password": jumpPassword, "jump host key": jumpKey, "agent": agent} {
+		if credentialSetKey(creds) == key {
+			t.Errorf("a different %s gives the same key", name)
+		}
+	}
+
+	// Keys depend on the per-process secret, so they cannot be checked
+	// against guessed passwords elsewhere
+	saved := credentialSetSecret
+	credentialSetSecret = []byte("another process")
+	defer func() { credentialSetSecret = saved }()
+	if credentialSetKey(base) == key {
+		t.Error("key does not depend on the process secret")
+	}
+}
@@ -0,0 +1,369 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	defaultSSHMaxSessions = 4
+	defaultSSHIdleTimeout = 5 * time.Minute
+	defaultSSHKeepalive   = 30 * time.Second
+)
+
+// sshPool keeps one multiplexed SSH connection per host and credential set.
+// Commands open sessions on the shared connection instead of dialing anew.
+// Connections through jump hosts are pooled per route: every jump host in
+// the chain is itself a pooled connection shared by the targets behind it.
+type sshPool struct {
+	mu          sync.Mutex
+	entries     map[string]*sshPoolEntry
+	maxSessions int
+	idleTimeout time.Duration
+	keepalive   time.Duration
+	janitor     sync.Once
+}
+
+// sshPoolEntry is the pooled connection for a single key
+type sshPoolEntry struct {
+	lastUsed int64 // unix nanoseconds (atomic); first for 64-bit alignment
+	inUse    int32 // commands currently routed through this connection (atomic)
+
+	key      string
+	addr     string
+	sessions chan struct{} // semaphore limiting concurrent sessions
+
+	mu     sync.Mutex // guards client; held while dialing
+	client *ssh.Client
+}
+
+// sshHop is one connection in a route: zero or more jump hosts followed by
+// the target itself
+type sshHop struct {
+	addr    string
+	config  *ssh.ClientConfig
+	credKey string
+	methods []string // auth methods that loaded, for logging
+}
+
+// routeKey identifies the pooled connection to the last hop of route
+func routeKey(route []sshHop) string {
+	parts := make([]string, len(route))
+	for i, hop := range route {
+		parts[i] = hop.credKey + "@" + hop.addr
+	}
+	return strings.Join(parts, " > ")
+}
+
+// sshConnPool is shared by every SSHClient
+var sshConnPool = newSSHPool(0, 0, 0)
+
+func newSSHPool(maxSessions int, idleTimeout, keepalive time.Duration) *sshPool {
+	if maxSessions <= 0 {
+		maxSessions = defaultSSHMaxSessions
+	}
+	if idleTimeout <= 0 {
+		idleTimeout = defaultSSHIdleTimeout
+	}
+	if keepalive <= 0 {
+		keepalive = defaultSSHKeepalive
+	}
+	return &sshPool{
+		entries:     make(map[string]*sshPoolEntry),
+		maxSessions: maxSessions,
+		idleTimeout: idleTimeout,
+		keepalive:   keepalive,
+	}
+}
+
+// entry returns the pool entry for key, creating it if needed
+func (p *sshPool) entry(key, addr string) *sshPoolEntry {
+	p.janitor.Do(func() { go p.evictIdle() })
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.entryLocked(key, addr)
+}
+
+// acquire returns the pool entry for key like entry, held so that it is
+// not evicted until released. Holding under the pool lock means eviction
+// never drops an entry between the lookup and the hold.
+func (p *sshPool) acquire(key, addr string) *sshPoolEntry {
+	p.janitor.Do(func() { go p.evictIdle() })
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e := p.entryLocked(key, addr)
+	e.hold()
+	return e
+}
+
+// entryLocked is entry with p.mu held
+func (p *sshPool) entryLocked(key, addr string) *sshPoolEntry {
+	e, ok := p.entries[key]
+	if !ok {
+		e = &sshPoolEntry{
+			key:      key,
+			addr:     addr,
+			sessions: make(chan struct{}, p.maxSessions),
+		}
+		p.entries[key] = e
+	}
+	return e
+}
+
+// run executes fn with a fresh session on the pooled connection to the last
+// hop of route. If the connection turns out to be dead it is replaced and fn
+// retried once.
+func (p *sshPool) run(route []sshHop, timeout time.Duration, fn func(*ssh.Session) error) error {
+	target := route[len(route)-1]
+
+	// Keep the whole chain from being evicted while the command runs
+	held := make([]*sshPoolEntry, len(route))
+	for i := range route {
+		held[i] = p.acquire(routeKey(route[:i+1]), route[i].addr)
+	}
+	defer func() {
+		for _, h := range held {
+			h.release()
+		}
+	}()
+	e := held[len(held)-1]
+
+	// Respect the per-host session limit
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	select {
+	case e.sessions <- struct{}{}:
+	case <-time.After(timeout):
+		return fmt.Errorf("ssh session limit reached for %s", target.addr)
+	}
+	defer func() { <-e.sessions }()
+
+	var lastErr error
+	for attempt := 0; attempt < 2; attempt++ {
+		client, err := p.connect(route, timeout)
+		if err != nil {
+			return fmt.Errorf("ssh dial failed: %w", err)
+		}
+
+		session, err := client.NewSession()
+		if err != nil {
+			// The connection is probably gone; drop it and redial
+			lastErr = fmt.Errorf("session creation failed: %w", err)
+			e.invalidate(client)
+			continue
+		}
+		err = fn(session)
+		session.Close()
+		return err
+	}
+	return lastErr
+}
+
+// probe checks that addr accepts TCP connections from the last jump host
+func (p *sshPool) probe(jumps []sshHop, addr string, timeout time.Duration) error {
+	for i := range jumps {
+		e := p.acquire(routeKey(jumps[:i+1]), jumps[i].addr)
+		defer e.release()
+	}
+	jump, err := p.connect(jumps, timeout)
+	if err != nil {
+		return fmt.Errorf("jump host %s: %w", jumps[len(jumps)-1].addr, err)
+	}
+	conn, err := dialThrough(jump, addr, timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// connect returns the live client for the last hop of route, dialing it
+// (and any jump hosts before it) if needed
+func (p *sshPool) connect(route []sshHop, timeout time.Duration) (*ssh.Client, error) {
+	hop := route[len(route)-1]
+	e := p.entry(routeKey(route), hop.addr)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.touch()
+	if e.client != nil {
+		return e.client, nil
+	}
+
+	var client *ssh.Client
+	var err error
+	if len(route) == 1 {
+		client, err = ssh.Dial("tcp", hop.addr, hop.config)
+	} else {
+		// Lock order is always target before jump host, so this cannot deadlock
+		var jump *ssh.Client
+		jump, err = p.connect(route[:len(route)-1], timeout)
+		if err != nil {
+			return nil, fmt.Errorf("jump host %s: %w", route[len(route)-2].addr, err)
+		}
+		client, err = dialSSHThrough(jump, hop, timeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	e.client = client
+	go p.keepAlive(e, client)
+	return client, nil
+}
+
+// dialThrough opens a TCP connection to addr from the jump host. The SSH
+// library has no deadline for this, so it is enforced here.
+func dialThrough(jump *ssh.Client, addr string, timeout time.Duration) (net.Conn, error) {
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		conn, err := jump.Dial("tcp", addr)
+		done <- result{conn, err}
+	}()
+	select {
+	case r := <-done:
+		return r.conn, r.err
+	case <-time.After(timeout):
+		go func() {
+			if r := <-done; r.conn != nil {
+				r.conn.Close()
+			}
+		}()
+		return nil, fmt.Errorf("dial %s through jump host timed out", addr)
+	}
+}
+
+// dialSSHThrough establishes an SSH connection to hop tunneled through jump
+func dialSSHThrough(jump *ssh.Client, hop sshHop, timeout time.Duration) (*ssh.Client, error) {
+	conn, err := dialThrough(jump, hop.addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		conn  ssh.Conn
+		chans <-chan ssh.NewChannel
+		reqs  <-chan *ssh.Request
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		c, chans, reqs, err := ssh.NewClientConn(conn, hop.addr, hop.config)
+		done <- result{c, chans, reqs, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			conn.Close()
+			return nil, r.err
+		}
+		return ssh.NewClient(r.conn, r.chans, r.reqs), nil
+	case <-time.After(timeout):
+		conn.Close()
+		return nil, fmt.Errorf("ssh handshake with %s timed out", hop.addr)
+	}
+}
+
+// hold marks the entry as in use so it is not evicted
+func (e *sshPoolEntry) hold() {
+	atomic.AddInt32(&e.inUse, 1)
+	e.touch()
+}
+
+// release undoes hold
+func (e *sshPoolEntry) release() {
+	e.touch()
+	atomic.AddInt32(&e.inUse, -1)
+}
+
+func (e *sshPoolEntry) touch() {
+	atomic.StoreInt64(&e.lastUsed, time.Now().UnixNano())
+}
+
+// invalidate closes client if it is still the entry's current connection
+func (e *sshPoolEntry) invalidate(client *ssh.Client) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.client == client {
+		e.client = nil
+	}
+	client.Close()
+}
+
+// keepAlive pings the server until the connection fails or is replaced
+func (p *sshPool) keepAlive(e *sshPoolEntry, client *ssh.Client) {
+	ticker := time.NewTicker(p.keepalive)
+	defer ticker.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		client.Wait()
+		close(done)
+	}()
+
+	for {
+		select {
+		case <-done:
+			e.invalidate(client)
+			return
+		case <-ticker.C:
+			if _, _, err := client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+				log.Printf("SSH keepalive to %s failed: %v", e.addr, err)
+				e.invalidate(client)
+				return
+			}
+		}
+	}
+}
+
+// evictIdle periodically closes connections that have not been used recently
+func (p *sshPool) evictIdle() {
+	interval := p.idleTimeout / 2
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		p.evict(now)
+	}
+}
+
+// evict closes the connections of entries idle for longer than the idle
+// timeout and removes the entries, so that routes to hosts no longer
+// monitored do not accumulate
+func (p *sshPool) evict(now time.Time) {
+	p.mu.Lock()
+	var idle []*sshPoolEntry
+	for key, e := range p.entries {
+		lastUsed := time.Unix(0, atomic.LoadInt64(&e.lastUsed))
+		if atomic.LoadInt32(&e.inUse) == 0 && now.Sub(lastUsed) > p.idleTimeout {
+			delete(p.entries, key)
+			idle = append(idle, e)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, e := range idle {
+		e.mu.Lock()
+		client := e.client
+		e.client = nil
+		e.mu.Unlock()
+		if client != nil {
+			client.Close()
+		}
+	}
+}
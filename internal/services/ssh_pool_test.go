@@ -0,0 +1,216 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testSSHServer is an in-process SSH server whose sessions echo the command
+// they are asked to run
+type testSSHServer struct {
+	addr       string
+	hostKey    ssh.PublicKey
+	handshakes int32 // Connections that authenticated
+	open       int32 // Connections not yet closed
+	rejects    int32 // Session requests still to be refused
+}
+
+func startTestSSHServer(t *testing.T) *testSSHServer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &ssh.ServerConfig{
+		PasswordCallback: func(_ ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) != "secret" {
+				return nil, errors.New("wrong password")
+			}
+			return nil, nil
+		},
+	}
+	cfg.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := &testSSHServer{addr: ln.Addr().String(), hostKey: signer.PublicKey()}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, cfg)
+		}
+	}()
+	return s
+}
+
+func (s *testSSHServer) serve(conn net.Conn, cfg *ssh.ServerConfig) {
+	sc, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+	if err != nil {
+		return
+	}
+	atomic.AddInt32(&s.handshakes, 1)
+	atomic.AddInt32(&s.open, 1)
+	go func() {
+		sc.Wait()
+		atomic.AddInt32(&s.open, -1)
+	}()
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		if nc.ChannelType() != "session" || atomic.AddInt32(&s.rejects, -1) >= 0 {
+			nc.Reject(ssh.Prohibited, "no sessions")
+			continue
+		}
+		ch, requests, err := nc.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			defer ch.Close()
+			for req := range requests {
+				var exec struct{ Command string }
+				if req.Type != "exec" || ssh.Unmarshal(req.Payload, &exec) != nil {
+					req.Reply(false, nil)
+					continue
+				}
+				req.Reply(true, nil)
+				ch.Write([]byte("ran " + exec.Command))
+				ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+				return
+			}
+		}()
+	}
+}
+
+// route returns a direct route to the server
+func (s *testSSHServer) route() []sshHop {
+	return []sshHop{{
+		addr: s.addr,
+		config: &ssh.ClientConfig{
+			User:            "monitor",
+			Auth:            []ssh.AuthMethod{ssh.Password("secret")},
+			HostKeyCallback: ssh.FixedHostKey(s.hostKey),
+			Timeout:         5 * time.Second,
+		},
+		credKey: "monitor",
+	}}
+}
+
+// runCommand runs command through the pool and returns its output
+func runCommand(p *sshPool, route []sshHop, command string) (string, error) {
+	var output string
+	err := p.run(route, 5*time.Second, func(session *ssh.Session) error {
+		out, err := session.Output(command)
+		output = string(out)
+		return err
+	})
+	return output, err
+}
+
+// waitFor polls cond until it holds or a second has passed
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func TestSSHPoolReusesConnection(t *testing.T) {
+	srv := startTestSSHServer(t)
+	p := newSSHPool(2, time.Minute, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if out, err := runCommand(p, srv.route(), "uptime"); err != nil || out != "ran uptime" {
+				t.Errorf("output %q, err %v", out, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&srv.handshakes); n != 1 {
+		t.Errorf("%d connections for ten commands, want 1", n)
+	}
+	if e := p.entries[routeKey(srv.route())]; e == nil || atomic.LoadInt32(&e.inUse) != 0 || len(e.sessions) != 0 {
+		t.Errorf("entry after the commands: %+v", e)
+	}
+}
+
+// A connection that can no longer open sessions is replaced
+func TestSSHPoolRedialsAfterSessionFailure(t *testing.T) {
+	srv := startTestSSHServer(t)
+	p := newSSHPool(0, time.Minute, time.Minute)
+	if _, err := runCommand(p, srv.route(), "true"); err != nil {
+		t.Fatal(err)
+	}
+
+	atomic.StoreInt32(&srv.rejects, 1)
+	if out, err := runCommand(p, srv.route(), "uptime"); err != nil || out != "ran uptime" {
+		t.Fatalf("output %q, err %v", out, err)
+	}
+	if n := atomic.LoadInt32(&srv.handshakes); n != 2 {
+		t.Errorf("%d connections, want a second one after the refused session", n)
+	}
+	waitFor(t, "the refusing connection to close", func() bool { return atomic.LoadInt32(&srv.open) == 1 })
+
+	// Two attempts at most
+	atomic.StoreInt32(&srv.rejects, 2)
+	if _, err := runCommand(p, srv.route(), "uptime"); err == nil || !strings.Contains(err.Error(), "session creation failed") {
+		t.Errorf("err = %v, want a session failure", err)
+	}
+}
+
+func TestSSHPoolEvictsIdleEntries(t *testing.T) {
+	srv := startTestSSHServer(t)
+	p := newSSHPool(0, time.Minute, time.Minute)
+	if _, err := runCommand(p, srv.route(), "true"); err != nil {
+		t.Fatal(err)
+	}
+	held := p.acquire("held", "192.0.2.1:22")
+
+	p.evict(time.Now())
+	if len(p.entries) != 2 {
+		t.Fatalf("%d entries after evicting nothing idle", len(p.entries))
+	}
+	p.evict(time.Now().Add(2 * time.Minute))
+	if _, ok := p.entries[routeKey(srv.route())]; ok || len(p.entries) != 1 || p.entries["held"] != held {
+		t.Fatalf("entries after eviction: %v", p.entries)
+	}
+	waitFor(t, "the idle connection to close", func() bool { return atomic.LoadInt32(&srv.open) == 0 })
+
+	held.release()
+	p.evict(time.Now().Add(2 * time.Minute))
+	if len(p.entries) != 0 {
+		t.Errorf("released entry kept: %v", p.entries)
+	}
+
+	// The next command dials again
+	if out, err := runCommand(p, srv.route(), "uptime"); err != nil || out != "ran uptime" {
+		t.Fatalf("output %q, err %v", out, err)
+	}
+	if n := atomic.LoadInt32(&srv.handshakes); n != 2 {
+		t.Errorf("%d connections, want 2", n)
+	}
+}
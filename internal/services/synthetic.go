@@ -17,10 +17,16 @@ import (
 var (
     syntheticResults map[string]models.SyntheticCheckResult
     syntheticMu      sync.RWMutex
+    syntheticOnce    sync.Once
 )
 
-// InitSynthetic initializes synthetic check runners based on config.
+// InitSynthetic initializes synthetic check runners based on config. Only
+// the first call starts them.
 func InitSynthetic(cfg *config.Config) {
+    syntheticOnce.Do(func() { startSynthetic(cfg) })
+}
+
+func startSynthetic(cfg *config.Config) {
     syntheticResults = make(map[string]models.SyntheticCheckResult)
     rand.Seed(time.Now().UnixNano())
 
@@ -168,8 +174,11 @@ func mockTarget(check config.SyntheticCheckConfig) string {
 
 func saveSyntheticResult(res models.SyntheticCheckResult) {
     syntheticMu.Lock()
-    defer syntheticMu.Unlock()
+    old, hadOld := syntheticResults[res.ID]
+    debounceSynthetic(old, &res)
     syntheticResults[res.ID] = res
+    syntheticMu.Unlock()
+    recordSyntheticEvent(old, hadOld, res)
 }
 
 // GetSyntheticResults returns a copy of the latest results.
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"sort"
+
+	"server-dashboard/internal/models"
+)
+
+// watchedUnitsFor returns the units on a device's watchlist: those of every
+// monitoring.services.watch entry that selects it, in config order
+func watchedUnitsFor(kind, id string) []string {
+	if Config == nil {
+		return nil
+	}
+	var tags []string
+	switch kind {
+	case KindServer:
+		for _, c := range Config.Servers {
+			if c.ID == id {
+				tags = c.Tags
+			}
+		}
+	case KindVM:
+		for _, c := range Config.VirtualMachines {
+			if c.ID == id {
+				tags = c.Tags
+			}
+		}
+	case KindSwitch:
+		for _, c := range Config.Switches {
+			if c.ID == id {
+				tags = c.Tags
+			}
+		}
+	}
+
+	var units []string
+	seen := make(map[string]bool)
+	for _, w := range Config.Monitoring.Services.Watch {
+		if len(w.Devices) > 0 && !containsString(w.Devices, id) {
+			continue
+		}
+		if len(w.Types) > 0 && !containsString(w.Types, kind) {
+			continue
+		}
+		if len(w.Tags) > 0 && !anyString(w.Tags, tags) {
+			continue
+		}
+		for _, name := range w.Units {
+			name = models.SystemdUnitName(name)
+			if name != "" && !seen[name] {
+				seen[name] = true
+				units = append(units, name)
+			}
+		}
+	}
+	return units
+}
+
+func anyString(list, values []string) bool {
+	for _, v := range values {
+		if containsString(list, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectServices picks the failed units and the device's watched units out
+// of the units a collector reported, and recounts FailedServices from them.
+// Samples without a unit list, such as from SNMP or older agents, keep the
+// count their collector reported and have no unit details.
+func selectServices(kind, id string, m *models.HostMetrics) {
+	if m.Units == nil {
+		return
+	}
+	units := make(map[string]*models.SystemdUnit, len(m.Units))
+	failed := []models.SystemdUnit{}
+	for i := range m.Units {
+		u := &m.Units[i]
+		units[u.Name] = u
+		if u.Failed() {
+			failed = append(failed, *u)
+		}
+	}
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Name < failed[j].Name })
+	m.FailedUnits = failed
+	m.FailedServices = len(failed)
+
+	m.Services = []models.ServiceHealth{}
+	for _, name := range watchedUnitsFor(kind, id) {
+		m.Services = append(m.Services, models.CheckService(name, units[name]))
+	}
+}
+
+// servicesDown counts the watched units that are not healthy
+func servicesDown(services []models.ServiceHealth) int {
+	n := 0
+	for _, s := range services {
+		if !s.Healthy {
+			n++
+		}
+	}
+	return n
+}
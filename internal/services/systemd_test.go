@@ -0,0 +1,68 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"server-dashboard/internal/config"
+	"server-dashboard/internal/models"
+)
+
+func TestSelectServices(t *testing.T) {
+	cfg := &config.Config{
+		Servers:         []config.ServerConfig{{ID: "web1", Tags: []string{"web"}}, {ID: "db1", Tags: []string{"db"}}},
+		VirtualMachines: []config.VirtualMachineConfig{{ID: "vm1", Tags: []string{"web"}}},
+	}
+	cfg.Monitoring.Services.Watch = []config.ServiceWatchConfig{
+		{Units: []string{"nginx", "postgresql"}, Tags: []string{"web"}},
+		{Units: []string{"postgresql.service", "backup.timer"}, Devices: []string{"web1", "db1"}},
+		{Units: []string{"qemu-guest-agent"}, Types: []string{KindVM}},
+		{Units: []string{"haproxy"}, Tags: []string{"web"}, Types: []string{KindServer}},
+	}
+	withConfig(t, cfg)
+
+	sample := func() *models.HostMetrics {
+		return &models.HostMetrics{FailedServices: 9, Units: []models.SystemdUnit{
+			{Name: "nginx.service", LoadState: "loaded", ActiveState: "active", SubState: "running", UnitFileState: "enabled"},
+			{Name: "postgresql.service", LoadState: "loaded", ActiveState: "inactive", SubState: "dead", UnitFileState: "disabled"},
+			{Name: "backup.service", LoadState: "loaded", ActiveState: "failed", SubState: "failed", Result: "exit-code"},
+			{Name: "srv-data.mount", LoadState: "loaded", ActiveState: "failed", SubState: "failed"},
+		}}
+	}
+	type watched struct{ name, problem string }
+	tests := []struct {
+		kind, id string
+		want     []watched
+	}{
+		{KindServer, "web1", []watched{
+			{"nginx.service", ""}, {"postgresql.service", "inactive (dead)"},
+			// Not in the sample, so not installed
+			{"backup.timer", "not found"}, {"haproxy.service", "not found"},
+		}},
+		{KindServer, "db1", []watched{{"postgresql.service", "inactive (dead)"}, {"backup.timer", "not found"}}},
+		{KindVM, "vm1", []watched{{"nginx.service", ""}, {"postgresql.service", "inactive (dead)"}, {"qemu-guest-agent.service", "not found"}}},
+		{KindSwitch, "sw1", nil},
+	}
+	for _, tt := range tests {
+		m := sample()
+		selectServices(tt.kind, tt.id, m)
+		var got []watched
+		for _, s := range m.Services {
+			got = append(got, watched{s.Name, s.Problem})
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %s watches %v, want %v", tt.kind, tt.id, got, tt.want)
+		}
+		if m.Services == nil || m.FailedServices != 2 || len(m.FailedUnits) != 2 ||
+			m.FailedUnits[0].Name != "backup.service" || m.FailedUnits[1].Name != "srv-data.mount" {
+			t.Errorf("%s %s: %d failed, units %+v, services %v", tt.kind, tt.id, m.FailedServices, m.FailedUnits, m.Services)
+		}
+	}
+
+	// Collectors without a unit list keep their count
+	m := &models.HostMetrics{FailedServices: 3}
+	selectServices(KindServer, "web1", m)
+	if m.FailedServices != 3 || m.FailedUnits != nil || m.Services != nil {
+		t.Errorf("sample without units: %+v", m)
+	}
+}
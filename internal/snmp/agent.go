@@ -0,0 +1,166 @@
+package snmp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"time"
+)
+
+// AgentConfig configures the agent side of the protocol. Leaving Community
+// or Username empty disables v2c or v3 respectively.
+type AgentConfig struct {
+	Community string
+
+	Username     string
+	AuthProtocol AuthProtocol
+	AuthPassword string
+	PrivProtocol PrivProtocol
+	PrivPassword string
+	EngineID     []byte // Generated when empty
+}
+
+// Request PDU types, as seen by AgentCodec.Handle
+const (
+	GetRequest     = pduGetRequest
+	GetNextRequest = pduGetNextRequest
+	GetBulkRequest = pduGetBulkRequest
+)
+
+// PDU is a request as an agent receives it, or the response it returns.
+// For GetBulk requests ErrorStatus and ErrorIndex carry non-repeaters and
+// max-repetitions.
+type PDU struct {
+	Type        byte
+	RequestID   int32
+	ErrorStatus int
+	ErrorIndex  int
+	VarBinds    []VarBind
+}
+
+// AgentCodec decodes requests and encodes replies for an agent with fixed
+// credentials, including USM authentication, decryption and reports. The
+// dashboard only polls; this is the counterpart that snmptest builds its
+// stand-in agents on.
+type AgentCodec struct {
+	cfg AgentConfig
+	usm *usm
+	// authUSM signs reports with the user's auth key but never encrypts
+	authUSM *usm
+	start   time.Time
+	boots   int32
+}
+
+// timeWindow is the allowed clock difference for authenticated requests
+const timeWindow = 150
+
+// NewAgentCodec checks the agent's credentials and returns its codec
+func NewAgentCodec(cfg AgentConfig) (*AgentCodec, error) {
+	c := &AgentCodec{cfg: cfg, start: time.Now(), boots: 1}
+	if cfg.Username != "" {
+		u, err := newUSM(cfg.Username, cfg.AuthProtocol, cfg.AuthPassword, cfg.PrivProtocol, cfg.PrivPassword)
+		if err != nil {
+			return nil, err
+		}
+		c.usm = u
+		if c.authUSM, err = newUSM(cfg.Username, cfg.AuthProtocol, cfg.AuthPassword, PrivNone, ""); err != nil {
+			return nil, err
+		}
+		if len(c.cfg.EngineID) == 0 {
+			// Enterprise-format engine ID with random octets (RFC 3411)
+			id := make([]byte, 13)
+			copy(id, []byte{0x80, 0x00, 0x1f, 0x88, 0x04})
+			rand.Read(id[5:])
+			c.cfg.EngineID = id
+		}
+	}
+	return c, nil
+}
+
+func (c *AgentCodec) engineTime() int32 {
+	return int32(time.Since(c.start) / time.Second)
+}
+
+// Handle returns the reply to one datagram, or nil to drop it. respond
+// answers requests that passed the community or USM checks; the codec
+// fills in the response's type and request ID.
+func (c *AgentCodec) Handle(b []byte, respond func(req PDU) PDU) []byte {
+	m, err := parseMessage(b)
+	if err != nil {
+		return nil
+	}
+	if m.version == version2c {
+		if c.cfg.Community == "" || m.community != c.cfg.Community {
+			return nil
+		}
+		resp, err := marshalCommunity(m.community, answer(m.pdu, respond))
+		if err != nil {
+			return nil
+		}
+		return resp
+	}
+	if c.usm == nil {
+		return nil
+	}
+
+	// Checks in the order of RFC 3414 3.2
+	if len(m.engineID) == 0 || !bytes.Equal(m.engineID, c.cfg.EngineID) {
+		m.parseScopedPDU(m.data) // discovery requests are plaintext
+		return c.report(m, usmStatsUnknownEngineIDs, false)
+	}
+	if m.user != c.usm.user {
+		return c.report(m, usmStatsUnknownUserNames, false)
+	}
+	if m.flags&(flagAuth|flagPriv) != c.usm.flags() {
+		return c.report(m, usmStatsUnsupportedSecLevels, false)
+	}
+	if err := c.usm.open(m, b); err != nil {
+		if err == errWrongDigest {
+			return c.report(m, usmStatsWrongDigests, false)
+		}
+		return c.report(m, usmStatsDecryptionErrors, false)
+	}
+	if m.flags&flagAuth != 0 {
+		if diff := m.time - c.engineTime(); m.boots != c.boots || diff > timeWindow || diff < -timeWindow {
+			return c.report(m, usmStatsNotInTimeWindows, true)
+		}
+	}
+
+	scoped, err := marshalScopedPDU(c.cfg.EngineID, m.contextName, answer(m.pdu, respond))
+	if err != nil {
+		return nil
+	}
+	resp, err := c.usm.seal(&message{msgID: m.msgID, engineID: c.cfg.EngineID, boots: c.boots, time: c.engineTime()}, scoped)
+	if err != nil {
+		return nil
+	}
+	return resp
+}
+
+// answer has respond answer a decoded request
+func answer(req *pdu, respond func(PDU) PDU) *pdu {
+	resp := respond(PDU{Type: req.tag, RequestID: req.requestID, ErrorStatus: req.errorStatus, ErrorIndex: req.errorIndex, VarBinds: req.varBinds})
+	return &pdu{tag: pduResponse, requestID: req.requestID, errorStatus: resp.ErrorStatus, errorIndex: resp.ErrorIndex, varBinds: resp.VarBinds}
+}
+
+// report builds a Report PDU carrying the counter that explains a rejection
+func (c *AgentCodec) report(m *message, oid OID, authenticated bool) []byte {
+	var requestID int32
+	if m.pdu != nil {
+		requestID = m.pdu.requestID
+	}
+	p := &pdu{tag: pduReport, requestID: requestID, varBinds: []VarBind{{OID: oid, Type: Counter32, Value: uint64(1)}}}
+	scoped, err := marshalScopedPDU(c.cfg.EngineID, "", p)
+	if err != nil {
+		return nil
+	}
+	reply := &message{msgID: m.msgID, engineID: c.cfg.EngineID, boots: c.boots, time: c.engineTime()}
+	if authenticated {
+		resp, err := c.authUSM.seal(reply, scoped)
+		if err != nil {
+			return nil
+		}
+		return resp
+	}
+	reply.data = scoped
+	return marshalV3(reply)
+}
@@ -0,0 +1,239 @@
+package snmp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ASN.1 tags used by SNMP messages, besides the value types
+const (
+	tagSequence = 0x30
+
+	pduGetRequest     = 0xa0
+	pduGetNextRequest = 0xa1
+	pduResponse       = 0xa2
+	pduGetBulkRequest = 0xa5
+	pduReport         = 0xa8
+)
+
+var errTruncated = errors.New("truncated message")
+
+// encodeLength encodes a BER definite length
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+// tlv encodes one tag-length-value element
+func tlv(tag byte, content ...[]byte) []byte {
+	n := 0
+	for _, c := range content {
+		n += len(c)
+	}
+	out := append([]byte{tag}, encodeLength(n)...)
+	for _, c := range content {
+		out = append(out, c...)
+	}
+	return out
+}
+
+// encodeInt encodes a signed integer in the fewest two's complement bytes
+func encodeInt(tag byte, v int64) []byte {
+	b := []byte{byte(v)}
+	for v > 127 || v < -128 {
+		v >>= 8
+		b = append([]byte{byte(v)}, b...)
+	}
+	return tlv(tag, b)
+}
+
+// encodeUint encodes an unsigned integer, adding a leading zero byte when
+// the high bit is set so it is not read back as negative
+func encodeUint(tag byte, v uint64) []byte {
+	b := []byte{byte(v)}
+	for v >>= 8; v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return tlv(tag, b)
+}
+
+// encodeOID encodes an object identifier
+func encodeOID(o OID) []byte {
+	if len(o) < 2 {
+		return tlv(byte(ObjectIdentifier), []byte{0})
+	}
+	b := encodeSubID(nil, o[0]*40+o[1])
+	for _, id := range o[2:] {
+		b = encodeSubID(b, id)
+	}
+	return tlv(byte(ObjectIdentifier), b)
+}
+
+func encodeSubID(b []byte, id uint32) []byte {
+	var tmp []byte
+	tmp = append(tmp, byte(id&0x7f))
+	for id >>= 7; id > 0; id >>= 7 {
+		tmp = append([]byte{byte(id&0x7f) | 0x80}, tmp...)
+	}
+	return append(b, tmp...)
+}
+
+// encodeValue encodes a variable binding value according to its type
+func encodeValue(v VarBind) ([]byte, error) {
+	switch v.Type {
+	case Integer:
+		n, ok := v.Int()
+		if !ok {
+			return nil, fmt.Errorf("%s: Integer needs a numeric value", v.OID)
+		}
+		return encodeInt(byte(v.Type), n), nil
+	case Counter32, Gauge32, TimeTicks, Counter64:
+		n, ok := v.Uint()
+		if !ok {
+			return nil, fmt.Errorf("%s: type %#x needs a non-negative value", v.OID, byte(v.Type))
+		}
+		return encodeUint(byte(v.Type), n), nil
+	case OctetString, IPAddress, Opaque:
+		b, _ := v.Value.([]byte)
+		if s, ok := v.Value.(string); ok {
+			b = []byte(s)
+		}
+		return tlv(byte(v.Type), b), nil
+	case ObjectIdentifier:
+		o, ok := v.Value.(OID)
+		if !ok {
+			return nil, fmt.Errorf("%s: ObjectIdentifier needs an OID value", v.OID)
+		}
+		return encodeOID(o), nil
+	case Null, NoSuchObject, NoSuchInstance, EndOfMibView:
+		return tlv(byte(v.Type)), nil
+	}
+	return nil, fmt.Errorf("%s: unsupported type %#x", v.OID, byte(v.Type))
+}
+
+// readTLV splits the first element off b. The returned content is a
+// sub-slice of b, so its position in the original buffer is preserved.
+func readTLV(b []byte) (tag byte, content, rest []byte, err error) {
+	if len(b) < 2 {
+		return 0, nil, nil, errTruncated
+	}
+	tag = b[0]
+	n := int(b[1])
+	hdr := 2
+	if n&0x80 != 0 {
+		octets := n & 0x7f
+		if octets == 0 || octets > 4 || len(b) < 2+octets {
+			return 0, nil, nil, fmt.Errorf("invalid length encoding")
+		}
+		n = 0
+		for _, c := range b[2 : 2+octets] {
+			n = n<<8 | int(c)
+		}
+		hdr += octets
+	}
+	if n < 0 || len(b)-hdr < n {
+		return 0, nil, nil, errTruncated
+	}
+	return tag, b[hdr : hdr+n], b[hdr+n:], nil
+}
+
+// expectTLV reads an element and checks its tag
+func expectTLV(b []byte, want byte) (content, rest []byte, err error) {
+	tag, content, rest, err := readTLV(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tag != want {
+		return nil, nil, fmt.Errorf("unexpected tag %#x, want %#x", tag, want)
+	}
+	return content, rest, nil
+}
+
+func decodeInt(content []byte) (int64, error) {
+	if len(content) == 0 || len(content) > 8 {
+		return 0, fmt.Errorf("invalid integer length %d", len(content))
+	}
+	v := int64(int8(content[0]))
+	for _, c := range content[1:] {
+		v = v<<8 | int64(c)
+	}
+	return v, nil
+}
+
+func decodeUint(content []byte) (uint64, error) {
+	if len(content) > 0 && content[0] == 0 {
+		content = content[1:]
+	}
+	if len(content) > 8 {
+		return 0, fmt.Errorf("invalid unsigned length %d", len(content))
+	}
+	var v uint64
+	for _, c := range content {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+func decodeOID(content []byte) (OID, error) {
+	if len(content) == 0 {
+		return nil, fmt.Errorf("empty OID")
+	}
+	var ids []uint32
+	var cur uint64
+	for i, c := range content {
+		cur = cur<<7 | uint64(c&0x7f)
+		if cur > 0xffffffff {
+			return nil, fmt.Errorf("OID sub-identifier overflows")
+		}
+		if c&0x80 != 0 {
+			if i == len(content)-1 {
+				return nil, errTruncated
+			}
+			continue
+		}
+		ids = append(ids, uint32(cur))
+		cur = 0
+	}
+	first := ids[0]
+	oid := OID{first / 40, first % 40}
+	if first >= 80 {
+		oid = OID{2, first - 80}
+	}
+	return append(oid, ids[1:]...), nil
+}
+
+// readInt reads an INTEGER element
+func readInt(b []byte) (int64, []byte, error) {
+	content, rest, err := expectTLV(b, byte(Integer))
+	if err != nil {
+		return 0, nil, err
+	}
+	v, err := decodeInt(content)
+	return v, rest, err
+}
+
+// decodeValue decodes a variable binding value element
+func decodeValue(tag byte, content []byte) (interface{}, error) {
+	switch Type(tag) {
+	case Integer:
+		return decodeInt(content)
+	case Counter32, Gauge32, TimeTicks, Counter64:
+		return decodeUint(content)
+	case OctetString, IPAddress, Opaque:
+		return append([]byte(nil), content...), nil
+	case ObjectIdentifier:
+		return decodeOID(content)
+	case Null, NoSuchObject, NoSuchInstance, EndOfMibView:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported value type %#x", tag)
+}
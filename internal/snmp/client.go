@@ -0,0 +1,298 @@
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Config selects the agent and credentials for a Client
+type Config struct {
+	Address   string        // host:port
+	Version   string        // "2c" or "3"
+	Community string        // v2c community
+	Timeout   time.Duration // Per attempt (default 2s)
+	Retries   int           // Resends after a timeout
+
+	// SNMPv3 user-based security
+	Username     string
+	AuthProtocol AuthProtocol
+	AuthPassword string
+	PrivProtocol PrivProtocol
+	PrivPassword string
+	ContextName  string
+}
+
+// walkRepetitions is the max-repetitions used for GetBulk while walking
+const walkRepetitions = 25
+
+// errorStatusNames maps PDU error-status values to their RFC 3416 names
+var errorStatusNames = map[int]string{
+	1: "tooBig", 2: "noSuchName", 3: "badValue", 4: "readOnly", 5: "genErr",
+	6: "noAccess", 7: "wrongType", 8: "wrongLength", 9: "wrongEncoding",
+	10: "wrongValue", 11: "noCreation", 12: "inconsistentValue",
+	13: "resourceUnavailable", 14: "commitFailed", 15: "undoFailed",
+	16: "authorizationError", 17: "notWritable", 18: "inconsistentName",
+}
+
+// ErrTimeout is returned when the agent does not answer
+var ErrTimeout = errors.New("no response from SNMP agent")
+
+// Client sends requests to one agent. Requests are serialized; a Client is
+// safe for concurrent use.
+type Client struct {
+	cfg  Config
+	conn net.Conn
+	usm  *usm
+
+	mu    sync.Mutex
+	reqID int32
+	buf   []byte
+
+	// Authoritative engine state, learned through discovery (SNMPv3)
+	engineID   []byte
+	boots      int32
+	engineTime int32
+	timeAt     time.Time
+}
+
+// Dial prepares a client for the agent. SNMP runs over UDP, so no packets
+// are exchanged until the first request.
+func Dial(cfg Config) (*Client, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 2 * time.Second
+	}
+	c := &Client{cfg: cfg, buf: make([]byte, 65535)}
+	switch cfg.Version {
+	case "2c", "":
+		c.cfg.Version = "2c"
+	case "3":
+		u, err := newUSM(cfg.Username, cfg.AuthProtocol, cfg.AuthPassword, cfg.PrivProtocol, cfg.PrivPassword)
+		if err != nil {
+			return nil, err
+		}
+		c.usm = u
+	default:
+		return nil, fmt.Errorf("unsupported SNMP version %q", cfg.Version)
+	}
+	conn, err := net.DialTimeout("udp", cfg.Address, cfg.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	c.conn = conn
+	return c, nil
+}
+
+// Close releases the client's socket
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Get fetches exact OIDs. Missing objects come back with an exception type
+// rather than an error; see VarBind.Exists.
+func (c *Client) Get(oids ...OID) ([]VarBind, error) {
+	return c.request(pduGetRequest, 0, 0, oids)
+}
+
+// GetNext fetches the object following each OID
+func (c *Client) GetNext(oids ...OID) ([]VarBind, error) {
+	return c.request(pduGetNextRequest, 0, 0, oids)
+}
+
+// GetBulk fetches the objects following the OIDs, repeating the last
+// len(oids)-nonRepeaters of them up to maxRepetitions times
+func (c *Client) GetBulk(nonRepeaters, maxRepetitions int, oids ...OID) ([]VarBind, error) {
+	return c.request(pduGetBulkRequest, nonRepeaters, maxRepetitions, oids)
+}
+
+// Walk calls fn for every object in the subtree rooted at root, in order
+func (c *Client) Walk(root OID, fn func(VarBind) error) error {
+	next := root
+	for {
+		vbs, err := c.GetBulk(0, walkRepetitions, next)
+		if err != nil {
+			return err
+		}
+		if len(vbs) == 0 {
+			return nil
+		}
+		for _, vb := range vbs {
+			if !vb.Exists() || !vb.OID.HasPrefix(root) {
+				return nil
+			}
+			if vb.OID.Compare(next) <= 0 {
+				return fmt.Errorf("agent returned OIDs out of order at %s", vb.OID)
+			}
+			if err := fn(vb); err != nil {
+				return err
+			}
+			next = vb.OID
+		}
+	}
+}
+
+func (c *Client) request(tag byte, nonRepeaters, maxRepetitions int, oids []OID) ([]VarBind, error) {
+	p := &pdu{tag: tag, errorStatus: nonRepeaters, errorIndex: maxRepetitions}
+	for _, oid := range oids {
+		p.varBinds = append(p.varBinds, VarBind{OID: oid, Type: Null})
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.usm != nil && c.engineID == nil {
+		if err := c.discover(); err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.exchange(p)
+		if err != nil {
+			return nil, err
+		}
+		if resp.tag == pduReport {
+			// The engine state was refreshed from the report; one retry
+			// covers agent restarts and clock drift
+			if attempt == 0 && len(resp.varBinds) > 0 &&
+				(resp.varBinds[0].OID.Compare(usmStatsNotInTimeWindows) == 0 ||
+					resp.varBinds[0].OID.Compare(usmStatsUnknownEngineIDs) == 0) {
+				continue
+			}
+			return nil, reportError(resp)
+		}
+		if resp.errorStatus != 0 {
+			name := errorStatusNames[resp.errorStatus]
+			if name == "" {
+				name = fmt.Sprintf("error %d", resp.errorStatus)
+			}
+			return nil, fmt.Errorf("agent returned %s (index %d)", name, resp.errorIndex)
+		}
+		return resp.varBinds, nil
+	}
+}
+
+// discover learns the agent's engine ID, boots and time (RFC 3414 4)
+func (c *Client) discover() error {
+	c.reqID++
+	scoped, err := marshalScopedPDU(nil, "", &pdu{tag: pduGetRequest, requestID: c.reqID})
+	if err != nil {
+		return err
+	}
+	msg := marshalV3(&message{msgID: c.reqID, flags: flagReportable, data: scoped})
+	resp, err := c.roundTrip(msg, func(b []byte) (*pdu, bool, error) {
+		m, err := parseMessage(b)
+		if err != nil || m.version != version3 || m.msgID != c.reqID {
+			return nil, false, nil
+		}
+		if err := m.parseScopedPDU(m.data); err != nil {
+			return nil, true, err
+		}
+		c.setEngine(m)
+		return m.pdu, true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("SNMPv3 engine discovery failed: %w", err)
+	}
+	if len(c.engineID) == 0 {
+		return fmt.Errorf("SNMPv3 engine discovery failed: agent did not report an engine ID")
+	}
+	if resp.tag != pduReport {
+		return fmt.Errorf("SNMPv3 engine discovery failed: unexpected response")
+	}
+	return nil
+}
+
+func (c *Client) setEngine(m *message) {
+	if len(m.engineID) == 0 {
+		return
+	}
+	c.engineID = append([]byte(nil), m.engineID...)
+	c.boots = m.boots
+	c.engineTime = m.time
+	c.timeAt = time.Now()
+}
+
+// exchange sends one request and waits for the matching response
+func (c *Client) exchange(p *pdu) (*pdu, error) {
+	c.reqID++
+	p.requestID = c.reqID
+	id := c.reqID
+
+	if c.usm == nil {
+		msg, err := marshalCommunity(c.cfg.Community, p)
+		if err != nil {
+			return nil, err
+		}
+		return c.roundTrip(msg, func(b []byte) (*pdu, bool, error) {
+			m, err := parseMessage(b)
+			if err != nil || m.version != version2c || m.pdu.requestID != id {
+				return nil, false, nil
+			}
+			return m.pdu, true, nil
+		})
+	}
+
+	scoped, err := marshalScopedPDU(c.engineID, c.cfg.ContextName, p)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := int32(time.Since(c.timeAt) / time.Second)
+	msg, err := c.usm.seal(&message{
+		msgID:    id,
+		flags:    flagReportable,
+		engineID: c.engineID,
+		boots:    c.boots,
+		time:     c.engineTime + elapsed,
+	}, scoped)
+	if err != nil {
+		return nil, err
+	}
+	return c.roundTrip(msg, func(b []byte) (*pdu, bool, error) {
+		m, err := parseMessage(b)
+		if err != nil || m.version != version3 || m.msgID != id {
+			return nil, false, nil
+		}
+		if err := c.usm.open(m, b); err != nil {
+			return nil, true, err
+		}
+		if m.pdu.tag == pduReport {
+			c.setEngine(m)
+			return m.pdu, true, nil
+		}
+		if m.flags&flagAuth != c.usm.flags()&flagAuth {
+			return nil, true, errors.New("response did not use the configured security level")
+		}
+		c.setEngine(m)
+		return m.pdu, true, nil
+	})
+}
+
+// roundTrip sends msg, resending after timeouts, until decode accepts a
+// datagram. decode returns false for datagrams that belong to other requests.
+func (c *Client) roundTrip(msg []byte, decode func([]byte) (*pdu, bool, error)) (*pdu, error) {
+	for try := 0; try <= c.cfg.Retries; try++ {
+		if _, err := c.conn.Write(msg); err != nil {
+			return nil, err
+		}
+		deadline := time.Now().Add(c.cfg.Timeout)
+		for {
+			c.conn.SetReadDeadline(deadline)
+			n, err := c.conn.Read(c.buf)
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					break
+				}
+				return nil, err
+			}
+			p, ok, err := decode(c.buf[:n])
+			if ok {
+				return p, err
+			}
+		}
+	}
+	return nil, ErrTimeout
+}
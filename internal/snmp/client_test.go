@@ -0,0 +1,187 @@
+package snmp_test
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"server-dashboard/internal/snmp"
+	"server-dashboard/internal/snmp/snmptest"
+)
+
+var (
+	sysDescr  = snmp.MustParseOID("1.3.6.1.2.1.1.1.0")
+	sysUpTime = snmp.MustParseOID("1.3.6.1.2.1.1.3.0")
+	sysName   = snmp.MustParseOID("1.3.6.1.2.1.1.5.0")
+)
+
+func agentValues() []snmp.VarBind {
+	return []snmp.VarBind{
+		{OID: sysDescr, Type: snmp.OctetString, Value: []byte("Test switch")},
+		{OID: sysUpTime, Type: snmp.TimeTicks, Value: uint64(123456)},
+		{OID: snmp.MustParseOID("1.3.6.1.2.1.2.2.1.3.1"), Type: snmp.Integer, Value: int64(-5)},
+		{OID: snmp.MustParseOID("1.3.6.1.2.1.2.2.1.10.1"), Type: snmp.Counter32, Value: uint64(4294967295)},
+		{OID: snmp.MustParseOID("1.3.6.1.2.1.2.2.1.10.2"), Type: snmp.Counter32, Value: uint64(2)},
+		{OID: snmp.MustParseOID("1.3.6.1.2.1.31.1.1.1.6.1"), Type: snmp.Counter64, Value: uint64(1 << 63)},
+	}
+}
+
+func startAgent(t *testing.T, cfg snmp.AgentConfig) *snmptest.Agent {
+	t.Helper()
+	a, err := snmptest.NewAgent("127.0.0.1:0", cfg, agentValues())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { a.Close() })
+	return a
+}
+
+func dial(t *testing.T, addr string, cfg snmp.Config) *snmp.Client {
+	t.Helper()
+	cfg.Address = addr
+	if cfg.Timeout == 0 {
+		cfg.Timeout = time.Second
+	}
+	c, err := snmp.Dial(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestClientSecurityLevels(t *testing.T) {
+	tests := []struct {
+		name  string
+		agent snmp.AgentConfig
+		cfg   snmp.Config
+	}{
+		{"v2c",
+			snmp.AgentConfig{Community: "pub"},
+			snmp.Config{Version: "2c", Community: "pub"}},
+		{"v3 noAuthNoPriv",
+			snmp.AgentConfig{Username: "mon"},
+			snmp.Config{Version: "3", Username: "mon"}},
+		{"v3 authNoPriv MD5",
+			snmp.AgentConfig{Username: "mon", AuthProtocol: snmp.AuthMD5, AuthPassword: "authpass1"},
+			snmp.Config{Version: "3", Username: "mon", AuthProtocol: snmp.AuthMD5, AuthPassword: "authpass1"}},
+		{"v3 authPriv SHA DES",
+			snmp.AgentConfig{Username: "mon", AuthProtocol: snmp.AuthSHA, AuthPassword: "authpass1", PrivProtocol: snmp.PrivDES, PrivPassword: "privpass1"},
+			snmp.Config{Version: "3", Username: "mon", AuthProtocol: snmp.AuthSHA, AuthPassword: "authpass1", PrivProtocol: snmp.PrivDES, PrivPassword: "privpass1"}},
+		{"v3 authPriv SHA-512 AES",
+			snmp.AgentConfig{Username: "mon", AuthProtocol: snmp.AuthSHA512, AuthPassword: "authpass1", PrivProtocol: snmp.PrivAES, PrivPassword: "privpass1"},
+			snmp.Config{Version: "3", Username: "mon", AuthProtocol: snmp.AuthSHA512, AuthPassword: "authpass1", PrivProtocol: snmp.PrivAES, PrivPassword: "privpass1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := dial(t, startAgent(t, tt.agent).Addr(), tt.cfg)
+
+			vbs, err := c.Get(sysDescr, sysUpTime, sysName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s, _ := vbs[0].Text(); s != "Test switch" {
+				t.Errorf("sysDescr = %q, want %q", s, "Test switch")
+			}
+			if n, _ := vbs[1].Uint(); n != 123456 {
+				t.Errorf("sysUpTime = %d, want 123456", n)
+			}
+			if vbs[2].Exists() {
+				t.Errorf("sysName = %v, want noSuchObject", vbs[2])
+			}
+
+			var walked []snmp.VarBind
+			if err := c.Walk(snmp.MustParseOID("1.3.6.1.2.1"), func(vb snmp.VarBind) error {
+				walked = append(walked, vb)
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if len(walked) != len(agentValues()) {
+				t.Fatalf("walked %d values, want %d: %v", len(walked), len(agentValues()), walked)
+			}
+			if n, _ := walked[5].Uint(); n != 1<<63 {
+				t.Errorf("ifHCInOctets.1 = %d, want %d", n, uint64(1<<63))
+			}
+			if n, _ := walked[2].Int(); n != -5 {
+				t.Errorf("ifType.1 = %d, want -5", n)
+			}
+		})
+	}
+}
+
+func TestClientWrongCredentials(t *testing.T) {
+	addr := startAgent(t, snmp.AgentConfig{
+		Community: "pub",
+		Username:  "mon", AuthProtocol: snmp.AuthSHA, AuthPassword: "authpass1", PrivProtocol: snmp.PrivAES, PrivPassword: "privpass1",
+	}).Addr()
+
+	tests := []struct {
+		name string
+		cfg  snmp.Config
+		want string
+	}{
+		// Agents drop requests with a wrong community without answering
+		{"wrong community",
+			snmp.Config{Version: "2c", Community: "private"}, snmp.ErrTimeout.Error()},
+		{"unknown user",
+			snmp.Config{Version: "3", Username: "admin", AuthProtocol: snmp.AuthSHA, AuthPassword: "authpass1", PrivProtocol: snmp.PrivAES, PrivPassword: "privpass1"},
+			"unknown SNMPv3 user"},
+		{"wrong auth password",
+			snmp.Config{Version: "3", Username: "mon", AuthProtocol: snmp.AuthSHA, AuthPassword: "wrongpass", PrivProtocol: snmp.PrivAES, PrivPassword: "privpass1"},
+			"wrong auth password or protocol"},
+		{"wrong auth protocol",
+			snmp.Config{Version: "3", Username: "mon", AuthProtocol: snmp.AuthMD5, AuthPassword: "authpass1", PrivProtocol: snmp.PrivAES, PrivPassword: "privpass1"},
+			"wrong auth password or protocol"},
+		{"wrong priv password",
+			snmp.Config{Version: "3", Username: "mon", AuthProtocol: snmp.AuthSHA, AuthPassword: "authpass1", PrivProtocol: snmp.PrivAES, PrivPassword: "wrongpass"},
+			"decryption failed"},
+		{"lower security level",
+			snmp.Config{Version: "3", Username: "mon", AuthProtocol: snmp.AuthSHA, AuthPassword: "authpass1"},
+			"does not support the requested security level"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Timeout = 200 * time.Millisecond
+			_, err := dial(t, addr, tt.cfg).Get(sysDescr)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Get = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// A client ignores replies it cannot decode and times out rather than
+// returning garbage
+func TestClientIgnoresMalformedReplies(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	replies := [][]byte{
+		{0x30, 0x84, 0, 0, 0, 0xff, 0x02, 0x01, 0x01}, // length past the end
+		{0x30, 0x03, 0x02, 0x01},                      // truncated version
+		{0x04, 0x00},                                  // not a sequence
+	}
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			for _, r := range replies {
+				conn.WriteTo(r, from)
+			}
+			conn.WriteTo(buf[:n-1], from) // the request echoed back, cut short
+		}
+	}()
+
+	c := dial(t, conn.LocalAddr().String(), snmp.Config{Version: "2c", Community: "pub", Timeout: 200 * time.Millisecond, Retries: 1})
+	if _, err := c.Get(sysDescr); !errors.Is(err, snmp.ErrTimeout) {
+		t.Errorf("Get = %v, want %v", err, snmp.ErrTimeout)
+	}
+}
@@ -0,0 +1,299 @@
+package snmp
+
+import (
+	"fmt"
+)
+
+// Message versions
+const (
+	version2c = 1
+	version3  = 3
+)
+
+// SNMPv3 msgFlags bits
+const (
+	flagAuth       = 0x01
+	flagPriv       = 0x02
+	flagReportable = 0x04
+)
+
+const (
+	securityModelUSM = 3
+	maxMessageSize   = 65507
+)
+
+// pdu is a request or response. For GetBulk requests errorStatus and
+// errorIndex carry non-repeaters and max-repetitions.
+type pdu struct {
+	tag         byte
+	requestID   int32
+	errorStatus int
+	errorIndex  int
+	varBinds    []VarBind
+}
+
+func (p *pdu) marshal() ([]byte, error) {
+	var vbs []byte
+	for _, v := range p.varBinds {
+		value, err := encodeValue(v)
+		if err != nil {
+			return nil, err
+		}
+		vbs = append(vbs, tlv(tagSequence, encodeOID(v.OID), value)...)
+	}
+	return tlv(p.tag,
+		encodeInt(byte(Integer), int64(p.requestID)),
+		encodeInt(byte(Integer), int64(p.errorStatus)),
+		encodeInt(byte(Integer), int64(p.errorIndex)),
+		tlv(tagSequence, vbs),
+	), nil
+}
+
+func parsePDU(b []byte) (*pdu, error) {
+	tag, content, _, err := readTLV(b)
+	if err != nil {
+		return nil, err
+	}
+	switch tag {
+	case pduGetRequest, pduGetNextRequest, pduResponse, pduGetBulkRequest, pduReport:
+	default:
+		return nil, fmt.Errorf("unsupported PDU type %#x", tag)
+	}
+	p := &pdu{tag: tag}
+	var n int64
+	if n, content, err = readInt(content); err != nil {
+		return nil, err
+	}
+	p.requestID = int32(n)
+	if n, content, err = readInt(content); err != nil {
+		return nil, err
+	}
+	p.errorStatus = int(n)
+	if n, content, err = readInt(content); err != nil {
+		return nil, err
+	}
+	p.errorIndex = int(n)
+
+	vbs, _, err := expectTLV(content, tagSequence)
+	if err != nil {
+		return nil, err
+	}
+	for len(vbs) > 0 {
+		var vb []byte
+		if vb, vbs, err = expectTLV(vbs, tagSequence); err != nil {
+			return nil, err
+		}
+		oidBytes, valueBytes, err := expectTLV(vb, byte(ObjectIdentifier))
+		if err != nil {
+			return nil, err
+		}
+		oid, err := decodeOID(oidBytes)
+		if err != nil {
+			return nil, err
+		}
+		vtag, vcontent, _, err := readTLV(valueBytes)
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeValue(vtag, vcontent)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", oid, err)
+		}
+		p.varBinds = append(p.varBinds, VarBind{OID: oid, Type: Type(vtag), Value: value})
+	}
+	return p, nil
+}
+
+// message is a decoded SNMP message. For v3 the slices alias the buffer the
+// message was parsed from, so authParams can be zeroed and filled in place.
+type message struct {
+	version   int
+	community string
+
+	// SNMPv3 header and USM security parameters
+	msgID      int32
+	flags      byte
+	engineID   []byte
+	boots      int32
+	time       int32
+	user       string
+	authParams []byte
+	privParams []byte
+	data       []byte // scoped PDU, encrypted when flagPriv is set
+
+	contextEngineID []byte
+	contextName     string
+	pdu             *pdu
+}
+
+// marshalCommunity encodes a v2c message
+func marshalCommunity(community string, p *pdu) ([]byte, error) {
+	body, err := p.marshal()
+	if err != nil {
+		return nil, err
+	}
+	return tlv(tagSequence,
+		encodeInt(byte(Integer), version2c),
+		tlv(byte(OctetString), []byte(community)),
+		body,
+	), nil
+}
+
+// marshalScopedPDU encodes the v3 scoped PDU
+func marshalScopedPDU(contextEngineID []byte, contextName string, p *pdu) ([]byte, error) {
+	body, err := p.marshal()
+	if err != nil {
+		return nil, err
+	}
+	return tlv(tagSequence,
+		tlv(byte(OctetString), contextEngineID),
+		tlv(byte(OctetString), []byte(contextName)),
+		body,
+	), nil
+}
+
+// marshalV3 encodes a v3 message around already prepared message data. When
+// the message is authenticated, authParams holds a zeroed placeholder.
+func marshalV3(m *message) []byte {
+	secParams := tlv(tagSequence,
+		tlv(byte(OctetString), m.engineID),
+		encodeInt(byte(Integer), int64(m.boots)),
+		encodeInt(byte(Integer), int64(m.time)),
+		tlv(byte(OctetString), []byte(m.user)),
+		tlv(byte(OctetString), m.authParams),
+		tlv(byte(OctetString), m.privParams),
+	)
+	header := tlv(tagSequence,
+		encodeInt(byte(Integer), int64(m.msgID)),
+		encodeInt(byte(Integer), maxMessageSize),
+		tlv(byte(OctetString), []byte{m.flags}),
+		encodeInt(byte(Integer), securityModelUSM),
+	)
+	return tlv(tagSequence,
+		encodeInt(byte(Integer), version3),
+		header,
+		tlv(byte(OctetString), secParams),
+		m.data,
+	)
+}
+
+// parseMessage decodes the outer message. v2c messages are decoded fully;
+// for v3 the scoped PDU is left in data for the USM layer to authenticate
+// and decrypt.
+func parseMessage(b []byte) (*message, error) {
+	content, _, err := expectTLV(b, tagSequence)
+	if err != nil {
+		return nil, err
+	}
+	version, content, err := readInt(content)
+	if err != nil {
+		return nil, err
+	}
+	m := &message{version: int(version)}
+
+	switch version {
+	case version2c:
+		community, rest, err := expectTLV(content, byte(OctetString))
+		if err != nil {
+			return nil, err
+		}
+		m.community = string(community)
+		if m.pdu, err = parsePDU(rest); err != nil {
+			return nil, err
+		}
+		return m, nil
+	case version3:
+	default:
+		return nil, fmt.Errorf("unsupported SNMP version %d", version)
+	}
+
+	header, content, err := expectTLV(content, tagSequence)
+	if err != nil {
+		return nil, err
+	}
+	var n int64
+	if n, header, err = readInt(header); err != nil {
+		return nil, err
+	}
+	m.msgID = int32(n)
+	if _, header, err = readInt(header); err != nil { // msgMaxSize
+		return nil, err
+	}
+	flags, header, err := expectTLV(header, byte(OctetString))
+	if err != nil {
+		return nil, err
+	}
+	if len(flags) != 1 {
+		return nil, fmt.Errorf("invalid msgFlags")
+	}
+	m.flags = flags[0]
+	if n, _, err = readInt(header); err != nil {
+		return nil, err
+	}
+	if n != securityModelUSM {
+		return nil, fmt.Errorf("unsupported security model %d", n)
+	}
+
+	secParams, data, err := expectTLV(content, byte(OctetString))
+	if err != nil {
+		return nil, err
+	}
+	sp, _, err := expectTLV(secParams, tagSequence)
+	if err != nil {
+		return nil, err
+	}
+	if m.engineID, sp, err = expectTLV(sp, byte(OctetString)); err != nil {
+		return nil, err
+	}
+	if n, sp, err = readInt(sp); err != nil {
+		return nil, err
+	}
+	m.boots = int32(n)
+	if n, sp, err = readInt(sp); err != nil {
+		return nil, err
+	}
+	m.time = int32(n)
+	user, sp, err := expectTLV(sp, byte(OctetString))
+	if err != nil {
+		return nil, err
+	}
+	m.user = string(user)
+	if m.authParams, sp, err = expectTLV(sp, byte(OctetString)); err != nil {
+		return nil, err
+	}
+	if m.privParams, _, err = expectTLV(sp, byte(OctetString)); err != nil {
+		return nil, err
+	}
+
+	if m.flags&flagPriv != 0 {
+		m.data, _, err = expectTLV(data, byte(OctetString))
+	} else {
+		_, _, _, err = readTLV(data)
+		m.data = data
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parseScopedPDU decodes a plaintext scoped PDU into the message. Trailing
+// bytes are ignored, which also discards DES padding.
+func (m *message) parseScopedPDU(b []byte) error {
+	content, _, err := expectTLV(b, tagSequence)
+	if err != nil {
+		return fmt.Errorf("invalid scoped PDU: %w", err)
+	}
+	engineID, content, err := expectTLV(content, byte(OctetString))
+	if err != nil {
+		return err
+	}
+	name, content, err := expectTLV(content, byte(OctetString))
+	if err != nil {
+		return err
+	}
+	m.contextEngineID = engineID
+	m.contextName = string(name)
+	m.pdu, err = parsePDU(content)
+	return err
+}
@@ -0,0 +1,190 @@
+package snmp
+
+import (
+	"errors"
+	"testing"
+)
+
+func testRequest() *pdu {
+	return &pdu{tag: pduGetRequest, requestID: 7, varBinds: []VarBind{
+		{OID: MustParseOID("1.3.6.1.2.1.1.1.0"), Type: Null},
+		{OID: MustParseOID("1.3.6.1.2.1.2.2.1.10.1"), Type: Counter32, Value: uint64(4294967295)},
+	}}
+}
+
+// sealedRequest encodes testRequest as a v3 authPriv message
+func sealedRequest(t *testing.T) []byte {
+	t.Helper()
+	u, err := newUSM("mon", AuthSHA, "authpass1", PrivAES, "privpass1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	engineID := []byte{0x80, 0x00, 0x1f, 0x88, 0x04, 1, 2, 3}
+	scoped, err := marshalScopedPDU(engineID, "", testRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := u.seal(&message{msgID: 7, flags: flagReportable, engineID: engineID, boots: 1, time: 10}, scoped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestParseMessageRoundTrip(t *testing.T) {
+	b, err := marshalCommunity("public", testRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := parseMessage(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.version != version2c || m.community != "public" || m.pdu.requestID != 7 || len(m.pdu.varBinds) != 2 {
+		t.Fatalf("got %+v", m)
+	}
+	if n, _ := m.pdu.varBinds[1].Uint(); n != 4294967295 {
+		t.Errorf("counter = %d, want 4294967295", n)
+	}
+
+	m, err = parseMessage(sealedRequest(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.version != version3 || m.user != "mon" || m.flags != flagAuth|flagPriv|flagReportable || m.boots != 1 || m.time != 10 {
+		t.Fatalf("got %+v", m)
+	}
+}
+
+func TestParseMessageTruncated(t *testing.T) {
+	v2c, err := marshalCommunity("public", testRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, b := range map[string][]byte{"v2c": v2c, "v3": sealedRequest(t)} {
+		for n := 0; n < len(b); n++ {
+			if _, err := parseMessage(b[:n]); err == nil {
+				t.Errorf("%s: %d of %d bytes parsed without error", name, n, len(b))
+			}
+		}
+	}
+}
+
+func TestParseMessageMalformed(t *testing.T) {
+	null := tlv(byte(Null))
+	varBind := func(oid, value []byte) []byte {
+		return tlv(tagSequence, tlv(byte(ObjectIdentifier), oid), value)
+	}
+	request := func(tag byte, vb []byte) []byte {
+		return tlv(tag, encodeInt(byte(Integer), 1), encodeInt(byte(Integer), 0), encodeInt(byte(Integer), 0), tlv(tagSequence, vb))
+	}
+	v2c := func(p []byte) []byte {
+		return tlv(tagSequence, encodeInt(byte(Integer), version2c), tlv(byte(OctetString), []byte("public")), p)
+	}
+	get := func(vb []byte) []byte { return v2c(request(pduGetRequest, vb)) }
+	v3 := func(flags []byte, model int64) []byte {
+		header := tlv(tagSequence, encodeInt(byte(Integer), 1), encodeInt(byte(Integer), maxMessageSize),
+			tlv(byte(OctetString), flags), encodeInt(byte(Integer), model))
+		secParams := tlv(tagSequence, tlv(byte(OctetString)), encodeInt(byte(Integer), 0), encodeInt(byte(Integer), 0),
+			tlv(byte(OctetString)), tlv(byte(OctetString)), tlv(byte(OctetString)))
+		return tlv(tagSequence, encodeInt(byte(Integer), version3), header, tlv(byte(OctetString), secParams),
+			tlv(tagSequence, tlv(byte(OctetString)), tlv(byte(OctetString)), request(pduGetRequest, nil)))
+	}
+	sysDescr := []byte{0x2b, 6, 1, 2, 1, 1, 1, 0}
+
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{"not a sequence", tlv(byte(OctetString), encodeInt(byte(Integer), version2c))},
+		{"indefinite length", []byte{0x30, 0x80, 0x02, 0x01, 0x01}},
+		{"length of five octets", []byte{0x30, 0x85, 0, 0, 0, 0, 3, 0x02, 0x01, 0x01}},
+		{"length past the end", []byte{0x30, 0x84, 0, 0, 0, 0xff, 0x02, 0x01, 0x01}},
+		{"empty version", tlv(tagSequence, tlv(byte(Integer)))},
+		{"version too long", tlv(tagSequence, tlv(byte(Integer), []byte{1, 1, 1, 1, 1, 1, 1, 1, 1}))},
+		{"SNMPv1", tlv(tagSequence, encodeInt(byte(Integer), 0), tlv(byte(OctetString), []byte("public")), request(pduGetRequest, varBind(sysDescr, null)))},
+		{"community not a string", tlv(tagSequence, encodeInt(byte(Integer), version2c), encodeInt(byte(Integer), 5), request(pduGetRequest, varBind(sysDescr, null)))},
+		{"SetRequest", v2c(request(0xa3, varBind(sysDescr, null)))},
+		{"unterminated OID", get(varBind([]byte{0x2b, 0x06, 0x81}, null))},
+		{"empty OID", get(varBind(nil, null))},
+		{"oversized sub-identifier", get(varBind([]byte{0x2b, 0x9f, 0xff, 0xff, 0xff, 0x7f}, null))},
+		{"unsupported value type", get(varBind(sysDescr, tlv(0x45, []byte{1})))},
+		{"counter too long", get(varBind(sysDescr, tlv(byte(Counter64), []byte{1, 2, 3, 4, 5, 6, 7, 8, 9})))},
+		{"truncated value", get(varBind(sysDescr, []byte{byte(OctetString), 10, 'a'}))},
+		{"v3 flags of two octets", v3([]byte{0, 0}, securityModelUSM)},
+		{"v3 community-based security model", v3([]byte{0}, 2)},
+		{"v3 priv flag on plaintext data", v3([]byte{flagPriv}, securityModelUSM)},
+	}
+	for _, tt := range tests {
+		if m, err := parseMessage(tt.b); err == nil {
+			t.Errorf("%s: parsed as %+v", tt.name, m)
+		}
+	}
+
+	// The builders themselves produce messages that parse
+	if _, err := parseMessage(get(varBind(sysDescr, null))); err != nil {
+		t.Errorf("valid v2c message: %v", err)
+	}
+	if _, err := parseMessage(v3([]byte{flagReportable}, securityModelUSM)); err != nil {
+		t.Errorf("valid v3 message: %v", err)
+	}
+}
+
+// Corrupting any single byte may yield a different valid message, but must
+// never panic
+func TestParseMessageCorrupted(t *testing.T) {
+	v2c, err := marshalCommunity("public", testRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, orig := range [][]byte{v2c, sealedRequest(t)} {
+		for i := range orig {
+			for _, c := range []byte{0x00, 0x7f, 0x80, 0x84, 0xff} {
+				b := append([]byte(nil), orig...)
+				b[i] = c
+				if m, err := parseMessage(b); err == nil && m.flags&flagPriv == 0 {
+					m.parseScopedPDU(m.data)
+				}
+			}
+		}
+	}
+}
+
+func TestOpenRejectsTamperedMessage(t *testing.T) {
+	u, err := newUSM("mon", AuthSHA, "authpass1", PrivAES, "privpass1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := sealedRequest(t)
+	m, err := parseMessage(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := u.open(m, b); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if m.pdu.requestID != 7 || len(m.pdu.varBinds) != 2 {
+		t.Fatalf("got %+v", m.pdu)
+	}
+
+	b = sealedRequest(t)
+	b[len(b)-1] ^= 0xff // last byte of the encrypted scoped PDU
+	if m, err = parseMessage(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.open(m, b); !errors.Is(err, errWrongDigest) {
+		t.Errorf("open = %v, want %v", err, errWrongDigest)
+	}
+
+	wrongPriv, err := newUSM("mon", AuthSHA, "authpass1", PrivAES, "wrongpass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = sealedRequest(t)
+	if m, err = parseMessage(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := wrongPriv.open(m, b); err == nil || errors.Is(err, errWrongDigest) {
+		t.Errorf("open with the wrong priv password = %v, want a decryption error", err)
+	}
+}
@@ -0,0 +1,159 @@
+// Package snmp implements the parts of SNMP v2c and v3 (USM) the dashboard
+// needs to poll devices: Get, GetNext and GetBulk requests. The agent side
+// of the message layer is exported for the stand-in agent in snmptest.
+package snmp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Type is the ASN.1 tag of a variable binding value
+type Type byte
+
+// Value types used in variable bindings
+const (
+	Integer          Type = 0x02
+	OctetString      Type = 0x04
+	Null             Type = 0x05
+	ObjectIdentifier Type = 0x06
+	IPAddress        Type = 0x40
+	Counter32        Type = 0x41
+	Gauge32          Type = 0x42
+	TimeTicks        Type = 0x43
+	Opaque           Type = 0x44
+	Counter64        Type = 0x46
+	NoSuchObject     Type = 0x80
+	NoSuchInstance   Type = 0x81
+	EndOfMibView     Type = 0x82
+)
+
+// OID is an object identifier such as 1.3.6.1.2.1.1.3.0
+type OID []uint32
+
+// ParseOID parses a dotted object identifier; a leading dot is allowed
+func ParseOID(s string) (OID, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), ".")
+	if s == "" {
+		return nil, fmt.Errorf("empty OID")
+	}
+	parts := strings.Split(s, ".")
+	oid := make(OID, len(parts))
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID %q", s)
+		}
+		oid[i] = uint32(n)
+	}
+	return oid, nil
+}
+
+// MustParseOID is ParseOID for OIDs known at compile time
+func MustParseOID(s string) OID {
+	oid, err := ParseOID(s)
+	if err != nil {
+		panic(err)
+	}
+	return oid
+}
+
+func (o OID) String() string {
+	parts := make([]string, len(o))
+	for i, n := range o {
+		parts[i] = strconv.FormatUint(uint64(n), 10)
+	}
+	return strings.Join(parts, ".")
+}
+
+// HasPrefix reports whether o lies within the subtree rooted at prefix
+func (o OID) HasPrefix(prefix OID) bool {
+	if len(o) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if o[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare orders OIDs lexicographically, as agents walk them
+func (o OID) Compare(other OID) int {
+	for i := 0; i < len(o) && i < len(other); i++ {
+		if o[i] != other[i] {
+			if o[i] < other[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(o) < len(other):
+		return -1
+	case len(o) > len(other):
+		return 1
+	}
+	return 0
+}
+
+// Append returns o with the sub-identifiers appended, e.g. a table index
+func (o OID) Append(ids ...uint32) OID {
+	out := make(OID, 0, len(o)+len(ids))
+	return append(append(out, o...), ids...)
+}
+
+// VarBind is one OID and its value. Value holds an int64 for Integer, a
+// uint64 for the counter, gauge and time types, []byte for OctetString,
+// IPAddress and Opaque, an OID for ObjectIdentifier and nil otherwise.
+type VarBind struct {
+	OID   OID
+	Type  Type
+	Value interface{}
+}
+
+// Exists reports whether the agent returned a value rather than an exception
+func (v VarBind) Exists() bool {
+	return v.Type != NoSuchObject && v.Type != NoSuchInstance && v.Type != EndOfMibView
+}
+
+// Uint returns numeric values as an unsigned integer
+func (v VarBind) Uint() (uint64, bool) {
+	switch n := v.Value.(type) {
+	case uint64:
+		return n, true
+	case int64:
+		if n >= 0 {
+			return uint64(n), true
+		}
+	}
+	return 0, false
+}
+
+// Int returns numeric values as a signed integer
+func (v VarBind) Int() (int64, bool) {
+	switch n := v.Value.(type) {
+	case int64:
+		return n, true
+	case uint64:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+// Text returns octet string values as a string
+func (v VarBind) Text() (string, bool) {
+	b, ok := v.Value.([]byte)
+	if !ok || v.Type != OctetString {
+		return "", false
+	}
+	return string(b), true
+}
+
+// OIDValue returns object identifier values
+func (v VarBind) OIDValue() (OID, bool) {
+	o, ok := v.Value.(OID)
+	return o, ok
+}
@@ -0,0 +1,136 @@
+// Package snmptest provides a stand-in SNMP agent for exercising the SNMP
+// client and the collectors built on it.
+package snmptest
+
+import (
+	"net"
+	"sort"
+	"sync"
+
+	"server-dashboard/internal/snmp"
+)
+
+// Agent is a minimal SNMP agent that answers Get, GetNext and GetBulk from
+// a fixed table of values. It stands in for real devices when exercising
+// collectors.
+type Agent struct {
+	codec *snmp.AgentCodec
+	conn  net.PacketConn
+
+	mu     sync.RWMutex
+	values []snmp.VarBind // sorted by OID
+}
+
+// NewAgent starts an agent on addr, e.g. "127.0.0.1:0"
+func NewAgent(addr string, cfg snmp.AgentConfig, values []snmp.VarBind) (*Agent, error) {
+	codec, err := snmp.NewAgentCodec(cfg)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	a := &Agent{codec: codec, conn: conn}
+	a.Set(values)
+	go a.serve()
+	return a, nil
+}
+
+// Addr returns the address the agent listens on
+func (a *Agent) Addr() string {
+	return a.conn.LocalAddr().String()
+}
+
+// Set replaces the agent's values, e.g. to advance counters between polls
+func (a *Agent) Set(values []snmp.VarBind) {
+	sorted := append([]snmp.VarBind(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].OID.Compare(sorted[j].OID) < 0 })
+	a.mu.Lock()
+	a.values = sorted
+	a.mu.Unlock()
+}
+
+// Close stops the agent
+func (a *Agent) Close() error {
+	return a.conn.Close()
+}
+
+func (a *Agent) serve() {
+	buf := make([]byte, 65535)
+	for {
+		n, from, err := a.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if resp := a.codec.Handle(buf[:n], a.respond); resp != nil {
+			a.conn.WriteTo(resp, from)
+		}
+	}
+}
+
+// respond answers a request PDU from the agent's values
+func (a *Agent) respond(req snmp.PDU) snmp.PDU {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	var resp snmp.PDU
+
+	switch req.Type {
+	case snmp.GetRequest:
+		for _, vb := range req.VarBinds {
+			resp.VarBinds = append(resp.VarBinds, a.lookup(vb.OID))
+		}
+	case snmp.GetNextRequest:
+		for _, vb := range req.VarBinds {
+			resp.VarBinds = append(resp.VarBinds, a.next(vb.OID))
+		}
+	case snmp.GetBulkRequest:
+		nonRepeaters := req.ErrorStatus
+		if nonRepeaters < 0 {
+			nonRepeaters = 0
+		}
+		if nonRepeaters > len(req.VarBinds) {
+			nonRepeaters = len(req.VarBinds)
+		}
+		for _, vb := range req.VarBinds[:nonRepeaters] {
+			resp.VarBinds = append(resp.VarBinds, a.next(vb.OID))
+		}
+		cursor := make([]snmp.OID, 0, len(req.VarBinds)-nonRepeaters)
+		for _, vb := range req.VarBinds[nonRepeaters:] {
+			cursor = append(cursor, vb.OID)
+		}
+		for r := 0; r < req.ErrorIndex && len(cursor) > 0; r++ {
+			done := true
+			for i, oid := range cursor {
+				vb := a.next(oid)
+				resp.VarBinds = append(resp.VarBinds, vb)
+				if vb.Type != snmp.EndOfMibView {
+					cursor[i] = vb.OID
+					done = false
+				}
+			}
+			if done {
+				break
+			}
+		}
+	default:
+		resp.ErrorStatus = 5 // genErr
+	}
+	return resp
+}
+
+func (a *Agent) lookup(oid snmp.OID) snmp.VarBind {
+	i := sort.Search(len(a.values), func(i int) bool { return a.values[i].OID.Compare(oid) >= 0 })
+	if i < len(a.values) && a.values[i].OID.Compare(oid) == 0 {
+		return a.values[i]
+	}
+	return snmp.VarBind{OID: oid, Type: snmp.NoSuchObject}
+}
+
+func (a *Agent) next(oid snmp.OID) snmp.VarBind {
+	i := sort.Search(len(a.values), func(i int) bool { return a.values[i].OID.Compare(oid) > 0 })
+	if i < len(a.values) {
+		return a.values[i]
+	}
+	return snmp.VarBind{OID: oid, Type: snmp.EndOfMibView}
+}
@@ -0,0 +1,387 @@
+package snmp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+	"sync"
+)
+
+// AuthProtocol is a USM authentication protocol (RFC 3414, RFC 7860)
+type AuthProtocol string
+
+// Supported authentication protocols
+const (
+	AuthNone   AuthProtocol = ""
+	AuthMD5    AuthProtocol = "MD5"
+	AuthSHA    AuthProtocol = "SHA"
+	AuthSHA224 AuthProtocol = "SHA224"
+	AuthSHA256 AuthProtocol = "SHA256"
+	AuthSHA384 AuthProtocol = "SHA384"
+	AuthSHA512 AuthProtocol = "SHA512"
+)
+
+// PrivProtocol is a USM privacy protocol (RFC 3414, RFC 3826)
+type PrivProtocol string
+
+// Supported privacy protocols. AES is AES-128 in CFB mode.
+const (
+	PrivNone PrivProtocol = ""
+	PrivDES  PrivProtocol = "DES"
+	PrivAES  PrivProtocol = "AES"
+)
+
+// ParseAuthProtocol accepts the protocol names used by net-snmp, e.g. "SHA",
+// "sha-256" or "SHA1"
+func ParseAuthProtocol(s string) (AuthProtocol, error) {
+	name := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(s), "-", ""))
+	switch name {
+	case "":
+		return AuthNone, nil
+	case "MD5":
+		return AuthMD5, nil
+	case "SHA", "SHA1":
+		return AuthSHA, nil
+	case "SHA224", "SHA256", "SHA384", "SHA512":
+		return AuthProtocol(name), nil
+	}
+	return AuthNone, fmt.Errorf("unsupported auth protocol %q", s)
+}
+
+// ParsePrivProtocol accepts "DES", "AES" or "AES128"
+func ParsePrivProtocol(s string) (PrivProtocol, error) {
+	name := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(s), "-", ""))
+	switch name {
+	case "":
+		return PrivNone, nil
+	case "DES":
+		return PrivDES, nil
+	case "AES", "AES128":
+		return PrivAES, nil
+	}
+	return PrivNone, fmt.Errorf("unsupported priv protocol %q", s)
+}
+
+func (a AuthProtocol) newHash() func() hash.Hash {
+	switch a {
+	case AuthMD5:
+		return md5.New
+	case AuthSHA:
+		return sha1.New
+	case AuthSHA224:
+		return sha256.New224
+	case AuthSHA256:
+		return sha256.New
+	case AuthSHA384:
+		return sha512.New384
+	case AuthSHA512:
+		return sha512.New
+	}
+	return nil
+}
+
+// macLen is the length of the truncated HMAC carried in msgAuthenticationParameters
+func (a AuthProtocol) macLen() int {
+	switch a {
+	case AuthMD5, AuthSHA:
+		return 12
+	case AuthSHA224:
+		return 16
+	case AuthSHA256:
+		return 24
+	case AuthSHA384:
+		return 32
+	case AuthSHA512:
+		return 48
+	}
+	return 0
+}
+
+// USM statistics reported by agents when they reject a request
+var (
+	usmStatsUnsupportedSecLevels = MustParseOID("1.3.6.1.6.3.15.1.1.1.0")
+	usmStatsNotInTimeWindows     = MustParseOID("1.3.6.1.6.3.15.1.1.2.0")
+	usmStatsUnknownUserNames     = MustParseOID("1.3.6.1.6.3.15.1.1.3.0")
+	usmStatsUnknownEngineIDs     = MustParseOID("1.3.6.1.6.3.15.1.1.4.0")
+	usmStatsWrongDigests         = MustParseOID("1.3.6.1.6.3.15.1.1.5.0")
+	usmStatsDecryptionErrors     = MustParseOID("1.3.6.1.6.3.15.1.1.6.0")
+)
+
+var errWrongDigest = errors.New("authentication failed: wrong digest")
+
+// usm holds one user's credentials and the keys localized to an engine
+type usm struct {
+	user     string
+	auth     AuthProtocol
+	priv     PrivProtocol
+	authPass string
+	privPass string
+
+	mu       sync.Mutex
+	engineID []byte
+	authKey  []byte
+	privKey  []byte
+	salt     uint64
+}
+
+func newUSM(user string, auth AuthProtocol, authPass string, priv PrivProtocol, privPass string) (*usm, error) {
+	if user == "" {
+		return nil, errors.New("SNMPv3 needs a username")
+	}
+	if priv != PrivNone && auth == AuthNone {
+		return nil, errors.New("SNMPv3 privacy requires an auth protocol")
+	}
+	if auth != AuthNone && len(authPass) < 8 {
+		return nil, errors.New("SNMPv3 auth password must be at least 8 characters")
+	}
+	if priv != PrivNone && len(privPass) < 8 {
+		return nil, errors.New("SNMPv3 priv password must be at least 8 characters")
+	}
+	u := &usm{user: user, auth: auth, priv: priv, authPass: authPass, privPass: privPass}
+	var seed [8]byte
+	rand.Read(seed[:])
+	u.salt = binary.BigEndian.Uint64(seed[:])
+	return u, nil
+}
+
+// flags returns the msgFlags for the user's security level
+func (u *usm) flags() byte {
+	var f byte
+	if u.auth != AuthNone {
+		f |= flagAuth
+	}
+	if u.priv != PrivNone {
+		f |= flagPriv
+	}
+	return f
+}
+
+// localize derives the user's keys for an engine, reusing them while the
+// engine ID stays the same
+func (u *usm) localize(engineID []byte) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.authKey != nil && bytes.Equal(u.engineID, engineID) {
+		return
+	}
+	u.engineID = append([]byte(nil), engineID...)
+	if u.auth == AuthNone {
+		return
+	}
+	u.authKey = passwordToKey(u.auth.newHash(), u.authPass, engineID)
+	if u.priv != PrivNone {
+		u.privKey = passwordToKey(u.auth.newHash(), u.privPass, engineID)
+	}
+}
+
+// passwordToKey implements the password to key algorithm of RFC 3414 A.2,
+// followed by localization to the engine ID
+func passwordToKey(newHash func() hash.Hash, password string, engineID []byte) []byte {
+	h := newHash()
+	pw := []byte(password)
+	buf := make([]byte, 64)
+	idx := 0
+	for count := 0; count < 1048576; count += len(buf) {
+		for i := range buf {
+			buf[i] = pw[idx%len(pw)]
+			idx++
+		}
+		h.Write(buf)
+	}
+	ku := h.Sum(nil)
+	h.Reset()
+	h.Write(ku)
+	h.Write(engineID)
+	h.Write(ku)
+	return h.Sum(nil)
+}
+
+func (u *usm) mac(msg []byte) []byte {
+	h := hmac.New(u.auth.newHash(), u.authKey)
+	h.Write(msg)
+	return h.Sum(nil)[:u.auth.macLen()]
+}
+
+// seal encrypts and authenticates a scoped PDU at the user's security
+// level and returns the encoded message. m carries the header fields.
+func (u *usm) seal(m *message, scopedPDU []byte) ([]byte, error) {
+	u.localize(m.engineID)
+	m.user = u.user
+	m.flags |= u.flags()
+	m.authParams, m.privParams, m.data = nil, nil, scopedPDU
+
+	if u.priv != PrivNone {
+		encrypted, salt, err := u.encrypt(scopedPDU, m.boots, m.time)
+		if err != nil {
+			return nil, err
+		}
+		m.privParams = salt
+		m.data = tlv(byte(OctetString), encrypted)
+	}
+	if u.auth == AuthNone {
+		return marshalV3(m), nil
+	}
+
+	m.authParams = make([]byte, u.auth.macLen())
+	msg := marshalV3(m)
+	// Re-parse to find the placeholder; its slice aliases msg
+	sealed, err := parseMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	copy(sealed.authParams, u.mac(msg))
+	return msg, nil
+}
+
+// open authenticates and decrypts a received v3 message and decodes its
+// scoped PDU. raw is the buffer m was parsed from.
+func (u *usm) open(m *message, raw []byte) error {
+	if m.flags&flagAuth != 0 {
+		if u.auth == AuthNone {
+			return errors.New("authenticated message for a user without auth")
+		}
+		u.localize(m.engineID)
+		got := append([]byte(nil), m.authParams...)
+		for i := range m.authParams {
+			m.authParams[i] = 0
+		}
+		want := u.mac(raw)
+		copy(m.authParams, got)
+		if !hmac.Equal(got, want) {
+			return errWrongDigest
+		}
+	}
+
+	plaintext := m.data
+	if m.flags&flagPriv != 0 {
+		if u.priv == PrivNone {
+			return errors.New("encrypted message for a user without privacy")
+		}
+		var err error
+		if plaintext, err = u.decrypt(m.data, m.privParams, m.boots, m.time); err != nil {
+			return err
+		}
+	}
+	return m.parseScopedPDU(plaintext)
+}
+
+func (u *usm) nextSalt() []byte {
+	u.mu.Lock()
+	u.salt++
+	n := u.salt
+	u.mu.Unlock()
+	salt := make([]byte, 8)
+	binary.BigEndian.PutUint64(salt, n)
+	return salt
+}
+
+// encrypt returns the ciphertext and the msgPrivacyParameters salt
+func (u *usm) encrypt(plaintext []byte, boots, engineTime int32) ([]byte, []byte, error) {
+	switch u.priv {
+	case PrivDES:
+		salt := u.nextSalt()
+		binary.BigEndian.PutUint32(salt, uint32(boots))
+		block, err := des.NewCipher(u.privKey[:8])
+		if err != nil {
+			return nil, nil, err
+		}
+		padded := plaintext
+		if rem := len(padded) % des.BlockSize; rem != 0 {
+			padded = append(append([]byte(nil), plaintext...), make([]byte, des.BlockSize-rem)...)
+		}
+		out := make([]byte, len(padded))
+		cipher.NewCBCEncrypter(block, desIV(u.privKey, salt)).CryptBlocks(out, padded)
+		return out, salt, nil
+	case PrivAES:
+		salt := u.nextSalt()
+		block, err := aes.NewCipher(u.privKey[:16])
+		if err != nil {
+			return nil, nil, err
+		}
+		out := make([]byte, len(plaintext))
+		cipher.NewCFBEncrypter(block, aesIV(boots, engineTime, salt)).XORKeyStream(out, plaintext)
+		return out, salt, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported priv protocol %q", u.priv)
+}
+
+func (u *usm) decrypt(ciphertext, salt []byte, boots, engineTime int32) ([]byte, error) {
+	if len(salt) != 8 {
+		return nil, errors.New("decryption failed: invalid privacy parameters")
+	}
+	switch u.priv {
+	case PrivDES:
+		if len(ciphertext)%des.BlockSize != 0 {
+			return nil, errors.New("decryption failed: ciphertext is not a whole number of blocks")
+		}
+		block, err := des.NewCipher(u.privKey[:8])
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, len(ciphertext))
+		cipher.NewCBCDecrypter(block, desIV(u.privKey, salt)).CryptBlocks(out, ciphertext)
+		return out, nil
+	case PrivAES:
+		block, err := aes.NewCipher(u.privKey[:16])
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, len(ciphertext))
+		cipher.NewCFBDecrypter(block, aesIV(boots, engineTime, salt)).XORKeyStream(out, ciphertext)
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported priv protocol %q", u.priv)
+}
+
+// desIV is the pre-IV from the privacy key XORed with the salt (RFC 3414 8.1.1.1)
+func desIV(privKey, salt []byte) []byte {
+	iv := make([]byte, 8)
+	for i := range iv {
+		iv[i] = privKey[8+i] ^ salt[i]
+	}
+	return iv
+}
+
+// aesIV is engine boots, engine time and the salt (RFC 3826 3.1.2.1)
+func aesIV(boots, engineTime int32, salt []byte) []byte {
+	iv := make([]byte, 16)
+	binary.BigEndian.PutUint32(iv, uint32(boots))
+	binary.BigEndian.PutUint32(iv[4:], uint32(engineTime))
+	copy(iv[8:], salt)
+	return iv
+}
+
+// reportError explains a Report PDU returned instead of a response
+func reportError(p *pdu) error {
+	if len(p.varBinds) == 0 {
+		return errors.New("agent returned an empty report")
+	}
+	oid := p.varBinds[0].OID
+	switch {
+	case oid.Compare(usmStatsUnsupportedSecLevels) == 0:
+		return errors.New("agent does not support the requested security level")
+	case oid.Compare(usmStatsNotInTimeWindows) == 0:
+		return errors.New("request was outside the agent's time window")
+	case oid.Compare(usmStatsUnknownUserNames) == 0:
+		return errors.New("unknown SNMPv3 user")
+	case oid.Compare(usmStatsUnknownEngineIDs) == 0:
+		return errors.New("unknown engine ID")
+	case oid.Compare(usmStatsWrongDigests) == 0:
+		return errors.New("authentication failed: wrong auth password or protocol")
+	case oid.Compare(usmStatsDecryptionErrors) == 0:
+		return errors.New("decryption failed: wrong priv password or protocol")
+	}
+	return fmt.Errorf("agent returned report %s", oid)
+}
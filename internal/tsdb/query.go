@@ -0,0 +1,163 @@
+package tsdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxPoints bounds the size of one query result
+const maxPoints = 10000
+
+// defaultPoints is the resolution used when a query gives no step
+const defaultPoints = 300
+
+// Point is one step of a query result
+type Point struct {
+	Time  time.Time `json:"t"`
+	Avg   float64   `json:"avg"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+	Count int       `json:"count"` // Raw samples behind the point
+}
+
+// Result is the answer to a query
+type Result struct {
+	Tier   string
+	Step   time.Duration
+	Points []Point
+}
+
+// Query returns one metric of a series between from and to, aggregated
+// into steps. It reads the finest tier that still holds data for from and
+// is no finer than step. A zero step picks one giving about 300 points.
+func (db *DB) Query(series, metric string, from, to time.Time, step time.Duration) (Result, error) {
+	if !from.Before(to) {
+		return Result{}, errors.New("from must be before to")
+	}
+	if step <= 0 {
+		step = to.Sub(from) / defaultPoints
+		if step < time.Second {
+			step = time.Second
+		}
+	}
+	if to.Sub(from)/step > maxPoints {
+		return Result{}, fmt.Errorf("step too small: more than %d points", maxPoints)
+	}
+
+	tr := db.chooseTier(from, step, time.Now())
+	if step < tr.step {
+		step = tr.step
+	}
+
+	buckets := make(map[int64]*bucket)
+	add := func(b bucket) {
+		if b.start.Before(from) || !b.start.Before(to) {
+			return
+		}
+		slot := b.start.Truncate(step)
+		if cur := buckets[slot.UnixNano()]; cur != nil {
+			cur.add(b)
+			return
+		}
+		b.start = slot
+		buckets[slot.UnixNano()] = &b
+	}
+
+	dir := filepath.Join(db.dir, tr.name, seriesDir(series))
+	for day := from.UTC().Truncate(24 * time.Hour); day.Before(to); day = day.Add(24 * time.Hour) {
+		data, err := os.ReadFile(filepath.Join(dir, dayOf(day)+".dat"))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return Result{}, fmt.Errorf("tsdb: %w", err)
+		}
+		decodeRecords(data, func(name string, b bucket) {
+			if name == metric {
+				add(b)
+			}
+		})
+	}
+
+	// Include the bucket still being filled so recent data shows up
+	db.mu.Lock()
+	if b := db.pending[pendingKey{tr.name, series, metric}]; b != nil {
+		add(*b)
+	}
+	db.mu.Unlock()
+
+	res := Result{Tier: tr.name, Step: step, Points: make([]Point, 0, len(buckets))}
+	for _, b := range buckets {
+		res.Points = append(res.Points, Point{
+			Time:  b.start,
+			Avg:   b.sum / float64(b.count),
+			Min:   b.min,
+			Max:   b.max,
+			Count: int(b.count),
+		})
+	}
+	sort.Slice(res.Points, func(i, j int) bool { return res.Points[i].Time.Before(res.Points[j].Time) })
+	return res, nil
+}
+
+// chooseTier picks the finest tier whose retention covers from and whose
+// resolution is no finer than step. When step is finer than every tier that
+// covers from, the finest of those is used; when none covers from, the
+// coarsest tier is.
+func (db *DB) chooseTier(from time.Time, step time.Duration, now time.Time) tier {
+	var covering []tier
+	for _, t := range db.tiers {
+		if !from.Before(now.Add(-t.retention)) {
+			covering = append(covering, t)
+		}
+	}
+	if len(covering) == 0 {
+		return db.tiers[len(db.tiers)-1]
+	}
+	best := covering[0]
+	for _, t := range covering {
+		if t.step <= step {
+			best = t
+		}
+	}
+	return best
+}
+
+// Metrics lists the metric names recorded for a series on its most recent
+// day of raw samples
+func (db *DB) Metrics(series string) ([]string, error) {
+	dir := filepath.Join(db.dir, TierRaw, seriesDir(series))
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tsdb: %w", err)
+	}
+	latest := ""
+	for _, f := range files {
+		if strings.HasSuffix(f.Name(), ".dat") && f.Name() > latest {
+			latest = f.Name()
+		}
+	}
+	if latest == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, latest))
+	if err != nil {
+		return nil, fmt.Errorf("tsdb: %w", err)
+	}
+	seen := make(map[string]bool)
+	decodeRecords(data, func(name string, _ bucket) { seen[name] = true })
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,107 @@
+package tsdb
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChooseTier(t *testing.T) {
+	db := &DB{tiers: []tier{
+		{name: TierRaw, retention: 48 * time.Hour},
+		{name: TierMinute, step: time.Minute, retention: 14 * 24 * time.Hour},
+		{name: TierHour, step: time.Hour, retention: 365 * 24 * time.Hour},
+	}}
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ago  time.Duration
+		step time.Duration
+		want string
+	}{
+		{time.Hour, time.Second, TierRaw},
+		{time.Hour, time.Minute, TierMinute},
+		{time.Hour, 5 * time.Minute, TierMinute},
+		{time.Hour, 2 * time.Hour, TierHour},
+		// Raw samples are gone; a finer step than any remaining tier
+		// reads the finest of them
+		{72 * time.Hour, time.Second, TierMinute},
+		{30 * 24 * time.Hour, time.Second, TierHour},
+		{400 * 24 * time.Hour, time.Minute, TierHour},
+	}
+	for _, tt := range tests {
+		if got := db.chooseTier(now.Add(-tt.ago), tt.step, now); got.name != tt.want {
+			t.Errorf("%v ago by %v: tier %s, want %s", tt.ago, tt.step, got.name, tt.want)
+		}
+	}
+}
+
+// A step coarser than the tier read aggregates several of its buckets
+// into each point
+func TestQueryStepCoarserThanTier(t *testing.T) {
+	db := openTest(t, t.TempDir())
+	base := time.Now().Add(-2 * time.Hour).Truncate(time.Hour)
+	for i := 0; i < 180; i++ {
+		if err := db.Append("server/web1", base.Add(time.Duration(i)*10*time.Second), map[string]float64{"x": float64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		step   time.Duration
+		tier   string
+		points int
+	}{
+		{30 * time.Second, TierRaw, 60},
+		{5 * time.Minute, TierMinute, 6},
+		{10 * time.Minute, TierMinute, 3},
+		{time.Hour, TierHour, 1},
+	}
+	for _, tt := range tests {
+		res, err := db.Query("server/web1", "x", base, base.Add(time.Hour), tt.step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Tier != tt.tier || res.Step != tt.step || len(res.Points) != tt.points {
+			t.Errorf("step %v: %s tier by %v, %d points, want %s tier, %d points", tt.step, res.Tier, res.Step, len(res.Points), tt.tier, tt.points)
+			continue
+		}
+		per := 180 / tt.points
+		for k, p := range res.Points {
+			first := float64(k * per)
+			if !p.Time.Equal(base.Add(time.Duration(k)*tt.step)) || p.Count != per ||
+				p.Min != first || p.Max != first+float64(per-1) || p.Avg != first+float64(per-1)/2 {
+				t.Errorf("step %v: point %d = %+v", tt.step, k, p)
+			}
+		}
+	}
+}
+
+func TestQueryStepFinerThanTier(t *testing.T) {
+	db := openTest(t, t.TempDir())
+	from := time.Now().Add(-72 * time.Hour)
+	res, err := db.Query("server/web1", "x", from, from.Add(time.Hour), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Tier != TierMinute || res.Step != time.Minute {
+		t.Errorf("got the %s tier by %v, want minutes", res.Tier, res.Step)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	db := openTest(t, t.TempDir())
+	now := time.Now()
+	if _, err := db.Query("s", "x", now, now, time.Minute); err == nil {
+		t.Error("empty range accepted")
+	}
+	if _, err := db.Query("s", "x", now.Add(-24*time.Hour), now, time.Second); err == nil || !strings.Contains(err.Error(), "step too small") {
+		t.Errorf("err = %v, want step too small", err)
+	}
+	res, err := db.Query("s", "x", now.Add(-5*time.Hour), now, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Step != time.Minute || len(res.Points) != 0 {
+		t.Errorf("default step %v with %d points, want 1m and none", res.Step, len(res.Points))
+	}
+}
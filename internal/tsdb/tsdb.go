@@ -0,0 +1,404 @@
+// Package tsdb is a small embedded time-series store for device metrics.
+//
+// Samples are kept in three tiers: raw samples, one-minute and one-hour
+// aggregates. Each tier keeps one append-only file per series per UTC day:
+//
+//	<dir>/<tier>/<series>/<YYYY-MM-DD>.dat
+//
+// Every record is an aggregate (count, sum, min, max) so raw samples and
+// downsampled buckets share one format, and a bucket written twice (for
+// example once before and once after a restart) simply merges on read.
+// Retention deletes whole day files.
+package tsdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tier names
+const (
+	TierRaw    = "raw"
+	TierMinute = "1m"
+	TierHour   = "1h"
+)
+
+// Options configures a store. Zero retentions use the defaults.
+type Options struct {
+	Dir             string
+	RawRetention    time.Duration // Default 48 hours
+	MinuteRetention time.Duration // Default 14 days
+	HourRetention   time.Duration // Default 365 days
+}
+
+// tier is one resolution level. Step is zero for raw samples.
+type tier struct {
+	name      string
+	step      time.Duration
+	retention time.Duration
+}
+
+// bucket is an aggregate over one step, or a single raw sample
+type bucket struct {
+	start time.Time
+	count uint32
+	sum   float64
+	min   float64
+	max   float64
+}
+
+func (b *bucket) add(o bucket) {
+	if b.count == 0 {
+		*b = o
+		return
+	}
+	b.count += o.count
+	b.sum += o.sum
+	b.min = math.Min(b.min, o.min)
+	b.max = math.Max(b.max, o.max)
+}
+
+// pendingKey identifies an open downsampling bucket
+type pendingKey struct {
+	tier   string
+	series string
+	metric string
+}
+
+// DB is an open store. It is safe for concurrent use.
+type DB struct {
+	dir   string
+	tiers []tier
+
+	mu      sync.Mutex
+	pending map[pendingKey]*bucket // open downsampling buckets
+	written map[string]bool        // day files appended to since Open
+	closed  bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// Open opens or creates a store and starts its background flush and
+// retention loop
+func Open(opts Options) (*DB, error) {
+	if opts.Dir == "" {
+		return nil, errors.New("tsdb: no directory")
+	}
+	if opts.RawRetention <= 0 {
+		opts.RawRetention = 48 * time.Hour
+	}
+	if opts.MinuteRetention <= 0 {
+		opts.MinuteRetention = 14 * 24 * time.Hour
+	}
+	if opts.HourRetention <= 0 {
+		opts.HourRetention = 365 * 24 * time.Hour
+	}
+	db := &DB{
+		dir: opts.Dir,
+		tiers: []tier{
+			{name: TierRaw, retention: opts.RawRetention},
+			{name: TierMinute, step: time.Minute, retention: opts.MinuteRetention},
+			{name: TierHour, step: time.Hour, retention: opts.HourRetention},
+		},
+		pending: make(map[pendingKey]*bucket),
+		written: make(map[string]bool),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	for _, t := range db.tiers {
+		if err := os.MkdirAll(filepath.Join(db.dir, t.name), 0700); err != nil {
+			return nil, fmt.Errorf("tsdb: %w", err)
+		}
+	}
+	db.enforceRetention(time.Now())
+	go db.loop()
+	return db, nil
+}
+
+// Close flushes open downsampling buckets and stops background work
+func (db *DB) Close() error {
+	db.mu.Lock()
+	if db.closed {
+		db.mu.Unlock()
+		return nil
+	}
+	db.closed = true
+	db.mu.Unlock()
+
+	close(db.stop)
+	<-db.done
+	return db.flush(time.Time{}, true)
+}
+
+// loop flushes finished buckets every minute and applies retention hourly
+func (db *DB) loop() {
+	defer close(db.done)
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	lastRetention := time.Now()
+	for {
+		select {
+		case <-db.stop:
+			return
+		case now := <-ticker.C:
+			if err := db.flush(now, false); err != nil {
+				log.Printf("History flush failed: %v", err)
+			}
+			if now.Sub(lastRetention) >= time.Hour {
+				db.enforceRetention(now)
+				lastRetention = now
+			}
+		}
+	}
+}
+
+// Append records samples for one series taken at t. Metric names must be
+// at most 255 bytes.
+func (db *DB) Append(series string, t time.Time, values map[string]float64) error {
+	if len(values) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		if len(name) == 0 || len(name) > 255 {
+			return fmt.Errorf("tsdb: invalid metric name %q", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var raw []byte
+	for _, name := range names {
+		v := values[name]
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		raw = appendRecord(raw, name, bucket{start: t, count: 1, sum: v, min: v, max: v})
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return errors.New("tsdb: closed")
+	}
+	if err := db.writeDay(TierRaw, series, dayOf(t), raw); err != nil {
+		return err
+	}
+
+	// Roll samples into the downsampled tiers. A bucket is written out once
+	// a sample for a later bucket arrives, or by the flush loop.
+	for _, tr := range db.tiers[1:] {
+		out := make(map[string][]byte)
+		for _, name := range names {
+			v := values[name]
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			key := pendingKey{tr.name, series, name}
+			start := t.Truncate(tr.step)
+			b := db.pending[key]
+			if b != nil && !b.start.Equal(start) {
+				day := dayOf(b.start)
+				out[day] = appendRecord(out[day], name, *b)
+				b = nil
+			}
+			if b == nil {
+				b = &bucket{}
+				db.pending[key] = b
+			}
+			b.add(bucket{start: start, count: 1, sum: v, min: v, max: v})
+		}
+		for day, data := range out {
+			if err := db.writeDay(tr.name, series, day, data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// flush writes buckets that ended before now, or every bucket when all is set
+func (db *DB) flush(now time.Time, all bool) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	type target struct{ tier, series, day string }
+	out := make(map[target][]byte)
+	for key, b := range db.pending {
+		if !all && now.Before(b.start.Add(db.tier(key.tier).step)) {
+			continue
+		}
+		t := target{key.tier, key.series, dayOf(b.start)}
+		out[t] = appendRecord(out[t], key.metric, *b)
+		delete(db.pending, key)
+	}
+	var firstErr error
+	for t, data := range out {
+		if err := db.writeDay(t.tier, t.series, t.day, data); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// tier returns the tier with the given name
+func (db *DB) tier(name string) tier {
+	for _, t := range db.tiers {
+		if t.name == name {
+			return t
+		}
+	}
+	return db.tiers[0]
+}
+
+// writeDay appends encoded records to a day file. The first write to a file
+// since Open drops a partial record a crash left at its end, which would
+// otherwise misalign every record appended after it.
+func (db *DB) writeDay(tierName, series, day string, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	dir := filepath.Join(db.dir, tierName, seriesDir(series))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("tsdb: %w", err)
+	}
+	path := filepath.Join(dir, day+".dat")
+	if !db.written[path] {
+		if err := truncatePartial(path); err != nil {
+			return fmt.Errorf("tsdb: %w", err)
+		}
+		db.written[path] = true
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("tsdb: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("tsdb: %w", err)
+	}
+	return f.Close()
+}
+
+// truncatePartial cuts a day file back to its last complete record
+func truncatePartial(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if n := completeLength(data); n < len(data) {
+		log.Printf("History: dropping %d bytes of a partial record at the end of %s", len(data)-n, path)
+		return os.Truncate(path, int64(n))
+	}
+	return nil
+}
+
+// enforceRetention deletes day files that lie entirely before each tier's
+// retention window
+func (db *DB) enforceRetention(now time.Time) {
+	for _, t := range db.tiers {
+		cutoff := now.Add(-t.retention)
+		seriesDirs, err := os.ReadDir(filepath.Join(db.dir, t.name))
+		if err != nil {
+			continue
+		}
+		for _, sd := range seriesDirs {
+			if !sd.IsDir() {
+				continue
+			}
+			dir := filepath.Join(db.dir, t.name, sd.Name())
+			files, err := os.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, f := range files {
+				day, err := time.Parse("2006-01-02", strings.TrimSuffix(f.Name(), ".dat"))
+				if err != nil {
+					continue
+				}
+				if day.Add(24 * time.Hour).Before(cutoff) {
+					if err := os.Remove(filepath.Join(dir, f.Name())); err != nil {
+						log.Printf("History retention: %v", err)
+					}
+				}
+			}
+		}
+	}
+}
+
+// Record layout, little endian:
+//
+//	name length (1) | name | start unix ms (8) | count (4) | sum (8) | min (8) | max (8)
+const recordFixedSize = 8 + 4 + 8 + 8 + 8
+
+func appendRecord(buf []byte, name string, b bucket) []byte {
+	buf = append(buf, byte(len(name)))
+	buf = append(buf, name...)
+	var fixed [recordFixedSize]byte
+	binary.LittleEndian.PutUint64(fixed[0:], uint64(b.start.UnixMilli()))
+	binary.LittleEndian.PutUint32(fixed[8:], b.count)
+	binary.LittleEndian.PutUint64(fixed[12:], math.Float64bits(b.sum))
+	binary.LittleEndian.PutUint64(fixed[20:], math.Float64bits(b.min))
+	binary.LittleEndian.PutUint64(fixed[28:], math.Float64bits(b.max))
+	return append(buf, fixed[:]...)
+}
+
+// completeLength returns the length of the complete records at the start
+// of data
+func completeLength(data []byte) int {
+	length := 0
+	for length < len(data) {
+		size := 1 + int(data[length]) + recordFixedSize
+		if len(data)-length < size {
+			break
+		}
+		length += size
+	}
+	return length
+}
+
+// decodeRecords calls fn for every complete record. A partial record at the
+// end, left by a crash mid-write, is ignored.
+func decodeRecords(data []byte, fn func(name string, b bucket)) {
+	for len(data) > 0 {
+		n := int(data[0])
+		if len(data) < 1+n+recordFixedSize {
+			return
+		}
+		name := string(data[1 : 1+n])
+		fixed := data[1+n:]
+		fn(name, bucket{
+			start: time.UnixMilli(int64(binary.LittleEndian.Uint64(fixed[0:]))),
+			count: binary.LittleEndian.Uint32(fixed[8:]),
+			sum:   math.Float64frombits(binary.LittleEndian.Uint64(fixed[12:])),
+			min:   math.Float64frombits(binary.LittleEndian.Uint64(fixed[20:])),
+			max:   math.Float64frombits(binary.LittleEndian.Uint64(fixed[28:])),
+		})
+		data = data[1+n+recordFixedSize:]
+	}
+}
+
+func dayOf(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// seriesDir maps a series name to a safe directory name
+func seriesDir(series string) string {
+	dir := url.QueryEscape(series)
+	if strings.HasPrefix(dir, ".") {
+		dir = "%2E" + dir[1:]
+	}
+	return dir
+}
@@ -0,0 +1,243 @@
+package tsdb
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func openTest(t *testing.T, dir string) *DB {
+	t.Helper()
+	db, err := Open(Options{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// dayFile is the path of a series' day file in a tier
+func dayFile(dir, tierName, series string, t time.Time) string {
+	return filepath.Join(dir, tierName, seriesDir(series), dayOf(t)+".dat")
+}
+
+// readRecords decodes a day file
+func readRecords(t *testing.T, path string) []bucket {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []bucket
+	decodeRecords(data, func(_ string, b bucket) { out = append(out, b) })
+	return out
+}
+
+func TestAppendRollsUpAndFlushes(t *testing.T) {
+	dir := t.TempDir()
+	db := openTest(t, dir)
+	base := time.Now().Add(-2 * time.Hour).Truncate(time.Hour)
+
+	for _, s := range []struct {
+		after time.Duration
+		v     float64
+	}{{10 * time.Second, 1}, {20 * time.Second, 5}, {30 * time.Second, math.NaN()}} {
+		if err := db.Append("server/web1", base.Add(s.after), map[string]float64{"x": s.v}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := readRecords(t, dayFile(dir, TierRaw, "server/web1", base)); len(got) != 2 {
+		t.Errorf("raw records = %+v, want 2 without the NaN", got)
+	}
+
+	// The minute bucket stays open until it ends
+	minuteFile := dayFile(dir, TierMinute, "server/web1", base)
+	if err := db.flush(base.Add(59*time.Second), false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(minuteFile); !os.IsNotExist(err) {
+		t.Errorf("open minute bucket written: %v", err)
+	}
+	if err := db.flush(base.Add(time.Minute), false); err != nil {
+		t.Fatal(err)
+	}
+	want := bucket{start: base, count: 2, sum: 6, min: 1, max: 5}
+	if got := readRecords(t, minuteFile); len(got) != 1 || !sameBucket(got[0], want) {
+		t.Errorf("minute records = %+v, want %+v", got, want)
+	}
+	if _, err := os.Stat(dayFile(dir, TierHour, "server/web1", base)); !os.IsNotExist(err) {
+		t.Errorf("open hour bucket written: %v", err)
+	}
+
+	// A sample for a later bucket writes out the one before
+	db.Append("server/web1", base.Add(time.Hour), map[string]float64{"x": 3})
+	if got := readRecords(t, dayFile(dir, TierHour, "server/web1", base)); len(got) != 1 || !sameBucket(got[0], want) {
+		t.Errorf("hour records = %+v, want %+v", got, want)
+	}
+
+	if err := db.Append("server/web1", base, map[string]float64{"": 1}); err == nil {
+		t.Error("empty metric name accepted")
+	}
+}
+
+// A bucket flushed on shutdown and filled again after a restart is written
+// twice and merges on read
+func TestBucketsMergeAcrossRestart(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Now().Add(-2 * time.Hour).Truncate(time.Hour)
+	for i, values := range [][]float64{{1, 2}, {3, 10}} {
+		db, err := Open(Options{Dir: dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j, v := range values {
+			at := base.Add(time.Duration(i*2+j) * 10 * time.Second)
+			if err := db.Append("server/web1", at, map[string]float64{"x": v}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := readRecords(t, dayFile(dir, TierMinute, "server/web1", base)); len(got) != 2 {
+		t.Errorf("minute records = %+v, want one per run", got)
+	}
+	db := openTest(t, dir)
+	for _, step := range []time.Duration{time.Minute, time.Hour} {
+		res, err := db.Query("server/web1", "x", base, base.Add(time.Hour), step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []Point{{Time: base, Avg: 4, Min: 1, Max: 10, Count: 4}}
+		if len(res.Points) != 1 || !res.Points[0].Time.Equal(base) || res.Points[0].Avg != 4 ||
+			res.Points[0].Min != 1 || res.Points[0].Max != 10 || res.Points[0].Count != 4 {
+			t.Errorf("%s tier: points = %+v, want %+v", res.Tier, res.Points, want)
+		}
+	}
+}
+
+func TestDecodeRecordsPartialTail(t *testing.T) {
+	first := bucket{start: time.UnixMilli(1700000000000), count: 1, sum: 2, min: 2, max: 2}
+	second := bucket{start: time.UnixMilli(1700000010000), count: 3, sum: 9, min: 1, max: 5}
+	one := appendRecord(nil, "load1", first)
+	data := appendRecord(one, "disk_percent", second)
+
+	for cut := 0; cut <= len(data); cut++ {
+		var names []string
+		decodeRecords(data[:cut], func(name string, b bucket) { names = append(names, name) })
+		want, complete := []string(nil), 0
+		switch {
+		case cut == len(data):
+			want, complete = []string{"load1", "disk_percent"}, len(data)
+		case cut >= len(one):
+			want, complete = []string{"load1"}, len(one)
+		}
+		if !reflect.DeepEqual(names, want) {
+			t.Errorf("%d of %d bytes: decoded %v, want %v", cut, len(data), names, want)
+		}
+		if got := completeLength(data[:cut]); got != complete {
+			t.Errorf("%d of %d bytes: complete length %d, want %d", cut, len(data), got, complete)
+		}
+	}
+
+	var got bucket
+	decodeRecords(data[len(one):], func(_ string, b bucket) { got = b })
+	if !sameBucket(got, second) {
+		t.Errorf("decoded %+v, want %+v", got, second)
+	}
+}
+
+// Records appended after a crash left a partial one stay readable
+func TestPartialRecordTruncatedBeforeAppend(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Now().Add(-2 * time.Hour).Truncate(time.Hour)
+	db, err := Open(Options{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Append("server/web1", base, map[string]float64{"x": 1})
+	db.Close()
+
+	raw := dayFile(dir, TierRaw, "server/web1", base)
+	f, err := os.OpenFile(raw, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(appendRecord(nil, "x", bucket{start: base, count: 1})[:10])
+	f.Close()
+
+	db = openTest(t, dir)
+	db.Append("server/web1", base.Add(10*time.Second), map[string]float64{"x": 2})
+	db.Append("server/web1", base.Add(20*time.Second), map[string]float64{"x": 3})
+	res, err := db.Query("server/web1", "x", base, base.Add(time.Minute), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []float64
+	for _, p := range res.Points {
+		values = append(values, p.Avg)
+	}
+	if res.Tier != TierRaw || !reflect.DeepEqual(values, []float64{1, 2, 3}) {
+		t.Errorf("%s tier: values = %v, want [1 2 3]", res.Tier, values)
+	}
+}
+
+func TestRetention(t *testing.T) {
+	dir := t.TempDir()
+	db := openTest(t, dir)
+	files := map[string]bool{ // Whether each file survives
+		"raw/s/2024-05-07.dat":  false,
+		"raw/s/2024-05-08.dat":  true,
+		"raw/s/notes.txt":       true,
+		"1m/s/2024-04-25.dat":   false,
+		"1m/s/2024-04-26.dat":   true,
+		"1h/s/2023-05-10.dat":   false,
+		"1h/s/2023-05-11.dat":   true,
+		"1h/t/2024-05-10.dat":   true,
+		"1h/t/2020-01-01.dat":   false,
+		"raw/t/2024-05-10.dat":  true,
+		"1m/u/not-a-date.dat":   true,
+		"raw/u/2024-05-10.part": true,
+	}
+	for name := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Default retentions: 48 hours raw, 14 days by minute, 365 days by hour.
+	// A day file goes once it ends before the cutoff.
+	db.enforceRetention(time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC))
+	for name, kept := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if exists := err == nil; exists != kept {
+			t.Errorf("%s: exists %v, want %v", name, exists, kept)
+		}
+	}
+}
+
+func TestSeriesDir(t *testing.T) {
+	for series, want := range map[string]string{
+		"server/web1": "server%2Fweb1",
+		"vm/a b":      "vm%2Fa+b",
+		"..":          "%2E.",
+		".hidden":     "%2Ehidden",
+	} {
+		if got := seriesDir(series); got != want {
+			t.Errorf("seriesDir(%q) = %q, want %q", series, got, want)
+		}
+	}
+}
+
+func sameBucket(a, b bucket) bool {
+	return a.start.Equal(b.start) && a.count == b.count && a.sum == b.sum && a.min == b.min && a.max == b.max
+}
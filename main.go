@@ -25,6 +25,7 @@ import (
 	"server-dashboard/internal/config"
 	"server-dashboard/internal/handlers"
 	"server-dashboard/internal/middleware"
+	"server-dashboard/internal/models"
 	"server-dashboard/internal/services"
 )
 
@@ -186,6 +187,13 @@ func main() {
 		"join": func(items []string, sep string) string {
 			return strings.Join(items, sep)
 		},
+		// throughput renders bytes per second as bits per second
+		"throughput":       models.FormatThroughput,
+		"statusLabel":      models.StatusLabel,
+		"statusBadgeClass": models.StatusBadgeClass,
+		// maintenanceWindow names the active maintenance window covering a
+		// device or synthetic check, or returns ""
+		"maintenanceWindow": services.MaintenanceWindowFor,
 	}
 
 	// Load templates from embedded filesystem
@@ -249,6 +257,14 @@ func main() {
 	r.HandleFunc("/account/password", handlers.PasswordChangePageHandler(cfg, templates, configPath)).Methods("GET", "POST")
 	r.HandleFunc("/account/users/new", handlers.UserCreatePageHandler(cfg, templates, configPath)).Methods("GET", "POST")
 	r.HandleFunc("/account/groups", handlers.GroupsPageHandler(cfg, templates, configPath)).Methods("GET", "POST")
+	r.HandleFunc("/admin/host-keys", handlers.HostKeysPageHandler(cfg, templates)).Methods("GET", "POST")
+	r.HandleFunc("/admin/notifications", handlers.NotificationsPageHandler(cfg, templates)).Methods("GET", "POST")
+
+	// Agent push endpoint; authenticated per device by bearer token
+	r.HandleFunc("/api/ingest", handlers.IngestHandler(cfg)).Methods("POST")
+
+	// Prometheus exposition; scrapers authenticate with metrics.token
+	r.HandleFunc("/metrics", handlers.MetricsHandler(cfg)).Methods("GET")
 
 	// Enforce auth after public endpoints are set
 	r.Use(middleware.AuthRequired(cfg.Auth.Enabled))
@@ -315,6 +331,28 @@ func main() {
 		handlers.SyntheticHandlerWithTemplates(cfg, templates)(w, r)
 	}).Methods("GET")
 
+	// Alerts and notifications
+	r.HandleFunc("/alerts", handlers.AlertsPageHandler(cfg, templates)).Methods("GET")
+	r.HandleFunc("/api/alerts", handlers.AlertsAPIHandler()).Methods("GET")
+	r.HandleFunc("/api/notifications", handlers.NotificationsAPIHandler()).Methods("GET")
+	r.HandleFunc("/api/notifications/test", handlers.NotificationTestHandler(cfg)).Methods("POST")
+
+	// State-transition timeline
+	r.HandleFunc("/events", handlers.EventsPageHandler(cfg, templates)).Methods("GET")
+	r.HandleFunc("/api/events", handlers.EventsAPIHandler()).Methods("GET")
+
+	// Maintenance windows and silences
+	r.HandleFunc("/maintenance", handlers.MaintenancePageHandler(cfg, templates)).Methods("GET", "POST")
+	r.HandleFunc("/api/maintenance", handlers.MaintenanceAPIHandler()).Methods("GET")
+	r.HandleFunc("/api/maintenance/windows", handlers.MaintenanceWindowAPIHandler(cfg)).Methods("POST")
+	r.HandleFunc("/api/maintenance/windows/{id}", handlers.MaintenanceWindowAPIHandler(cfg)).Methods("DELETE")
+	r.HandleFunc("/api/maintenance/silences", handlers.SilenceAPIHandler(cfg)).Methods("POST")
+	r.HandleFunc("/api/maintenance/silences/{id}", handlers.SilenceAPIHandler(cfg)).Methods("DELETE")
+
+	// Device state and metrics history API
+	r.HandleFunc("/api/devices/{id}", handlers.DeviceAPIHandler()).Methods("GET")
+	r.HandleFunc("/api/devices/{id}/metrics", handlers.DeviceMetricsHandler()).Methods("GET")
+
 	// Monitoring control API endpoints
 	r.HandleFunc("/api/monitoring/status", handlers.GetMonitoringStatus).Methods("GET")
 	r.HandleFunc("/api/monitoring/start", handlers.StartMonitoring).Methods("POST")
@@ -373,6 +411,8 @@ func main() {
 			log.Fatalf("Shutdown error: %v", err)
 		}
 
+		services.CloseHistory()
+		services.CloseEvents()
 		log.Printf("Server shut down successfully")
 	}
 }
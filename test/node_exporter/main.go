@@ -0,0 +1,41 @@
+// Command node_exporter is a stand-in for Prometheus node_exporter that
+// serves a fixed exposition, for trying the node_exporter collector without
+// a real exporter:
+//
+//	go run ./test/node_exporter -addr 127.0.0.1:9100
+//
+// and point a device at it with node_exporter.url.
+package main
+
+import (
+	_ "embed"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+)
+
+//go:embed metrics.prom
+var fixture []byte
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:9100", "listen address")
+	file := flag.String("file", "", "serve this exposition instead of the built-in fixture")
+	flag.Parse()
+
+	body := fixture
+	if *file != "" {
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		body = data
+	}
+
+	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write(body)
+	})
+	log.Printf("Mock node_exporter serving /metrics on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -0,0 +1,226 @@
+// Command notify_sink is a local stand-in for the services notifications
+// are sent to, for trying notification channels without real ones:
+//
+//	go run ./test/notify_sink -secret change-me -smtp-user alerts -smtp-pass secret
+//
+// It prints every webhook, Slack/Mattermost post and email it receives.
+// Point channels at it with
+//
+//	url: "http://127.0.0.1:9099/webhook"    (any path works)
+//	smtp: {host: "127.0.0.1", port: 2525, insecure_skip_verify: true, ...}
+//
+// The SMTP side offers STARTTLS with a throwaway self-signed certificate and
+// AUTH PLAIN once TLS is up. -fail makes the first requests fail so retries
+// can be observed.
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"server-dashboard/internal/notify"
+)
+
+func main() {
+	httpAddr := flag.String("http", "127.0.0.1:9099", "webhook listen address (empty to disable)")
+	smtpAddr := flag.String("smtp", "127.0.0.1:2525", "SMTP listen address (empty to disable)")
+	secret := flag.String("secret", "", "verify webhook signatures with this secret")
+	user := flag.String("smtp-user", "", "require AUTH PLAIN with this username")
+	pass := flag.String("smtp-pass", "", "password for -smtp-user")
+	fail := flag.Int("fail", 0, "answer the first N webhook requests and emails with a temporary failure")
+	flag.Parse()
+
+	failures := int64(*fail)
+	shouldFail := func() bool { return atomic.AddInt64(&failures, -1) >= 0 }
+
+	if *smtpAddr != "" {
+		cert, err := selfSignedCert()
+		if err != nil {
+			log.Fatal(err)
+		}
+		s := &smtpSink{tls: &tls.Config{Certificates: []tls.Certificate{cert}}, user: *user, pass: *pass, fail: shouldFail}
+		ln, err := net.Listen("tcp", *smtpAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("SMTP sink listening on %s", *smtpAddr)
+		go s.serve(ln)
+	}
+
+	if *httpAddr == "" {
+		select {}
+	}
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if *secret != "" {
+			ts := r.Header.Get(notify.TimestampHeader)
+			if !notify.Verify(*secret, ts, body, r.Header.Get(notify.SignatureHeader)) {
+				log.Printf("webhook %s: bad signature", r.URL.Path)
+				http.Error(w, "bad signature", http.StatusUnauthorized)
+				return
+			}
+		}
+		if shouldFail() {
+			log.Printf("webhook %s: failing on purpose", r.URL.Path)
+			http.Error(w, "temporary failure", http.StatusServiceUnavailable)
+			return
+		}
+		log.Printf("webhook %s (%s):\n%s", r.URL.Path, r.Header.Get("Content-Type"), body)
+		fmt.Fprintln(w, "ok")
+	})
+	log.Printf("Webhook sink listening on %s", *httpAddr)
+	log.Fatal(http.ListenAndServe(*httpAddr, nil))
+}
+
+type smtpSink struct {
+	tls        *tls.Config
+	user, pass string
+	fail       func() bool
+}
+
+func (s *smtpSink) serve(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			log.Fatal(err)
+		}
+		go s.session(conn)
+	}
+}
+
+// session speaks just enough ESMTP for net/smtp and common relays' clients
+func (s *smtpSink) session(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Minute))
+	tp := textproto.NewConn(conn)
+	secure, authed := false, s.user == ""
+	var from string
+	var to []string
+
+	tp.PrintfLine("220 notify-sink ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			ext := []string{"notify-sink", "8BITMIME"}
+			if !secure {
+				ext = append(ext, "STARTTLS")
+			} else if s.user != "" {
+				ext = append(ext, "AUTH PLAIN")
+			}
+			for i, e := range ext {
+				sep := "-"
+				if i == len(ext)-1 {
+					sep = " "
+				}
+				tp.PrintfLine("250%s%s", sep, e)
+			}
+		case "STARTTLS":
+			if secure {
+				tp.PrintfLine("503 already using TLS")
+				continue
+			}
+			tp.PrintfLine("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				log.Printf("smtp: TLS handshake: %v", err)
+				return
+			}
+			conn, secure = tlsConn, true
+			tp = textproto.NewConn(conn)
+		case "AUTH":
+			mech, initial, _ := strings.Cut(arg, " ")
+			if !secure || !strings.EqualFold(mech, "PLAIN") {
+				tp.PrintfLine("504 only AUTH PLAIN over TLS")
+				continue
+			}
+			if initial == "" {
+				tp.PrintfLine("334 ")
+				if initial, err = tp.ReadLine(); err != nil {
+					return
+				}
+			}
+			creds, _ := base64.StdEncoding.DecodeString(initial)
+			parts := strings.Split(string(creds), "\x00")
+			if len(parts) == 3 && parts[1] == s.user && parts[2] == s.pass {
+				authed = true
+				tp.PrintfLine("235 authenticated")
+			} else {
+				tp.PrintfLine("535 authentication failed")
+			}
+		case "MAIL":
+			if !authed {
+				tp.PrintfLine("530 authentication required")
+				continue
+			}
+			from, to = strings.TrimPrefix(arg, "FROM:"), nil
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			to = append(to, strings.TrimPrefix(arg, "TO:"))
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 end with <CRLF>.<CRLF>")
+			data, err := io.ReadAll(tp.DotReader())
+			if err != nil {
+				return
+			}
+			if s.fail() {
+				log.Printf("smtp: failing on purpose")
+				tp.PrintfLine("451 temporary failure")
+				continue
+			}
+			log.Printf("email from %s to %s (tls=%v):\n%s", from, strings.Join(to, ", "), secure, data)
+			tp.PrintfLine("250 queued")
+		case "RSET", "NOOP":
+			tp.PrintfLine("250 ok")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// selfSignedCert makes a certificate for localhost and 127.0.0.1
+func selfSignedCert() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "notify-sink"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
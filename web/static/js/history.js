@@ -0,0 +1,152 @@
+(function() {
+  const SVG_NS = 'http://www.w3.org/2000/svg';
+  const WIDTH = 400;
+  const HEIGHT = 120;
+  const PAD = 4;
+
+  function formatValue(value, unit) {
+    if (unit === 'B/s') {
+      const units = ['B/s', 'KB/s', 'MB/s', 'GB/s'];
+      let i = 0;
+      while (Math.abs(value) >= 1024 && i < units.length - 1) {
+        value /= 1024;
+        i++;
+      }
+      return value.toFixed(1) + ' ' + units[i];
+    }
+    const digits = Math.abs(value) >= 100 ? 0 : (Math.abs(value) >= 10 ? 1 : 2);
+    return value.toFixed(digits) + (unit ? (unit === '%' ? '%' : ' ' + unit) : '');
+  }
+
+  function svgElement(name, attrs) {
+    const el = document.createElementNS(SVG_NS, name);
+    Object.keys(attrs).forEach(k => el.setAttribute(k, attrs[k]));
+    return el;
+  }
+
+  // Draws the average as a line over a band from min to max
+  function drawChart(container, data) {
+    const label = container.dataset.label;
+    const unit = container.dataset.unit || '';
+    container.innerHTML = '';
+
+    const header = document.createElement('div');
+    header.className = 'd-flex justify-content-between small mb-1';
+    const title = document.createElement('span');
+    title.className = 'fw-semibold';
+    title.textContent = label;
+    const current = document.createElement('span');
+    current.className = 'text-muted';
+    header.appendChild(title);
+    header.appendChild(current);
+    container.appendChild(header);
+
+    const points = data.points || [];
+    if (points.length === 0) {
+      const empty = document.createElement('div');
+      empty.className = 'text-muted small border rounded p-3 text-center';
+      empty.textContent = 'No data for this range';
+      container.appendChild(empty);
+      return;
+    }
+
+    const from = new Date(data.from).getTime();
+    const to = new Date(data.to).getTime();
+    let lo = Math.min(...points.map(p => p.min));
+    let hi = Math.max(...points.map(p => p.max));
+    if (unit === '%') {
+      lo = Math.min(lo, 0);
+      hi = Math.max(hi, 100);
+    }
+    if (hi === lo) {
+      hi = lo + 1;
+    }
+    const x = t => PAD + (new Date(t).getTime() - from) / (to - from) * (WIDTH - 2 * PAD);
+    const y = v => HEIGHT - PAD - (v - lo) / (hi - lo) * (HEIGHT - 2 * PAD);
+
+    const svg = svgElement('svg', {
+      viewBox: `0 0 ${WIDTH} ${HEIGHT}`,
+      class: 'w-100 border rounded',
+      preserveAspectRatio: 'none',
+      role: 'img',
+      'aria-label': label + ' history'
+    });
+
+    // Split into runs so gaps in collection show as gaps in the line
+    const gap = data.step_seconds * 1000 * 2.5;
+    const runs = [];
+    points.forEach((p, i) => {
+      if (i === 0 || new Date(p.t) - new Date(points[i - 1].t) > gap) {
+        runs.push([]);
+      }
+      runs[runs.length - 1].push(p);
+    });
+
+    runs.forEach(run => {
+      const upper = run.map(p => `${x(p.t).toFixed(1)},${y(p.max).toFixed(1)}`);
+      const lower = run.slice().reverse().map(p => `${x(p.t).toFixed(1)},${y(p.min).toFixed(1)}`);
+      svg.appendChild(svgElement('polygon', {
+        points: upper.concat(lower).join(' '),
+        fill: 'var(--bs-primary)',
+        'fill-opacity': '0.15',
+        stroke: 'none'
+      }));
+      svg.appendChild(svgElement('polyline', {
+        points: run.map(p => `${x(p.t).toFixed(1)},${y(p.avg).toFixed(1)}`).join(' '),
+        fill: 'none',
+        stroke: 'var(--bs-primary)',
+        'stroke-width': '1.5',
+        'vector-effect': 'non-scaling-stroke'
+      }));
+    });
+    container.appendChild(svg);
+
+    const last = points[points.length - 1];
+    current.textContent = formatValue(last.avg, unit);
+    container.title = `${label}: min ${formatValue(lo, unit)}, max ${formatValue(hi, unit)} ` +
+      `(${data.tier} tier, ${data.step_seconds}s steps)`;
+
+    const footer = document.createElement('div');
+    footer.className = 'd-flex justify-content-between text-muted';
+    footer.style.fontSize = '0.75rem';
+    footer.innerHTML = '<span></span><span></span>';
+    footer.children[0].textContent = new Date(from).toLocaleString();
+    footer.children[1].textContent = new Date(to).toLocaleString();
+    container.appendChild(footer);
+  }
+
+  function load(section, range) {
+    const device = encodeURIComponent(section.dataset.device);
+    const message = section.querySelector('[data-history-message]');
+    message.classList.add('d-none');
+
+    section.querySelectorAll('.history-chart').forEach(container => {
+      const metric = encodeURIComponent(container.dataset.metric);
+      fetch(`/api/devices/${device}/metrics?name=${metric}&from=-${range}`, { credentials: 'same-origin' })
+        .then(resp => {
+          if (!resp.ok) {
+            return resp.text().then(text => { throw new Error(text.trim() || resp.statusText); });
+          }
+          return resp.json();
+        })
+        .then(data => drawChart(container, data))
+        .catch(err => {
+          message.textContent = 'History unavailable: ' + err.message;
+          message.classList.remove('d-none');
+          container.innerHTML = '';
+        });
+    });
+  }
+
+  document.addEventListener('DOMContentLoaded', () => {
+    const section = document.getElementById('metric-history');
+    if (!section) return;
+    const buttons = section.querySelectorAll('[data-range]');
+    buttons.forEach(btn => btn.addEventListener('click', () => {
+      buttons.forEach(b => b.classList.toggle('active', b === btn));
+      load(section, btn.dataset.range);
+    }));
+    const active = section.querySelector('[data-range].active');
+    load(section, active ? active.dataset.range : '24h');
+  });
+})();